@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ImJafran/aeon/internal/audit"
+	"github.com/ImJafran/aeon/internal/config"
+	"github.com/ImJafran/aeon/internal/memory"
+)
+
+const auditUsage = `Usage:
+  aeon audit list [flags]     Show recent tool calls and approval decisions
+  aeon audit verify           Check the hash chain for edited or removed entries
+  aeon audit export [flags]   Write entries as JSON lines, hashes included
+      -n N         only the newest N entries (list defaults to 50)
+      -t tool      only this tool
+      -d decision  only this decision (allowed, denied, pending, approved, rejected, timeout)
+      -since age   only entries newer than this (24h, 7d) or this date (2006-01-02)
+      -o file      export to a file instead of stdout`
+
+// runAudit reads the audit log of tool calls in the database, without
+// starting the agent.
+func runAudit(args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, auditUsage)
+		os.Exit(2)
+	}
+
+	store, err := memory.NewStore(filepath.Join(config.AeonHome(), "aeon.db"))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(1)
+	}
+	defer store.Close()
+
+	log, err := audit.New(store.DB())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	ctx := context.Background()
+	switch args[0] {
+	case "list":
+		err = listAudit(ctx, log, args[1:])
+	case "verify":
+		var ok bool
+		if ok, err = verifyAudit(ctx, log); err == nil && !ok {
+			os.Exit(1)
+		}
+	case "export":
+		err = exportAudit(ctx, log, args[1:])
+	default:
+		fmt.Fprintln(os.Stderr, auditUsage)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// parseAuditFlags adds the filter flags list and export share to fs and
+// parses args.
+func parseAuditFlags(fs *flag.FlagSet, args []string, limit int) (audit.Filter, error) {
+	n := fs.Int("n", limit, "only the newest N entries")
+	tool := fs.String("t", "", "only this tool")
+	decision := fs.String("d", "", "only this decision")
+	since := fs.String("since", "", "only entries newer than this age (24h, 7d) or date (2006-01-02)")
+	fs.Parse(args)
+
+	f := audit.Filter{Tool: *tool, Decision: *decision, Limit: *n}
+	if *since != "" {
+		t, err := parseSince(*since)
+		if err != nil {
+			return f, err
+		}
+		f.Since = t
+	}
+	return f, nil
+}
+
+// parseSince reads an age (90m, 24h, 7d) or a date (2006-01-02).
+func parseSince(s string) (time.Time, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		if d, err := time.ParseDuration(days + "h"); err == nil {
+			return time.Now().Add(-24 * d), nil
+		}
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid -since %q (want an age like 24h or 7d, or a date like 2006-01-02)", s)
+}
+
+func listAudit(ctx context.Context, log *audit.Log, args []string) error {
+	f, err := parseAuditFlags(flag.NewFlagSet("audit list", flag.ExitOnError), args, 50)
+	if err != nil {
+		return err
+	}
+	entries, err := log.List(ctx, f)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		fmt.Println("No audit entries.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tTIME\tTOOL\tDECISION\tFROM\tAPPROVER\tDURATION\tEXIT\tARGS")
+	for _, e := range entries {
+		from := e.Channel
+		if e.UserID != "" {
+			from += ":" + e.UserID
+		}
+		exit := "-"
+		if e.ExitCode != nil {
+			exit = fmt.Sprint(*e.ExitCode)
+		} else if e.IsError {
+			exit = "error"
+		}
+		args := strings.Join(strings.Fields(e.Args), " ")
+		if len(args) > 60 {
+			args = strings.ToValidUTF8(args[:60], "") + "…"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", e.ID, e.Time.Local().Format("2006-01-02 15:04:05"),
+			e.Tool, e.Decision, dash(from), dash(e.Approver), time.Duration(e.DurationMS)*time.Millisecond, exit, args)
+	}
+	return w.Flush()
+}
+
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// verifyAudit checks the chain and reports whether it's intact.
+func verifyAudit(ctx context.Context, log *audit.Log) (bool, error) {
+	v, err := log.Verify(ctx)
+	if err != nil {
+		return false, err
+	}
+	switch {
+	case v.BrokenAt != 0:
+		fmt.Printf("FAILED at entry %d: %s.\n", v.BrokenAt, v.Problem)
+		fmt.Printf("%d entries before it check out.\n", v.Entries-1)
+	case !v.OK():
+		fmt.Printf("FAILED: %s.\n", v.Problem)
+	case v.Entries == 0:
+		fmt.Println("OK: the audit log is empty.")
+	default:
+		fmt.Printf("OK: %d entries, chain intact.\n", v.Entries)
+		fmt.Printf("Head: %s\n", v.Head)
+		fmt.Println("Keep the head hash somewhere else to detect the whole log being rewritten later.")
+	}
+	return v.OK(), nil
+}
+
+func exportAudit(ctx context.Context, log *audit.Log, args []string) error {
+	fs := flag.NewFlagSet("audit export", flag.ExitOnError)
+	out := fs.String("o", "", "output file (default: stdout)")
+	f, err := parseAuditFlags(fs, args, 0)
+	if err != nil {
+		return err
+	}
+
+	w := io.Writer(os.Stdout)
+	if *out != "" {
+		file, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+	n, err := log.Export(ctx, w, f)
+	if err == nil && *out != "" {
+		fmt.Printf("Exported %d audit entries to %s.\n", n, *out)
+	}
+	return err
+}
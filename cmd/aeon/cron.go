@@ -0,0 +1,133 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ImJafran/aeon/internal/config"
+	"github.com/ImJafran/aeon/internal/memory"
+	"github.com/ImJafran/aeon/internal/scheduler"
+)
+
+const cronUsage = `Usage:
+  aeon cron list                      List scheduled jobs
+  aeon cron history [-n N] [job-id]   Show recent runs, of one job or all`
+
+// runCron inspects scheduled jobs and their run history in the database,
+// without starting the agent.
+func runCron(args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, cronUsage)
+		os.Exit(2)
+	}
+
+	store, err := memory.NewStore(filepath.Join(config.AeonHome(), "aeon.db"))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(1)
+	}
+	defer store.Close()
+
+	sched, err := scheduler.New(store.DB(), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "list":
+		err = printCronJobs(sched)
+	case "history":
+		fs := flag.NewFlagSet("cron history", flag.ExitOnError)
+		limit := fs.Int("n", 20, "number of runs to show")
+		fs.Parse(args[1:])
+		var id int64
+		if fs.NArg() > 0 {
+			if id, err = strconv.ParseInt(fs.Arg(0), 10, 64); err != nil {
+				fmt.Fprintf(os.Stderr, "Invalid job id %q\n", fs.Arg(0))
+				os.Exit(2)
+			}
+		}
+		err = printCronHistory(sched, id, *limit)
+	default:
+		fmt.Fprintln(os.Stderr, cronUsage)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func printCronJobs(sched *scheduler.Scheduler) error {
+	jobs, err := sched.List(false)
+	if err != nil {
+		return err
+	}
+	if len(jobs) == 0 {
+		fmt.Println("No scheduled jobs.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tTYPE\tSCHEDULE\tSTATUS\tNEXT RUN\tFAILS")
+	for _, j := range jobs {
+		status := "enabled"
+		if j.AutoPaused() {
+			status = "auto-paused"
+		} else if !j.Enabled {
+			status = "paused"
+		}
+		schedule := j.Schedule
+		if j.Timezone != "" {
+			schedule += " (" + j.Timezone + ")"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%d\n", j.ID, j.Name, j.JobKind(), schedule, status,
+			j.NextRun.Local().Format("2006-01-02 15:04"), j.FailCount)
+	}
+	return w.Flush()
+}
+
+func printCronHistory(sched *scheduler.Scheduler, id int64, limit int) error {
+	if id > 0 {
+		job, err := sched.Get(id)
+		if err != nil {
+			return fmt.Errorf("job %d not found", id)
+		}
+		fmt.Printf("Job #%d %s — %s, %d consecutive failures\n", job.ID, job.Name, job.Schedule, job.FailCount)
+		if job.AutoPaused() {
+			fmt.Println("Auto-paused after repeated failures; fix the cause, then resume it.")
+		}
+		fmt.Println()
+	}
+
+	runs, err := sched.History(id, limit)
+	if err != nil {
+		return err
+	}
+	if len(runs) == 0 {
+		fmt.Println("No runs recorded.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "STARTED\tJOB\tTRIGGER\tSTATUS\tDURATION\tERROR")
+	for _, r := range runs {
+		duration := "-"
+		if d := r.Duration(); d > 0 {
+			duration = d.Round(time.Millisecond).String()
+		}
+		errText, _, _ := strings.Cut(r.Error, "\n")
+		fmt.Fprintf(w, "%s\t%d %s\t%s\t%s\t%s\t%s\n", r.StartedAt.Local().Format("2006-01-02 15:04:05"),
+			r.JobID, r.JobName, r.Trigger, r.Status, duration, errText)
+	}
+	return w.Flush()
+}
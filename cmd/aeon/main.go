@@ -17,6 +17,8 @@ import (
 	"github.com/ImJafran/aeon/internal/bus"
 	"github.com/ImJafran/aeon/internal/channels"
 	"github.com/ImJafran/aeon/internal/config"
+	"github.com/ImJafran/aeon/internal/mcp"
+	"github.com/ImJafran/aeon/internal/sandbox"
 )
 
 const shutdownTimeout = 10 * time.Second
@@ -24,6 +26,10 @@ const shutdownTimeout = 10 * time.Second
 var version = "0.0.2-beta"
 
 func main() {
+	// Sandboxed commands start as this binary; set up the sandbox and exec them
+	sandbox.Init()
+	mcp.ClientVersion = version
+
 	if len(os.Args) > 1 {
 		switch os.Args[1] {
 		case "init":
@@ -38,6 +44,24 @@ func main() {
 		case "serve":
 			runServe()
 			return
+		case "mcp":
+			runMCP(os.Args[2:])
+			return
+		case "cron":
+			runCron(os.Args[2:])
+			return
+		case "memory":
+			runMemory(os.Args[2:])
+			return
+		case "policy":
+			runPolicy(os.Args[2:])
+			return
+		case "audit":
+			runAudit(os.Args[2:])
+			return
+		case "secrets":
+			runSecrets(os.Args[2:])
+			return
 		case "uninstall":
 			runUninstall()
 			return
@@ -225,6 +249,8 @@ func runInteractive() {
 	// Setup and start scheduler
 	deps.SetupSchedulerTrigger()
 	deps.StartScheduler(ctx)
+	deps.StartEmbeddingBackfill(ctx)
+	deps.WatchPolicy(ctx)
 
 	// Print banner
 	home := config.AeonHome()
@@ -248,17 +274,9 @@ func runInteractive() {
 	activeChannels = append(activeChannels, cli)
 
 	// Start additional enabled channels
-	if cfg.Channels.Telegram != nil && cfg.Channels.Telegram.Enabled && cfg.Channels.Telegram.BotToken != "" {
-		tg := channels.NewTelegram(cfg.Channels.Telegram.BotToken, cfg.Channels.Telegram.AllowedUsers, logger)
-		if cfg.Provider.Gemini != nil && cfg.Provider.Gemini.APIKey != "" {
-			tg.SetTranscriber(channels.NewGeminiTranscriber(cfg.Provider.Gemini.APIKey, cfg.Provider.Gemini.DefaultModel))
-		}
-		if err := tg.Start(ctx, deps.Bus); err != nil {
-			logger.Error("failed to start telegram channel", "error", err)
-		} else {
-			logger.Info("telegram channel started")
-			activeChannels = append(activeChannels, tg)
-		}
+	if tg := startTelegram(cfg, ctx, deps.Bus, logger); tg != nil {
+		logger.Info("telegram channel started")
+		activeChannels = append(activeChannels, tg)
 	}
 
 	startOptionalChannels(cfg, ctx, deps.Bus, logger, &activeChannels)
@@ -325,22 +343,16 @@ func runServe() {
 	// Setup and start scheduler
 	deps.SetupSchedulerTrigger()
 	deps.StartScheduler(ctx)
+	deps.StartEmbeddingBackfill(ctx)
+	deps.WatchPolicy(ctx)
 
 	// Start all enabled channels
 	var activeChannels []stoppable
 	var channelNames []string
 
-	if cfg.Channels.Telegram != nil && cfg.Channels.Telegram.Enabled && cfg.Channels.Telegram.BotToken != "" {
-		tg := channels.NewTelegram(cfg.Channels.Telegram.BotToken, cfg.Channels.Telegram.AllowedUsers, logger)
-		if cfg.Provider.Gemini != nil && cfg.Provider.Gemini.APIKey != "" {
-			tg.SetTranscriber(channels.NewGeminiTranscriber(cfg.Provider.Gemini.APIKey, cfg.Provider.Gemini.DefaultModel))
-		}
-		if err := tg.Start(ctx, deps.Bus); err != nil {
-			logger.Error("failed to start telegram channel", "error", err)
-		} else {
-			activeChannels = append(activeChannels, tg)
-			channelNames = append(channelNames, "telegram")
-		}
+	if tg := startTelegram(cfg, ctx, deps.Bus, logger); tg != nil {
+		activeChannels = append(activeChannels, tg)
+		channelNames = append(channelNames, "telegram")
 	}
 
 	startOptionalChannelsWithNames(cfg, ctx, deps.Bus, logger, &activeChannels, &channelNames)
@@ -442,12 +454,36 @@ func printUsage() {
 	fmt.Println("Usage:")
 	fmt.Println("  aeon              Start interactive CLI mode")
 	fmt.Println("  aeon serve        Start daemon mode (all enabled channels)")
+	fmt.Println("  aeon mcp          Serve Aeon's tools over MCP (stdio; --http for HTTP)")
+	fmt.Println("  aeon cron         List scheduled jobs and their run history")
+	fmt.Println("  aeon memory       Inspect, edit, export, import and consolidate memory")
+	fmt.Println("  aeon policy       Show the security policy rules and test tool calls against them")
+	fmt.Println("  aeon audit        List, verify and export the audit log of tool calls")
+	fmt.Println("  aeon secrets      Store API keys and tokens in an encrypted vault")
 	fmt.Println("  aeon init         First-time setup wizard")
 	fmt.Println("  aeon uninstall    Remove Aeon completely (binary, data, service)")
 	fmt.Println("  aeon version      Show version")
 	fmt.Println("  aeon help         Show this help")
 }
 
+// startTelegram starts the Telegram channel if it is enabled. Returns nil if
+// it is not configured or fails to start.
+func startTelegram(cfg *config.Config, ctx context.Context, msgBus *bus.MessageBus, logger *slog.Logger) stoppable {
+	c := cfg.Channels.Telegram
+	if c == nil || !c.Enabled || c.BotToken == "" {
+		return nil
+	}
+	tg := channels.NewTelegram(c.BotToken, c.AllowedUsers, logger)
+	if cfg.Provider.Gemini != nil && cfg.Provider.Gemini.APIKey != "" {
+		tg.SetTranscriber(channels.NewGeminiTranscriber(cfg.Provider.Gemini.APIKey, cfg.Provider.Gemini.DefaultModel))
+	}
+	if err := tg.Start(ctx, msgBus); err != nil {
+		logger.Error("failed to start telegram channel", "error", err)
+		return nil
+	}
+	return tg
+}
+
 // startOptionalChannels starts all non-Telegram optional channels.
 func startOptionalChannels(cfg *config.Config, ctx context.Context, msgBus *bus.MessageBus, logger *slog.Logger, activeChannels *[]stoppable) {
 	var names []string
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/ImJafran/aeon/internal/bootstrap"
+	"github.com/ImJafran/aeon/internal/bus"
+	"github.com/ImJafran/aeon/internal/config"
+	"github.com/ImJafran/aeon/internal/mcp"
+	"github.com/ImJafran/aeon/internal/tools"
+)
+
+const mcpInstructions = "Aeon is a persistent agent on this machine. Its tools run shell commands, read and edit files, " +
+	"store and recall long-term memories, schedule cron jobs and run evolved skills. Dangerous commands may need the owner's approval."
+
+// runMCP serves the tool registry over MCP: stdio by default, or streamable
+// HTTP with --http. stdout is reserved for the protocol in stdio mode.
+func runMCP(args []string) {
+	fs := flag.NewFlagSet("mcp", flag.ExitOnError)
+	useHTTP := fs.Bool("http", false, "serve streamable HTTP instead of stdio")
+	addr := fs.String("addr", "", "HTTP listen address (default: mcp.serve.listen_addr)")
+	fs.Parse(args)
+
+	cfgPath := config.DefaultConfigPath()
+	if _, err := os.Stat(cfgPath); os.IsNotExist(err) {
+		fmt.Fprintln(os.Stderr, "No config found. Run 'aeon init' first.")
+		os.Exit(1)
+	}
+
+	cfg, err := config.Load(cfgPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	logger, closeLog := setupLogger(cfg)
+	defer closeLog()
+
+	deps, err := bootstrap.BuildDeps(cfg, logger)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer deps.Close()
+	deps.WatchPolicy(ctx)
+
+	handler := tools.NewMCPServerHandler(deps.Registry, deps.SecAdapter)
+	handler.SetAllowedTools(cfg.MCP.Serve.Tools)
+
+	if cfg.MCP.Serve.ApprovalChannel != "" || cfg.Security.ApprovalChannel != "" {
+		stop, err := startApprovalRelay(ctx, cfg, deps, handler)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		defer stop()
+	}
+
+	server := mcp.NewServer("aeon", version, handler, logger)
+	server.SetInstructions(mcpInstructions)
+
+	if !*useHTTP {
+		logger.Info("mcp server ready", "transport", "stdio", "tools", len(handler.Tools()))
+		if err := server.ServeStdio(ctx, os.Stdin, os.Stdout); err != nil && !errors.Is(err, context.Canceled) {
+			logger.Error("mcp stdio server stopped", "error", err)
+		}
+		return
+	}
+
+	listen := cfg.MCP.Serve.ListenAddr
+	if *addr != "" {
+		listen = *addr
+	}
+	if cfg.MCP.Serve.AuthToken == "" && !loopbackAddr(listen) {
+		fmt.Fprintf(os.Stderr, "Error: set mcp.serve.auth_token to serve MCP on %s; without one, only loopback addresses such as 127.0.0.1:8765 are allowed\n", listen)
+		os.Exit(1)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/mcp", server.HTTPHandler(cfg.MCP.Serve.AuthToken, cfg.MCP.Serve.AllowedOrigins))
+	srv := &http.Server{Addr: listen, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, done := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer done()
+		srv.Shutdown(shutdownCtx)
+	}()
+
+	fmt.Fprintf(os.Stderr, "🌱 Aeon v%s — MCP server on http://%s/mcp (%d tools)\n", version, listen, len(handler.Tools()))
+	if cfg.MCP.Serve.AuthToken == "" {
+		logger.Warn("mcp http server has no auth_token; any local process can run tools", "addr", listen)
+	}
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// loopbackAddr reports whether a listen address only accepts local
+// connections. An empty host listens on every interface.
+func loopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	return err == nil && mcp.IsLoopback(host)
+}
+
+// startApprovalRelay starts the approval channel, mcp.serve's or else the
+// security one, and escalates calls that need approval to it. Approvals,
+// /pending and who may answer work as in the agent; anything else gets a
+// short explanation.
+func startApprovalRelay(ctx context.Context, cfg *config.Config, deps *bootstrap.Deps, handler *tools.MCPServerHandler) (func(), error) {
+	msgBus, logger := deps.Bus, deps.Logger
+	name, chatID := cfg.MCP.Serve.ApprovalChannel, cfg.MCP.Serve.ApprovalChatID
+	if name == "" {
+		name, chatID = cfg.Security.ApprovalChannel, cfg.Security.ApprovalChatID
+	}
+
+	// Start just the approval channel, not every enabled one
+	only := *cfg
+	only.Channels = config.ChannelsConfig{}
+	var started []stoppable
+	switch name {
+	case "telegram":
+		only.Channels.Telegram = cfg.Channels.Telegram
+		if tg := startTelegram(&only, ctx, msgBus, logger); tg != nil {
+			started = append(started, tg)
+		}
+	default:
+		only.Channels.Webhook = pick(name == "webhook", cfg.Channels.Webhook)
+		only.Channels.WebSocket = pick(name == "websocket", cfg.Channels.WebSocket)
+		only.Channels.Discord = pick(name == "discord", cfg.Channels.Discord)
+		only.Channels.Slack = pick(name == "slack", cfg.Channels.Slack)
+		only.Channels.Email = pick(name == "email", cfg.Channels.Email)
+		only.Channels.WhatsApp = pick(name == "whatsapp", cfg.Channels.WhatsApp)
+		startOptionalChannels(&only, ctx, msgBus, logger, &started)
+	}
+	if len(started) == 0 {
+		return nil, fmt.Errorf("approval channel %q is not enabled or failed to start", name)
+	}
+
+	gate := deps.Approvals
+	gate.SetApprovalChat(name, chatID)
+	handler.SetApprover(func(ctx context.Context, description string) (bool, string, error) {
+		logger.Info("mcp_approval_requested", "channel", name, "info", description)
+		approved, approver, err := gate.RequestApproval(ctx, "", "", "MCP tool call\n"+description)
+		logger.Info("mcp_approval_resolved", "approved", approved, "approver", approver, "error", err)
+		return approved, approver, err
+	})
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-msgBus.Inbound():
+				if !ok {
+					return
+				}
+				if gate.HandleMessage(msg) {
+					continue
+				}
+				msgBus.Send(bus.OutboundMessage{
+					Channel: msg.Channel,
+					ChatID:  msg.ChatID,
+					Content: "This Aeon instance is serving MCP and only handles /approve, /deny and /pending for tool calls.",
+				})
+			}
+		}
+	}()
+
+	logger.Info("mcp approvals routed", "channel", name, "chat_id", chatID)
+	return func() {
+		for _, ch := range started {
+			ch.Stop()
+		}
+	}, nil
+}
+
+// pick returns c if keep is true, nil otherwise.
+func pick[T any](keep bool, c *T) *T {
+	if keep {
+		return c
+	}
+	return nil
+}
@@ -0,0 +1,471 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ImJafran/aeon/internal/agent"
+	"github.com/ImJafran/aeon/internal/bootstrap"
+	"github.com/ImJafran/aeon/internal/config"
+	"github.com/ImJafran/aeon/internal/memory"
+	"github.com/ImJafran/aeon/internal/providers"
+)
+
+const memoryUsage = `Usage:
+  aeon memory list [-c category] [-n N]  List the newest memories
+  aeon memory search [-n N] <query>      Search memories by keyword and meaning
+  aeon memory show <memory-id>           Show a memory with its provenance and history
+  aeon memory add [flags] <content>      Store a memory (-c category, -t tags, -i importance, -s scope)
+  aeon memory edit [flags] <id> [text]   Edit a memory, keeping the old version (same flags as add, except -s)
+  aeon memory forget <memory-id>         Delete a memory
+  aeon memory export [-f jsonl|markdown] [-c category] [-o file]
+                                         Export memories (stdout by default)
+  aeon memory import [-f jsonl|markdown] <file|->
+                                         Import memories, skipping ones already stored
+  aeon memory stats                      Count memories by category and scope
+  aeon memory consolidate [--dry-run]    Merge old memories and prune stale ones now
+  aeon memory consolidations [-n N]      Show what consolidation merged and pruned
+  aeon memory undo <memory-id>           Restore the originals of a merged memory, or a pruned memory`
+
+// runMemory works on the memory database directly, without starting the agent.
+func runMemory(args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, memoryUsage)
+		os.Exit(2)
+	}
+
+	store, err := memory.NewStore(filepath.Join(config.AeonHome(), "aeon.db"))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(1)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	switch args[0] {
+	case "list":
+		fs := flag.NewFlagSet("memory list", flag.ExitOnError)
+		category := fs.String("c", "", "only this category")
+		limit := fs.Int("n", 20, "number of memories to show")
+		fs.Parse(args[1:])
+		var entries []memory.Entry
+		if entries, err = store.List(ctx, memory.Category(*category), *limit); err == nil {
+			err = printMemories(entries)
+		}
+	case "search":
+		fs := flag.NewFlagSet("memory search", flag.ExitOnError)
+		limit := fs.Int("n", 10, "number of results")
+		fs.Parse(args[1:])
+		if fs.NArg() == 0 {
+			fmt.Fprintln(os.Stderr, memoryUsage)
+			os.Exit(2)
+		}
+		setEmbedder(store)
+		var entries []memory.Entry
+		if entries, err = store.RecallText(ctx, strings.Join(fs.Args(), " "), *limit); err == nil {
+			err = printMemories(entries)
+		}
+	case "show":
+		err = showMemory(ctx, store, memoryID(args[1:]))
+	case "add":
+		err = addMemory(ctx, store, args[1:])
+	case "edit":
+		err = editMemory(ctx, store, args[1:])
+	case "forget":
+		id := memoryID(args[1:])
+		var e *memory.Entry
+		if e, err = store.Get(ctx, id); err != nil {
+			err = fmt.Errorf("memory %d not found", id)
+		} else if err = store.Forget(ctx, id); err == nil {
+			fmt.Printf("Forgot #%d: %s\n", id, e.Content)
+		}
+	case "export":
+		err = exportMemories(ctx, store, args[1:])
+	case "import":
+		err = importMemories(ctx, store, args[1:])
+	case "stats":
+		err = printMemoryStats(ctx, store)
+	case "consolidate":
+		fs := flag.NewFlagSet("memory consolidate", flag.ExitOnError)
+		dryRun := fs.Bool("dry-run", false, "report what would be merged or pruned without changing anything")
+		fs.Parse(args[1:])
+		err = consolidateMemory(ctx, store, *dryRun)
+	case "consolidations":
+		fs := flag.NewFlagSet("memory consolidations", flag.ExitOnError)
+		limit := fs.Int("n", 50, "number of records to show")
+		fs.Parse(args[1:])
+		err = printConsolidations(ctx, store, *limit)
+	case "undo":
+		var n int
+		if n, err = store.UndoConsolidation(ctx, memoryID(args[1:])); err == nil {
+			fmt.Printf("Restored %d memories.\n", n)
+		}
+	default:
+		fmt.Fprintln(os.Stderr, memoryUsage)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// consolidateMemory runs consolidation once. A real run summarizes with the
+// configured providers' fast route; a dry run needs no provider.
+func consolidateMemory(ctx context.Context, store *memory.Store, dryRun bool) error {
+	cfg, err := config.Load(config.DefaultConfigPath())
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+
+	var summarize memory.SummarizeFunc
+	if !dryRun {
+		provider, err := providers.FromConfig(cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+		if err != nil {
+			return fmt.Errorf("consolidation needs a provider: %w", err)
+		}
+		costs := agent.NewCostTracker()
+		costs.SetStore(store)
+		costs.SetPrices(cfg.Pricing)
+		summarize = bootstrap.MemorySummarizer(provider, costs)
+	}
+
+	c := memory.NewConsolidator(store, summarize)
+	c.SetMinCandidates(cfg.Memory.CompactionThreshold)
+	report, err := c.Run(ctx, dryRun)
+	fmt.Println(report.String())
+	return err
+}
+
+func printConsolidations(ctx context.Context, store *memory.Store, limit int) error {
+	records, err := store.ConsolidationLog(ctx, limit)
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		fmt.Println("Nothing has been consolidated.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "WHEN\tACTION\tORIGINAL\tINTO\tSTATUS\tCONTENT")
+	for _, r := range records {
+		into, status := "-", "applied"
+		if r.MemoryID > 0 {
+			into = fmt.Sprintf("#%d", r.MemoryID)
+		}
+		if r.UndoneAt != nil {
+			status = "undone"
+		}
+		content, _, _ := strings.Cut(r.Original.Content, "\n")
+		if runes := []rune(content); len(runes) > 60 {
+			content = string(runes[:60]) + "..."
+		}
+		fmt.Fprintf(w, "%s\t%s\t#%d [%s]\t%s\t%s\t%s\n", r.ConsolidatedAt.Local().Format("2006-01-02 15:04"),
+			r.Action, r.Original.ID, r.Original.Category, into, status, content)
+	}
+	return w.Flush()
+}
+
+// memoryID parses the single memory ID argument, exiting on bad usage.
+func memoryID(args []string) int64 {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, memoryUsage)
+		os.Exit(2)
+	}
+	id, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid memory id %q\n", args[0])
+		os.Exit(2)
+	}
+	return id
+}
+
+// setEmbedder enables semantic search and embeds what the CLI stores, with
+// the configured embedder. Without a readable config, memory stays keyword-only.
+func setEmbedder(store *memory.Store) {
+	cfg, err := config.Load(config.DefaultConfigPath())
+	if err != nil {
+		return
+	}
+	if embedder := bootstrap.NewEmbedder(cfg.Memory.Embeddings); embedder != nil {
+		store.SetEmbedder(embedder, cfg.Memory.Embeddings.MinSimilarity)
+	}
+}
+
+func printMemories(entries []memory.Entry) error {
+	if len(entries) == 0 {
+		fmt.Println("No memories found.")
+		return nil
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tCATEGORY\tSCOPE\tCREATED\tTAGS\tCONTENT")
+	for _, e := range entries {
+		content, _, _ := strings.Cut(e.Content, "\n")
+		if runes := []rune(content); len(runes) > 70 {
+			content = string(runes[:70]) + "..."
+		}
+		tags := e.Tags
+		if tags == "" {
+			tags = "-"
+		}
+		fmt.Fprintf(w, "#%d\t%s\t%s\t%s\t%s\t%s\n", e.ID, e.Category, e.Scope,
+			e.CreatedAt.Local().Format("2006-01-02 15:04"), tags, content)
+	}
+	return w.Flush()
+}
+
+func showMemory(ctx context.Context, store *memory.Store, id int64) error {
+	e, err := store.Get(ctx, id)
+	if err != nil {
+		return fmt.Errorf("memory %d not found", id)
+	}
+	fmt.Printf("Memory #%d (version %d)\n\n%s\n\n", e.ID, e.Version, e.Content)
+	fmt.Printf("Category:    %s\n", e.Category)
+	fmt.Printf("Tags:        %s\n", e.Tags)
+	fmt.Printf("Importance:  %.2f\n", e.Importance)
+	fmt.Printf("Scope:       %s\n", e.Scope)
+	fmt.Printf("Created:     %s\n", e.CreatedAt.Local().Format("2006-01-02 15:04"))
+	fmt.Printf("Accessed:    %s (%d times)\n", e.AccessedAt.Local().Format("2006-01-02 15:04"), e.AccessCount)
+	if src := e.Source; src.Channel != "" || src.Session != "" {
+		from := []string{src.Channel}
+		if src.UserID != "" {
+			from = append(from, "user "+src.UserID)
+		}
+		if src.Session != "" {
+			from = append(from, "session "+src.Session)
+		}
+		fmt.Printf("Source:      %s\n", strings.Join(from, ", "))
+		if src.Message != "" {
+			fmt.Printf("Message:     %s\n", src.Message)
+		}
+	}
+	if e.Supersedes > 0 {
+		fmt.Printf("Supersedes:  #%d\n", e.Supersedes)
+	}
+
+	versions, err := store.Versions(ctx, id)
+	if err != nil || len(versions) == 0 {
+		return err
+	}
+	fmt.Println("\nHistory:")
+	for _, v := range versions {
+		how := "edited"
+		if v.ReplacedBy > 0 {
+			how = fmt.Sprintf("superseded by #%d", v.ReplacedBy)
+		}
+		fmt.Printf("  #%d v%d, %s %s: %s\n", v.MemoryID, v.Version, how,
+			v.ReplacedAt.Local().Format("2006-01-02 15:04"), v.Content)
+	}
+	return nil
+}
+
+// memoryFlags are the fields add and edit accept.
+type memoryFlags struct {
+	fs         *flag.FlagSet
+	category   *string
+	tags       *string
+	importance *float64
+}
+
+func newMemoryFlags(name string) memoryFlags {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	return memoryFlags{
+		fs:         fs,
+		category:   fs.String("c", "", "category: core, daily, conversation, lesson, correction or custom"),
+		tags:       fs.String("t", "", "comma-separated tags"),
+		importance: fs.Float64("i", 0, "importance 0-1 (default: by category)"),
+	}
+}
+
+func (f memoryFlags) validate() error {
+	switch memory.Category(*f.category) {
+	case "", memory.CategoryCore, memory.CategoryDaily, memory.CategoryConversation,
+		memory.CategoryLesson, memory.CategoryCorrection, memory.CategoryCustom:
+	default:
+		return fmt.Errorf("unknown category %q", *f.category)
+	}
+	if *f.importance < 0 || *f.importance > 1 {
+		return fmt.Errorf("importance must be between 0 and 1")
+	}
+	return nil
+}
+
+func addMemory(ctx context.Context, store *memory.Store, args []string) error {
+	f := newMemoryFlags("memory add")
+	scopeFlag := f.fs.String("s", "global", "scope: global, user:<channel>:<user id> or chat:<channel>:<chat id>")
+	f.fs.Parse(args)
+	content := strings.TrimSpace(strings.Join(f.fs.Args(), " "))
+	if content == "" {
+		fmt.Fprintln(os.Stderr, memoryUsage)
+		os.Exit(2)
+	}
+	if err := f.validate(); err != nil {
+		return err
+	}
+	scope, err := memory.ParseScope(*scopeFlag)
+	if err != nil {
+		return err
+	}
+
+	setEmbedder(store)
+	id, err := store.Save(ctx, memory.Entry{
+		Category:   memory.Category(*f.category),
+		Content:    content,
+		Tags:       *f.tags,
+		Importance: *f.importance,
+		Scope:      scope,
+		Source:     memory.Provenance{Channel: "cli"},
+	})
+	if err == nil {
+		fmt.Printf("Stored memory #%d.\n", id)
+	}
+	return err
+}
+
+func editMemory(ctx context.Context, store *memory.Store, args []string) error {
+	f := newMemoryFlags("memory edit")
+	f.fs.Parse(args)
+	if f.fs.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, memoryUsage)
+		os.Exit(2)
+	}
+	id := memoryID(f.fs.Args()[:1])
+	content := strings.TrimSpace(strings.Join(f.fs.Args()[1:], " "))
+	if err := f.validate(); err != nil {
+		return err
+	}
+	if content == "" && *f.category == "" && *f.tags == "" && *f.importance == 0 {
+		return fmt.Errorf("nothing to change: give new text, -c, -t or -i")
+	}
+
+	setEmbedder(store)
+	version, err := store.Update(ctx, id, memory.Change{
+		Category:   memory.Category(*f.category),
+		Content:    content,
+		Tags:       *f.tags,
+		Importance: *f.importance,
+		Source:     memory.Provenance{Channel: "cli"},
+	})
+	if err == nil {
+		fmt.Printf("Updated memory #%d to version %d.\n", id, version)
+	}
+	return err
+}
+
+// memoryFormat picks the export/import format: the flag if set, else
+// Markdown for .md files and JSONL otherwise.
+func memoryFormat(flagValue, path string) (string, error) {
+	switch flagValue {
+	case memory.FormatJSONL, memory.FormatMarkdown:
+		return flagValue, nil
+	case "md":
+		return memory.FormatMarkdown, nil
+	case "":
+		if ext := strings.ToLower(filepath.Ext(path)); ext == ".md" || ext == ".markdown" {
+			return memory.FormatMarkdown, nil
+		}
+		return memory.FormatJSONL, nil
+	}
+	return "", fmt.Errorf("unknown format %q (jsonl or markdown)", flagValue)
+}
+
+func exportMemories(ctx context.Context, store *memory.Store, args []string) error {
+	fs := flag.NewFlagSet("memory export", flag.ExitOnError)
+	formatFlag := fs.String("f", "", "jsonl or markdown (default: from -o, else jsonl)")
+	category := fs.String("c", "", "only this category")
+	out := fs.String("o", "", "output file (default: stdout)")
+	fs.Parse(args)
+	format, err := memoryFormat(*formatFlag, *out)
+	if err != nil {
+		return err
+	}
+
+	w := io.Writer(os.Stdout)
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	n, err := store.Export(ctx, w, format, memory.Category(*category))
+	if err == nil && *out != "" {
+		fmt.Printf("Exported %d memories to %s.\n", n, *out)
+	}
+	return err
+}
+
+func importMemories(ctx context.Context, store *memory.Store, args []string) error {
+	fs := flag.NewFlagSet("memory import", flag.ExitOnError)
+	formatFlag := fs.String("f", "", "jsonl or markdown (default: from the file name, else jsonl)")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, memoryUsage)
+		os.Exit(2)
+	}
+	path := fs.Arg(0)
+	format, err := memoryFormat(*formatFlag, path)
+	if err != nil {
+		return err
+	}
+
+	r := io.Reader(os.Stdin)
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	setEmbedder(store)
+	result, err := store.Import(ctx, r, format)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Imported %d memories, skipped %d already stored.\n", result.Added, result.Duplicates)
+	return nil
+}
+
+func printMemoryStats(ctx context.Context, store *memory.Store) error {
+	setEmbedder(store)
+	st, err := store.Stats(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Memories:      %d\n", st.Total)
+	if st.Total == 0 {
+		return nil
+	}
+	fmt.Printf("Oldest:        %s\n", st.Oldest.Local().Format("2006-01-02"))
+	fmt.Printf("Newest:        %s\n", st.Newest.Local().Format("2006-01-02"))
+	fmt.Printf("Embedded:      %d\n", st.Embedded)
+	fmt.Printf("Old versions:  %d\n", st.Versions)
+	fmt.Printf("Consolidated:  %d\n", st.Consolidated)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "\nCATEGORY\tCOUNT")
+	for _, c := range []memory.Category{memory.CategoryCore, memory.CategoryCorrection, memory.CategoryLesson,
+		memory.CategoryDaily, memory.CategoryConversation, memory.CategoryCustom} {
+		if n := st.ByCategory[c]; n > 0 {
+			fmt.Fprintf(w, "%s\t%d\n", c, n)
+		}
+	}
+	fmt.Fprintln(w, "\nSCOPE\tCOUNT")
+	for _, kind := range []string{memory.KindGlobal, memory.KindUser, memory.KindChat} {
+		if n := st.ByScope[kind]; n > 0 {
+			fmt.Fprintf(w, "%s\t%d\n", kind, n)
+		}
+	}
+	return w.Flush()
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ImJafran/aeon/internal/config"
+	"github.com/ImJafran/aeon/internal/security"
+)
+
+const policyUsage = `Usage:
+  aeon policy show [-f file]                      List the policy file's rules in order
+  aeon policy test [flags] <tool> [json-args]     Show which rule decides a tool call, and the outcome
+      -u user     user ID the call comes from
+      -c channel  channel the call comes from (telegram, discord, cli, scheduler, ...)
+      -f file     policy file to use instead of the configured one`
+
+// runPolicy inspects the security policy file and tries tool calls against
+// it, without starting the agent.
+func runPolicy(args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, policyUsage)
+		os.Exit(2)
+	}
+
+	cfg, err := config.Load(config.DefaultConfigPath())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "show":
+		fs := flag.NewFlagSet("policy show", flag.ExitOnError)
+		file := fs.String("f", cfg.Security.PolicyFile, "policy file")
+		fs.Parse(args[1:])
+		err = showPolicy(*file)
+	case "test":
+		fs := flag.NewFlagSet("policy test", flag.ExitOnError)
+		user := fs.String("u", "", "user ID")
+		channel := fs.String("c", "", "channel")
+		file := fs.String("f", cfg.Security.PolicyFile, "policy file")
+		fs.Parse(args[1:])
+		if fs.NArg() == 0 || fs.NArg() > 2 {
+			fmt.Fprintln(os.Stderr, policyUsage)
+			os.Exit(2)
+		}
+		call := security.ToolCall{Tool: fs.Arg(0), Args: json.RawMessage("{}"), Channel: *channel, UserID: *user}
+		if fs.NArg() == 2 {
+			call.Args = json.RawMessage(fs.Arg(1))
+		}
+		err = testPolicy(cfg, *file, call)
+	default:
+		fmt.Fprintln(os.Stderr, policyUsage)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func showPolicy(path string) error {
+	f, err := security.LoadPolicyFile(path)
+	if err != nil {
+		return err
+	}
+	rs := f.Rules()
+	if len(rs.Rules) == 0 {
+		fmt.Printf("No rules in %s; every call is %s.\n", path, decisionText(rs.Default))
+		return nil
+	}
+	fmt.Printf("%s: %d rules, default %s\n\n", path, len(rs.Rules), rs.Default)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "#\tNAME\tDECISION\tMATCHES")
+	for i, r := range rs.Rules {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", i+1, r.Name, r.Decision, ruleConditions(r))
+	}
+	return w.Flush()
+}
+
+// ruleConditions summarizes what a rule matches.
+func ruleConditions(r security.Rule) string {
+	var parts []string
+	add := func(name string, values []string) {
+		if len(values) > 0 {
+			parts = append(parts, name+"="+strings.Join(values, ","))
+		}
+	}
+	add("tools", r.Tools)
+	add("commands", r.Commands)
+	add("paths", r.Paths)
+	add("users", r.Users)
+	add("channels", r.Channels)
+	keys := make([]string, 0, len(r.Args))
+	for k := range r.Args {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		parts = append(parts, fmt.Sprintf("args.%s=/%s/", k, r.Args[k]))
+	}
+	if len(parts) == 0 {
+		return "every call"
+	}
+	return strings.Join(parts, " ")
+}
+
+func testPolicy(cfg *config.Config, path string, call security.ToolCall) error {
+	var args map[string]any
+	if err := json.Unmarshal(call.Args, &args); err != nil {
+		return fmt.Errorf("arguments must be a JSON object: %w", err)
+	}
+	f, err := security.LoadPolicyFile(path)
+	if err != nil {
+		return err
+	}
+	policy := security.NewPolicy(cfg.Security.DenyPatterns, cfg.Security.AllowedPaths)
+	policy.DenyPaths(cfg.Secrets.KeyFile)
+	policy.SetRules(f)
+
+	m := policy.CheckTool(call)
+	fmt.Printf("Rule:      %s\n", strings.TrimPrefix(m.Label(), "policy "))
+	fmt.Printf("Decision:  %s\n", m.Decision)
+	if m.Reason != "" {
+		fmt.Printf("Reason:    %s\n", m.Reason)
+	}
+
+	// The tool's own checks, which a deny or approve rule comes before
+	builtin, reason := security.Allowed, ""
+	command, _ := args["command"].(string)
+	file, _ := args["path"].(string)
+	switch {
+	case call.Tool == "shell_exec" && command != "":
+		builtin, reason = policy.CheckCommand(command)
+	case strings.HasPrefix(call.Tool, "file_") && file != "":
+		builtin, reason = policy.CheckPath(file)
+	}
+	if reason != "" {
+		fmt.Printf("Built-in:  %s — %s\n", builtin, reason)
+	}
+
+	outcome := "runs"
+	switch {
+	case m.Decision == security.RuleDeny || builtin == security.Denied:
+		outcome = "blocked"
+	case m.Decision == security.RuleApprove:
+		outcome = "needs approval"
+	case builtin == security.NeedsApproval && m.Rule != nil && m.Decision == security.RuleAllow:
+		outcome = "runs (the rule waives the built-in approval)"
+	case builtin == security.NeedsApproval:
+		outcome = "needs approval"
+	}
+	if m.Decision == security.RuleAudit && outcome != "blocked" {
+		outcome += ", logged for audit"
+	}
+	fmt.Printf("Outcome:   %s\n", outcome)
+	return nil
+}
+
+func decisionText(d string) string {
+	switch d {
+	case security.RuleDeny:
+		return "denied"
+	case security.RuleApprove:
+		return "sent for approval"
+	case security.RuleAudit:
+		return "audited"
+	default:
+		return "left to the built-in checks"
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ImJafran/aeon/internal/config"
+	"github.com/ImJafran/aeon/internal/secrets"
+)
+
+const secretsUsage = `Usage:
+  aeon secrets set <name> [value]   Store a secret; prompts for the value, or reads it from stdin
+  aeon secrets get <name>           Print a secret's value
+  aeon secrets list                 List secret names and when they were set
+  aeon secrets rm <name>            Remove a secret
+
+Refer to a secret in config.json as "secret://<name>". The vault is locked
+with the key file, or with $AEON_SECRETS_PASSPHRASE if it's set when the
+vault is created. Restart Aeon for changes to take effect.`
+
+// runSecrets manages the encrypted secrets vault, without starting the agent.
+func runSecrets(args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, secretsUsage)
+		os.Exit(2)
+	}
+	switch {
+	case args[0] == "list" && len(args) == 1:
+	case (args[0] == "get" || args[0] == "rm") && len(args) == 2:
+	case args[0] == "set" && (len(args) == 2 || len(args) == 3):
+	default:
+		fmt.Fprintln(os.Stderr, secretsUsage)
+		os.Exit(2)
+	}
+
+	cfg, err := config.LoadSecrets(config.DefaultConfigPath())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	_, statErr := os.Stat(cfg.Vault)
+	isNew := os.IsNotExist(statErr)
+	vault, err := cfg.Open()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "set":
+		err = setSecret(vault, cfg, isNew, args[1:])
+	case "get":
+		value, ok := vault.Get(args[1])
+		if !ok {
+			err = fmt.Errorf("no secret %s", args[1])
+		} else {
+			fmt.Println(value)
+		}
+	case "list":
+		listSecrets(vault)
+	case "rm":
+		if !vault.Delete(args[1]) {
+			err = fmt.Errorf("no secret %s", args[1])
+		} else if err = vault.Save(); err == nil {
+			fmt.Printf("Removed %s.\n", args[1])
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func setSecret(vault *secrets.Vault, cfg config.SecretsConfig, isNew bool, args []string) error {
+	name := args[0]
+	if err := secrets.ValidName(name); err != nil {
+		return err
+	}
+	var value string
+	if len(args) == 2 {
+		value = args[1]
+	} else {
+		var err error
+		if value, err = readSecret(name); err != nil {
+			return err
+		}
+	}
+	if err := vault.Set(name, value); err != nil {
+		return err
+	}
+	if err := vault.Save(); err != nil {
+		return err
+	}
+
+	if isNew {
+		if secrets.Passphrase() != "" {
+			fmt.Printf("Created %s, locked with $%s.\n", cfg.Vault, secrets.PassphraseEnv)
+		} else {
+			fmt.Printf("Created %s, locked with the key in %s. Keep a copy of the key; without it the secrets are lost.\n", cfg.Vault, cfg.KeyFile)
+		}
+	}
+	fmt.Printf("Stored %s. Use it in config.json as \"%s%s\".\n", name, secrets.Scheme, name)
+	return nil
+}
+
+// readSecret prompts for a value without echoing it, or reads all of stdin
+// when it isn't a terminal.
+func readSecret(name string) (string, error) {
+	if fi, err := os.Stdin.Stat(); err != nil || fi.Mode()&os.ModeCharDevice == 0 {
+		data, err := io.ReadAll(os.Stdin)
+		return strings.TrimRight(string(data), "\r\n"), err
+	}
+
+	fmt.Printf("Value for %s: ", name)
+	if stty("-echo") == nil {
+		defer func() {
+			stty("echo")
+			fmt.Println()
+		}()
+	}
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func stty(arg string) error {
+	cmd := exec.Command("stty", arg)
+	cmd.Stdin = os.Stdin
+	return cmd.Run()
+}
+
+func listSecrets(vault *secrets.Vault) {
+	list := vault.List()
+	if len(list) == 0 {
+		fmt.Println("No secrets stored. Add one with: aeon secrets set <name>")
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tUPDATED")
+	for _, s := range list {
+		fmt.Fprintf(w, "%s\t%s\n", s.Name, s.Updated.Local().Format("2006-01-02 15:04"))
+	}
+	w.Flush()
+}
@@ -0,0 +1,80 @@
+package agent
+
+import (
+	"path"
+
+	"github.com/ImJafran/aeon/internal/bus"
+)
+
+// Role is what the users given it may do.
+type Role struct {
+	Name     string
+	Tools    []string // tool names, with * and ? globs
+	Commands []string // slash commands such as /status, or * for all
+	Approve  bool     // may answer approval requests
+}
+
+// AllowsTool reports whether the role may use the tool.
+func (r Role) AllowsTool(name string) bool {
+	for _, g := range r.Tools {
+		if ok, _ := path.Match(g, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsCommand reports whether the role may use a slash command. /help
+// is always allowed.
+func (r Role) AllowsCommand(cmd string) bool {
+	return cmd == "/help" || contains(r.Commands, "*") || contains(r.Commands, cmd)
+}
+
+// Roles gives the users behind messages their role. Users are listed as
+// channel:userID, channel:* for anyone on a channel, or * for anyone else.
+type Roles struct {
+	roles map[string]Role
+	users map[string]string // identity -> role name
+}
+
+// NewRoles maps users to roles by name.
+func NewRoles(roles []Role, users map[string]string) *Roles {
+	r := &Roles{roles: make(map[string]Role, len(roles)), users: users}
+	for _, role := range roles {
+		r.roles[role.Name] = role
+	}
+	return r
+}
+
+// Role returns the role with this name. A nil Roles has none.
+func (r *Roles) Role(name string) (Role, bool) {
+	if r == nil {
+		return Role{}, false
+	}
+	role, ok := r.roles[name]
+	return role, ok
+}
+
+// clientChosenIDs are the channels whose user IDs the client picks, as
+// WebSocket clients do with ?chat_id= and webhook callers with user_id.
+// Their users are never matched by ID.
+var clientChosenIDs = map[string]bool{"websocket": true, "webhook": true}
+
+// RoleFor returns the role of msg's sender, or false if they have none. A
+// nil Roles has none for anyone.
+func (r *Roles) RoleFor(msg bus.InboundMessage) (Role, bool) {
+	if r == nil {
+		return Role{}, false
+	}
+	ids := []string{approverID(msg), msg.Channel + ":*", "*"}
+	if clientChosenIDs[msg.Channel] {
+		ids = ids[1:]
+	}
+	for _, id := range ids {
+		if name, ok := r.users[id]; ok {
+			role, ok := r.roles[name]
+			return role, ok
+		}
+	}
+	return Role{}, false
+}
@@ -0,0 +1,137 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ImJafran/aeon/internal/bus"
+	"github.com/ImJafran/aeon/internal/providers"
+	"github.com/ImJafran/aeon/internal/tools"
+)
+
+func testRoles() *Roles {
+	return NewRoles([]Role{
+		{Name: "admin", Tools: []string{"*"}, Commands: []string{"*"}, Approve: true},
+		{Name: "viewer", Tools: []string{"echo_tool", "memory_*"}, Commands: []string{"/status"}},
+	}, map[string]string{
+		"telegram:1":  "admin",
+		"telegram:*":  "viewer",
+		"cli:local":   "admin",
+		"websocket:*": "viewer",
+		"websocket:1": "admin",
+		"webhook:*":   "viewer",
+		"webhook:1":   "admin",
+	})
+}
+
+func TestRoleFor(t *testing.T) {
+	roles := testRoles()
+	tests := []struct {
+		msg  bus.InboundMessage
+		role string
+	}{
+		{bus.InboundMessage{Channel: "telegram", ChatID: "c", UserID: "1"}, "admin"},
+		{bus.InboundMessage{Channel: "telegram", ChatID: "c", UserID: "2"}, "viewer"},
+		{bus.InboundMessage{Channel: "cli", ChatID: "default", UserID: "local"}, "admin"},
+		{bus.InboundMessage{Channel: "discord", ChatID: "c", UserID: "1"}, ""},
+		{bus.InboundMessage{Channel: "websocket", ChatID: "1", UserID: "1"}, "viewer"},
+		{bus.InboundMessage{Channel: "webhook", ChatID: "1", UserID: "1"}, "viewer"},
+	}
+	for _, tt := range tests {
+		role, ok := roles.RoleFor(tt.msg)
+		if ok != (tt.role != "") || role.Name != tt.role {
+			t.Errorf("%s:%s: got %q, %v; want %q", tt.msg.Channel, tt.msg.UserID, role.Name, ok, tt.role)
+		}
+	}
+
+	viewer, _ := roles.RoleFor(bus.InboundMessage{Channel: "telegram", UserID: "2"})
+	if !viewer.AllowsTool("memory_search") || viewer.AllowsTool("file_write") {
+		t.Error("expected the viewer's tool globs to apply")
+	}
+	if !viewer.AllowsCommand("/help") || !viewer.AllowsCommand("/status") || viewer.AllowsCommand("/new") {
+		t.Error("expected the viewer to get /help and /status only")
+	}
+
+	var none *Roles
+	if _, ok := none.RoleFor(bus.InboundMessage{Channel: "telegram", UserID: "1"}); ok {
+		t.Error("nil roles should give no one a role")
+	}
+}
+
+// writeTool records whether it ran.
+type writeTool struct{ ran bool }
+
+func (t *writeTool) Name() string                { return "file_write" }
+func (t *writeTool) Description() string         { return "writes files" }
+func (t *writeTool) Parameters() json.RawMessage { return json.RawMessage(`{"type":"object"}`) }
+func (t *writeTool) Execute(_ context.Context, _ json.RawMessage) (tools.ToolResult, error) {
+	t.ran = true
+	return tools.ToolResult{ForLLM: "written"}, nil
+}
+
+func TestViewerLimitedToRoleTools(t *testing.T) {
+	provider := newMockProvider("test",
+		providers.CompletionResponse{ToolCalls: []providers.ToolCall{{ID: "c1", Name: "file_write", Arguments: "{}"}}},
+		providers.CompletionResponse{Content: "I can't write files for you."},
+	)
+	loop, msgBus, outCh := setupTestLoop(provider)
+	write := &writeTool{}
+	loop.registry.Register(write)
+	loop.SetRoles(testRoles())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go loop.Run(ctx)
+
+	msgBus.Publish(bus.InboundMessage{Channel: "telegram", ChatID: "c", UserID: "2", Content: "write a file"})
+	if out := waitForReply(t, outCh); out.Content != "I can't write files for you." {
+		t.Fatalf("unexpected reply %+v", out)
+	}
+	if write.ran {
+		t.Error("a viewer's turn should not run file_write")
+	}
+	if defs := provider.requests[0].Tools; len(defs) != 1 || defs[0].Name != "echo_tool" {
+		t.Errorf("expected only the viewer's tools offered, got %+v", defs)
+	}
+	last := provider.requests[1].Messages
+	if result := last[len(last)-1].Content; !strings.Contains(result, "BLOCKED: the viewer role may not use file_write") {
+		t.Errorf("expected the call refused, got %q", result)
+	}
+
+	msgBus.Publish(bus.InboundMessage{Channel: "telegram", ChatID: "c", UserID: "2", Content: "/new"})
+	if out := waitForReply(t, outCh); !strings.Contains(out.Content, "viewer role may not use /new") {
+		t.Errorf("expected /new refused, got %q", out.Content)
+	}
+	msgBus.Publish(bus.InboundMessage{Channel: "telegram", ChatID: "c", UserID: "2", Content: "/status"})
+	if out := waitForReply(t, outCh); !strings.Contains(out.Content, "Tools: 1 loaded") {
+		t.Errorf("expected /status with the viewer's tools, got %q", out.Content)
+	}
+
+	msgBus.Publish(bus.InboundMessage{Channel: "discord", ChatID: "d", UserID: "9", Content: "hi"})
+	if out := waitForReply(t, outCh); out.Content != "You don't have access to this agent." {
+		t.Errorf("expected a user without a role refused, got %q", out.Content)
+	}
+	if len(provider.requests) != 2 {
+		t.Errorf("refused messages should not reach the provider, got %d requests", len(provider.requests))
+	}
+}
+
+func TestApprovalRights(t *testing.T) {
+	msgBus := bus.New(64)
+	outCh := msgBus.Subscribe()
+	gate := NewApprovalGate(msgBus, 5*time.Second)
+	gate.SetRoles(testRoles())
+
+	id, result := requestApproval(t, gate, outCh, "telegram", "c")
+	gate.HandleMessage(bus.InboundMessage{Channel: "telegram", ChatID: "c", UserID: "2", Content: "/approve " + id})
+	if reply := <-outCh; !strings.Contains(reply.Content, "that you can answer") {
+		t.Errorf("a viewer should not be able to approve, got %q", reply.Content)
+	}
+	gate.HandleMessage(bus.InboundMessage{Channel: "telegram", ChatID: "c", UserID: "1", Content: "/approve " + id})
+	if r := waitOutcome(t, result); !r.approved || r.approver != "telegram:1" {
+		t.Errorf("expected the admin to approve, got %+v", r)
+	}
+}
@@ -2,20 +2,51 @@ package agent
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
+	"log/slog"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/ImJafran/aeon/internal/bus"
+	"github.com/ImJafran/aeon/internal/tools"
 )
 
 // ApprovalGate handles human-in-the-loop approval for dangerous tool operations.
+// Each request gets an ID that /approve <id> and /deny <id> refer to, from any
+// channel; /pending lists the requests the sender may answer. Only the
+// configured approvers and roles with approval rights count, or by default
+// anyone in the chat that asked or in the approval chat.
 type ApprovalGate struct {
-	bus     *bus.MessageBus
-	timeout time.Duration
-	mu      sync.Mutex
-	pending map[string]chan bool
+	bus       *bus.MessageBus
+	timeout   time.Duration
+	approvers []string // user IDs or channel:userID; empty means the asking chat
+	quorum    int      // approvals needed from different approvers
+	channel   string   // approval channel: requests go there, and approvers must answer from it
+	chatID    string
+	roles     *Roles // roles with Approve may answer too
+	logger    *slog.Logger
+	mu        sync.Mutex
+	pending   map[string]*approvalRequest
+}
+
+// approvalRequest is a tool call waiting for approvers.
+type approvalRequest struct {
+	id          string
+	channel     string // chat that asked; empty for MCP clients
+	chatID      string
+	description string
+	created     time.Time
+	approvals   []string // approvers who approved so far
+	done        chan approvalResult
+}
+
+type approvalResult struct {
+	approved  bool
+	approvers []string // who approved, or who denied
 }
 
 func NewApprovalGate(b *bus.MessageBus, timeout time.Duration) *ApprovalGate {
@@ -25,74 +56,261 @@ func NewApprovalGate(b *bus.MessageBus, timeout time.Duration) *ApprovalGate {
 	return &ApprovalGate{
 		bus:     b,
 		timeout: timeout,
-		pending: make(map[string]chan bool),
+		quorum:  1,
+		logger:  slog.Default(),
+		pending: make(map[string]*approvalRequest),
 	}
 }
 
-// RequestApproval sends an approval request to the user and waits for /approve or /deny.
-func (g *ApprovalGate) RequestApproval(ctx context.Context, channel, chatID, description string) (bool, error) {
-	id := fmt.Sprintf("approval_%d", time.Now().UnixNano())
-	ch := make(chan bool, 1)
+// SetApprovers limits who may answer to approvers, as channel:userID, and
+// requires quorum of them to approve. Any one of them
+// denying rejects the request.
+func (g *ApprovalGate) SetApprovers(approvers []string, quorum int) {
+	g.approvers = approvers
+	g.quorum = max(quorum, 1)
+}
+
+// SetApprovalChat sends every request to chatID on channel. With approvers
+// set, only answers from that channel count.
+func (g *ApprovalGate) SetApprovalChat(channel, chatID string) {
+	g.channel, g.chatID = channel, chatID
+}
 
+// SetRoles also lets users whose role has approval rights answer. Like
+// approvers, they must answer from the approval channel if one is set.
+func (g *ApprovalGate) SetRoles(roles *Roles) {
+	g.roles = roles
+}
+
+func (g *ApprovalGate) SetLogger(logger *slog.Logger) {
+	g.logger = logger
+}
+
+// Timeout returns how long a request waits for approvers.
+func (g *ApprovalGate) Timeout() time.Duration {
+	return g.timeout
+}
+
+// RequestApproval sends an approval request and waits until it's approved,
+// denied or times out, returning who decided (channel:user, comma-separated
+// for a quorum). channel and chatID are the chat asking, if any; it gets the
+// request unless an approval chat is set. A timeout returns
+// tools.ErrApprovalTimeout.
+func (g *ApprovalGate) RequestApproval(ctx context.Context, channel, chatID, description string) (bool, string, error) {
+	req := &approvalRequest{
+		channel:     channel,
+		chatID:      chatID,
+		description: description,
+		created:     time.Now(),
+		done:        make(chan approvalResult, 1),
+	}
 	g.mu.Lock()
-	g.pending[id] = ch
+	for req.id == "" || g.pending[req.id] != nil {
+		req.id = newApprovalID()
+	}
+	g.pending[req.id] = req
 	g.mu.Unlock()
 
-	defer func() {
-		g.mu.Lock()
-		delete(g.pending, id)
-		g.mu.Unlock()
-	}()
-
-	// Send approval request to user
-	g.bus.Send(bus.OutboundMessage{
-		Channel: channel,
-		ChatID:  chatID,
-		Content: fmt.Sprintf("⚠️ Approval required:\n%s\n\nReply /approve or /deny", description),
-	})
+	g.logger.Info("approval_requested", "id", req.id, "channel", channel, "chat_id", chatID, "quorum", g.quorum)
+	g.announce(req)
 
 	timer := time.NewTimer(g.timeout)
 	defer timer.Stop()
 
 	select {
-	case approved := <-ch:
-		return approved, nil
+	case r := <-req.done:
+		return r.approved, strings.Join(r.approvers, ","), nil
 	case <-timer.C:
-		return false, fmt.Errorf("approval timed out after %v", g.timeout)
 	case <-ctx.Done():
-		return false, ctx.Err()
 	}
-}
 
-// HandleApprovalCommand processes /approve or /deny commands.
-// Returns true if the command was handled (was an approval response).
-func (g *ApprovalGate) HandleApprovalCommand(command string) bool {
 	g.mu.Lock()
-	defer g.mu.Unlock()
+	delete(g.pending, req.id)
+	g.mu.Unlock()
+	// An answer may have come in just before the request was withdrawn
+	select {
+	case r := <-req.done:
+		return r.approved, strings.Join(r.approvers, ","), nil
+	default:
+	}
+	if ctx.Err() != nil {
+		return false, "", ctx.Err()
+	}
+	g.logger.Info("approval_timeout", "id", req.id)
+	return false, "", fmt.Errorf("%w after %v", tools.ErrApprovalTimeout, g.timeout)
+}
 
-	if len(g.pending) == 0 {
-		return false
+func newApprovalID() string {
+	b := make([]byte, 3)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// announce sends the request to the approval chat, or to the asking chat
+// when there is none. Channels that support it render buttons.
+func (g *ApprovalGate) announce(req *approvalRequest) {
+	text := fmt.Sprintf("⚠️ Approval required [%s]:\n%s\n\nReply /approve %s or /deny %s", req.id, req.description, req.id, req.id)
+	if g.quorum > 1 {
+		text += fmt.Sprintf(" (%d approvals needed)", g.quorum)
 	}
+	meta := map[string]string{"approval": "true", "approval_id": req.id}
 
-	cmd := strings.TrimSpace(strings.ToLower(command))
-	approved := cmd == "/approve"
-	denied := cmd == "/deny"
+	if g.channel == "" {
+		g.bus.Send(bus.OutboundMessage{Channel: req.channel, ChatID: req.chatID, Content: text, Metadata: meta})
+		return
+	}
+	g.bus.Send(bus.OutboundMessage{Channel: g.channel, ChatID: g.chatID, Content: text, Metadata: meta})
+	if req.channel != "" && (req.channel != g.channel || req.chatID != g.chatID) {
+		g.bus.Send(bus.OutboundMessage{
+			Channel: req.channel,
+			ChatID:  req.chatID,
+			Content: fmt.Sprintf("⏳ Waiting for approval [%s] in %s:\n%s", req.id, g.channel, req.description),
+		})
+	}
+}
 
-	if !approved && !denied {
+// HandleMessage acts on /approve, /deny and /pending. Returns false if msg
+// is something else, or a bare /approve or /deny with nothing for its
+// sender to answer, so it can be handled as an ordinary message.
+func (g *ApprovalGate) HandleMessage(msg bus.InboundMessage) bool {
+	fields := strings.Fields(strings.ToLower(msg.Content))
+	if len(fields) == 0 {
 		return false
 	}
+	switch fields[0] {
+	case "/pending":
+		g.mu.Lock()
+		text := g.pendingText(msg)
+		g.mu.Unlock()
+		g.reply(msg, text)
+		return true
+	case "/approve", "/deny":
+	default:
+		return false
+	}
+	approve := fields[0] == "/approve"
+	who := approverID(msg)
 
-	// Resolve the most recent pending approval
-	for id, ch := range g.pending {
-		select {
-		case ch <- approved:
+	g.mu.Lock()
+	var req *approvalRequest
+	if len(fields) > 1 {
+		req = g.pending[fields[1]]
+		if req == nil || !g.mayAnswer(req, msg) {
+			g.mu.Unlock()
+			if req != nil {
+				g.logger.Warn("approval_unauthorized", "id", req.id, "from", who)
+			}
+			g.reply(msg, fmt.Sprintf("No pending approval %s that you can answer.", fields[1]))
+			return true
+		}
+	} else {
+		answerable := g.answerable(msg)
+		switch len(answerable) {
+		case 0:
+			g.mu.Unlock()
+			return false
+		case 1:
+			req = answerable[0]
 		default:
+			text := fmt.Sprintf("Several approvals are pending; answer one by ID, e.g. %s %s.\n\n%s", fields[0], answerable[0].id, g.pendingText(msg))
+			g.mu.Unlock()
+			g.reply(msg, text)
+			return true
 		}
-		delete(g.pending, id)
+	}
+
+	var result *approvalResult
+	if !approve {
+		result = &approvalResult{approved: false, approvers: []string{who}}
+	} else {
+		if !contains(req.approvals, who) {
+			req.approvals = append(req.approvals, who)
+		}
+		if len(req.approvals) >= g.quorum {
+			result = &approvalResult{approved: true, approvers: req.approvals}
+		}
+	}
+	count := len(req.approvals)
+	if result != nil {
+		delete(g.pending, req.id)
+		req.done <- *result
+	}
+	g.mu.Unlock()
+
+	if result == nil {
+		g.logger.Info("approval_vote", "id", req.id, "approver", who, "approvals", count, "quorum", g.quorum)
+		g.reply(msg, fmt.Sprintf("Approval %s: %d of %d approvals, waiting for %d more.", req.id, count, g.quorum, g.quorum-count))
 		return true
 	}
+	decision := "denied"
+	if result.approved {
+		decision = "approved"
+	}
+	g.logger.Info("approval_decided", "id", req.id, "decision", decision, "approvers", strings.Join(result.approvers, ","))
+	// The asking chat sees the outcome from the turn that was waiting
+	if msg.Channel != req.channel || msg.ChatID != req.chatID {
+		g.reply(msg, fmt.Sprintf("Request %s %s.", req.id, decision))
+	}
+	return true
+}
 
-	return false
+// mayAnswer reports whether msg's sender may answer req. Callers hold g.mu.
+func (g *ApprovalGate) mayAnswer(req *approvalRequest, msg bus.InboundMessage) bool {
+	if len(g.approvers) > 0 || g.roles != nil {
+		if g.channel != "" && msg.Channel != g.channel {
+			return false
+		}
+		if msg.UserID == "" {
+			return false
+		}
+		// Only as channel:userID: a bare ID could be claimed on another channel
+		if !clientChosenIDs[msg.Channel] && contains(g.approvers, msg.Channel+":"+msg.UserID) {
+			return true
+		}
+		role, ok := g.roles.RoleFor(msg)
+		return ok && role.Approve
+	}
+	if g.channel != "" && msg.Channel == g.channel && msg.ChatID == g.chatID {
+		return true
+	}
+	return req.channel != "" && msg.Channel == req.channel && msg.ChatID == req.chatID
+}
+
+// answerable returns the requests msg's sender may answer, oldest first.
+// Callers hold g.mu.
+func (g *ApprovalGate) answerable(msg bus.InboundMessage) []*approvalRequest {
+	var reqs []*approvalRequest
+	for _, req := range g.pending {
+		if g.mayAnswer(req, msg) {
+			reqs = append(reqs, req)
+		}
+	}
+	sort.Slice(reqs, func(i, j int) bool { return reqs[i].created.Before(reqs[j].created) })
+	return reqs
+}
+
+// pendingText lists the requests msg's sender may answer. Callers hold g.mu.
+func (g *ApprovalGate) pendingText(msg bus.InboundMessage) string {
+	reqs := g.answerable(msg)
+	if len(reqs) == 0 {
+		return "No pending approvals."
+	}
+	var sb strings.Builder
+	sb.WriteString("Pending approvals:")
+	for _, req := range reqs {
+		from := "MCP client"
+		if req.channel != "" {
+			from = req.channel + ":" + req.chatID
+		}
+		left := time.Until(req.created.Add(g.timeout)).Round(time.Second)
+		summary, _, _ := strings.Cut(req.description, "\n")
+		fmt.Fprintf(&sb, "\n[%s] from %s, %d of %d approvals, %v left\n  %s", req.id, from, len(req.approvals), g.quorum, max(left, 0), summary)
+	}
+	return sb.String()
+}
+
+func (g *ApprovalGate) reply(msg bus.InboundMessage, text string) {
+	g.bus.Send(bus.OutboundMessage{Channel: msg.Channel, ChatID: msg.ChatID, Content: text})
 }
 
 // HasPending returns true if there are pending approval requests.
@@ -101,3 +319,22 @@ func (g *ApprovalGate) HasPending() bool {
 	defer g.mu.Unlock()
 	return len(g.pending) > 0
 }
+
+// approverID identifies who answered, as channel:userID, falling back to
+// the chat ID on channels without users.
+func approverID(msg bus.InboundMessage) string {
+	id := msg.UserID
+	if id == "" {
+		id = msg.ChatID
+	}
+	return msg.Channel + ":" + id
+}
+
+func contains(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
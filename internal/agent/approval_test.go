@@ -2,78 +2,80 @@ package agent
 
 import (
 	"context"
+	"errors"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/ImJafran/aeon/internal/bus"
+	"github.com/ImJafran/aeon/internal/tools"
 )
 
-func TestApprovalGateApprove(t *testing.T) {
-	msgBus := bus.New(64)
-	outCh := msgBus.Subscribe()
-	gate := NewApprovalGate(msgBus, 5*time.Second)
-
-	ctx := context.Background()
+type approvalOutcome struct {
+	approved bool
+	approver string
+	err      error
+}
 
-	// Start approval request in background
-	result := make(chan bool, 1)
+// requestApproval starts a request in the background and returns its ID,
+// read from the request message, and where its outcome arrives.
+func requestApproval(t *testing.T, gate *ApprovalGate, outCh <-chan bus.OutboundMessage, channel, chatID string) (string, chan approvalOutcome) {
+	t.Helper()
+	result := make(chan approvalOutcome, 1)
 	go func() {
-		approved, err := gate.RequestApproval(ctx, "test", "1", "dangerous command")
-		if err != nil {
-			t.Errorf("unexpected error: %v", err)
-		}
-		result <- approved
+		approved, approver, err := gate.RequestApproval(context.Background(), channel, chatID, "dangerous command")
+		result <- approvalOutcome{approved, approver, err}
 	}()
 
-	// Wait for approval message to be sent
 	select {
 	case msg := <-outCh:
-		if msg.Content == "" {
-			t.Error("expected approval message")
+		id := msg.Metadata["approval_id"]
+		if msg.Metadata["approval"] != "true" || id == "" || !strings.Contains(msg.Content, "/approve "+id) {
+			t.Fatalf("expected an approval request with an ID, got %+v", msg)
 		}
+		return id, result
 	case <-time.After(2 * time.Second):
 		t.Fatal("timeout waiting for approval message")
 	}
+	return "", nil
+}
 
-	// Approve
-	handled := gate.HandleApprovalCommand("/approve")
-	if !handled {
-		t.Error("expected command to be handled")
-	}
-
+func waitOutcome(t *testing.T, result chan approvalOutcome) approvalOutcome {
+	t.Helper()
 	select {
-	case approved := <-result:
-		if !approved {
-			t.Error("expected approval to be true")
-		}
+	case r := <-result:
+		return r
 	case <-time.After(2 * time.Second):
 		t.Fatal("timeout waiting for result")
 	}
+	return approvalOutcome{}
 }
 
-func TestApprovalGateDeny(t *testing.T) {
+func TestApprovalGateApprove(t *testing.T) {
 	msgBus := bus.New(64)
-	msgBus.Subscribe() // drain output
+	outCh := msgBus.Subscribe()
 	gate := NewApprovalGate(msgBus, 5*time.Second)
 
-	ctx := context.Background()
-
-	result := make(chan bool, 1)
-	go func() {
-		approved, _ := gate.RequestApproval(ctx, "test", "1", "test")
-		result <- approved
-	}()
+	id, result := requestApproval(t, gate, outCh, "telegram", "1")
+	if !gate.HandleMessage(bus.InboundMessage{Channel: "telegram", ChatID: "1", UserID: "42", Content: "/approve " + id}) {
+		t.Error("expected command to be handled")
+	}
+	r := waitOutcome(t, result)
+	if !r.approved || r.approver != "telegram:42" || r.err != nil {
+		t.Errorf("expected approval by telegram:42, got %+v", r)
+	}
+}
 
-	time.Sleep(50 * time.Millisecond) // let goroutine start
-	gate.HandleApprovalCommand("/deny")
+func TestApprovalGateDeny(t *testing.T) {
+	msgBus := bus.New(64)
+	outCh := msgBus.Subscribe()
+	gate := NewApprovalGate(msgBus, 5*time.Second)
 
-	select {
-	case approved := <-result:
-		if approved {
-			t.Error("expected denial")
-		}
-	case <-time.After(2 * time.Second):
-		t.Fatal("timeout")
+	// A bare /deny answers the only request the chat can answer
+	_, result := requestApproval(t, gate, outCh, "telegram", "1")
+	gate.HandleMessage(bus.InboundMessage{Channel: "telegram", ChatID: "1", Content: "/deny"})
+	if r := waitOutcome(t, result); r.approved || r.approver != "telegram:1" || r.err != nil {
+		t.Errorf("expected denial, got %+v", r)
 	}
 }
 
@@ -83,18 +85,125 @@ func TestApprovalGateTimeout(t *testing.T) {
 	gate := NewApprovalGate(msgBus, 100*time.Millisecond)
 
 	ctx := context.Background()
-	_, err := gate.RequestApproval(ctx, "test", "1", "test")
-	if err == nil {
-		t.Error("expected timeout error")
+	_, _, err := gate.RequestApproval(ctx, "test", "1", "test")
+	if !errors.Is(err, tools.ErrApprovalTimeout) {
+		t.Errorf("expected timeout error, got %v", err)
+	}
+	if gate.HasPending() {
+		t.Error("a timed out request should not stay pending")
 	}
 }
 
 func TestApprovalGateNoPending(t *testing.T) {
 	msgBus := bus.New(64)
+	outCh := msgBus.Subscribe()
 	gate := NewApprovalGate(msgBus, 5*time.Second)
 
-	handled := gate.HandleApprovalCommand("/approve")
+	handled := gate.HandleMessage(bus.InboundMessage{Channel: "telegram", ChatID: "1", Content: "/approve"})
 	if handled {
 		t.Error("should not handle when no pending approvals")
 	}
+	if !gate.HandleMessage(bus.InboundMessage{Channel: "telegram", ChatID: "1", Content: "/approve abc123"}) {
+		t.Error("an unknown ID should get a reply")
+	}
+	if msg := <-outCh; !strings.Contains(msg.Content, "No pending approval abc123") {
+		t.Errorf("unexpected reply %q", msg.Content)
+	}
+}
+
+func TestApprovalGateQuorum(t *testing.T) {
+	msgBus := bus.New(64)
+	outCh := msgBus.Subscribe()
+	gate := NewApprovalGate(msgBus, 5*time.Second)
+	gate.SetApprovers([]string{"telegram:1", "telegram:2", "telegram:3"}, 2)
+	gate.SetApprovalChat("telegram", "ops")
+
+	id, result := requestApproval(t, gate, outCh, "discord", "chan")
+	if msg := <-outCh; msg.Channel != "discord" || !strings.Contains(msg.Content, "Waiting for approval ["+id+"] in telegram") {
+		t.Fatalf("expected the asking chat to be told where approval happens, got %+v", msg)
+	}
+
+	// Not an approver, or an approver's ID on another channel
+	for _, msg := range []bus.InboundMessage{
+		{Channel: "discord", ChatID: "chan", UserID: "9", Content: "/approve " + id},
+		{Channel: "discord", ChatID: "chan", UserID: "1", Content: "/approve " + id},
+	} {
+		gate.HandleMessage(msg)
+		if reply := <-outCh; !strings.Contains(reply.Content, "that you can answer") {
+			t.Errorf("%s:%s should not be able to approve, got %q", msg.Channel, msg.UserID, reply.Content)
+		}
+	}
+	if gate.HandleMessage(bus.InboundMessage{Channel: "discord", ChatID: "chan", UserID: "9", Content: "/approve"}) {
+		t.Error("a bare /approve from someone who can't answer should be left alone")
+	}
+
+	gate.HandleMessage(bus.InboundMessage{Channel: "telegram", ChatID: "ops", UserID: "1", Content: "/approve " + id})
+	if reply := <-outCh; !strings.Contains(reply.Content, "1 of 2 approvals") {
+		t.Errorf("expected progress, got %q", reply.Content)
+	}
+	// The same approver again doesn't count twice
+	gate.HandleMessage(bus.InboundMessage{Channel: "telegram", ChatID: "ops", UserID: "1", Content: "/approve " + id})
+	<-outCh
+
+	gate.HandleMessage(bus.InboundMessage{Channel: "telegram", ChatID: "dm", UserID: "3", Content: "/pending"})
+	if reply := <-outCh; !strings.Contains(reply.Content, "["+id+"] from discord:chan, 1 of 2 approvals") {
+		t.Errorf("expected the request listed, got %q", reply.Content)
+	}
+	gate.HandleMessage(bus.InboundMessage{Channel: "telegram", ChatID: "dm", UserID: "3", Content: "/approve " + id})
+	r := waitOutcome(t, result)
+	if !r.approved || r.approver != "telegram:1,telegram:3" {
+		t.Errorf("expected approval by two approvers, got %+v", r)
+	}
+	if reply := <-outCh; reply.ChatID != "dm" || !strings.Contains(reply.Content, "approved") {
+		t.Errorf("expected the last approver to be told, got %+v", reply)
+	}
+}
+
+func TestApproverIDNotClaimedOnAnotherChannel(t *testing.T) {
+	msgBus := bus.New(64)
+	outCh := msgBus.Subscribe()
+	gate := NewApprovalGate(msgBus, 5*time.Second)
+	gate.SetApprovers([]string{"telegram:42"}, 1)
+
+	id, result := requestApproval(t, gate, outCh, "webhook", "hook")
+
+	// Webhook callers send their own user_id, so they can claim any ID
+	gate.HandleMessage(bus.InboundMessage{Channel: "webhook", ChatID: "hook", UserID: "42", Content: "/approve " + id})
+	if reply := <-outCh; !strings.Contains(reply.Content, "that you can answer") {
+		t.Errorf("a webhook message with a Telegram approver's ID should be rejected, got %q", reply.Content)
+	}
+
+	gate.HandleMessage(bus.InboundMessage{Channel: "telegram", ChatID: "dm", UserID: "42", Content: "/approve " + id})
+	if r := waitOutcome(t, result); !r.approved || r.approver != "telegram:42" {
+		t.Errorf("expected approval by telegram:42, got %+v", r)
+	}
+}
+
+func TestApprovalGateSeveralPending(t *testing.T) {
+	msgBus := bus.New(64)
+	outCh := msgBus.Subscribe()
+	gate := NewApprovalGate(msgBus, 5*time.Second)
+	gate.SetApprovalChat("telegram", "ops")
+
+	// MCP clients have no chat of their own
+	first, firstResult := requestApproval(t, gate, outCh, "", "")
+	second, secondResult := requestApproval(t, gate, outCh, "", "")
+
+	ops := bus.InboundMessage{Channel: "telegram", ChatID: "ops", UserID: "5", Content: "/approve"}
+	gate.HandleMessage(ops)
+	if reply := <-outCh; !strings.Contains(reply.Content, "answer one by ID") {
+		t.Errorf("expected a bare /approve to ask for an ID, got %q", reply.Content)
+	}
+
+	ops.Content = "/deny " + second
+	gate.HandleMessage(ops)
+	<-outCh
+	if r := waitOutcome(t, secondResult); r.approved {
+		t.Errorf("expected the second request denied, got %+v", r)
+	}
+	ops.Content = "/approve"
+	gate.HandleMessage(ops)
+	if r := waitOutcome(t, firstResult); !r.approved || r.approver != "telegram:5" {
+		t.Errorf("expected %s approved, got %+v", first, r)
+	}
 }
@@ -0,0 +1,141 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ImJafran/aeon/internal/bus"
+	"github.com/ImJafran/aeon/internal/providers"
+)
+
+// BudgetLimits caps token usage and spend. Zero values mean unlimited.
+type BudgetLimits struct {
+	DailyTokens   int
+	MonthlyTokens int
+	DailyUSD      float64
+	MonthlyUSD    float64
+	WarnPercent   int // past this share of any limit, warn and route to the fast provider
+}
+
+type budgetState int
+
+const (
+	budgetOK budgetState = iota
+	budgetWarn
+	budgetExhausted
+)
+
+// BudgetStatus is the result of checking usage against the configured limits.
+type BudgetStatus struct {
+	State  budgetState
+	Reason string // which limit was hit, for the user
+	Notify bool   // first warning today, tell the user
+}
+
+// SetBudget sets the daily and monthly limits enforced by Check.
+func (ct *CostTracker) SetBudget(limits BudgetLimits) {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+	ct.limits = limits
+}
+
+// Check compares today's and this month's usage against the limits and
+// reports the most severe state.
+func (ct *CostTracker) Check() BudgetStatus {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+
+	now := time.Now()
+	ct.period.roll(ct.store, now)
+	p := ct.period
+	l := ct.limits
+
+	checks := []struct {
+		name      string
+		used, max float64
+		unit      func(float64) string
+	}{
+		{"daily token", float64(p.dayTokens), float64(l.DailyTokens), formatTokens},
+		{"monthly token", float64(p.monthTokens), float64(l.MonthlyTokens), formatTokens},
+		{"daily spend", p.dayCost, l.DailyUSD, formatUSD},
+		{"monthly spend", p.monthCost, l.MonthlyUSD, formatUSD},
+	}
+
+	warnAt := float64(l.WarnPercent) / 100
+	if l.WarnPercent <= 0 || l.WarnPercent > 100 {
+		warnAt = 0.8
+	}
+
+	var status BudgetStatus
+	for _, c := range checks {
+		if c.max <= 0 {
+			continue
+		}
+		share := c.used / c.max
+		switch {
+		case share >= 1:
+			return BudgetStatus{
+				State:  budgetExhausted,
+				Reason: fmt.Sprintf("%s budget exhausted (%s of %s)", c.name, c.unit(c.used), c.unit(c.max)),
+			}
+		case share >= warnAt && status.State == budgetOK:
+			status = BudgetStatus{
+				State:  budgetWarn,
+				Reason: fmt.Sprintf("%s budget %.0f%% used (%s of %s)", c.name, share*100, c.unit(c.used), c.unit(c.max)),
+			}
+		}
+	}
+
+	if status.State == budgetWarn && ct.warned != p.day {
+		ct.warned = p.day
+		status.Notify = true
+	}
+	return status
+}
+
+func formatTokens(v float64) string {
+	return fmt.Sprintf("%d tokens", int(v))
+}
+
+// checkBudget enforces the usage budget before an LLM call. It returns the
+// routing hint to use and false if the call must not be made; the user is
+// told in either case.
+func (a *AgentLoop) checkBudget(channel, chatID string) (hint string, ok bool) {
+	if a.costTracker == nil {
+		return "", true
+	}
+
+	status := a.costTracker.Check()
+	switch status.State {
+	case budgetExhausted:
+		a.logger.Warn("budget_exhausted", "reason", status.Reason, "channel", channel)
+		a.bus.Send(bus.OutboundMessage{
+			Channel: channel,
+			ChatID:  chatID,
+			Content: fmt.Sprintf("[Budget] %s. Requests resume when the budget resets; see /cost.", status.Reason),
+		})
+		return "", false
+	case budgetWarn:
+		if status.Notify {
+			a.logger.Warn("budget_warning", "reason", status.Reason)
+			a.bus.Send(bus.OutboundMessage{
+				Channel: channel,
+				ChatID:  chatID,
+				Content: fmt.Sprintf("[Budget] %s. Switching to the fast model.", status.Reason),
+			})
+		}
+		return "fast", true
+	}
+	return "", true
+}
+
+// recordUsage adds a response's usage to the cost tracker.
+func (a *AgentLoop) recordUsage(ctx context.Context, sessionID string, resp providers.CompletionResponse) {
+	if a.costTracker == nil {
+		return
+	}
+	if err := a.costTracker.Record(ctx, sessionID, resp); err != nil {
+		a.logger.Warn("failed to record usage", "session", sessionID, "error", err)
+	}
+}
@@ -0,0 +1,201 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ImJafran/aeon/internal/providers"
+)
+
+const (
+	// outputReserve is kept free in the context window for the model's reply.
+	outputReserve = 4096
+	// contextFill is the share of the window we plan to use, since token counts are estimates.
+	contextFill = 0.8
+	// minHistoryBudget keeps tiny or misconfigured windows from evicting everything.
+	minHistoryBudget = 2000
+	// minClippedToolResult is the shortest a tool result is cut to when it alone overflows the window.
+	minClippedToolResult = 2000
+)
+
+const summarySystemPrompt = `You keep a running summary of a conversation between a user and Aeon, their AI assistant. Merge the new messages into the existing summary.
+Keep facts, decisions, names, preferences, open tasks and tool results that may matter later. Drop small talk and anything superseded.
+Write plain prose, under 250 words. Reply with the summary only.`
+
+// estimateTokens approximates a token count at four characters per token.
+func estimateTokens(text string) int {
+	return (len(text) + 3) / 4
+}
+
+// estimateMessages approximates the tokens a message list adds to a request.
+func estimateMessages(msgs []providers.Message) int {
+	n := 0
+	for _, m := range msgs {
+		n += 4 + estimateTokens(m.Content)
+		for _, tc := range m.ToolCalls {
+			n += 4 + estimateTokens(tc.Name) + estimateTokens(tc.Arguments)
+		}
+	}
+	return n
+}
+
+// historyBudget returns how many tokens of conversation history fit in one
+// request to provider alongside the system prompt and tool definitions.
+func historyBudget(provider providers.Provider, systemPrompt string, tools []providers.ToolDef) int {
+	toolJSON, _ := json.Marshal(tools)
+	window := providers.ContextWindow(provider)
+	budget := int(float64(window-outputReserve)*contextFill) - estimateTokens(systemPrompt) - estimateTokens(string(toolJSON))
+	if budget < minHistoryBudget {
+		budget = minHistoryBudget
+	}
+	return budget
+}
+
+// turnStarts returns the index of every user message that opens a turn.
+// A turn runs until the next one, so cutting at these indexes never separates
+// a tool call from its result.
+func turnStarts(msgs []providers.Message) []int {
+	var starts []int
+	for i, m := range msgs {
+		if m.Role == "user" && m.ToolCallID == "" {
+			starts = append(starts, i)
+		}
+	}
+	return starts
+}
+
+// summarySection renders the session summary for the system prompt.
+func summarySection(summary string) string {
+	if summary == "" {
+		return ""
+	}
+	return "\n\n<conversation_summary>\nEarlier in this conversation:\n" + summary + "\n</conversation_summary>"
+}
+
+// fitContext keeps a session's history within the provider's context budget.
+// Whole turns are evicted oldest first and folded into the session summary;
+// the turn in progress is never evicted. History is also capped at twice
+// maxHistoryMessages, trimming back to maxHistoryMessages.
+func (a *AgentLoop) fitContext(ctx context.Context, sess *session, provider providers.Provider, systemPrompt string, tools []providers.ToolDef) {
+	budget := historyBudget(provider, systemPrompt+summarySection(sess.summary), tools)
+	total := estimateMessages(sess.history)
+
+	maxCount := len(sess.history)
+	if len(sess.history) > a.maxHistoryMessages*2 {
+		maxCount = a.maxHistoryMessages
+	}
+
+	starts := turnStarts(sess.history)
+	cut := 0
+	for k := 1; k < len(starts); k++ {
+		if total <= budget && len(sess.history)-cut <= maxCount {
+			break
+		}
+		total -= estimateMessages(sess.history[cut:starts[k]])
+		cut = starts[k]
+	}
+
+	if cut > 0 {
+		evicted := sess.history[:cut]
+		sess.history = append([]providers.Message(nil), sess.history[cut:]...)
+		a.foldIntoSummary(ctx, sess, provider, evicted)
+	}
+
+	if total > budget {
+		clipToolResults(sess.history, total-budget)
+	}
+}
+
+// foldIntoSummary merges evicted messages into the session's rolling summary
+// and persists it. If summarization fails the messages are dropped anyway;
+// the context has to fit either way.
+func (a *AgentLoop) foldIntoSummary(ctx context.Context, sess *session, provider providers.Provider, evicted []providers.Message) {
+	summary, err := a.summarize(ctx, provider, sess.id, sess.summary, evicted)
+	if err != nil {
+		a.logger.Warn("context summarization failed", "session", sess.id, "error", err)
+	} else {
+		sess.summary = summary
+	}
+
+	if a.memStore != nil {
+		if err := a.memStore.SaveSummary(ctx, sess.id, sess.summary, len(sess.history)); err != nil {
+			a.logger.Warn("failed to save summary", "session", sess.id, "error", err)
+		}
+	}
+
+	a.logger.Info("context_compacted",
+		"session", sess.id,
+		"evicted", len(evicted),
+		"kept", len(sess.history),
+		"summary_len", len(sess.summary),
+	)
+}
+
+// summarize asks the fast provider to merge msgs into the previous summary.
+func (a *AgentLoop) summarize(ctx context.Context, provider providers.Provider, sessionID, previous string, msgs []providers.Message) (string, error) {
+	var b strings.Builder
+	if previous != "" {
+		fmt.Fprintf(&b, "Existing summary:\n%s\n\n", previous)
+	}
+	b.WriteString("New messages:\n")
+	for _, m := range msgs {
+		switch m.Role {
+		case "tool":
+			fmt.Fprintf(&b, "[tool result] %s\n", truncateStr(m.Content, 1000))
+		case "assistant":
+			if m.Content != "" {
+				fmt.Fprintf(&b, "[assistant] %s\n", truncateStr(m.Content, 2000))
+			}
+			for _, tc := range m.ToolCalls {
+				fmt.Fprintf(&b, "[assistant called %s] %s\n", tc.Name, truncateStr(tc.Arguments, 300))
+			}
+		default:
+			fmt.Fprintf(&b, "[%s] %s\n", m.Role, truncateStr(m.Content, 2000))
+		}
+	}
+
+	resp, err := provider.Complete(ctx, providers.CompletionRequest{
+		SystemPrompt: summarySystemPrompt,
+		Messages:     []providers.Message{{Role: "user", Content: b.String()}},
+		Hint:         "fast",
+	})
+	if err != nil {
+		return "", err
+	}
+	a.recordUsage(ctx, sessionID, resp)
+
+	summary := strings.TrimSpace(resp.Content)
+	if summary == "" {
+		return "", fmt.Errorf("provider returned an empty summary")
+	}
+	return summary, nil
+}
+
+// clipToolResults shortens the largest tool results until roughly excess
+// tokens are freed. Used when the current turn alone overflows the window.
+func clipToolResults(msgs []providers.Message, excess int) {
+	var idx []int
+	for i, m := range msgs {
+		if m.Role == "tool" && len(m.Content) > minClippedToolResult {
+			idx = append(idx, i)
+		}
+	}
+	sort.Slice(idx, func(x, y int) bool { return len(msgs[idx[x]].Content) > len(msgs[idx[y]].Content) })
+
+	for _, i := range idx {
+		if excess <= 0 {
+			return
+		}
+		content := msgs[i].Content
+		keep := len(content) - excess*4 - 100 // room for the omission note
+		if keep < minClippedToolResult {
+			keep = minClippedToolResult
+		}
+		omitted := len(content) - keep
+		msgs[i].Content = fmt.Sprintf("%s\n[... %d characters omitted to fit the context window ...]", strings.ToValidUTF8(content[:keep], ""), omitted)
+		excess -= estimateTokens(content) - estimateTokens(msgs[i].Content)
+	}
+}
@@ -0,0 +1,132 @@
+package agent
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ImJafran/aeon/internal/bus"
+	"github.com/ImJafran/aeon/internal/memory"
+	"github.com/ImJafran/aeon/internal/providers"
+)
+
+// smallWindowProvider is a mockProvider with a tiny context window, so any
+// real history overflows the minimum budget.
+type smallWindowProvider struct{ *mockProvider }
+
+func (p smallWindowProvider) ContextWindow() int { return 4096 }
+
+// bigTurn returns a user question, a tool exchange and a reply of roughly
+// 1000 tokens in total.
+func bigTurn(id string) []providers.Message {
+	return []providers.Message{
+		{Role: "user", Content: "question " + id},
+		{Role: "assistant", ToolCalls: []providers.ToolCall{{ID: "call_" + id, Name: "echo_tool", Arguments: "{}"}}},
+		{Role: "tool", ToolCallID: "call_" + id, Content: strings.Repeat("x", 4000)},
+		{Role: "assistant", Content: "answer " + id},
+	}
+}
+
+func TestFitContextEvictsWholeTurns(t *testing.T) {
+	provider := smallWindowProvider{newMockProvider("test",
+		providers.CompletionResponse{Content: "User asked questions a and b.", Provider: "test"},
+	)}
+	loop, _, _ := setupTestLoop(provider)
+
+	sess := &session{id: "s1"}
+	for _, id := range []string{"a", "b", "c"} {
+		sess.history = append(sess.history, bigTurn(id)...)
+	}
+	sess.history = append(sess.history, providers.Message{Role: "user", Content: "current question"})
+
+	loop.fitContext(context.Background(), sess, provider, "system", nil)
+
+	if sess.history[0].Role != "user" || sess.history[0].Content != "question c" {
+		t.Fatalf("expected history to start at the newest whole turn, got %+v", sess.history[0])
+	}
+	if len(sess.history) != 5 {
+		t.Fatalf("expected one turn plus the current question, got %d messages", len(sess.history))
+	}
+	if sess.summary != "User asked questions a and b." {
+		t.Fatalf("summary not updated: %q", sess.summary)
+	}
+
+	req := provider.requests[0]
+	if req.Hint != "fast" || !strings.Contains(req.Messages[0].Content, "question a") || !strings.Contains(req.Messages[0].Content, "question b") {
+		t.Fatalf("summary request should cover the evicted turns: %+v", req)
+	}
+}
+
+func TestFitContextClipsOversizedToolResult(t *testing.T) {
+	provider := smallWindowProvider{newMockProvider("test")}
+	loop, _, _ := setupTestLoop(provider)
+
+	sess := &session{id: "s1", history: []providers.Message{
+		{Role: "user", Content: "read the big file"},
+		{Role: "assistant", ToolCalls: []providers.ToolCall{{ID: "call_1", Name: "file_read", Arguments: "{}"}}},
+		{Role: "tool", ToolCallID: "call_1", Content: strings.Repeat("y", 40000)},
+	}}
+
+	loop.fitContext(context.Background(), sess, provider, "system", nil)
+
+	if len(provider.requests) != 0 {
+		t.Fatal("the turn in progress should never be summarized")
+	}
+	result := sess.history[2].Content
+	if len(result) >= 40000 || !strings.Contains(result, "characters omitted") {
+		t.Fatalf("expected oversized tool result to be clipped, got %d chars", len(result))
+	}
+	if estimateMessages(sess.history) > historyBudget(provider, "system", nil) {
+		t.Fatal("history still exceeds the budget after clipping")
+	}
+}
+
+func TestSummarySurvivesRestart(t *testing.T) {
+	store, err := memory.NewStore(filepath.Join(t.TempDir(), "aeon.db"))
+	if err != nil {
+		t.Fatalf("creating store: %v", err)
+	}
+	defer store.Close()
+
+	provider := smallWindowProvider{newMockProvider("test",
+		providers.CompletionResponse{Content: "first reply " + strings.Repeat("z", 10000), Provider: "test"},
+		providers.CompletionResponse{Content: "Summary: the user said hello.", Provider: "test"},
+		providers.CompletionResponse{Content: "second reply", Provider: "test"},
+	)}
+	loop, msgBus, outCh := setupTestLoop(provider)
+	loop.SetMemoryStore(store)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go loop.Run(ctx)
+	msgBus.Publish(bus.InboundMessage{Channel: "telegram", ChatID: "1", Content: "hello"})
+	waitForReply(t, outCh)
+	msgBus.Publish(bus.InboundMessage{Channel: "telegram", ChatID: "1", Content: "again"})
+	waitForReply(t, outCh)
+	cancel()
+
+	if !strings.Contains(provider.requests[2].SystemPrompt, "the user said hello") {
+		t.Fatalf("summary missing from system prompt: %q", provider.requests[2].SystemPrompt)
+	}
+
+	// A fresh loop on the same store simulates a restart
+	second := newMockProvider("test")
+	loop, msgBus, outCh = setupTestLoop(second)
+	loop.SetMemoryStore(store)
+
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	go loop.Run(ctx)
+	msgBus.Publish(bus.InboundMessage{Channel: "telegram", ChatID: "1", Content: "and again"})
+	waitForReply(t, outCh)
+
+	req := second.requests[0]
+	if !strings.Contains(req.SystemPrompt, "the user said hello") {
+		t.Fatalf("summary not restored after restart: %q", req.SystemPrompt)
+	}
+	for _, m := range req.Messages {
+		if m.Content == "hello" {
+			t.Fatalf("summarized message was reloaded: %+v", req.Messages)
+		}
+	}
+}
@@ -1,78 +1,299 @@
 package agent
 
 import (
+	"context"
 	"fmt"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
+	"github.com/ImJafran/aeon/internal/config"
+	"github.com/ImJafran/aeon/internal/memory"
 	"github.com/ImJafran/aeon/internal/providers"
 )
 
-// CostTracker records token usage across provider calls.
+// defaultPrices are list prices in USD per million tokens, keyed by model name
+// prefix. The pricing section of the config overrides or extends them.
+var defaultPrices = map[string]config.ModelPrice{
+	"claude-opus-4-5":   {Input: 5, Output: 25},
+	"claude-opus-4":     {Input: 15, Output: 75},
+	"claude-sonnet-4":   {Input: 3, Output: 15},
+	"claude-3-7-sonnet": {Input: 3, Output: 15},
+	"claude-3-5-sonnet": {Input: 3, Output: 15},
+	"claude-haiku-4-5":  {Input: 1, Output: 5},
+	"claude-3-5-haiku":  {Input: 0.8, Output: 4},
+	"gpt-4.1-mini":      {Input: 0.4, Output: 1.6},
+	"gpt-4.1":           {Input: 2, Output: 8},
+	"gpt-4o-mini":       {Input: 0.15, Output: 0.6},
+	"gpt-4o":            {Input: 2.5, Output: 10},
+	"gemini-2.5-pro":    {Input: 1.25, Output: 10},
+	"gemini-2.5-flash":  {Input: 0.3, Output: 2.5},
+	"gemini-2.0-flash":  {Input: 0.1, Output: 0.4},
+}
+
+// CostTracker records token usage and spend across provider calls. Totals since
+// start are kept in memory; with a store, usage is also persisted per day,
+// provider, model and session so budgets survive restarts.
 type CostTracker struct {
 	mu           sync.Mutex
 	inputTokens  int
 	outputTokens int
+	costUSD      float64
 	requests     int
 	perProvider  map[string]*providerUsage
+	unpriced     map[string]bool // models used without a known price
+
+	store  *memory.Store
+	prices map[string]config.ModelPrice
+	limits BudgetLimits
+	period usagePeriod
+	warned string // day a budget warning was last sent
 }
 
 type providerUsage struct {
 	inputTokens  int
 	outputTokens int
+	costUSD      float64
 	requests     int
 }
 
 func NewCostTracker() *CostTracker {
 	return &CostTracker{
 		perProvider: make(map[string]*providerUsage),
+		unpriced:    make(map[string]bool),
+		prices:      defaultPrices,
+	}
+}
+
+// SetStore persists usage to the store and loads today's and this month's
+// totals from it, so budgets carry over across restarts.
+func (ct *CostTracker) SetStore(store *memory.Store) error {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+	ct.store = store
+	return ct.period.load(store, time.Now())
+}
+
+// SetPrices adds per-model prices (USD per million tokens) on top of the
+// built-in table. Keys are model name prefixes.
+func (ct *CostTracker) SetPrices(prices map[string]config.ModelPrice) {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+	merged := make(map[string]config.ModelPrice, len(defaultPrices)+len(prices))
+	for k, v := range defaultPrices {
+		merged[k] = v
+	}
+	for k, v := range prices {
+		merged[strings.ToLower(k)] = v
+	}
+	ct.prices = merged
+}
+
+// priceFor returns the price of a model, matching the longest known prefix.
+func (ct *CostTracker) priceFor(model string) (config.ModelPrice, bool) {
+	model = strings.ToLower(model)
+	// Strip vendor prefixes like "anthropic/claude-..." (OpenRouter style)
+	if i := strings.LastIndex(model, "/"); i >= 0 {
+		model = model[i+1:]
+	}
+	best := ""
+	for prefix := range ct.prices {
+		if strings.HasPrefix(model, prefix) && len(prefix) > len(best) {
+			best = prefix
+		}
 	}
+	if best == "" {
+		return config.ModelPrice{}, false
+	}
+	return ct.prices[best], true
 }
 
-// Record adds a provider response's token usage to the tracker.
-func (ct *CostTracker) Record(usage providers.TokenUsage, providerName string) {
+// Record adds a provider response's token usage and cost to the tracker.
+// sessionID attributes the usage to a conversation or background task.
+func (ct *CostTracker) Record(ctx context.Context, sessionID string, resp providers.CompletionResponse) error {
 	ct.mu.Lock()
 	defer ct.mu.Unlock()
 
+	usage := resp.Usage
+	var cost float64
+	if price, ok := ct.priceFor(resp.Model); ok {
+		cost = (float64(usage.InputTokens)*price.Input + float64(usage.OutputTokens)*price.Output) / 1e6
+	} else if resp.Model != "" && usage.InputTokens+usage.OutputTokens > 0 {
+		ct.unpriced[resp.Model] = true
+	}
+
 	ct.inputTokens += usage.InputTokens
 	ct.outputTokens += usage.OutputTokens
+	ct.costUSD += cost
 	ct.requests++
 
-	pu, ok := ct.perProvider[providerName]
+	pu, ok := ct.perProvider[resp.Provider]
 	if !ok {
 		pu = &providerUsage{}
-		ct.perProvider[providerName] = pu
+		ct.perProvider[resp.Provider] = pu
 	}
 	pu.inputTokens += usage.InputTokens
 	pu.outputTokens += usage.OutputTokens
+	pu.costUSD += cost
 	pu.requests++
+
+	now := time.Now()
+	ct.period.add(ct.store, now, usage.InputTokens+usage.OutputTokens, cost)
+
+	if ct.store == nil {
+		return nil
+	}
+	return ct.store.RecordUsage(ctx, memory.Usage{
+		Day:          dayKey(now),
+		Provider:     resp.Provider,
+		Model:        resp.Model,
+		SessionID:    sessionID,
+		InputTokens:  usage.InputTokens,
+		OutputTokens: usage.OutputTokens,
+		CostUSD:      cost,
+		Requests:     1,
+	})
 }
 
-// Summary returns a formatted string of the current session's token usage.
-func (ct *CostTracker) Summary() string {
+// Summary returns a formatted report of token usage and spend.
+func (ct *CostTracker) Summary(ctx context.Context) string {
 	ct.mu.Lock()
 	defer ct.mu.Unlock()
 
 	total := ct.inputTokens + ct.outputTokens
-	s := fmt.Sprintf("Session Token Usage:\n  Total: %d tokens (%d in / %d out)\n  Requests: %d",
-		total, ct.inputTokens, ct.outputTokens, ct.requests)
+	s := fmt.Sprintf("Token Usage (since start):\n  Total: %d tokens (%d in / %d out)\n  Requests: %d\n  Cost: %s",
+		total, ct.inputTokens, ct.outputTokens, ct.requests, formatUSD(ct.costUSD))
 
 	if len(ct.perProvider) > 1 {
 		s += "\n  Per provider:"
-		for name, pu := range ct.perProvider {
-			s += fmt.Sprintf("\n    %s: %d tokens (%d in / %d out), %d requests",
-				name, pu.inputTokens+pu.outputTokens, pu.inputTokens, pu.outputTokens, pu.requests)
+		for _, name := range sortedKeys(ct.perProvider) {
+			pu := ct.perProvider[name]
+			s += fmt.Sprintf("\n    %s: %d tokens (%d in / %d out), %d requests, %s",
+				name, pu.inputTokens+pu.outputTokens, pu.inputTokens, pu.outputTokens, pu.requests, formatUSD(pu.costUSD))
+		}
+	}
+
+	ct.period.roll(ct.store, time.Now())
+	s += fmt.Sprintf("\nToday: %d tokens, %s%s", ct.period.dayTokens, formatUSD(ct.period.dayCost),
+		formatLimits(ct.limits.DailyTokens, ct.limits.DailyUSD))
+	s += fmt.Sprintf("\nThis month: %d tokens, %s%s", ct.period.monthTokens, formatUSD(ct.period.monthCost),
+		formatLimits(ct.limits.MonthlyTokens, ct.limits.MonthlyUSD))
+
+	if ct.store != nil {
+		if rows, err := ct.store.UsageByModelSince(ctx, dayKey(time.Now())); err == nil && len(rows) > 0 {
+			s += "\n  By model today:"
+			for _, u := range rows {
+				model := u.Model
+				if model == "" {
+					model = "(unknown model)"
+				}
+				s += fmt.Sprintf("\n    %s %s: %d tokens, %d requests, %s", u.Provider, model, u.Tokens(), u.Requests, formatUSD(u.CostUSD))
+			}
 		}
 	}
 
+	if len(ct.unpriced) > 0 {
+		s += fmt.Sprintf("\nNo price known for: %s (add them under \"pricing\" in config)", strings.Join(sortedKeys(ct.unpriced), ", "))
+	}
+
 	return s
 }
 
-// Reset clears all tracked usage.
+// Reset clears usage tracked since start. Persisted daily and monthly totals are kept.
 func (ct *CostTracker) Reset() {
 	ct.mu.Lock()
 	defer ct.mu.Unlock()
 	ct.inputTokens = 0
 	ct.outputTokens = 0
+	ct.costUSD = 0
 	ct.requests = 0
 	ct.perProvider = make(map[string]*providerUsage)
 }
+
+// usagePeriod holds usage totals for the current day and month.
+type usagePeriod struct {
+	day         string
+	month       string
+	dayTokens   int
+	dayCost     float64
+	monthTokens int
+	monthCost   float64
+}
+
+func dayKey(t time.Time) string   { return t.Format("2006-01-02") }
+func monthKey(t time.Time) string { return t.Format("2006-01") }
+
+// load reads the totals for the day and month containing now from the store.
+func (p *usagePeriod) load(store *memory.Store, now time.Time) error {
+	*p = usagePeriod{day: dayKey(now), month: monthKey(now)}
+	if store == nil {
+		return nil
+	}
+	ctx := context.Background()
+	day, err := store.UsageSince(ctx, p.day)
+	if err != nil {
+		return err
+	}
+	month, err := store.UsageSince(ctx, p.month+"-01")
+	if err != nil {
+		return err
+	}
+	p.dayTokens, p.dayCost = day.Tokens(), day.CostUSD
+	p.monthTokens, p.monthCost = month.Tokens(), month.CostUSD
+	return nil
+}
+
+// roll starts new totals when the day or month has changed since the last call.
+func (p *usagePeriod) roll(store *memory.Store, now time.Time) {
+	if p.day == dayKey(now) {
+		return
+	}
+	if p.month == monthKey(now) {
+		p.day = dayKey(now)
+		p.dayTokens, p.dayCost = 0, 0
+		return
+	}
+	if err := p.load(store, now); err != nil {
+		*p = usagePeriod{day: dayKey(now), month: monthKey(now)}
+	}
+}
+
+func (p *usagePeriod) add(store *memory.Store, now time.Time, tokens int, cost float64) {
+	p.roll(store, now)
+	p.dayTokens += tokens
+	p.dayCost += cost
+	p.monthTokens += tokens
+	p.monthCost += cost
+}
+
+func formatUSD(v float64) string {
+	if v > 0 && v < 0.01 {
+		return fmt.Sprintf("$%.4f", v)
+	}
+	return fmt.Sprintf("$%.2f", v)
+}
+
+// formatLimits renders configured limits as a suffix, e.g. " (limits: 100000 tokens, $5.00)".
+func formatLimits(tokens int, usd float64) string {
+	var parts []string
+	if tokens > 0 {
+		parts = append(parts, fmt.Sprintf("%d tokens", tokens))
+	}
+	if usd > 0 {
+		parts = append(parts, formatUSD(usd))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return " (limit: " + strings.Join(parts, ", ") + ")"
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -1,20 +1,34 @@
 package agent
 
 import (
+	"context"
+	"path/filepath"
 	"strings"
 	"testing"
 
+	"github.com/ImJafran/aeon/internal/bus"
+	"github.com/ImJafran/aeon/internal/config"
+	"github.com/ImJafran/aeon/internal/memory"
 	"github.com/ImJafran/aeon/internal/providers"
 )
 
+func usageResp(provider, model string, in, out int) providers.CompletionResponse {
+	return providers.CompletionResponse{
+		Provider: provider,
+		Model:    model,
+		Usage:    providers.TokenUsage{InputTokens: in, OutputTokens: out},
+	}
+}
+
 func TestCostTracker(t *testing.T) {
 	ct := NewCostTracker()
+	ctx := context.Background()
 
-	ct.Record(providers.TokenUsage{InputTokens: 100, OutputTokens: 50}, "anthropic")
-	ct.Record(providers.TokenUsage{InputTokens: 200, OutputTokens: 80}, "anthropic")
-	ct.Record(providers.TokenUsage{InputTokens: 50, OutputTokens: 20}, "gemini")
+	ct.Record(ctx, "s1", usageResp("anthropic", "", 100, 50))
+	ct.Record(ctx, "s1", usageResp("anthropic", "", 200, 80))
+	ct.Record(ctx, "s1", usageResp("gemini", "", 50, 20))
 
-	summary := ct.Summary()
+	summary := ct.Summary(ctx)
 	if !strings.Contains(summary, "350 in") {
 		t.Errorf("expected 350 input tokens in summary, got: %s", summary)
 	}
@@ -26,8 +40,120 @@ func TestCostTracker(t *testing.T) {
 	}
 
 	ct.Reset()
-	summary = ct.Summary()
+	summary = ct.Summary(ctx)
 	if !strings.Contains(summary, "Total: 0") {
 		t.Errorf("expected 0 tokens after reset, got: %s", summary)
 	}
 }
+
+func TestCostTrackerPricing(t *testing.T) {
+	ct := NewCostTracker()
+	ct.SetPrices(map[string]config.ModelPrice{"my-local": {Input: 1, Output: 2}})
+	ctx := context.Background()
+
+	// 1M in + 1M out on claude-sonnet-4 at $3/$15
+	ct.Record(ctx, "s1", usageResp("anthropic", "claude-sonnet-4-20250514", 1000000, 1000000))
+	// configured price, matched through a vendor prefix
+	ct.Record(ctx, "s1", usageResp("openai_compat", "acme/my-local-7b", 500000, 500000))
+	ct.Record(ctx, "s1", usageResp("openai_compat", "mystery-model", 10, 10))
+
+	summary := ct.Summary(ctx)
+	if !strings.Contains(summary, "Cost: $19.50") {
+		t.Errorf("expected $19.50 total, got: %s", summary)
+	}
+	if !strings.Contains(summary, "No price known for: mystery-model") {
+		t.Errorf("expected unpriced model to be reported, got: %s", summary)
+	}
+}
+
+func TestCostTrackerPersistsUsage(t *testing.T) {
+	store, err := memory.NewStore(filepath.Join(t.TempDir(), "aeon.db"))
+	if err != nil {
+		t.Fatalf("creating store: %v", err)
+	}
+	defer store.Close()
+	ctx := context.Background()
+
+	ct := NewCostTracker()
+	if err := ct.SetStore(store); err != nil {
+		t.Fatalf("set store: %v", err)
+	}
+	ct.Record(ctx, "s1", usageResp("anthropic", "claude-sonnet-4", 600, 400))
+	ct.Record(ctx, "s2", usageResp("anthropic", "claude-sonnet-4", 600, 400))
+
+	// A new tracker on the same store simulates a restart
+	restarted := NewCostTracker()
+	if err := restarted.SetStore(store); err != nil {
+		t.Fatalf("set store: %v", err)
+	}
+	restarted.SetBudget(BudgetLimits{DailyTokens: 2000})
+	if status := restarted.Check(); status.State != budgetExhausted {
+		t.Fatalf("expected persisted usage to exhaust the daily budget, got %+v", status)
+	}
+	if summary := restarted.Summary(ctx); !strings.Contains(summary, "anthropic claude-sonnet-4: 2000 tokens, 2 requests") {
+		t.Errorf("expected per-model usage for today, got: %s", summary)
+	}
+}
+
+func TestBudgetCheck(t *testing.T) {
+	ct := NewCostTracker()
+	ct.SetBudget(BudgetLimits{DailyTokens: 1000, WarnPercent: 80})
+	ctx := context.Background()
+
+	ct.Record(ctx, "s1", usageResp("test", "", 500, 0))
+	if status := ct.Check(); status.State != budgetOK {
+		t.Fatalf("expected ok at 50%%, got %+v", status)
+	}
+
+	ct.Record(ctx, "s1", usageResp("test", "", 350, 0))
+	status := ct.Check()
+	if status.State != budgetWarn || !status.Notify {
+		t.Fatalf("expected first warning at 85%%, got %+v", status)
+	}
+	if status := ct.Check(); status.State != budgetWarn || status.Notify {
+		t.Fatalf("expected warning without a repeat notification, got %+v", status)
+	}
+
+	ct.Record(ctx, "s1", usageResp("test", "", 150, 0))
+	status = ct.Check()
+	if status.State != budgetExhausted || !strings.Contains(status.Reason, "daily token") {
+		t.Fatalf("expected daily token budget exhausted, got %+v", status)
+	}
+}
+
+func TestBudgetEnforcedInLoop(t *testing.T) {
+	provider := newMockProvider("test",
+		providers.CompletionResponse{Content: "first", Provider: "test", Usage: providers.TokenUsage{InputTokens: 850}},
+		providers.CompletionResponse{Content: "second", Provider: "test", Usage: providers.TokenUsage{InputTokens: 200}},
+	)
+	loop, msgBus, outCh := setupTestLoop(provider)
+	ct := NewCostTracker()
+	ct.SetBudget(BudgetLimits{DailyTokens: 1000, WarnPercent: 80})
+	loop.SetCostTracker(ct)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go loop.Run(ctx)
+
+	msgBus.Publish(bus.InboundMessage{Channel: "test", ChatID: "1", Content: "one"})
+	waitForReply(t, outCh)
+
+	// Past the warning threshold: the user is told and the fast route is used
+	msgBus.Publish(bus.InboundMessage{Channel: "test", ChatID: "1", Content: "two"})
+	if warn := waitForReply(t, outCh); !strings.Contains(warn.Content, "[Budget]") {
+		t.Fatalf("expected budget warning, got %q", warn.Content)
+	}
+	waitForReply(t, outCh)
+	if provider.requests[1].Hint != "fast" {
+		t.Errorf("expected fast hint after the warning, got %q", provider.requests[1].Hint)
+	}
+
+	// Exhausted: refused without calling the provider
+	msgBus.Publish(bus.InboundMessage{Channel: "test", ChatID: "1", Content: "three"})
+	if refusal := waitForReply(t, outCh); !strings.Contains(refusal.Content, "exhausted") {
+		t.Fatalf("expected budget refusal, got %q", refusal.Content)
+	}
+	if len(provider.requests) != 2 {
+		t.Errorf("expected no provider call once the budget is exhausted, got %d calls", len(provider.requests))
+	}
+}
@@ -0,0 +1,79 @@
+package agent
+
+import (
+	"context"
+
+	"github.com/ImJafran/aeon/internal/bus"
+)
+
+// defaultMaxConcurrentTurns caps how many LLM turns run at once across all chats.
+const defaultMaxConcurrentTurns = 4
+
+// chatQueue holds messages waiting for a chat whose worker is busy.
+type chatQueue struct {
+	pending []bus.InboundMessage
+}
+
+// dispatch hands a message to its chat's worker, starting one if the chat is idle.
+// Messages for the same chat are processed in arrival order; different chats run in parallel.
+func (a *AgentLoop) dispatch(ctx context.Context, msg bus.InboundMessage) {
+	key := sessionKey(msg.Channel, msg.ChatID)
+
+	// Approval replies must reach the turn that is blocked waiting for them,
+	// not queue up behind it.
+	if a.approvalGate.HandleMessage(msg) {
+		return
+	}
+
+	a.queuesMu.Lock()
+	if q, busy := a.queues[key]; busy {
+		q.pending = append(q.pending, msg)
+		a.queuesMu.Unlock()
+		return
+	}
+	a.queues[key] = &chatQueue{}
+	a.queuesMu.Unlock()
+
+	a.workers.Add(1)
+	go a.drain(ctx, key, msg)
+}
+
+// drain processes messages for one chat until its queue is empty.
+func (a *AgentLoop) drain(ctx context.Context, key string, msg bus.InboundMessage) {
+	defer a.workers.Done()
+	for {
+		a.handleMessage(ctx, msg)
+
+		a.queuesMu.Lock()
+		q := a.queues[key]
+		if len(q.pending) == 0 || ctx.Err() != nil {
+			delete(a.queues, key)
+			a.queuesMu.Unlock()
+			return
+		}
+		msg = q.pending[0]
+		q.pending = q.pending[1:]
+		a.queuesMu.Unlock()
+	}
+}
+
+// acquireTurn blocks until a global turn slot is free. Returns false if ctx is cancelled first.
+func (a *AgentLoop) acquireTurn(ctx context.Context, channel, chatID string) bool {
+	select {
+	case a.turnSlots <- struct{}{}:
+		return true
+	default:
+	}
+
+	a.emitStatus(channel, chatID, "Waiting for other conversations to finish...")
+	select {
+	case a.turnSlots <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (a *AgentLoop) releaseTurn() {
+	<-a.turnSlots
+}
@@ -0,0 +1,235 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ImJafran/aeon/internal/bus"
+	"github.com/ImJafran/aeon/internal/memory"
+	"github.com/ImJafran/aeon/internal/providers"
+)
+
+const (
+	extractTimeout    = 60 * time.Second // per post-turn extraction
+	extractRelated    = 8                // existing memories shown to the extractor
+	duplicateOverlap  = 0.7              // word overlap at which a new memory duplicates an old one
+	maxExtractedItems = 5                // per exchange
+)
+
+const extractSystemPrompt = `You maintain the long-term memory of a personal agent. Read the latest exchange and decide what is worth remembering for future conversations.
+
+Remember: facts about the user and their world (names, projects, systems, accounts), preferences, decisions, corrections the user made, and lessons learned from mistakes.
+Skip: small talk, one-off requests, anything only true right now, secrets and credentials, and anything already in the existing memories.
+
+Reply with a JSON array only, no prose. Each item:
+{"content": "one self-contained fact", "category": "core|correction|lesson|daily", "tags": "comma,separated", "importance": 0.0-1.0, "replaces": <id>}
+
+- core: durable facts and preferences (importance 0.7-0.9)
+- correction: the user corrected the agent or an earlier fact (0.9)
+- lesson: something that failed and how to avoid it (0.85)
+- daily: useful for the next few days only (0.3-0.5)
+- replaces: the id of an existing memory this one updates or contradicts; omit otherwise.
+
+Reply [] if nothing is worth remembering.`
+
+// extractedMemory is one memory proposed by the extractor.
+type extractedMemory struct {
+	Content    string  `json:"content"`
+	Category   string  `json:"category"`
+	Tags       string  `json:"tags"`
+	Importance float64 `json:"importance"`
+	Replaces   int64   `json:"replaces"`
+}
+
+// SetMemoryExtraction enables extracting memories from each exchange after
+// the reply is sent. Off by default.
+func (a *AgentLoop) SetMemoryExtraction(enabled bool) {
+	a.extractMemories = enabled
+}
+
+// extractAfterTurn reads a finished exchange in the background and stores
+// what is worth remembering. It never delays the reply.
+func (a *AgentLoop) extractAfterTurn(ctx context.Context, sessionID string, msg bus.InboundMessage, reply string) {
+	if !a.extractMemories || a.memStore == nil || a.provider == nil || strings.TrimSpace(reply) == "" {
+		return
+	}
+	a.workers.Add(1)
+	go func() {
+		defer a.workers.Done()
+		ctx, cancel := context.WithTimeout(ctx, extractTimeout)
+		defer cancel()
+
+		src := memory.Provenance{Session: sessionID, Channel: msg.Channel, UserID: msg.UserID, Message: msg.Content}
+		stored, replaced, err := a.extractMemoriesFrom(ctx, src, reply)
+		if err != nil {
+			a.logger.Warn("memory extraction failed", "session", sessionID, "error", err)
+			return
+		}
+		if stored > 0 || replaced > 0 {
+			a.logger.Info("memories extracted", "session", sessionID, "stored", stored, "replaced", replaced)
+		}
+	}()
+}
+
+// extractMemoriesFrom asks the fast route for memories in the exchange
+// between src.Message and reply, and stores them with src as provenance,
+// skipping duplicates and superseding the memories they replace. It returns
+// how many were stored new and how many replaced an old one.
+func (a *AgentLoop) extractMemoriesFrom(ctx context.Context, src memory.Provenance, reply string) (int, int, error) {
+	// Extraction already uses the fast route, so the budget can only stop it
+	if a.costTracker != nil {
+		if status := a.costTracker.Check(); status.State == budgetExhausted {
+			a.logger.Info("memory extraction skipped", "session", src.Session, "reason", status.Reason)
+			return 0, 0, nil
+		}
+	}
+	if a.scrubber != nil {
+		src.Message = a.scrubber.ScrubCredentials(src.Message)
+		reply = a.scrubber.ScrubCredentials(reply)
+	}
+	userText := src.Message
+
+	related, _ := a.memStore.RecallText(ctx, userText+"\n"+reply, extractRelated)
+	relatedIDs := make(map[int64]bool, len(related))
+
+	var b strings.Builder
+	if len(related) > 0 {
+		b.WriteString("Existing memories:\n")
+		for _, e := range related {
+			relatedIDs[e.ID] = true
+			fmt.Fprintf(&b, "[id %d, %s] %s\n", e.ID, e.Category, truncateStr(e.Content, 300))
+		}
+		b.WriteString("\n")
+	}
+	fmt.Fprintf(&b, "Latest exchange:\n[user] %s\n[assistant] %s\n", truncateStr(userText, 2000), truncateStr(reply, 2000))
+
+	resp, err := a.provider.Complete(ctx, providers.CompletionRequest{
+		SystemPrompt: extractSystemPrompt,
+		Messages:     []providers.Message{{Role: "user", Content: b.String()}},
+		Hint:         "fast",
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	a.recordUsage(ctx, src.Session, resp)
+
+	items, err := parseExtracted(resp.Content)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	stored, replaced := 0, 0
+	for _, item := range items {
+		content := strings.TrimSpace(item.Content)
+		if content == "" {
+			continue
+		}
+		if a.scrubber != nil {
+			content = a.scrubber.ScrubCredentials(content)
+		}
+		entry := memory.Entry{
+			Category:   extractedCategory(item.Category),
+			Content:    content,
+			Tags:       item.Tags,
+			Importance: min(max(item.Importance, 0), 1),
+			Source:     src,
+			Scope:      a.memStore.ScopeFor(ctx, ""),
+		}
+
+		if item.Replaces > 0 && relatedIDs[item.Replaces] {
+			entry.Supersedes = item.Replaces
+			if _, err := a.memStore.Save(ctx, entry); err != nil {
+				return stored, replaced, err
+			}
+			relatedIDs[item.Replaces] = false
+			replaced++
+			continue
+		}
+		if a.isDuplicateMemory(ctx, content) {
+			continue
+		}
+		if _, err := a.memStore.Save(ctx, entry); err != nil {
+			return stored, replaced, err
+		}
+		stored++
+	}
+	return stored, replaced, nil
+}
+
+// parseExtracted reads the extractor's JSON array, tolerating code fences
+// and stray prose around it.
+func parseExtracted(text string) ([]extractedMemory, error) {
+	start, end := strings.Index(text, "["), strings.LastIndex(text, "]")
+	if start < 0 || end < start {
+		return nil, nil
+	}
+	var items []extractedMemory
+	if err := json.Unmarshal([]byte(text[start:end+1]), &items); err != nil {
+		return nil, fmt.Errorf("parsing extracted memories: %w", err)
+	}
+	if len(items) > maxExtractedItems {
+		items = items[:maxExtractedItems]
+	}
+	return items, nil
+}
+
+func extractedCategory(name string) memory.Category {
+	switch name {
+	case "core":
+		return memory.CategoryCore
+	case "correction":
+		return memory.CategoryCorrection
+	case "lesson":
+		return memory.CategoryLesson
+	case "daily":
+		return memory.CategoryDaily
+	default:
+		return memory.CategoryCustom
+	}
+}
+
+// isDuplicateMemory reports whether a stored memory already says content.
+func (a *AgentLoop) isDuplicateMemory(ctx context.Context, content string) bool {
+	existing, err := a.memStore.RecallText(ctx, content, 3)
+	if err != nil {
+		return false
+	}
+	for _, e := range existing {
+		if wordOverlap(content, e.Content) >= duplicateOverlap {
+			return true
+		}
+	}
+	return false
+}
+
+// wordOverlap returns the share of the shorter text's words found in the
+// other, ignoring case, punctuation and short words.
+func wordOverlap(a, b string) float64 {
+	words := func(s string) map[string]bool {
+		set := map[string]bool{}
+		for _, w := range strings.Fields(strings.ToLower(s)) {
+			w = strings.Trim(w, ".,!?;:\"'()[]{}")
+			if len(w) > 2 {
+				set[w] = true
+			}
+		}
+		return set
+	}
+	wa, wb := words(a), words(b)
+	if len(wa) > len(wb) {
+		wa, wb = wb, wa
+	}
+	if len(wa) == 0 {
+		return 0
+	}
+	shared := 0
+	for w := range wa {
+		if wb[w] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(wa))
+}
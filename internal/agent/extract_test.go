@@ -0,0 +1,176 @@
+package agent
+
+import (
+	"context"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ImJafran/aeon/internal/bus"
+	"github.com/ImJafran/aeon/internal/memory"
+	"github.com/ImJafran/aeon/internal/providers"
+)
+
+func newExtractStore(t *testing.T) *memory.Store {
+	t.Helper()
+	store, err := memory.NewStore(filepath.Join(t.TempDir(), "aeon.db"))
+	if err != nil {
+		t.Fatalf("creating store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestMemoryExtractedAfterTurn(t *testing.T) {
+	store := newExtractStore(t)
+	provider := newMockProvider("test",
+		providers.CompletionResponse{Content: "Noted, I'll use Postgres 16.", Provider: "test"},
+		providers.CompletionResponse{Content: "```json\n" +
+			`[{"content": "The team's database is Postgres 16", "category": "core", "tags": "postgres", "importance": 0.8}]` +
+			"\n```"},
+	)
+	loop, msgBus, outCh := setupTestLoop(provider)
+	loop.SetMemoryStore(store)
+	loop.SetMemoryExtraction(true)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go loop.Run(ctx)
+	msgBus.Publish(bus.InboundMessage{Channel: "test", ChatID: "1", Content: "We run Postgres 16 everywhere"})
+	waitForReply(t, outCh)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		core, _ := store.List(ctx, memory.CategoryCore, 5)
+		if len(core) == 1 {
+			if core[0].Content != "The team's database is Postgres 16" {
+				t.Fatalf("unexpected memory %+v", core[0])
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the extracted memory")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if hint := provider.requests[1].Hint; hint != "fast" {
+		t.Errorf("expected extraction on the fast route, got %q", hint)
+	}
+}
+
+func TestMemoryExtractionDisabledByDefault(t *testing.T) {
+	store := newExtractStore(t)
+	provider := newMockProvider("test", providers.CompletionResponse{Content: "Hi!", Provider: "test"})
+	loop, msgBus, outCh := setupTestLoop(provider)
+	loop.SetMemoryStore(store)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go loop.Run(ctx)
+	msgBus.Publish(bus.InboundMessage{Channel: "test", ChatID: "1", Content: "I like tea"})
+	waitForReply(t, outCh)
+	cancel()
+	time.Sleep(50 * time.Millisecond)
+
+	if len(provider.requests) != 1 {
+		t.Errorf("expected no extraction call, got %d requests", len(provider.requests))
+	}
+}
+
+func TestExtractDeduplicatesAndSupersedes(t *testing.T) {
+	store := newExtractStore(t)
+	ctx := context.Background()
+	coffee, _ := store.MemStore(ctx, memory.CategoryCore, "User drinks coffee black", "preferences", 0.8)
+	editor, _ := store.MemStore(ctx, memory.CategoryCore, "User's editor is Vim", "tools", 0.8)
+
+	provider := newMockProvider("test", providers.CompletionResponse{Content: `[
+		{"content": "User drinks their coffee black", "category": "core"},
+		{"content": "User switched from Vim to Helix as their editor", "category": "correction", "replaces": ` + strconv.FormatInt(editor, 10) + `},
+		{"content": "User's SECRET token is abc", "category": "core", "importance": 3},
+		{"content": "Should not replace an unrelated id", "category": "daily", "replaces": 999}
+	]`})
+	loop, _, _ := setupTestLoop(provider)
+	loop.SetMemoryStore(store)
+	loop.SetScrubber(&mockScrubber{})
+
+	src := memory.Provenance{Session: "s1", Channel: "slack", UserID: "U1", Message: "I moved to Helix, and my editor question: which one did I use?"}
+	stored, replaced, err := loop.extractMemoriesFrom(ctx, src, "You used Vim; noted Helix now.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored != 2 || replaced != 1 {
+		t.Fatalf("expected 2 stored and 1 replaced, got %d and %d", stored, replaced)
+	}
+
+	if _, err := store.Get(ctx, coffee); err != nil {
+		t.Error("the duplicate should leave the original in place")
+	}
+	if _, err := store.Get(ctx, editor); err == nil {
+		t.Error("the superseded memory should be gone")
+	}
+	corrections, _ := store.List(ctx, memory.CategoryCorrection, 5)
+	if len(corrections) != 1 || !strings.Contains(corrections[0].Content, "Helix") {
+		t.Fatalf("unexpected corrections %+v", corrections)
+	}
+	e, _ := store.Get(ctx, corrections[0].ID)
+	if e.Supersedes != editor || e.Source.UserID != "U1" || e.Source.Channel != "slack" {
+		t.Errorf("expected the correction to supersede %d with provenance, got %+v", editor, e)
+	}
+	all, _ := store.List(ctx, "", 10)
+	for _, e := range all {
+		if strings.Contains(e.Content, "SECRET") {
+			t.Errorf("stored an unscrubbed memory: %q", e.Content)
+		}
+		if strings.Contains(e.Content, "coffee") && e.ID != coffee {
+			t.Errorf("stored a duplicate: %q", e.Content)
+		}
+	}
+}
+
+func TestExtractionStopsWhenBudgetExhausted(t *testing.T) {
+	provider := newMockProvider("test", providers.CompletionResponse{Content: `[{"content": "User drinks tea", "category": "core"}]`})
+	loop, _, _ := setupTestLoop(provider)
+	loop.SetMemoryStore(newExtractStore(t))
+	ct := NewCostTracker()
+	ct.SetBudget(BudgetLimits{DailyTokens: 1000})
+	ctx := context.Background()
+	ct.Record(ctx, "s1", usageResp("test", "", 1000, 0))
+	loop.SetCostTracker(ct)
+
+	stored, _, err := loop.extractMemoriesFrom(ctx, memory.Provenance{Session: "s1", Message: "I drink tea"}, "Noted.")
+	if err != nil || stored != 0 {
+		t.Fatalf("expected extraction skipped, got %d stored (%v)", stored, err)
+	}
+	if len(provider.requests) != 0 {
+		t.Errorf("expected no provider call over budget, got %d", len(provider.requests))
+	}
+}
+
+func TestMemoryRecallScopedToUser(t *testing.T) {
+	store := newExtractStore(t)
+	ctx := context.Background()
+	store.Save(ctx, memory.Entry{Category: memory.CategoryCore, Content: "The office wifi is aeon-guest"})
+	store.Save(ctx, memory.Entry{Category: memory.CategoryCore, Content: "Alice is vegetarian", Scope: memory.UserScope("slack", "U-alice")})
+
+	provider := newMockProvider("test")
+	loop, msgBus, outCh := setupTestLoop(provider)
+	loop.SetMemoryStore(store)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go loop.Run(ctx)
+
+	msgBus.Publish(bus.InboundMessage{Channel: "slack", ChatID: "C1", UserID: "U-bob", Content: "Where should we order lunch?"})
+	waitForReply(t, outCh)
+	msgBus.Publish(bus.InboundMessage{Channel: "slack", ChatID: "C1", UserID: "U-alice", Content: "Where should we order lunch?"})
+	waitForReply(t, outCh)
+
+	bob, alice := provider.requests[0].SystemPrompt, provider.requests[1].SystemPrompt
+	if !strings.Contains(bob, "aeon-guest") || strings.Contains(bob, "vegetarian") {
+		t.Errorf("bob should only see the global memory:\n%s", bob)
+	}
+	if !strings.Contains(alice, "aeon-guest") || !strings.Contains(alice, "vegetarian") {
+		t.Errorf("alice should see her own memory:\n%s", alice)
+	}
+}
@@ -0,0 +1,125 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+
+	"github.com/ImJafran/aeon/internal/memory"
+	"github.com/ImJafran/aeon/internal/providers"
+)
+
+// toHistoryEntry converts a conversation message into its persisted form.
+func toHistoryEntry(m providers.Message, provider string) memory.HistoryEntry {
+	e := memory.HistoryEntry{
+		Role:       m.Role,
+		Content:    m.Content,
+		ToolCallID: m.ToolCallID,
+		Provider:   provider,
+	}
+	if len(m.ToolCalls) > 0 {
+		if data, err := json.Marshal(m.ToolCalls); err == nil {
+			e.ToolCalls = string(data)
+		}
+	}
+	return e
+}
+
+// fromHistoryEntry rebuilds a conversation message from its persisted form.
+func fromHistoryEntry(e memory.HistoryEntry) providers.Message {
+	m := providers.Message{
+		Role:       e.Role,
+		Content:    e.Content,
+		ToolCallID: e.ToolCallID,
+	}
+	if e.ToolCalls != "" {
+		json.Unmarshal([]byte(e.ToolCalls), &m.ToolCalls)
+	}
+	return m
+}
+
+// repairHistory makes a window of history safe to send to any provider:
+//   - it starts at a user message, never mid tool exchange
+//   - every tool result directly follows the assistant message that called it
+//   - every assistant tool call has a result (unanswered calls are dropped)
+//
+// Windows cut from the middle of the table, crashes mid-turn and trimming
+// can all break these rules; both Anthropic and OpenAI reject such requests.
+func repairHistory(msgs []providers.Message) []providers.Message {
+	start := 0
+	for start < len(msgs) && (msgs[start].Role != "user" || msgs[start].ToolCallID != "") {
+		start++
+	}
+
+	out := make([]providers.Message, 0, len(msgs)-start)
+	for i := start; i < len(msgs); i++ {
+		m := msgs[i]
+		if m.Role == "tool" {
+			continue // not preceded by its assistant message, see below
+		}
+		if m.Role != "assistant" || len(m.ToolCalls) == 0 {
+			out = append(out, m)
+			continue
+		}
+
+		// Collect the results that immediately follow this assistant message.
+		results := make(map[string]providers.Message)
+		j := i + 1
+		for ; j < len(msgs) && msgs[j].Role == "tool"; j++ {
+			results[msgs[j].ToolCallID] = msgs[j]
+		}
+
+		var calls []providers.ToolCall
+		var answered []providers.Message
+		for _, tc := range m.ToolCalls {
+			if r, ok := results[tc.ID]; ok {
+				calls = append(calls, tc)
+				answered = append(answered, r)
+			}
+		}
+		if len(calls) > 0 {
+			m.ToolCalls = calls
+			out = append(out, m)
+			out = append(out, answered...)
+		} else if m.Content != "" {
+			out = append(out, providers.Message{Role: "assistant", Content: m.Content})
+		}
+		i = j - 1
+	}
+
+	return normalizeToolCallIDs(out)
+}
+
+// validToolCallID matches IDs accepted by both Anthropic and OpenAI-compatible APIs.
+var validToolCallID = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// normalizeToolCallIDs rewrites tool call IDs that another provider may reject
+// (empty, or containing characters outside [a-zA-Z0-9_-]). Some OpenAI-compatible
+// backends produce such IDs, and a session may be replayed to a different provider
+// after /model or a failover.
+func normalizeToolCallIDs(msgs []providers.Message) []providers.Message {
+	renamed := make(map[string]string)
+	n := 0
+	for i := range msgs {
+		if msgs[i].Role == "assistant" && len(msgs[i].ToolCalls) > 0 {
+			calls := make([]providers.ToolCall, len(msgs[i].ToolCalls))
+			copy(calls, msgs[i].ToolCalls)
+			for k, tc := range calls {
+				if validToolCallID.MatchString(tc.ID) {
+					continue
+				}
+				n++
+				id := fmt.Sprintf("call_aeon_%d", n)
+				renamed[tc.ID] = id
+				calls[k].ID = id
+			}
+			msgs[i].ToolCalls = calls
+		}
+		if msgs[i].Role == "tool" {
+			if id, ok := renamed[msgs[i].ToolCallID]; ok {
+				msgs[i].ToolCallID = id
+			}
+		}
+	}
+	return msgs
+}
@@ -0,0 +1,70 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/ImJafran/aeon/internal/providers"
+)
+
+func TestRepairHistoryDropsLeadingToolExchange(t *testing.T) {
+	msgs := []providers.Message{
+		{Role: "tool", ToolCallID: "a", Content: "orphan result"},
+		{Role: "assistant", Content: "answer to an older question"},
+		{Role: "user", Content: "hi"},
+		{Role: "assistant", ToolCalls: []providers.ToolCall{{ID: "b", Name: "echo_tool", Arguments: "{}"}}},
+		{Role: "tool", ToolCallID: "b", Content: "echoed"},
+		{Role: "assistant", Content: "done"},
+	}
+
+	got := repairHistory(msgs)
+	if len(got) != 4 || got[0].Role != "user" {
+		t.Fatalf("expected history to start at the user message, got %+v", got)
+	}
+	if got[1].ToolCalls[0].ID != "b" || got[2].ToolCallID != "b" {
+		t.Fatalf("tool exchange not preserved: %+v", got)
+	}
+}
+
+func TestRepairHistoryDropsUnansweredCalls(t *testing.T) {
+	msgs := []providers.Message{
+		{Role: "user", Content: "run two things"},
+		{Role: "assistant", Content: "on it", ToolCalls: []providers.ToolCall{
+			{ID: "a", Name: "echo_tool"},
+			{ID: "b", Name: "echo_tool"},
+		}},
+		{Role: "tool", ToolCallID: "a", Content: "first"},
+		{Role: "user", Content: "hello?"},
+		{Role: "assistant", ToolCalls: []providers.ToolCall{{ID: "c", Name: "echo_tool"}}},
+	}
+
+	got := repairHistory(msgs)
+	if len(got) != 4 {
+		t.Fatalf("expected 4 messages, got %d: %+v", len(got), got)
+	}
+	if calls := got[1].ToolCalls; len(calls) != 1 || calls[0].ID != "a" {
+		t.Fatalf("expected only the answered call to remain, got %+v", calls)
+	}
+	if got[3].Role != "user" {
+		t.Fatalf("trailing unanswered call should be dropped, got %+v", got[3])
+	}
+}
+
+func TestRepairHistoryNormalizesToolCallIDs(t *testing.T) {
+	msgs := []providers.Message{
+		{Role: "user", Content: "hi"},
+		{Role: "assistant", ToolCalls: []providers.ToolCall{{ID: "fn:0", Name: "echo_tool"}}},
+		{Role: "tool", ToolCallID: "fn:0", Content: "echoed"},
+	}
+
+	got := repairHistory(msgs)
+	id := got[1].ToolCalls[0].ID
+	if !validToolCallID.MatchString(id) {
+		t.Fatalf("tool call ID not normalized: %q", id)
+	}
+	if got[2].ToolCallID != id {
+		t.Fatalf("tool result not relinked: %q != %q", got[2].ToolCallID, id)
+	}
+	if msgs[1].ToolCalls[0].ID != "fn:0" {
+		t.Fatal("input slice was modified")
+	}
+}
@@ -2,14 +2,17 @@ package agent
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/ImJafran/aeon/internal/audit"
 	"github.com/ImJafran/aeon/internal/bus"
 	"github.com/ImJafran/aeon/internal/config"
 	"github.com/ImJafran/aeon/internal/memory"
@@ -39,17 +42,28 @@ type AgentLoop struct {
 	subMgr       *SubagentManager
 	costTracker  *CostTracker
 	approvalGate *ApprovalGate
+	roles        *Roles // nil: everyone may do everything
 	logger             *slog.Logger
-	sessionID          string
 	systemPrompt       string
 	maxHistoryMessages int
 	maxIterations      int
-	history            []providers.Message // in-memory conversation history for current session
-	recentErrors       []string            // last N tool errors for runtime context
+	streaming          bool // request streamed replies and forward partial text
+	extractMemories    bool // extract memories from each exchange in the background
+	sessionsMu         sync.Mutex
+	sessions           map[string]*session // per (channel, chat_id) conversations
+	queuesMu           sync.Mutex
+	queues             map[string]*chatQueue // chats with a running worker
+	workers            sync.WaitGroup
+	turnSlots          chan struct{} // global limit on concurrent LLM turns
+	errorsMu           sync.Mutex
+	recentErrors       []string // last N tool errors for runtime context
 }
 
 func NewAgentLoop(b *bus.MessageBus, provider providers.Provider, registry *tools.Registry, logger *slog.Logger) *AgentLoop {
+	gate := NewApprovalGate(b, 0)
+	gate.SetLogger(logger)
 	return &AgentLoop{
+		approvalGate:       gate,
 		bus:                b,
 		provider:           provider,
 		registry:           registry,
@@ -57,7 +71,10 @@ func NewAgentLoop(b *bus.MessageBus, provider providers.Provider, registry *tool
 		logger:             logger,
 		maxHistoryMessages: defaultMaxHistoryMessages,
 		maxIterations:      defaultMaxIterations,
-		sessionID:          fmt.Sprintf("session_%d", time.Now().UnixNano()),
+		streaming:          true,
+		sessions:           make(map[string]*session),
+		queues:             make(map[string]*chatQueue),
+		turnSlots:          make(chan struct{}, defaultMaxConcurrentTurns),
 	}
 }
 
@@ -73,6 +90,19 @@ func (a *AgentLoop) SetMaxIterations(n int) {
 	}
 }
 
+// SetMaxConcurrentTurns sets how many chats may run an LLM turn at the same time.
+// Must be called before Run.
+func (a *AgentLoop) SetMaxConcurrentTurns(n int) {
+	if n > 0 {
+		a.turnSlots = make(chan struct{}, n)
+	}
+}
+
+// SetStreaming enables or disables streamed replies (on by default).
+func (a *AgentLoop) SetStreaming(enabled bool) {
+	a.streaming = enabled
+}
+
 func (a *AgentLoop) SetScrubber(s CredentialScrubber) {
 	a.scrubber = s
 }
@@ -81,10 +111,21 @@ func (a *AgentLoop) SetSubagentManager(m *SubagentManager) {
 	a.subMgr = m
 }
 
+// SetCostTracker replaces the loop's cost tracker, e.g. to share one with subagents.
+func (a *AgentLoop) SetCostTracker(ct *CostTracker) {
+	a.costTracker = ct
+}
+
 func (a *AgentLoop) SetApprovalGate(g *ApprovalGate) {
 	a.approvalGate = g
 }
 
+// SetRoles limits each user to the tools and commands of their role, and
+// ignores users without one.
+func (a *AgentLoop) SetRoles(r *Roles) {
+	a.roles = r
+}
+
 func (a *AgentLoop) SetMemoryStore(m *memory.Store) {
 	a.memStore = m
 }
@@ -98,65 +139,49 @@ func (a *AgentLoop) SetSystemPrompt(prompt string) {
 }
 
 func (a *AgentLoop) Run(ctx context.Context) {
-	a.logger.Info("agent loop started", "session", a.sessionID)
-
-	// Load prior conversation history from database
-	a.loadHistory(ctx)
+	a.logger.Info("agent loop started")
 
 	for {
 		select {
 		case <-ctx.Done():
+			a.workers.Wait()
 			a.logger.Info("agent loop stopped")
 			return
 		case msg, ok := <-a.bus.Inbound():
 			if !ok {
+				a.workers.Wait()
 				return
 			}
-			a.handleMessage(ctx, msg)
+			a.dispatch(ctx, msg)
 		}
 	}
 }
 
-// loadHistory loads the last N messages from the most recent session into memory.
-func (a *AgentLoop) loadHistory(ctx context.Context) {
+// loadHistory loads the last N messages of a resumed session into memory.
+func (a *AgentLoop) loadHistory(ctx context.Context, s *session) {
 	if a.memStore == nil {
 		return
 	}
 
-	// Resume the most recent session instead of starting empty
-	prevSession, err := a.memStore.GetLatestSessionID()
-	if err == nil && prevSession != "" {
-		a.sessionID = prevSession
-		a.logger.Info("resuming session", "session", prevSession)
-	}
-
-	rows, err := a.memStore.GetHistory(ctx, a.sessionID, a.maxHistoryMessages)
+	rows, err := a.memStore.GetHistory(ctx, s.id, a.maxHistoryMessages)
 	if err != nil {
 		a.logger.Warn("failed to load history", "error", err)
 		return
 	}
 
+	msgs := make([]providers.Message, 0, len(rows))
 	for _, row := range rows {
-		role := row["role"]
-		// Skip tool messages from resumed sessions — tool call IDs and
-		// tool_calls arrays aren't persisted, so these produce invalid
-		// Anthropic API requests (orphaned tool_result without tool_use).
-		if role == "tool" {
-			continue
-		}
-		a.history = append(a.history, providers.Message{
-			Role:    role,
-			Content: row["content"],
-		})
+		msgs = append(msgs, fromHistoryEntry(row))
 	}
+	s.history = repairHistory(msgs)
 
 	// Ensure history doesn't end with an assistant message (provider expects user turn next)
-	for len(a.history) > 0 && a.history[len(a.history)-1].Role == "assistant" {
-		a.history = a.history[:len(a.history)-1]
+	for len(s.history) > 0 && s.history[len(s.history)-1].Role == "assistant" {
+		s.history = s.history[:len(s.history)-1]
 	}
 
-	if len(a.history) > 0 {
-		a.logger.Info("loaded conversation history", "messages", len(a.history))
+	if len(s.history) > 0 {
+		a.logger.Info("loaded conversation history", "session", s.id, "messages", len(s.history))
 	}
 }
 
@@ -172,13 +197,26 @@ func (a *AgentLoop) handleMessage(ctx context.Context, msg bus.InboundMessage) {
 		return
 	}
 
-	// Handle slash commands
-	if len(msg.Content) > 0 && msg.Content[0] == '/' {
-		// Check if this is an approval response first
-		if a.approvalGate != nil && a.approvalGate.HandleApprovalCommand(msg.Content) {
+	// With roles configured, users get only what their role allows
+	var role *Role
+	if a.roles != nil {
+		r, ok := a.roles.RoleFor(msg)
+		if !ok {
+			a.logger.Warn("access_denied", "channel", msg.Channel, "user_id", msg.UserID)
+			a.bus.Send(bus.OutboundMessage{
+				Channel: msg.Channel,
+				ChatID:  msg.ChatID,
+				Content: "You don't have access to this agent.",
+			})
 			return
 		}
-		a.handleCommand(ctx, msg)
+		role = &r
+		ctx = tools.WithToolFilter(ctx, r.Name, r.AllowsTool)
+	}
+
+	// Handle slash commands
+	if len(msg.Content) > 0 && msg.Content[0] == '/' {
+		a.handleCommand(ctx, msg, role)
 		return
 	}
 
@@ -224,28 +262,41 @@ func (a *AgentLoop) handleHeartbeat(ctx context.Context, msg bus.InboundMessage)
 }
 
 func (a *AgentLoop) runAgentLoop(ctx context.Context, msg bus.InboundMessage) {
+	if !a.acquireTurn(ctx, msg.Channel, msg.ChatID) {
+		return
+	}
+	defer a.releaseTurn()
+
 	turnStart := time.Now()
+	sess := a.sessionFor(ctx, msg.Channel, msg.ChatID)
+	provider := a.providerFor(sess)
 
 	// Add user message to history
 	userMsg := providers.Message{Role: "user", Content: msg.Content}
-	a.history = append(a.history, userMsg)
-	a.saveToHistory(ctx, "user", msg.Content)
+	sess.history = append(sess.history, userMsg)
+	a.saveToHistory(ctx, sess, userMsg, "")
+
+	// Memory is recalled from, and stored into, the scopes of this user and chat
+	ctx = memory.WithAudience(ctx, memory.Audience{Channel: msg.Channel, ChatID: msg.ChatID, UserID: msg.UserID})
 
 	// Build system prompt with relevant memories injected
-	systemPrompt := a.buildSystemPrompt(ctx, msg.Content)
+	systemPrompt := a.buildSystemPrompt(ctx, provider, msg.Content)
 
-	// Build messages: full conversation history
-	messages := make([]providers.Message, len(a.history))
-	copy(messages, a.history)
+	// Memory tools record the session, user and message a memory came from
+	source := msg.Content
+	if a.scrubber != nil {
+		source = a.scrubber.ScrubCredentials(source)
+	}
+	toolCtx := tools.WithSource(ctx, sess.id, msg.UserID, source)
 
-	toolDefs := a.registry.ToolDefs()
+	toolDefs := a.registry.ToolDefsFor(toolCtx)
 
-	// Wire retry callback so user sees "Retrying with..." on provider failover
-	if chain, ok := a.provider.(*providers.ProviderChain); ok {
-		chain.SetRetryCallback(func(failed, next string) {
+	// Wire retry callback so user sees "Retrying with..." on provider failover.
+	// The callback lives on a per-turn copy since other chats share the chain.
+	if chain, ok := provider.(*providers.ProviderChain); ok {
+		provider = chain.WithRetryCallback(func(failed, next string) {
 			a.emitStatus(msg.Channel, msg.ChatID, fmt.Sprintf("Retrying with %s...", next))
 		})
-		defer chain.SetRetryCallback(nil)
 	}
 
 	for i := 0; i < a.maxIterations; i++ {
@@ -253,17 +304,36 @@ func (a *AgentLoop) runAgentLoop(ctx context.Context, msg bus.InboundMessage) {
 			a.emitStatus(msg.Channel, msg.ChatID, "Processing...")
 		}
 
-		llmStart := time.Now()
-		resp, err := a.provider.Complete(ctx, providers.CompletionRequest{
-			SystemPrompt: systemPrompt,
+		// Stop or downgrade to the fast route when the usage budget runs low
+		hint, ok := a.checkBudget(msg.Channel, msg.ChatID)
+		if !ok {
+			return
+		}
+
+		// Keep the conversation within the model's context window, folding
+		// evicted turns into the session summary
+		a.fitContext(ctx, sess, provider, systemPrompt, toolDefs)
+		messages := make([]providers.Message, len(sess.history))
+		copy(messages, sess.history)
+
+		req := providers.CompletionRequest{
+			SystemPrompt: systemPrompt + summarySection(sess.summary),
 			Messages:     messages,
 			Tools:        toolDefs,
-		})
+			Hint:         hint,
+		}
+		stream := a.newReplyStream(msg.Channel, msg.ChatID, fmt.Sprintf("%s-%d-%d", sess.id, turnStart.UnixNano(), i))
+		if a.streaming {
+			req.OnStream = stream.onEvent
+		}
+
+		llmStart := time.Now()
+		resp, err := provider.Complete(ctx, req)
 		llmDuration := time.Since(llmStart)
 
 		if err != nil {
 			a.logger.Error("llm_request",
-				"provider", a.provider.Name(),
+				"provider", provider.Name(),
 				"latency_ms", llmDuration.Milliseconds(),
 				"error", err,
 				"iteration", i,
@@ -278,9 +348,7 @@ func (a *AgentLoop) runAgentLoop(ctx context.Context, msg bus.InboundMessage) {
 		}
 
 		// Record token usage
-		if a.costTracker != nil {
-			a.costTracker.Record(resp.Usage, resp.Provider)
-		}
+		a.recordUsage(ctx, sess.id, resp)
 
 		// Structured LLM request log
 		a.logger.Info("llm_request",
@@ -297,17 +365,20 @@ func (a *AgentLoop) runAgentLoop(ctx context.Context, msg bus.InboundMessage) {
 
 		// If there are tool calls, execute them
 		if len(resp.ToolCalls) > 0 {
+			// Leave any interim text the user was watching complete
+			stream.flush()
+
 			// Add assistant message with tool calls
 			assistantMsg := providers.Message{
 				Role:      "assistant",
 				Content:   resp.Content,
 				ToolCalls: resp.ToolCalls,
 			}
-			messages = append(messages, assistantMsg)
-			a.history = append(a.history, assistantMsg)
+			sess.history = append(sess.history, assistantMsg)
+			a.saveToHistory(ctx, sess, assistantMsg, resp.Provider)
 
 			// Execute tools (parallel for independent calls)
-			results := a.executeTools(ctx, resp.ToolCalls, msg.Channel, msg.ChatID)
+			results := a.executeTools(toolCtx, resp.ToolCalls, msg.Channel, msg.ChatID)
 			for _, result := range results {
 				// Scrub credentials from tool output before it enters conversation
 				forLLM := result.ForLLM
@@ -319,8 +390,8 @@ func (a *AgentLoop) runAgentLoop(ctx context.Context, msg bus.InboundMessage) {
 					Content:    forLLM,
 					ToolCallID: result.ToolCallID,
 				}
-				messages = append(messages, toolMsg)
-				a.history = append(a.history, toolMsg)
+				sess.history = append(sess.history, toolMsg)
+				a.saveToHistory(ctx, sess, toolMsg, "")
 
 				// Send user-visible output if any (scrub credentials first)
 				if result.ForUser != "" && !result.Silent {
@@ -345,26 +416,28 @@ func (a *AgentLoop) runAgentLoop(ctx context.Context, msg bus.InboundMessage) {
 				outContent = a.scrubber.ScrubCredentials(outContent)
 			}
 			a.bus.Send(bus.OutboundMessage{
-				Channel: msg.Channel,
-				ChatID:  msg.ChatID,
-				Content: outContent,
+				Channel:  msg.Channel,
+				ChatID:   msg.ChatID,
+				Content:  outContent,
+				Metadata: stream.finalMetadata(),
 			})
 
 			// Save assistant response to history
-			a.history = append(a.history, providers.Message{
-				Role:    "assistant",
-				Content: resp.Content,
-			})
-			a.saveToHistory(ctx, "assistant", resp.Content)
+			replyMsg := providers.Message{Role: "assistant", Content: resp.Content}
+			sess.history = append(sess.history, replyMsg)
+			a.saveToHistory(ctx, sess, replyMsg, resp.Provider)
 
-			// Trim in-memory history if it gets too long
-			a.trimHistory()
+			// Heartbeats report on tasks; there is nothing to learn from them
+			if msg.Channel != "system" {
+				a.extractAfterTurn(ctx, sess.id, msg, resp.Content)
+			}
 		}
 		a.logger.Info("turn_complete",
 			"total_ms", time.Since(turnStart).Milliseconds(),
 			"iterations", i+1,
 			"response_len", len(resp.Content),
 			"channel", msg.Channel,
+			"session", sess.id,
 		)
 		return
 	}
@@ -376,36 +449,32 @@ func (a *AgentLoop) runAgentLoop(ctx context.Context, msg bus.InboundMessage) {
 	})
 }
 
-// saveToHistory persists a message to the SQLite conversation_history table.
-func (a *AgentLoop) saveToHistory(ctx context.Context, role, content string) {
+// saveToHistory persists a message, including tool calls and results, to the
+// SQLite conversation_history table. provider is set for assistant messages.
+func (a *AgentLoop) saveToHistory(ctx context.Context, s *session, m providers.Message, provider string) {
 	if a.memStore == nil {
 		return
 	}
-	if err := a.memStore.SaveHistory(ctx, a.sessionID, role, content); err != nil {
+	if err := a.memStore.AppendHistory(ctx, s.id, toHistoryEntry(m, provider)); err != nil {
 		a.logger.Warn("failed to save history", "error", err)
 	}
 }
 
-// trimHistory keeps the in-memory history bounded.
-// Drops oldest messages beyond 2x maxHistoryMessages, keeping the most recent ones.
-func (a *AgentLoop) trimHistory() {
-	limit := a.maxHistoryMessages * 2
-	if len(a.history) > limit {
-		a.history = a.history[len(a.history)-a.maxHistoryMessages:]
-	}
-}
-
-// clearHistory resets the conversation for /new command.
-func (a *AgentLoop) clearHistory(ctx context.Context) {
-	a.history = nil
+// clearHistory resets a chat's conversation for the /new and /model commands.
+// The session keeps its model override but starts under a fresh session ID.
+func (a *AgentLoop) clearHistory(ctx context.Context, s *session) {
+	s.history = nil
+	s.summary = ""
 	if a.memStore != nil {
-		a.memStore.ClearHistory(ctx, a.sessionID)
+		a.memStore.ClearHistory(ctx, s.id)
 	}
-	a.sessionID = fmt.Sprintf("session_%d", time.Now().UnixNano())
+	s.id = newSessionID()
+	a.saveSession(ctx, s)
 }
 
 func (a *AgentLoop) executeTools(ctx context.Context, calls []providers.ToolCall, channel, chatID string) []tools.ToolResult {
 	results := make([]tools.ToolResult, len(calls))
+	ctx = tools.WithOrigin(ctx, channel, chatID)
 
 	executeSingle := func(idx int, tc providers.ToolCall) {
 		// Emit status update so the user sees what tool is running
@@ -443,9 +512,9 @@ func (a *AgentLoop) executeTools(ctx context.Context, calls []providers.ToolCall
 		// Handle approval flow — request user confirmation for dangerous commands
 		if result.NeedsApproval {
 			a.logger.Info("tool_approval_requested", "tool", tc.Name, "info", result.ApprovalInfo)
-			if a.waitForApproval(ctx, channel, chatID, result.ApprovalInfo) {
+			if decision, approver := a.waitForApproval(ctx, channel, chatID, result.ApprovalInfo); decision == audit.Approved {
 				// Re-execute with approval bypass (deny patterns still enforced)
-				approvedCtx := tools.WithApproved(ctx)
+				approvedCtx := tools.WithApprovedBy(ctx, approver)
 
 				toolStart = time.Now()
 				result, err = a.registry.Execute(approvedCtx, tc.Name, []byte(tc.Arguments))
@@ -475,6 +544,7 @@ func (a *AgentLoop) executeTools(ctx context.Context, calls []providers.ToolCall
 				)
 				result.ToolCallID = tc.ID
 			} else {
+				a.registry.AuditApproval(ctx, tc.Name, []byte(tc.Arguments), decision, approver)
 				result = tools.ToolResult{
 					ToolCallID: tc.ID,
 					ForLLM:     "User denied the command execution. Do not retry without asking.",
@@ -504,68 +574,37 @@ func (a *AgentLoop) executeTools(ctx context.Context, calls []providers.ToolCall
 	return results
 }
 
-// waitForApproval sends an approval request with inline buttons and waits for user response.
-// Reads directly from the bus inbound channel (safe because the main loop is blocked here).
-func (a *AgentLoop) waitForApproval(ctx context.Context, channel, chatID, description string) bool {
-	// Send approval request with metadata for inline keyboard buttons
-	a.bus.Send(bus.OutboundMessage{
-		Channel:  channel,
-		ChatID:   chatID,
-		Content:  fmt.Sprintf("⚠️ Approval required:\n%s", description),
-		Metadata: map[string]string{"approval": "true"},
-	})
-
-	timeout := time.NewTimer(60 * time.Second)
-	defer timeout.Stop()
-
-	var buffered []bus.InboundMessage
-
-	for {
-		select {
-		case msg := <-a.bus.Inbound():
-			cmd := strings.TrimSpace(strings.ToLower(msg.Content))
-			if cmd == "/approve" {
-				a.logger.Info("tool_approval_granted")
-				// Re-publish buffered messages so they aren't lost
-				for _, m := range buffered {
-					go a.bus.Publish(m)
-				}
-				return true
-			}
-			if cmd == "/deny" {
-				a.logger.Info("tool_approval_denied")
-				for _, m := range buffered {
-					go a.bus.Publish(m)
-				}
-				a.bus.Send(bus.OutboundMessage{
-					Channel: channel,
-					ChatID:  chatID,
-					Content: "Command denied.",
-				})
-				return false
-			}
-			// Buffer non-approval messages for later
-			buffered = append(buffered, msg)
+// waitForApproval sends an approval request through the approval gate and
+// waits for its approvers. Replies are routed to the gate by dispatch, so other
+// chats keep running while this turn waits. It returns the audit decision
+// (approved, rejected or timeout) and who answered.
+func (a *AgentLoop) waitForApproval(ctx context.Context, channel, chatID, description string) (decision, approver string) {
+	approved, approver, err := a.approvalGate.RequestApproval(ctx, channel, chatID, description)
+	switch {
+	case errors.Is(err, tools.ErrApprovalTimeout):
+		a.logger.Info("tool_approval_timeout")
+		a.bus.Send(bus.OutboundMessage{
+			Channel: channel,
+			ChatID:  chatID,
+			Content: fmt.Sprintf("Approval timed out (%v). Command not executed.", a.approvalGate.Timeout()),
+		})
+		return audit.Timeout, ""
 
-		case <-timeout.C:
-			a.logger.Info("tool_approval_timeout")
-			for _, m := range buffered {
-				go a.bus.Publish(m)
-			}
-			a.bus.Send(bus.OutboundMessage{
-				Channel: channel,
-				ChatID:  chatID,
-				Content: "Approval timed out (60s). Command not executed.",
-			})
-			return false
+	case err != nil:
+		return audit.Rejected, ""
 
-		case <-ctx.Done():
-			for _, m := range buffered {
-				go a.bus.Publish(m)
-			}
-			return false
-		}
+	case approved:
+		a.logger.Info("tool_approval_granted", "approver", approver)
+		return audit.Approved, approver
 	}
+
+	a.logger.Info("tool_approval_denied", "approver", approver)
+	a.bus.Send(bus.OutboundMessage{
+		Channel: channel,
+		ChatID:  chatID,
+		Content: "Command denied.",
+	})
+	return audit.Rejected, approver
 }
 
 // readWorkspaceFile reads a file from the workspace directory, returning empty string on error.
@@ -578,7 +617,7 @@ func readWorkspaceFile(name string) string {
 	return strings.TrimSpace(string(data))
 }
 
-func (a *AgentLoop) buildSystemPrompt(ctx context.Context, query string) string {
+func (a *AgentLoop) buildSystemPrompt(ctx context.Context, provider providers.Provider, query string) string {
 	var b strings.Builder
 
 	// 1. SOUL.md — identity and personality (who am I)
@@ -609,9 +648,9 @@ func (a *AgentLoop) buildSystemPrompt(ctx context.Context, query string) string
 	}
 
 	// 5. Runtime context — compact, only dynamic info
-	if a.provider != nil {
+	if provider != nil {
 		fmt.Fprintf(&b, "Provider: %s | Time: %s | Skills: %d",
-			a.provider.Name(),
+			provider.Name(),
 			time.Now().Format("2006-01-02 15:04 MST"),
 			a.skillCount(),
 		)
@@ -622,11 +661,13 @@ func (a *AgentLoop) buildSystemPrompt(ctx context.Context, query string) string
 		}
 		b.WriteString("\n")
 	}
+	a.errorsMu.Lock()
 	if len(a.recentErrors) > 0 {
 		b.WriteString("Recent errors: ")
 		b.WriteString(strings.Join(a.recentErrors, "; "))
 		b.WriteString("\n")
 	}
+	a.errorsMu.Unlock()
 
 	// 6. Safety boundary
 	b.WriteString(`
@@ -651,6 +692,8 @@ func (a *AgentLoop) skillCount() int {
 func (a *AgentLoop) recordToolError(toolName, errMsg string) {
 	const maxErrors = 3
 	entry := fmt.Sprintf("%s: %s", toolName, truncateStr(errMsg, 100))
+	a.errorsMu.Lock()
+	defer a.errorsMu.Unlock()
 	a.recentErrors = append(a.recentErrors, entry)
 	if len(a.recentErrors) > maxErrors {
 		a.recentErrors = a.recentErrors[len(a.recentErrors)-maxErrors:]
@@ -674,6 +717,26 @@ func (a *AgentLoop) emitStatus(channel, chatID, status string) {
 	})
 }
 
+// redactionSummary formats credential redaction counts by source, like
+// "5 (config 2, pattern 3)".
+func redactionSummary(counts map[string]int64) string {
+	var total int64
+	sources := make([]string, 0, len(counts))
+	for source, n := range counts {
+		total += n
+		sources = append(sources, source)
+	}
+	if total == 0 {
+		return "0"
+	}
+	sort.Strings(sources)
+	parts := make([]string, len(sources))
+	for i, source := range sources {
+		parts[i] = fmt.Sprintf("%s %d", source, counts[source])
+	}
+	return fmt.Sprintf("%d (%s)", total, strings.Join(parts, ", "))
+}
+
 // humanToolName converts a tool name like "shell_exec" to "shell" for display.
 func humanToolName(name string) string {
 	for _, suffix := range []string{"_exec", "_read", "_write", "_manage", "_search", "_list"} {
@@ -682,35 +745,56 @@ func humanToolName(name string) string {
 	return strings.ReplaceAll(name, "_", " ")
 }
 
-func (a *AgentLoop) handleCommand(ctx context.Context, msg bus.InboundMessage) {
+// handleCommand runs a slash command. role is the sender's, or nil when
+// roles aren't configured.
+func (a *AgentLoop) handleCommand(ctx context.Context, msg bus.InboundMessage, role *Role) {
 	var response string
 
 	cmd := strings.Fields(msg.Content)
+	sess := a.sessionFor(ctx, msg.Channel, msg.ChatID)
+
+	if role != nil && !role.AllowsCommand(cmd[0]) {
+		a.bus.Send(bus.OutboundMessage{
+			Channel: msg.Channel,
+			ChatID:  msg.ChatID,
+			Content: fmt.Sprintf("The %s role may not use %s.", role.Name, cmd[0]),
+		})
+		return
+	}
 
 	switch cmd[0] {
 	case "/status":
 		providerName := "none"
-		if a.provider != nil {
-			providerName = a.provider.Name()
+		if provider := a.providerFor(sess); provider != nil {
+			providerName = provider.Name()
 		}
-		toolCount := len(a.registry.ToolDefs())
+		toolCount := len(a.registry.ToolDefsFor(ctx))
 		taskCount := 0
 		if a.subMgr != nil {
 			taskCount = a.subMgr.Count()
 		}
-		historyCount := len(a.history)
+		historyCount := len(sess.history)
 		response = fmt.Sprintf("Aeon Status:\n  Provider: %s\n  Tools: %d loaded\n  Active tasks: %d\n  Session: %d messages", providerName, toolCount, taskCount, historyCount)
+		if r, ok := a.scrubber.(interface{ Redactions() map[string]int64 }); ok {
+			response += "\n  Redacted: " + redactionSummary(r.Redactions())
+		}
 	case "/model":
 		if chain, ok := a.provider.(*providers.ProviderChain); ok {
 			if len(cmd) < 2 {
+				current := chain.PrimaryName()
+				if sc, ok := a.providerFor(sess).(*providers.ProviderChain); ok {
+					current = sc.PrimaryName()
+				}
 				response = fmt.Sprintf("Current: %s\nAvailable: %s\nUsage: /model <name>",
-					chain.PrimaryName(), strings.Join(chain.AvailableNames(), ", "))
-			} else if err := chain.SwitchTo(cmd[1]); err != nil {
+					current, strings.Join(chain.AvailableNames(), ", "))
+			} else if switched, err := chain.WithPrimary(cmd[1]); err != nil {
 				response = err.Error()
 			} else {
-				// Clear history to avoid cross-provider tool call ID mismatches
-				a.clearHistory(ctx)
-				response = fmt.Sprintf("Switched to %s (conversation reset)", chain.PrimaryName())
+				// Only this chat switches. Clear its history to avoid
+				// cross-provider tool call ID mismatches.
+				sess.model = cmd[1]
+				a.clearHistory(ctx, sess)
+				response = fmt.Sprintf("Switched to %s (conversation reset)", switched.PrimaryName())
 			}
 		} else {
 			response = "Single provider mode — no switching available."
@@ -718,7 +802,7 @@ func (a *AgentLoop) handleCommand(ctx context.Context, msg bus.InboundMessage) {
 	case "/skills":
 		response = "Use find_skills tool to list installed skills."
 	case "/new":
-		a.clearHistory(ctx)
+		a.clearHistory(ctx, sess)
 		response = "Conversation cleared. Starting fresh."
 	case "/stop":
 		if a.subMgr != nil {
@@ -733,12 +817,12 @@ func (a *AgentLoop) handleCommand(ctx context.Context, msg bus.InboundMessage) {
 		}
 	case "/cost":
 		if a.costTracker != nil {
-			response = a.costTracker.Summary()
+			response = a.costTracker.Summary(ctx)
 		} else {
 			response = "Cost tracking not available."
 		}
 	case "/help":
-		response = "Commands:\n  /status  — Show system status\n  /model   — Switch AI provider\n  /skills  — List evolved skills\n  /cost    — Show token usage stats\n  /new     — Start fresh conversation\n  /stop    — Cancel running tasks\n  /help    — Show this help"
+		response = "Commands:\n  /status  — Show system status\n  /model   — Switch AI provider\n  /skills  — List evolved skills\n  /cost    — Show token usage and spend\n  /new     — Start fresh conversation\n  /stop    — Cancel running tasks\n  /pending — List approvals waiting for you\n  /help    — Show this help"
 	default:
 		response = fmt.Sprintf("Unknown command: %s. Type /help for available commands.", cmd[0])
 	}
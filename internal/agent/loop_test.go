@@ -5,11 +5,14 @@ import (
 	"encoding/json"
 	"io"
 	"log/slog"
+	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/ImJafran/aeon/internal/bus"
+	"github.com/ImJafran/aeon/internal/memory"
 	"github.com/ImJafran/aeon/internal/providers"
 	"github.com/ImJafran/aeon/internal/tools"
 )
@@ -24,6 +27,7 @@ type mockProvider struct {
 	errors    []error
 	idx       int
 	name      string
+	requests  []providers.CompletionRequest
 }
 
 func newMockProvider(name string, resps ...providers.CompletionResponse) *mockProvider {
@@ -33,7 +37,8 @@ func newMockProvider(name string, resps ...providers.CompletionResponse) *mockPr
 func (m *mockProvider) Name() string     { return m.name }
 func (m *mockProvider) Available() bool   { return true }
 
-func (m *mockProvider) Complete(_ context.Context, _ providers.CompletionRequest) (providers.CompletionResponse, error) {
+func (m *mockProvider) Complete(_ context.Context, req providers.CompletionRequest) (providers.CompletionResponse, error) {
+	m.requests = append(m.requests, req)
 	i := m.idx
 	m.idx++
 	if i < len(m.errors) && m.errors[i] != nil {
@@ -258,8 +263,331 @@ func TestCostTrackerIntegration(t *testing.T) {
 		t.Fatal("timeout")
 	}
 
-	summary := loop.costTracker.Summary()
+	summary := loop.costTracker.Summary(context.Background())
 	if !strings.Contains(summary, "100 in") || !strings.Contains(summary, "50 out") {
 		t.Errorf("cost tracker should have recorded tokens, got: %s", summary)
 	}
 }
+
+// waitForReply reads outbound messages until a non-status reply arrives.
+func waitForReply(t *testing.T, outCh chan bus.OutboundMessage) bus.OutboundMessage {
+	t.Helper()
+	deadline := time.After(2 * time.Second)
+	for {
+		select {
+		case out := <-outCh:
+			if out.Metadata != nil && out.Metadata[bus.MetaStatus] == "true" {
+				continue
+			}
+			return out
+		case <-deadline:
+			t.Fatal("timeout waiting for reply")
+		}
+	}
+}
+
+func TestSessionsArePerChat(t *testing.T) {
+	provider := newMockProvider("test")
+	loop, msgBus, outCh := setupTestLoop(provider)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go loop.Run(ctx)
+
+	msgBus.Publish(bus.InboundMessage{Channel: "telegram", ChatID: "1", Content: "hello from one"})
+	waitForReply(t, outCh)
+
+	msgBus.Publish(bus.InboundMessage{Channel: "slack", ChatID: "1", Content: "hello from slack"})
+	waitForReply(t, outCh)
+
+	// Same chat ID on a different channel must not see the telegram conversation
+	if got := provider.requests[1].Messages; len(got) != 1 || got[0].Content != "hello from slack" {
+		t.Fatalf("slack chat saw foreign history: %+v", got)
+	}
+
+	// /new in the slack chat must leave the telegram chat untouched
+	msgBus.Publish(bus.InboundMessage{Channel: "slack", ChatID: "1", Content: "/new"})
+	waitForReply(t, outCh)
+
+	msgBus.Publish(bus.InboundMessage{Channel: "telegram", ChatID: "1", Content: "still there?"})
+	waitForReply(t, outCh)
+
+	if got := provider.requests[2].Messages; len(got) != 3 {
+		t.Fatalf("expected telegram history of 3 messages, got %d: %+v", len(got), got)
+	}
+}
+
+// gateProvider blocks requests whose latest message contains "slow" until release is closed.
+type gateProvider struct {
+	mu      sync.Mutex
+	calls   []string
+	started chan string
+	release chan struct{}
+}
+
+func newGateProvider() *gateProvider {
+	return &gateProvider{started: make(chan string, 16), release: make(chan struct{})}
+}
+
+func (g *gateProvider) Name() string    { return "gate" }
+func (g *gateProvider) Available() bool { return true }
+
+func (g *gateProvider) Complete(ctx context.Context, req providers.CompletionRequest) (providers.CompletionResponse, error) {
+	last := req.Messages[len(req.Messages)-1].Content
+	g.mu.Lock()
+	g.calls = append(g.calls, last)
+	g.mu.Unlock()
+	g.started <- last
+
+	if strings.Contains(last, "slow") {
+		select {
+		case <-g.release:
+		case <-ctx.Done():
+			return providers.CompletionResponse{}, ctx.Err()
+		}
+	}
+	return providers.CompletionResponse{Content: "done: " + last, Provider: "gate"}, nil
+}
+
+func (g *gateProvider) callCount() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return len(g.calls)
+}
+
+func TestChatsRunConcurrently(t *testing.T) {
+	provider := newGateProvider()
+	loop, msgBus, outCh := setupTestLoop(provider)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go loop.Run(ctx)
+
+	msgBus.Publish(bus.InboundMessage{Channel: "telegram", ChatID: "1", Content: "slow task"})
+	<-provider.started
+	msgBus.Publish(bus.InboundMessage{Channel: "telegram", ChatID: "1", Content: "queued behind slow"})
+
+	// Another chat gets answered while chat 1 is still busy
+	msgBus.Publish(bus.InboundMessage{Channel: "telegram", ChatID: "2", Content: "quick question"})
+	if out := waitForReply(t, outCh); out.ChatID != "2" {
+		t.Fatalf("expected reply to chat 2 first, got %+v", out)
+	}
+
+	// Chat 1's second message must wait for its first turn
+	if n := provider.callCount(); n != 2 {
+		t.Fatalf("expected 2 provider calls while chat 1 is busy, got %d", n)
+	}
+
+	close(provider.release)
+	first := waitForReply(t, outCh)
+	second := waitForReply(t, outCh)
+	if first.Content != "done: slow task" || second.Content != "done: queued behind slow" {
+		t.Fatalf("chat 1 replies out of order: %q, %q", first.Content, second.Content)
+	}
+}
+
+func TestMaxConcurrentTurns(t *testing.T) {
+	provider := newGateProvider()
+	loop, msgBus, outCh := setupTestLoop(provider)
+	loop.SetMaxConcurrentTurns(1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go loop.Run(ctx)
+
+	msgBus.Publish(bus.InboundMessage{Channel: "telegram", ChatID: "1", Content: "slow task"})
+	<-provider.started
+	msgBus.Publish(bus.InboundMessage{Channel: "telegram", ChatID: "2", Content: "quick question"})
+
+	// Chat 2 is told it is waiting, but doesn't reach the provider
+	deadline := time.After(2 * time.Second)
+	for waiting := false; !waiting; {
+		select {
+		case out := <-outCh:
+			waiting = out.ChatID == "2" && out.Metadata[bus.MetaStatus] == "true"
+		case <-deadline:
+			t.Fatal("timeout waiting for queued status")
+		}
+	}
+	if n := provider.callCount(); n != 1 {
+		t.Fatalf("expected 1 provider call with limit 1, got %d", n)
+	}
+
+	close(provider.release)
+	waitForReply(t, outCh)
+	waitForReply(t, outCh)
+	if n := provider.callCount(); n != 2 {
+		t.Fatalf("expected 2 provider calls after release, got %d", n)
+	}
+}
+
+// approvalTool asks for approval on its first call and runs on the re-execution.
+type approvalTool struct{ calls int }
+
+func (t *approvalTool) Name() string                { return "risky_tool" }
+func (t *approvalTool) Description() string         { return "needs approval" }
+func (t *approvalTool) Parameters() json.RawMessage { return json.RawMessage(`{"type":"object"}`) }
+func (t *approvalTool) Execute(_ context.Context, _ json.RawMessage) (tools.ToolResult, error) {
+	t.calls++
+	if t.calls == 1 {
+		return tools.ToolResult{NeedsApproval: true, ApprovalInfo: "risky"}, nil
+	}
+	return tools.ToolResult{ForLLM: "ran risky", Silent: true}, nil
+}
+
+func TestApprovalRoutedToWaitingChat(t *testing.T) {
+	provider := newMockProvider("test",
+		providers.CompletionResponse{ToolCalls: []providers.ToolCall{{ID: "c1", Name: "risky_tool", Arguments: "{}"}}},
+		providers.CompletionResponse{Content: "approved and done"},
+		providers.CompletionResponse{Content: "other chat reply"},
+	)
+	loop, msgBus, outCh := setupTestLoop(provider)
+	loop.registry.Register(&approvalTool{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go loop.Run(ctx)
+
+	msgBus.Publish(bus.InboundMessage{Channel: "telegram", ChatID: "1", Content: "do the risky thing"})
+	if out := waitForReply(t, outCh); out.Metadata["approval"] != "true" {
+		t.Fatalf("expected approval request, got %+v", out)
+	}
+
+	// /approve from a different chat is an ordinary command there, not an approval
+	msgBus.Publish(bus.InboundMessage{Channel: "telegram", ChatID: "2", Content: "/approve"})
+	if out := waitForReply(t, outCh); out.ChatID != "2" || !strings.Contains(out.Content, "Unknown command") {
+		t.Fatalf("expected unknown command reply in chat 2, got %+v", out)
+	}
+
+	msgBus.Publish(bus.InboundMessage{Channel: "telegram", ChatID: "1", Content: "/approve"})
+	if out := waitForReply(t, outCh); out.ChatID != "1" || out.Content != "approved and done" {
+		t.Fatalf("expected approved turn to finish, got %+v", out)
+	}
+}
+
+func TestResumedSessionKeepsToolTranscript(t *testing.T) {
+	store, err := memory.NewStore(filepath.Join(t.TempDir(), "aeon.db"))
+	if err != nil {
+		t.Fatalf("creating store: %v", err)
+	}
+	defer store.Close()
+
+	first := newMockProvider("test",
+		providers.CompletionResponse{
+			ToolCalls: []providers.ToolCall{{ID: "call_1", Name: "echo_tool", Arguments: `{"input":"x"}`}},
+			Provider:  "test",
+		},
+		providers.CompletionResponse{Content: "echo done", Provider: "test"},
+	)
+	loop, msgBus, outCh := setupTestLoop(first)
+	loop.SetMemoryStore(store)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go loop.Run(ctx)
+	msgBus.Publish(bus.InboundMessage{Channel: "telegram", ChatID: "1", Content: "echo something"})
+	waitForReply(t, outCh) // tool output
+	waitForReply(t, outCh) // final answer
+	msgBus.Publish(bus.InboundMessage{Channel: "telegram", ChatID: "1", Content: "thanks"})
+	waitForReply(t, outCh)
+	cancel()
+
+	// A fresh loop on the same store simulates a restart
+	second := newMockProvider("test")
+	loop, msgBus, outCh = setupTestLoop(second)
+	loop.SetMemoryStore(store)
+
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	go loop.Run(ctx)
+	msgBus.Publish(bus.InboundMessage{Channel: "telegram", ChatID: "1", Content: "what did you echo?"})
+	waitForReply(t, outCh)
+
+	msgs := second.requests[0].Messages
+	var sawCall, sawResult bool
+	for _, m := range msgs {
+		if m.Role == "assistant" && len(m.ToolCalls) == 1 && m.ToolCalls[0].ID == "call_1" && m.ToolCalls[0].Arguments == `{"input":"x"}` {
+			sawCall = true
+		}
+		if m.Role == "tool" && m.ToolCallID == "call_1" && m.Content == "echo_result" {
+			sawResult = true
+		}
+	}
+	if !sawCall || !sawResult {
+		t.Fatalf("resumed history lost the tool exchange: %+v", msgs)
+	}
+}
+
+// streamingProvider streams its reply word by word through OnStream.
+type streamingProvider struct{ reply string }
+
+func (p *streamingProvider) Name() string    { return "streaming" }
+func (p *streamingProvider) Available() bool { return true }
+func (p *streamingProvider) Complete(_ context.Context, req providers.CompletionRequest) (providers.CompletionResponse, error) {
+	if req.OnStream != nil {
+		for _, word := range strings.SplitAfter(p.reply, " ") {
+			req.OnStream(providers.StreamEvent{Text: word})
+		}
+	}
+	return providers.CompletionResponse{Content: p.reply, Provider: "streaming"}, nil
+}
+
+func TestStreamedReply(t *testing.T) {
+	loop, msgBus, outCh := setupTestLoop(&streamingProvider{reply: "the key is SECRET ok"})
+	loop.SetScrubber(&mockScrubber{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go loop.Run(ctx)
+
+	msgBus.Publish(bus.InboundMessage{Channel: "cli", ChatID: "local", Content: "tell me"})
+
+	var partial bus.OutboundMessage
+	for partial.Metadata[bus.MetaPartial] != "true" {
+		select {
+		case partial = <-outCh:
+		case <-time.After(2 * time.Second):
+			t.Fatal("timeout waiting for partial reply")
+		}
+	}
+	if partial.Metadata[bus.MetaStreamID] == "" || !strings.HasPrefix("the key is SECRET ok", partial.Content) {
+		t.Fatalf("unexpected partial message %+v", partial)
+	}
+
+	var final bus.OutboundMessage
+	for final = waitForReply(t, outCh); final.Metadata[bus.MetaPartial] == "true"; final = waitForReply(t, outCh) {
+		if strings.Contains(final.Content, "SECRET") {
+			t.Fatalf("partial reply not scrubbed: %q", final.Content)
+		}
+	}
+	if final.Content != "the key is [REDACTED] ok" {
+		t.Errorf("unexpected final content %q", final.Content)
+	}
+	if final.Metadata[bus.MetaStreamID] != partial.Metadata[bus.MetaStreamID] {
+		t.Errorf("final reply not linked to stream: %+v", final.Metadata)
+	}
+}
+
+func TestStreamingDisabled(t *testing.T) {
+	loop, msgBus, outCh := setupTestLoop(&streamingProvider{reply: "hello there"})
+	loop.SetStreaming(false)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go loop.Run(ctx)
+
+	msgBus.Publish(bus.InboundMessage{Channel: "cli", ChatID: "local", Content: "hi"})
+	out := waitForReply(t, outCh)
+	if out.Metadata[bus.MetaPartial] == "true" || out.Metadata[bus.MetaStreamID] != "" {
+		t.Fatalf("expected a plain reply with streaming off, got %+v", out)
+	}
+}
+
+func TestRedactionSummary(t *testing.T) {
+	if got := redactionSummary(nil); got != "0" {
+		t.Errorf("expected 0 with nothing redacted, got %q", got)
+	}
+	if got := redactionSummary(map[string]int64{"pattern": 3, "config": 2}); got != "5 (config 2, pattern 3)" {
+		t.Errorf("unexpected summary %q", got)
+	}
+}
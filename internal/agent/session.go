@@ -0,0 +1,85 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ImJafran/aeon/internal/memory"
+	"github.com/ImJafran/aeon/internal/providers"
+)
+
+// session holds the conversation state for a single (channel, chat_id) pair.
+type session struct {
+	id      string
+	channel string
+	chatID  string
+	model   string              // provider override set via /model, empty = chain default
+	history []providers.Message // in-memory conversation history for this chat
+	summary string              // rolling summary of turns evicted from history
+}
+
+func newSessionID() string {
+	return fmt.Sprintf("session_%d", time.Now().UnixNano())
+}
+
+func sessionKey(channel, chatID string) string {
+	return channel + ":" + chatID
+}
+
+// sessionFor returns the session for a chat, resuming it from the database
+// the first time the chat is seen.
+func (a *AgentLoop) sessionFor(ctx context.Context, channel, chatID string) *session {
+	key := sessionKey(channel, chatID)
+
+	a.sessionsMu.Lock()
+	defer a.sessionsMu.Unlock()
+
+	if s, ok := a.sessions[key]; ok {
+		return s
+	}
+
+	s := &session{id: newSessionID(), channel: channel, chatID: chatID}
+	if a.memStore != nil {
+		if prev, err := a.memStore.GetChatSession(ctx, channel, chatID); err == nil {
+			s.id = prev.ID
+			s.model = prev.Model
+			s.summary = prev.Summary
+			a.logger.Info("resuming session", "session", s.id, "channel", channel, "chat_id", chatID)
+			a.loadHistory(ctx, s)
+		} else {
+			a.saveSession(ctx, s)
+		}
+	}
+	a.sessions[key] = s
+	return s
+}
+
+// saveSession persists the session row so the chat resumes it after a restart.
+func (a *AgentLoop) saveSession(ctx context.Context, s *session) {
+	if a.memStore == nil {
+		return
+	}
+	err := a.memStore.SaveSession(ctx, memory.Session{
+		ID:      s.id,
+		Channel: s.channel,
+		ChatID:  s.chatID,
+		Model:   s.model,
+	})
+	if err != nil {
+		a.logger.Warn("failed to save session", "session", s.id, "error", err)
+	}
+}
+
+// providerFor returns the provider a session should talk to, honouring its /model override.
+func (a *AgentLoop) providerFor(s *session) providers.Provider {
+	if s.model == "" {
+		return a.provider
+	}
+	if chain, ok := a.provider.(*providers.ProviderChain); ok {
+		if p, err := chain.WithPrimary(s.model); err == nil {
+			return p
+		}
+	}
+	return a.provider
+}
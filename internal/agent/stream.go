@@ -0,0 +1,78 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ImJafran/aeon/internal/bus"
+	"github.com/ImJafran/aeon/internal/providers"
+)
+
+// streamInterval throttles partial updates so channels get a few updates a
+// second rather than one message per token.
+const streamInterval = 300 * time.Millisecond
+
+// replyStream forwards a provider's streamed output to the user's channel as
+// partial outbound messages. Each message carries the full text so far, so a
+// dropped update is harmless.
+type replyStream struct {
+	a       *AgentLoop
+	channel string
+	chatID  string
+	id      string
+	text    strings.Builder
+	sent    int // length of text already delivered
+	last    time.Time
+}
+
+func (a *AgentLoop) newReplyStream(channel, chatID, id string) *replyStream {
+	return &replyStream{a: a, channel: channel, chatID: chatID, id: id}
+}
+
+// onEvent is passed to the provider as CompletionRequest.OnStream.
+func (s *replyStream) onEvent(ev providers.StreamEvent) {
+	switch {
+	case ev.Reset:
+		s.text.Reset()
+		s.sent = 0
+	case ev.ToolName != "":
+		s.a.emitStatus(s.channel, s.chatID, fmt.Sprintf("Preparing %s...", humanToolName(ev.ToolName)))
+	case ev.Text != "":
+		s.text.WriteString(ev.Text)
+		if time.Since(s.last) >= streamInterval {
+			s.flush()
+		}
+	}
+}
+
+// flush delivers any text not yet sent.
+func (s *replyStream) flush() {
+	if s.text.Len() == s.sent {
+		return
+	}
+	content := s.text.String()
+	if s.a.scrubber != nil {
+		content = s.a.scrubber.ScrubCredentials(content)
+	}
+	s.a.bus.Send(bus.OutboundMessage{
+		Channel: s.channel,
+		ChatID:  s.chatID,
+		Content: content,
+		Metadata: map[string]string{
+			bus.MetaPartial:  "true",
+			bus.MetaStreamID: s.id,
+		},
+	})
+	s.sent = s.text.Len()
+	s.last = time.Now()
+}
+
+// finalMetadata returns metadata for the completed reply, linking it to the
+// partial messages so channels can replace them instead of posting anew.
+func (s *replyStream) finalMetadata() map[string]string {
+	if s.sent == 0 {
+		return nil
+	}
+	return map[string]string{bus.MetaStreamID: s.id}
+}
@@ -49,6 +49,7 @@ type SubagentManager struct {
 	provider providers.Provider
 	registry *tools.Registry
 	scrubber CredentialScrubber
+	costs    *CostTracker
 	msgBus   *bus.MessageBus
 	logger   *slog.Logger
 }
@@ -70,6 +71,11 @@ func (m *SubagentManager) SetScrubber(s CredentialScrubber) {
 	m.scrubber = s
 }
 
+// SetCostTracker records subagent usage and enforces the usage budget.
+func (m *SubagentManager) SetCostTracker(ct *CostTracker) {
+	m.costs = ct
+}
+
 // Spawn creates a new background task.
 func (m *SubagentManager) Spawn(ctx context.Context, description, channel, chatID string) (string, error) {
 	m.mu.Lock()
@@ -100,7 +106,7 @@ func (m *SubagentManager) Spawn(ctx context.Context, description, channel, chatI
 			m.mu.Unlock()
 		}()
 
-		result, err := m.runSubagent(taskCtx, description)
+		result, err := m.runSubagent(taskCtx, taskID, description)
 		task.SetResult(result, err)
 
 		var content string
@@ -125,8 +131,16 @@ func (m *SubagentManager) Spawn(ctx context.Context, description, channel, chatI
 	return taskID, nil
 }
 
+// Run executes task synchronously and returns its result, for callers that
+// deliver the outcome themselves, such as scheduled agent jobs. Usage is
+// recorded under id.
+func (m *SubagentManager) Run(ctx context.Context, id, task string) (string, error) {
+	return m.runSubagent(ctx, id, task)
+}
+
 // runSubagent runs a simplified agent loop for the background task.
-func (m *SubagentManager) runSubagent(ctx context.Context, task string) (string, error) {
+// Usage is recorded under the task ID.
+func (m *SubagentManager) runSubagent(ctx context.Context, taskID, task string) (string, error) {
 	if m.provider == nil {
 		return "", fmt.Errorf("no provider available")
 	}
@@ -139,7 +153,8 @@ func (m *SubagentManager) runSubagent(ctx context.Context, task string) (string,
 Complete the task efficiently and return a concise result.
 You have access to tools for file operations, shell commands, and more.`
 
-	toolDefs := m.registry.ToolDefs()
+	// A subagent spawned from a chat keeps the limits of the user's role
+	toolDefs := m.registry.ToolDefsFor(ctx)
 
 	maxIterations := 15
 	for i := 0; i < maxIterations; i++ {
@@ -149,6 +164,13 @@ You have access to tools for file operations, shell commands, and more.`
 		default:
 		}
 
+		// Subagents already use the fast route, so the budget can only stop them
+		if m.costs != nil {
+			if status := m.costs.Check(); status.State == budgetExhausted {
+				return "", fmt.Errorf("%s", status.Reason)
+			}
+		}
+
 		resp, err := m.provider.Complete(ctx, providers.CompletionRequest{
 			SystemPrompt: systemPrompt,
 			Messages:     messages,
@@ -158,6 +180,11 @@ You have access to tools for file operations, shell commands, and more.`
 		if err != nil {
 			return "", fmt.Errorf("provider error: %w", err)
 		}
+		if m.costs != nil {
+			if err := m.costs.Record(ctx, taskID, resp); err != nil {
+				m.logger.Warn("failed to record usage", "task", taskID, "error", err)
+			}
+		}
 
 		if len(resp.ToolCalls) > 0 {
 			messages = append(messages, providers.Message{
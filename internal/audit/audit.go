@@ -0,0 +1,329 @@
+// Package audit keeps an append-only, hash-chained record of tool calls and
+// approval decisions, so tampering with past entries can be detected.
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Decisions.
+const (
+	Allowed  = "allowed"  // ran without needing approval
+	Denied   = "denied"   // blocked by a check or policy rule, not run
+	Pending  = "pending"  // held for approval
+	Approved = "approved" // ran after a human approved it
+	Rejected = "rejected" // a human denied the approval
+	Timeout  = "timeout"  // nobody answered the approval in time
+)
+
+// maxArgs is how many bytes of a call's arguments an entry keeps.
+const maxArgs = 4096
+
+// timeFormat stores times at fixed width, so they sort as text.
+const timeFormat = "2006-01-02T15:04:05.000000000Z"
+
+// Entry is one audited tool call or approval decision.
+type Entry struct {
+	ID         int64     `json:"id"`
+	Time       time.Time `json:"time"`
+	Tool       string    `json:"tool"`
+	Args       string    `json:"args"` // scrubbed of credentials, truncated
+	Decision   string    `json:"decision"`
+	Reason     string    `json:"reason,omitempty"`
+	Channel    string    `json:"channel,omitempty"` // where the call came from
+	ChatID     string    `json:"chat_id,omitempty"`
+	UserID     string    `json:"user_id,omitempty"`
+	Approver   string    `json:"approver,omitempty"` // channel:user who answered the approval
+	DurationMS int64     `json:"duration_ms"`
+	ExitCode   *int      `json:"exit_code,omitempty"` // of the command the tool ran, if any
+	IsError    bool      `json:"is_error,omitempty"`
+	ResultHash string    `json:"result_hash,omitempty"` // SHA-256 of the result the model got
+	PrevHash   string    `json:"prev_hash"`
+	Hash       string    `json:"hash"`
+}
+
+// Scrubber removes credentials from text.
+type Scrubber interface {
+	ScrubCredentials(text string) string
+}
+
+// Log is the audit log, a table in the Aeon database. Triggers reject
+// updates and deletes, and each entry's hash covers the previous entry's,
+// so Verify finds entries changed or removed behind the triggers' back.
+type Log struct {
+	db       *sql.DB
+	scrubber Scrubber
+	mu       sync.Mutex
+}
+
+// New opens the audit log in db, creating its table if needed.
+func New(db *sql.DB) (*Log, error) {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS audit_log (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			time TEXT NOT NULL,
+			tool TEXT NOT NULL,
+			args TEXT NOT NULL,
+			decision TEXT NOT NULL,
+			reason TEXT NOT NULL,
+			channel TEXT NOT NULL,
+			chat_id TEXT NOT NULL,
+			user_id TEXT NOT NULL,
+			approver TEXT NOT NULL,
+			duration_ms INTEGER NOT NULL,
+			exit_code INTEGER,
+			is_error BOOLEAN NOT NULL,
+			result_hash TEXT NOT NULL,
+			prev_hash TEXT NOT NULL,
+			hash TEXT NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_audit_log_tool ON audit_log(tool, id);
+		CREATE TRIGGER IF NOT EXISTS audit_log_no_update BEFORE UPDATE ON audit_log
+			BEGIN SELECT RAISE(ABORT, 'audit log is append-only'); END;
+		CREATE TRIGGER IF NOT EXISTS audit_log_no_delete BEFORE DELETE ON audit_log
+			BEGIN SELECT RAISE(ABORT, 'audit log is append-only'); END;
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("initializing audit schema: %w", err)
+	}
+	return &Log{db: db}, nil
+}
+
+// SetScrubber removes credentials from arguments and reasons before they're stored.
+func (l *Log) SetScrubber(s Scrubber) {
+	l.scrubber = s
+}
+
+// HashResult returns the hash entries store for a tool result.
+func HashResult(result string) string {
+	sum := sha256.Sum256([]byte(result))
+	return hex.EncodeToString(sum[:])
+}
+
+// Record appends e to the log, filling in its ID, time and hashes. It goes
+// ahead when ctx is cancelled, as a stopped call is still worth a record.
+func (l *Log) Record(ctx context.Context, e Entry) (Entry, error) {
+	ctx = context.WithoutCancel(ctx)
+	if l.scrubber != nil {
+		e.Args = l.scrubber.ScrubCredentials(e.Args)
+		e.Reason = l.scrubber.ScrubCredentials(e.Reason)
+	}
+	if len(e.Args) > maxArgs {
+		e.Args = strings.ToValidUTF8(e.Args[:maxArgs], "") + "…[truncated]"
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	e.Time = e.Time.UTC()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// An immediate transaction keeps the chain linear when another Aeon
+	// process (aeon mcp next to aeon serve) writes to the same database.
+	conn, err := l.db.Conn(ctx)
+	if err != nil {
+		return e, err
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, "BEGIN IMMEDIATE"); err != nil {
+		return e, fmt.Errorf("recording audit entry: %w", err)
+	}
+	err = func() error {
+		err := conn.QueryRowContext(ctx, "SELECT COALESCE(MAX(id), 0) FROM audit_log").Scan(&e.ID)
+		if err != nil {
+			return err
+		}
+		if e.ID > 0 {
+			if err := conn.QueryRowContext(ctx, "SELECT hash FROM audit_log WHERE id = ?", e.ID).Scan(&e.PrevHash); err != nil {
+				return err
+			}
+		}
+		e.ID++
+		e.Hash = e.chainHash()
+		_, err = conn.ExecContext(ctx, `INSERT INTO audit_log
+			(id, time, tool, args, decision, reason, channel, chat_id, user_id, approver,
+			 duration_ms, exit_code, is_error, result_hash, prev_hash, hash)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			e.ID, e.Time.Format(timeFormat), e.Tool, e.Args, e.Decision, e.Reason, e.Channel, e.ChatID,
+			e.UserID, e.Approver, e.DurationMS, e.ExitCode, e.IsError, e.ResultHash, e.PrevHash, e.Hash)
+		return err
+	}()
+	if err != nil {
+		conn.ExecContext(ctx, "ROLLBACK")
+		return e, fmt.Errorf("recording audit entry: %w", err)
+	}
+	if _, err := conn.ExecContext(ctx, "COMMIT"); err != nil {
+		conn.ExecContext(ctx, "ROLLBACK")
+		return e, fmt.Errorf("recording audit entry: %w", err)
+	}
+	return e, nil
+}
+
+// chainHash is the SHA-256 of the previous entry's hash and every field of
+// this one but its own hash.
+func (e Entry) chainHash() string {
+	c := e
+	c.Hash = ""
+	c.Time = c.Time.UTC()
+	data, _ := json.Marshal(c)
+	sum := sha256.Sum256(append([]byte(e.PrevHash+"\n"), data...))
+	return hex.EncodeToString(sum[:])
+}
+
+// Filter selects entries. Zero fields match everything.
+type Filter struct {
+	Tool     string
+	Decision string
+	Since    time.Time
+	Limit    int // newest entries only; 0 means all
+}
+
+// List returns the entries matching f, oldest first.
+func (l *Log) List(ctx context.Context, f Filter) ([]Entry, error) {
+	var where []string
+	var args []any
+	if f.Tool != "" {
+		where, args = append(where, "tool = ?"), append(args, f.Tool)
+	}
+	if f.Decision != "" {
+		where, args = append(where, "decision = ?"), append(args, f.Decision)
+	}
+	if !f.Since.IsZero() {
+		where, args = append(where, "time >= ?"), append(args, f.Since.UTC().Format(timeFormat))
+	}
+	query := "SELECT " + entryColumns + " FROM audit_log"
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY id DESC"
+	if f.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", f.Limit)
+	}
+
+	rows, err := l.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var entries []Entry
+	for rows.Next() {
+		e, err := scanEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+	return entries, rows.Err()
+}
+
+// Get returns the entry with the given ID.
+func (l *Log) Get(ctx context.Context, id int64) (Entry, error) {
+	row := l.db.QueryRowContext(ctx, "SELECT "+entryColumns+" FROM audit_log WHERE id = ?", id)
+	return scanEntry(row)
+}
+
+const entryColumns = `id, time, tool, args, decision, reason, channel, chat_id, user_id, approver,
+	duration_ms, exit_code, is_error, result_hash, prev_hash, hash`
+
+func scanEntry(row interface{ Scan(...any) error }) (Entry, error) {
+	var e Entry
+	var ts string
+	var exit sql.NullInt64
+	err := row.Scan(&e.ID, &ts, &e.Tool, &e.Args, &e.Decision, &e.Reason, &e.Channel, &e.ChatID, &e.UserID,
+		&e.Approver, &e.DurationMS, &exit, &e.IsError, &e.ResultHash, &e.PrevHash, &e.Hash)
+	if err != nil {
+		return e, err
+	}
+	e.Time, _ = time.Parse(timeFormat, ts)
+	if exit.Valid {
+		code := int(exit.Int64)
+		e.ExitCode = &code
+	}
+	return e, nil
+}
+
+// Verification is the outcome of checking the hash chain.
+type Verification struct {
+	Entries  int    // entries checked
+	Head     string // hash of the last entry; note it to detect a rewritten log later
+	BrokenAt int64  // first entry that doesn't check out, 0 if none
+	Problem  string
+}
+
+// OK reports whether the chain is intact.
+func (v Verification) OK() bool {
+	return v.BrokenAt == 0 && v.Problem == ""
+}
+
+// Verify recomputes the hash chain from the first entry. It finds entries
+// edited, inserted or removed, including the newest ones, by comparing the
+// IDs against the table's autoincrement counter.
+func (l *Log) Verify(ctx context.Context) (Verification, error) {
+	var v Verification
+	rows, err := l.db.QueryContext(ctx, "SELECT "+entryColumns+" FROM audit_log ORDER BY id")
+	if err != nil {
+		return v, err
+	}
+	defer rows.Close()
+
+	var prev Entry
+	for rows.Next() {
+		e, err := scanEntry(rows)
+		if err != nil {
+			return v, err
+		}
+		v.Entries++
+		switch {
+		case e.ID != prev.ID+1:
+			v.BrokenAt, v.Problem = e.ID, fmt.Sprintf("entries %d to %d before it are missing", prev.ID+1, e.ID-1)
+		case e.PrevHash != prev.Hash:
+			v.BrokenAt, v.Problem = e.ID, "it does not link to the entry before it"
+		case e.chainHash() != e.Hash:
+			v.BrokenAt, v.Problem = e.ID, "it was modified after it was recorded"
+		}
+		if v.BrokenAt != 0 {
+			return v, nil
+		}
+		prev = e
+	}
+	if err := rows.Err(); err != nil {
+		return v, err
+	}
+	v.Head = prev.Hash
+
+	var seq int64
+	l.db.QueryRowContext(ctx, "SELECT seq FROM sqlite_sequence WHERE name = 'audit_log'").Scan(&seq)
+	if seq > prev.ID {
+		v.Problem = fmt.Sprintf("the newest entries (%d to %d) are missing", prev.ID+1, seq)
+	}
+	return v, nil
+}
+
+// Export writes the entries matching f as JSON lines, hashes included, so
+// the chain can be checked outside Aeon.
+func (l *Log) Export(ctx context.Context, w io.Writer, f Filter) (int, error) {
+	entries, err := l.List(ctx, f)
+	if err != nil {
+		return 0, err
+	}
+	enc := json.NewEncoder(w)
+	for i, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			return i, err
+		}
+	}
+	return len(entries), nil
+}
@@ -0,0 +1,163 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+func setupTestLog(t *testing.T) (*Log, *sql.DB) {
+	t.Helper()
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	log, err := New(db)
+	if err != nil {
+		t.Fatalf("failed to create audit log: %v", err)
+	}
+	return log, db
+}
+
+type mockScrubber struct{}
+
+func (mockScrubber) ScrubCredentials(text string) string {
+	return strings.ReplaceAll(text, "hunter2", "[REDACTED]")
+}
+
+func record(t *testing.T, log *Log, entries ...Entry) {
+	t.Helper()
+	for _, e := range entries {
+		if _, err := log.Record(context.Background(), e); err != nil {
+			t.Fatalf("record error: %v", err)
+		}
+	}
+}
+
+func TestRecordAndList(t *testing.T) {
+	log, _ := setupTestLog(t)
+	log.SetScrubber(mockScrubber{})
+	ctx := context.Background()
+
+	code := 2
+	record(t, log,
+		Entry{Tool: "shell_exec", Args: `{"command":"curl -u me:hunter2 x"}`, Decision: Allowed, Channel: "telegram", UserID: "42", ExitCode: &code, IsError: true},
+		Entry{Tool: "file_write", Args: `{"path":"/etc/passwd"}`, Decision: Denied, Reason: "outside allowed paths"},
+		Entry{Tool: "shell_exec", Args: `{"command":"sudo reboot"}`, Decision: Approved, Approver: "telegram:42"},
+		Entry{Tool: "shell_exec", Args: strings.Repeat("x", maxArgs+100), Decision: Timeout},
+	)
+
+	all, err := log.List(ctx, Filter{})
+	if err != nil {
+		t.Fatalf("list error: %v", err)
+	}
+	if len(all) != 4 || all[0].ID != 1 || all[3].ID != 4 {
+		t.Fatalf("expected 4 entries oldest first, got %+v", all)
+	}
+	first := all[0]
+	if strings.Contains(first.Args, "hunter2") || !strings.Contains(first.Args, "[REDACTED]") {
+		t.Errorf("expected arguments scrubbed, got %q", first.Args)
+	}
+	if first.ExitCode == nil || *first.ExitCode != 2 || !first.IsError || first.Channel != "telegram" || first.PrevHash != "" {
+		t.Errorf("first entry not stored as recorded: %+v", first)
+	}
+	if all[1].PrevHash != first.Hash || all[1].ExitCode != nil {
+		t.Errorf("second entry should link to the first: %+v", all[1])
+	}
+	if len(all[3].Args) > maxArgs+len("…[truncated]") {
+		t.Errorf("expected long arguments truncated, got %d bytes", len(all[3].Args))
+	}
+
+	shell, _ := log.List(ctx, Filter{Tool: "shell_exec", Limit: 2})
+	if len(shell) != 2 || shell[0].ID != 3 || shell[1].ID != 4 {
+		t.Errorf("expected the newest 2 shell_exec entries, got %+v", shell)
+	}
+	approved, _ := log.List(ctx, Filter{Decision: Approved})
+	if len(approved) != 1 || approved[0].Approver != "telegram:42" {
+		t.Errorf("expected the approved entry, got %+v", approved)
+	}
+	if recent, _ := log.List(ctx, Filter{Since: time.Now().Add(time.Hour)}); len(recent) != 0 {
+		t.Errorf("expected no entries in the future, got %d", len(recent))
+	}
+
+	var buf bytes.Buffer
+	n, err := log.Export(ctx, &buf, Filter{})
+	if err != nil || n != 4 {
+		t.Fatalf("export = %d, %v", n, err)
+	}
+	var exported Entry
+	json.Unmarshal([]byte(strings.SplitN(buf.String(), "\n", 2)[0]), &exported)
+	if exported.Hash != first.Hash || exported.chainHash() != first.Hash {
+		t.Errorf("expected exported entries to verify, got %+v", exported)
+	}
+}
+
+func TestLogIsAppendOnly(t *testing.T) {
+	log, db := setupTestLog(t)
+	record(t, log, Entry{Tool: "shell_exec", Args: "{}", Decision: Allowed})
+
+	if _, err := db.Exec("UPDATE audit_log SET decision = 'denied'"); err == nil {
+		t.Error("expected updates to be rejected")
+	}
+	if _, err := db.Exec("DELETE FROM audit_log"); err == nil {
+		t.Error("expected deletes to be rejected")
+	}
+}
+
+func TestVerify(t *testing.T) {
+	ctx := context.Background()
+	setup := func() (*Log, *sql.DB) {
+		log, db := setupTestLog(t)
+		for _, tool := range []string{"a", "b", "c", "d"} {
+			record(t, log, Entry{Tool: tool, Args: "{}", Decision: Allowed})
+		}
+		// Someone with the database file can drop the triggers
+		db.Exec("DROP TRIGGER audit_log_no_update")
+		db.Exec("DROP TRIGGER audit_log_no_delete")
+		return log, db
+	}
+
+	intact, _ := setup()
+	v, err := intact.Verify(ctx)
+	if err != nil || !v.OK() || v.Entries != 4 || v.Head == "" {
+		t.Fatalf("expected an intact chain, got %+v, %v", v, err)
+	}
+
+	tests := []struct {
+		name   string
+		tamper string
+		broken int64
+		want   string
+	}{
+		{"edited", "UPDATE audit_log SET decision = 'denied' WHERE id = 2", 2, "modified"},
+		{"deleted", "DELETE FROM audit_log WHERE id = 2", 3, "entries 2 to 2"},
+		{"truncated", "DELETE FROM audit_log WHERE id >= 3", 0, "newest entries (3 to 4)"},
+	}
+	for _, tt := range tests {
+		log, db := setup()
+		if _, err := db.Exec(tt.tamper); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		v, err := log.Verify(ctx)
+		if err != nil || v.OK() || v.BrokenAt != tt.broken || !strings.Contains(v.Problem, tt.want) {
+			t.Errorf("%s: got %+v, %v; want broken at %d with %q", tt.name, v, err, tt.broken, tt.want)
+		}
+	}
+
+	// An edited entry given a fresh hash breaks the link from the next one
+	log, db := setup()
+	e, _ := log.Get(ctx, 2)
+	e.Decision = Denied
+	db.Exec("UPDATE audit_log SET decision = ?, hash = ? WHERE id = 2", e.Decision, e.chainHash())
+	if v, _ := log.Verify(ctx); v.BrokenAt != 3 || !strings.Contains(v.Problem, "does not link") {
+		t.Errorf("rehashed: got %+v, want broken at 3", v)
+	}
+}
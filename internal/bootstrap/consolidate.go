@@ -0,0 +1,112 @@
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ImJafran/aeon/internal/agent"
+	"github.com/ImJafran/aeon/internal/memory"
+	"github.com/ImJafran/aeon/internal/providers"
+)
+
+// consolidationJob is the built-in job that consolidates memory.
+const consolidationJob = "__memory_consolidation__"
+
+// consolidationSession is the session ID its token usage is recorded under.
+const consolidationSession = "memory_consolidation"
+
+const consolidateSystemPrompt = `You merge related memories of a personal agent into one.
+Write a single concise memory that keeps every durable fact: names, preferences, decisions, numbers, dates and lessons.
+Drop small talk and anything that was only true for a moment. If memories contradict each other, keep the newest.
+Reply with the merged memory only, no preamble.`
+
+// MemorySummarizer returns a SummarizeFunc that merges memories with the
+// provider's fast route. costs may be nil.
+func MemorySummarizer(provider providers.Provider, costs *agent.CostTracker) memory.SummarizeFunc {
+	return func(ctx context.Context, memories []memory.Entry) (string, error) {
+		if provider == nil {
+			return "", fmt.Errorf("no provider available")
+		}
+		var b strings.Builder
+		for _, m := range memories {
+			fmt.Fprintf(&b, "- [%s, %s] %s\n", m.Category, m.CreatedAt.Format("2006-01-02"), m.Content)
+		}
+		resp, err := provider.Complete(ctx, providers.CompletionRequest{
+			SystemPrompt: consolidateSystemPrompt,
+			Messages:     []providers.Message{{Role: "user", Content: b.String()}},
+			Hint:         "fast",
+		})
+		if err != nil {
+			return "", err
+		}
+		if costs != nil {
+			costs.Record(ctx, consolidationSession, resp)
+		}
+		summary := strings.TrimSpace(resp.Content)
+		if summary == "" {
+			return "", fmt.Errorf("provider returned an empty summary")
+		}
+		return summary, nil
+	}
+}
+
+// runConsolidation consolidates memory. With memory.auto_save off it is a
+// dry run. The report becomes the run's output in the cron history.
+func (d *Deps) runConsolidation(ctx context.Context) (string, error) {
+	if d.MemStore == nil {
+		return "", fmt.Errorf("memory store is not available")
+	}
+	c := memory.NewConsolidator(d.MemStore, MemorySummarizer(d.Provider, d.Costs))
+	c.SetMinCandidates(d.Cfg.Memory.CompactionThreshold)
+
+	report, err := c.Run(ctx, !d.Cfg.Memory.AutoSave)
+	if err != nil {
+		return report.String(), err
+	}
+	d.Logger.Info("memory consolidated",
+		"dry_run", report.DryRun,
+		"merged", report.MergedCount(),
+		"pruned", len(report.Pruned),
+		"errors", len(report.Errors),
+	)
+	if len(report.Errors) > 0 && len(report.Merges) == 0 {
+		return report.String(), fmt.Errorf("consolidation failed: %s", report.Errors[0])
+	}
+	return report.String(), nil
+}
+
+// ensureConsolidationJob keeps the __memory_consolidation__ job in line with
+// memory.consolidate_interval: created if missing, rescheduled if the
+// interval changed, removed if it is "off".
+func (d *Deps) ensureConsolidationJob() {
+	if d.Scheduler == nil || d.MemStore == nil {
+		return
+	}
+
+	jobs, err := d.Scheduler.List(false)
+	if err != nil {
+		return
+	}
+	interval := d.Cfg.Memory.ConsolidateInterval
+	schedule := "every " + interval
+	for _, j := range jobs {
+		if j.Name != consolidationJob {
+			continue
+		}
+		if interval != "off" && j.Schedule == schedule {
+			return // already exists
+		}
+		d.Scheduler.Delete(j.ID)
+	}
+	if interval == "off" || interval == "" {
+		return
+	}
+
+	_, err = d.Scheduler.Create(consolidationJob, schedule, "", "memory consolidation", "{}")
+	if err != nil {
+		d.Logger.Warn("failed to create memory consolidation job", "error", err)
+	} else {
+		d.Logger.Info("memory consolidation job registered", "interval", interval, "dry_run", !d.Cfg.Memory.AutoSave)
+	}
+}
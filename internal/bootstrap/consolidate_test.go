@@ -0,0 +1,101 @@
+package bootstrap
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ImJafran/aeon/internal/memory"
+	"github.com/ImJafran/aeon/internal/providers"
+	"github.com/ImJafran/aeon/internal/scheduler"
+)
+
+type summaryProvider struct{ hints []string }
+
+func (p *summaryProvider) Complete(_ context.Context, req providers.CompletionRequest) (providers.CompletionResponse, error) {
+	p.hints = append(p.hints, req.Hint)
+	return providers.CompletionResponse{Content: "  The user deploys on Fridays.\n"}, nil
+}
+func (p *summaryProvider) Name() string    { return "summary" }
+func (p *summaryProvider) Available() bool { return true }
+
+func newConsolidationDeps(t *testing.T) (*Deps, *summaryProvider) {
+	t.Helper()
+	d, _ := newJobDeps(t)
+	store, err := memory.NewStore(filepath.Join(t.TempDir(), "aeon.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	d.MemStore = store
+	if d.Scheduler, err = scheduler.New(store.DB(), d.Logger); err != nil {
+		t.Fatal(err)
+	}
+	p := &summaryProvider{}
+	d.Provider = p
+	d.Cfg.Memory.ConsolidateInterval = "24h"
+
+	ctx := context.Background()
+	for _, content := range []string{"Deployed on Friday", "Friday deploy went fine"} {
+		id, _ := store.MemStore(ctx, memory.CategoryDaily, content, "deploy", 0.6)
+		store.DB().Exec("UPDATE memories SET created_at = datetime('now', '-10 days') WHERE id = ?", id)
+	}
+	return d, p
+}
+
+func TestRunConsolidationJob(t *testing.T) {
+	d, p := newConsolidationDeps(t)
+	job := scheduler.Job{Name: consolidationJob}
+
+	// Without auto_save the scheduled run only reports
+	out, err := d.runJob(context.Background(), job)
+	if err != nil || !strings.Contains(out, "Would merge 2 memories") || len(p.hints) != 0 {
+		t.Fatalf("expected a dry run, got %q (%v), provider calls %v", out, err, p.hints)
+	}
+
+	d.Cfg.Memory.AutoSave = true
+	out, err = d.runJob(context.Background(), job)
+	if err != nil || !strings.Contains(out, "Merged 2 memories") {
+		t.Fatalf("expected a merge, got %q (%v)", out, err)
+	}
+	if len(p.hints) != 1 || p.hints[0] != "fast" {
+		t.Errorf("expected one call on the fast route, got %v", p.hints)
+	}
+	core, _ := d.MemStore.List(context.Background(), memory.CategoryCore, 5)
+	if len(core) != 1 || core[0].Content != "The user deploys on Fridays." {
+		t.Errorf("unexpected core memories %+v", core)
+	}
+}
+
+func TestEnsureConsolidationJob(t *testing.T) {
+	d, _ := newConsolidationDeps(t)
+	find := func() []scheduler.Job {
+		jobs, _ := d.Scheduler.List(false)
+		var found []scheduler.Job
+		for _, j := range jobs {
+			if j.Name == consolidationJob {
+				found = append(found, j)
+			}
+		}
+		return found
+	}
+
+	d.ensureConsolidationJob()
+	d.ensureConsolidationJob()
+	if jobs := find(); len(jobs) != 1 || jobs[0].Schedule != "every 24h" {
+		t.Fatalf("expected one daily job, got %+v", jobs)
+	}
+
+	d.Cfg.Memory.ConsolidateInterval = "7d"
+	d.ensureConsolidationJob()
+	if jobs := find(); len(jobs) != 1 || jobs[0].Schedule != "every 7d" {
+		t.Fatalf("expected the job rescheduled, got %+v", jobs)
+	}
+
+	d.Cfg.Memory.ConsolidateInterval = "off"
+	d.ensureConsolidationJob()
+	if jobs := find(); len(jobs) != 0 {
+		t.Fatalf("expected the job removed, got %+v", jobs)
+	}
+}
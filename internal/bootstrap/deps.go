@@ -4,16 +4,24 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"os"
+	"path"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/ImJafran/aeon/internal/agent"
+	"github.com/ImJafran/aeon/internal/audit"
 	"github.com/ImJafran/aeon/internal/bus"
-	"github.com/ImJafran/aeon/internal/channels"
 	"github.com/ImJafran/aeon/internal/config"
+	"github.com/ImJafran/aeon/internal/mcp"
 	"github.com/ImJafran/aeon/internal/memory"
 	"github.com/ImJafran/aeon/internal/providers"
+	"github.com/ImJafran/aeon/internal/sandbox"
 	"github.com/ImJafran/aeon/internal/scheduler"
+	"github.com/ImJafran/aeon/internal/secrets"
 	"github.com/ImJafran/aeon/internal/security"
 	"github.com/ImJafran/aeon/internal/skills"
 	"github.com/ImJafran/aeon/internal/tools"
@@ -26,10 +34,16 @@ type Deps struct {
 	Registry    *tools.Registry
 	Provider    providers.Provider
 	SubMgr      *agent.SubagentManager
+	Costs       *agent.CostTracker
 	Loop        *agent.AgentLoop
 	Scheduler   *scheduler.Scheduler
 	SkillLoader *skills.Loader
 	SecAdapter  *security.PolicyAdapter
+	PolicyFile  *security.PolicyFile
+	Audit       *audit.Log
+	Approvals   *agent.ApprovalGate
+	Roles       *agent.Roles // nil when no roles are configured
+	MCPClients  []*mcp.Client
 	Logger      *slog.Logger
 	Cfg         *config.Config
 
@@ -48,7 +62,26 @@ func BuildDeps(cfg *config.Config, logger *slog.Logger) (*Deps, error) {
 
 	// Initialize security policy
 	secPolicy := security.NewPolicy(cfg.Security.DenyPatterns, cfg.Security.AllowedPaths)
+	secPolicy.DenyPaths(cfg.Secrets.KeyFile) // it unlocks every secret in the vault
 	d.SecAdapter = security.NewAdapter(secPolicy)
+	policyFile, err := security.LoadPolicyFile(cfg.Security.PolicyFile)
+	if err != nil {
+		return nil, err
+	}
+	d.PolicyFile = policyFile
+	secPolicy.SetRules(policyFile)
+	if n := len(policyFile.Rules().Rules); n > 0 {
+		logger.Info("policy rules loaded", "path", policyFile.Path(), "rules", n)
+	}
+
+	// Known credentials are redacted from all output, not just what the patterns catch
+	vault, err := openVault(cfg.Secrets, logger)
+	if err != nil {
+		return nil, err
+	}
+	if err := configureScrubbing(secPolicy, cfg, vault, logger); err != nil {
+		return nil, err
+	}
 
 	// Initialize memory store
 	dbPath := filepath.Join(home, "aeon.db")
@@ -60,19 +93,42 @@ func BuildDeps(cfg *config.Config, logger *slog.Logger) (*Deps, error) {
 	d.MemStore = memStore
 	d.MemCount, _ = memStore.Count(context.Background())
 	logger.Info("memory store ready", "path", dbPath, "entries", d.MemCount)
+	if embedder := NewEmbedder(cfg.Memory.Embeddings); embedder != nil {
+		memStore.SetEmbedder(embedder, cfg.Memory.Embeddings.MinSimilarity)
+		logger.Info("semantic recall enabled", "embedder", embedder.Name())
+	}
+	memStore.SetDefaultScope(cfg.Memory.DefaultScope)
+
+	// Initialize audit log
+	auditLog, err := audit.New(memStore.DB())
+	if err != nil {
+		d.Close()
+		return nil, err
+	}
+	auditLog.SetScrubber(d.SecAdapter)
+	d.Audit = auditLog
 
 	// Initialize tool registry with DNA tools
 	d.Registry = tools.NewRegistry()
 	d.Registry.SetLogger(logger)
+	d.Registry.SetPolicy(d.SecAdapter)
+	d.Registry.SetAuditor(auditLog)
 	dnaTools := tools.RegisterDNATools(d.Registry)
 	dnaTools.ShellExec.SetSecurity(d.SecAdapter)
 	dnaTools.FileRead.SetSecurity(d.SecAdapter)
 	dnaTools.FileWrite.SetSecurity(d.SecAdapter)
 	dnaTools.FileEdit.SetSecurity(d.SecAdapter)
+	if p, ok := cfg.Security.Sandbox.ForTool("shell_exec"); ok {
+		opts := sandboxOptions(cfg.Security.Sandbox, p)
+		dnaTools.ShellExec.SetSandbox(&opts)
+		checkSandbox("shell_exec", opts, logger)
+	}
 
 	// Register memory tools
 	d.Registry.Register(tools.NewMemoryStore(memStore))
 	d.Registry.Register(tools.NewMemoryRecall(memStore))
+	d.Registry.Register(tools.NewMemoryUpdate(memStore))
+	d.Registry.Register(tools.NewMemoryForget(memStore))
 
 	// Register log tool
 	d.Registry.Register(tools.NewLogRead())
@@ -81,6 +137,24 @@ func BuildDeps(cfg *config.Config, logger *slog.Logger) (*Deps, error) {
 	skillsDir := filepath.Join(home, "skills")
 	venvPath := filepath.Join(home, "base_venv")
 	d.SkillLoader = skills.NewLoader(skillsDir, venvPath)
+	d.SkillLoader.SetSandbox(func(name string) (sandbox.Options, bool) {
+		p, ok := cfg.Security.Sandbox.ForSkill(name)
+		return sandboxOptions(cfg.Security.Sandbox, p), ok
+	})
+	if p, ok := cfg.Security.Sandbox.ForSkill("*"); ok {
+		checkSandbox("skills", sandboxOptions(cfg.Security.Sandbox, p), logger)
+	}
+	if vault != nil {
+		d.SkillLoader.SetSecrets(func(name string) map[string]string {
+			env := make(map[string]string)
+			for _, secret := range cfg.Secrets.ForSkill(name) {
+				if value, ok := vault.Get(secret); ok {
+					env[secret] = value
+				}
+			}
+			return env
+		})
+	}
 	if err := d.SkillLoader.LoadAll(); err != nil {
 		logger.Warn("failed to load skills", "error", err)
 	}
@@ -98,9 +172,18 @@ func BuildDeps(cfg *config.Config, logger *slog.Logger) (*Deps, error) {
 		logger.Warn("failed to initialize scheduler", "error", err)
 	} else {
 		d.Scheduler = sched
+		sched.SetScrubber(d.SecAdapter)
+		if cfg.Scheduler.Timezone != "" {
+			if loc, err := time.LoadLocation(cfg.Scheduler.Timezone); err == nil {
+				sched.SetLocation(loc)
+			}
+		}
 		d.Registry.Register(tools.NewCronManage(sched))
 	}
 
+	// Connect MCP servers; their tools register as each one comes up
+	d.startMCP(cfg, logger)
+
 	logger.Info("tools registered", "count", d.Registry.Count())
 
 	// Initialize provider chain
@@ -109,9 +192,25 @@ func BuildDeps(cfg *config.Config, logger *slog.Logger) (*Deps, error) {
 		logger.Warn("no provider available, running in echo mode", "error", err)
 	}
 
+	// Initialize usage tracking, shared by the agent loop and subagents
+	costs := agent.NewCostTracker()
+	if err := costs.SetStore(memStore); err != nil {
+		logger.Warn("failed to load token usage", "error", err)
+	}
+	costs.SetPrices(cfg.Pricing)
+	d.Costs = costs
+	costs.SetBudget(agent.BudgetLimits{
+		DailyTokens:   cfg.Agent.DailyTokenLimit,
+		MonthlyTokens: cfg.Agent.MonthlyTokenLimit,
+		DailyUSD:      cfg.Agent.DailyBudgetUSD,
+		MonthlyUSD:    cfg.Agent.MonthlyBudgetUSD,
+		WarnPercent:   cfg.Agent.BudgetWarnPercent,
+	})
+
 	// Initialize subagent manager
 	d.SubMgr = agent.NewSubagentManager(d.Provider, d.Registry, d.Bus, logger)
 	d.SubMgr.SetScrubber(d.SecAdapter)
+	d.SubMgr.SetCostTracker(costs)
 	d.Registry.Register(tools.NewSpawnAgent(d.SubMgr))
 	d.Registry.Register(tools.NewListTasks(d.SubMgr))
 
@@ -126,41 +225,68 @@ func BuildDeps(cfg *config.Config, logger *slog.Logger) (*Deps, error) {
 	d.Loop = agent.NewAgentLoop(d.Bus, d.Provider, d.Registry, logger)
 	d.Loop.SetScrubber(d.SecAdapter)
 	d.Loop.SetSubagentManager(d.SubMgr)
+	d.Loop.SetCostTracker(costs)
 	d.Loop.SetMemoryStore(memStore)
 	d.Loop.SetSkillLoader(d.SkillLoader)
 	d.Loop.SetSystemPrompt(cfg.Agent.SystemPrompt)
 	d.Loop.SetMaxHistoryMessages(cfg.Agent.MaxHistoryMessages)
 	d.Loop.SetMaxIterations(cfg.Agent.MaxIterations)
+	d.Loop.SetMaxConcurrentTurns(cfg.Agent.MaxConcurrentTurns)
+	d.Loop.SetStreaming(!cfg.Agent.DisableStreaming)
+	d.Loop.SetMemoryExtraction(cfg.Memory.AutoExtract)
+	d.Approvals = newApprovalGate(cfg, d.Bus, logger)
+	d.Loop.SetApprovalGate(d.Approvals)
+	if d.Roles = newRoles(cfg.Security.Access); d.Roles != nil {
+		d.Loop.SetRoles(d.Roles)
+		d.Approvals.SetRoles(d.Roles)
+	}
 
 	return d, nil
 }
 
-// SetupSchedulerTrigger configures the scheduler's trigger callback.
+// startMCP creates a client for each configured MCP server and connects in
+// the background, so a slow or missing server doesn't hold up startup.
+// Clients restart their server and re-sync tools if the connection drops.
+func (d *Deps) startMCP(cfg *config.Config, logger *slog.Logger) {
+	names := make([]string, 0, len(cfg.MCP.Servers))
+	for name := range cfg.MCP.Servers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		srv := cfg.MCP.Servers[name]
+		if srv.Disabled {
+			continue
+		}
+		var client *mcp.Client
+		if srv.URL != "" {
+			client = mcp.NewHTTPClient(name, srv.URL, srv.Headers, logger)
+		} else {
+			client = mcp.NewStdioClient(name, srv.Command, srv.Args, srv.Env, srv.Dir, logger)
+		}
+		tools.NewMCPBridge(d.Registry, client, d.SecAdapter, logger)
+		client.Start()
+		d.MCPClients = append(d.MCPClients, client)
+	}
+
+	if len(d.MCPClients) > 0 {
+		d.Registry.Register(tools.NewMCPResources(d.MCPClients, d.SecAdapter))
+		d.Registry.Register(tools.NewMCPPrompts(d.MCPClients))
+		logger.Info("mcp servers configured", "count", len(d.MCPClients))
+	}
+}
+
+// SetupSchedulerTrigger makes the scheduler run jobs through runJob.
 func (d *Deps) SetupSchedulerTrigger() {
 	if d.Scheduler == nil {
 		return
 	}
-	d.Scheduler.OnTrigger(func(job scheduler.Job) {
-		if scheduler.IsOneShot(job.Schedule) {
-			if d.Cfg.Channels.Telegram != nil {
-				for _, uid := range d.Cfg.Channels.Telegram.AllowedUsers {
-					d.Bus.Send(bus.OutboundMessage{
-						Channel: channels.TelegramChannelName,
-						ChatID:  fmt.Sprintf("%d", uid),
-						Content: fmt.Sprintf("Reminder: %s", job.Command),
-					})
-				}
-			}
-			return
-		}
-		d.Bus.Publish(bus.InboundMessage{
-			Channel: "system",
-			Content: fmt.Sprintf("[cron:%s] %s", job.Name, job.Command),
-		})
-	})
+	d.Scheduler.OnTrigger(d.runJob)
 
-	// Register built-in heartbeat job if not already present
+	// Register built-in heartbeat and memory consolidation jobs if not already present
 	d.ensureHeartbeatJob()
+	d.ensureConsolidationJob()
 }
 
 // ensureHeartbeatJob creates the __heartbeat__ cron job if it doesn't exist.
@@ -180,13 +306,13 @@ func (d *Deps) ensureHeartbeatJob() {
 		return
 	}
 	for _, j := range jobs {
-		if j.Name == "__heartbeat__" {
+		if j.Name == heartbeatJob {
 			return // already exists
 		}
 	}
 
 	schedule := "every " + interval
-	_, err = d.Scheduler.Create("__heartbeat__", schedule, "", "heartbeat", "{}")
+	_, err = d.Scheduler.Create(heartbeatJob, schedule, "", "heartbeat", "{}")
 	if err != nil {
 		d.Logger.Warn("failed to create heartbeat job", "error", err)
 	} else {
@@ -194,6 +320,172 @@ func (d *Deps) ensureHeartbeatJob() {
 	}
 }
 
+// openVault opens the secrets vault, or returns nil if there is none. Skills
+// granted secrets the vault doesn't hold are warned about.
+func openVault(cfg config.SecretsConfig, logger *slog.Logger) (*secrets.Vault, error) {
+	if _, err := os.Stat(cfg.Vault); err != nil {
+		if len(cfg.Skills) > 0 {
+			logger.Warn("secrets granted to skills but there is no vault", "path", cfg.Vault)
+		}
+		return nil, nil
+	}
+	vault, err := cfg.Open()
+	if err != nil {
+		return nil, fmt.Errorf("opening secrets vault: %w", err)
+	}
+	for skill, names := range cfg.Skills {
+		for _, name := range names {
+			if _, ok := vault.Get(name); !ok {
+				logger.Warn("secret granted to skill is not in the vault", "skill", skill, "secret", name)
+			}
+		}
+	}
+	logger.Info("secrets vault opened", "path", cfg.Vault, "secrets", len(vault.List()))
+	return vault, nil
+}
+
+// configureScrubbing gives the scrubber the exact credentials to redact:
+// the vault's secrets and the key or passphrase that unlocks it, the
+// config's own credentials, and the env vars and files listed under
+// security.scrub, plus its extra patterns.
+func configureScrubbing(p *security.Policy, cfg *config.Config, vault *secrets.Vault, logger *slog.Logger) error {
+	p.SetLogger(logger)
+	if vault != nil {
+		p.AddSecrets("vault", vault.Values()...)
+	}
+	// What unlocks the vault; Passphrase also drops it from the environment
+	// before any skill, MCP server or command is started
+	p.AddSecrets("vault", secrets.Passphrase())
+	if key, err := os.ReadFile(cfg.Secrets.KeyFile); err == nil {
+		p.AddSecrets("vault", strings.TrimSpace(string(key)))
+	}
+	p.AddSecrets("config", cfg.Credentials()...)
+	for _, kv := range os.Environ() {
+		name, value, _ := strings.Cut(kv, "=")
+		for _, pat := range cfg.Security.Scrub.Env {
+			if ok, _ := path.Match(pat, name); ok {
+				p.AddSecrets("env", value)
+				break
+			}
+		}
+	}
+	for _, file := range cfg.Security.Scrub.Files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			logger.Warn("secret file to scrub is unreadable", "path", file, "error", err)
+			continue
+		}
+		p.AddSecrets("file", fileSecrets(string(data))...)
+	}
+	return p.AddPatterns(cfg.Security.Scrub.Patterns...)
+}
+
+// assignment matches a KEY=VALUE line of a .env-style file.
+var assignment = regexp.MustCompile(`^(?:export\s+)?[A-Za-z_][A-Za-z0-9_.]*\s*=\s*(.*)$`)
+
+// fileSecrets returns what to redact of a secret file: its whole contents
+// and the value of each KEY=VALUE line. Other lines, like a PEM armor line
+// or a brace of a JSON file, are too common to redact on their own.
+func fileSecrets(contents string) []string {
+	values := []string{strings.TrimSpace(contents)}
+	for _, line := range strings.Split(contents, "\n") {
+		if m := assignment.FindStringSubmatch(strings.TrimSpace(line)); m != nil {
+			values = append(values, strings.Trim(strings.TrimSpace(m[1]), `"'`))
+		}
+	}
+	return values
+}
+
+// sandboxOptions converts a configured sandbox profile. Aeon's home, with
+// its database, config and vault key, is hidden but for the skills and
+// their virtualenv.
+func sandboxOptions(cfg config.SandboxConfig, p config.SandboxProfile) sandbox.Options {
+	home := config.AeonHome()
+	return sandbox.Options{
+		Network:      p.HasNetwork(),
+		Writable:     p.Writable,
+		Hidden:       []string{home},
+		Visible:      []string{filepath.Join(home, "skills"), filepath.Join(home, "base_venv")},
+		CPUs:         p.CPUs,
+		MemoryMB:     p.MemoryMB,
+		Pids:         p.Pids,
+		Env:          p.Env,
+		CgroupParent: cfg.CgroupParent,
+	}
+}
+
+// checkSandbox warns at startup when sandboxed commands would fail.
+func checkSandbox(what string, opts sandbox.Options, logger *slog.Logger) {
+	if err := sandbox.Check(opts); err != nil {
+		logger.Warn("sandbox unavailable, sandboxed commands will fail", "for", what, "error", err)
+		return
+	}
+	logger.Info("sandbox enabled", "for", what, "network", opts.Network)
+}
+
+// newApprovalGate builds the approval gate from the security config:
+// timeout, approvers, quorum and approval chat.
+func newApprovalGate(cfg *config.Config, b *bus.MessageBus, logger *slog.Logger) *agent.ApprovalGate {
+	timeout, _ := time.ParseDuration(cfg.Security.ApprovalTimeout)
+	gate := agent.NewApprovalGate(b, timeout)
+	gate.SetLogger(logger)
+	gate.SetApprovers(cfg.Security.Approvers, cfg.Security.ApprovalQuorum)
+	gate.SetApprovalChat(cfg.Security.ApprovalChannel, cfg.Security.ApprovalChatID)
+	return gate
+}
+
+// newRoles builds the roles channel users have, or nil if none are
+// configured and everyone may do everything.
+func newRoles(c config.AccessConfig) *agent.Roles {
+	if len(c.Roles) == 0 {
+		return nil
+	}
+	roles := make([]agent.Role, 0, len(c.Roles))
+	for name, r := range c.Roles {
+		roles = append(roles, agent.Role{Name: name, Tools: r.Tools, Commands: r.Commands, Approve: r.Approve})
+	}
+	return agent.NewRoles(roles, c.Users)
+}
+
+// NewEmbedder builds the configured memory embedder, or nil for "none".
+func NewEmbedder(cfg config.EmbeddingsConfig) memory.Embedder {
+	switch cfg.Provider {
+	case "openai":
+		return memory.NewOpenAIEmbedder(cfg.BaseURL, cfg.APIKey, cfg.Model, cfg.Dimensions)
+	case "none":
+		return nil
+	default:
+		return memory.NewHashEmbedder(cfg.Dimensions)
+	}
+}
+
+// StartEmbeddingBackfill embeds, in the background, memories stored before
+// semantic recall was enabled or under a different embedder.
+func (d *Deps) StartEmbeddingBackfill(ctx context.Context) {
+	if d.MemStore == nil {
+		return
+	}
+	go func() {
+		n, err := d.MemStore.BackfillEmbeddings(ctx)
+		if err != nil && ctx.Err() == nil {
+			d.Logger.Warn("embedding backfill stopped", "embedded", n, "error", err)
+		} else if n > 0 {
+			d.Logger.Info("embedding backfill done", "embedded", n)
+		}
+	}()
+}
+
+// policyReloadInterval is how often the policy file is checked for changes.
+const policyReloadInterval = 2 * time.Second
+
+// WatchPolicy reloads the policy file's rules when it changes, so edits
+// apply without a restart.
+func (d *Deps) WatchPolicy(ctx context.Context) {
+	if d.PolicyFile != nil {
+		go d.PolicyFile.Watch(ctx, policyReloadInterval, d.Logger)
+	}
+}
+
 // StartScheduler starts the scheduler if available.
 func (d *Deps) StartScheduler(ctx context.Context) {
 	if d.Scheduler != nil {
@@ -204,6 +496,9 @@ func (d *Deps) StartScheduler(ctx context.Context) {
 
 // Close cleans up all shared dependencies.
 func (d *Deps) Close() {
+	for _, c := range d.MCPClients {
+		c.Close()
+	}
 	if d.MemStore != nil {
 		d.MemStore.Close()
 	}
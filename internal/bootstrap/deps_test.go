@@ -0,0 +1,14 @@
+package bootstrap
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFileSecrets(t *testing.T) {
+	env := "# database\nexport DB_PASSWORD='hunter22'\nAPI_TOKEN = tok-123\n\n{\n-----END PRIVATE KEY-----\n"
+	got := fileSecrets(env)
+	if len(got) != 3 || got[0] != strings.TrimSpace(env) || got[1] != "hunter22" || got[2] != "tok-123" {
+		t.Errorf("expected the contents and the two values, got %q", got)
+	}
+}
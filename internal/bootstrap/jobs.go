@@ -0,0 +1,164 @@
+package bootstrap
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ImJafran/aeon/internal/bus"
+	"github.com/ImJafran/aeon/internal/channels"
+	"github.com/ImJafran/aeon/internal/scheduler"
+	"github.com/ImJafran/aeon/internal/tools"
+)
+
+// heartbeatJob is the built-in job that runs HEARTBEAT.md through the agent loop.
+const heartbeatJob = "__heartbeat__"
+
+// runJob executes a scheduled job by kind and delivers the outcome to the
+// job's target. The returned error is the job's real outcome, so the
+// scheduler records failures and auto-pauses jobs that keep failing.
+func (d *Deps) runJob(ctx context.Context, job scheduler.Job) (string, error) {
+	if job.Name == heartbeatJob {
+		// The agent loop handles heartbeats in its own session and reports there
+		d.Bus.Publish(bus.InboundMessage{
+			Channel: "system",
+			Content: fmt.Sprintf("[cron:%s] %s", job.Name, job.Command),
+		})
+		return "", nil
+	}
+	if job.Name == consolidationJob {
+		return d.runConsolidation(ctx)
+	}
+
+	kind := job.JobKind()
+	var out string
+	var err error
+	if job.Role != "" {
+		// A job runs within the limits of its creator's role
+		role, ok := d.Roles.Role(job.Role)
+		if !ok {
+			err = fmt.Errorf("the %s role that created it no longer exists", job.Role)
+			d.deliver(job, fmt.Sprintf("Scheduled job %q failed: %v", job.Name, err))
+			return "", err
+		}
+		ctx = tools.WithToolFilter(ctx, role.Name, role.AllowsTool)
+	}
+	switch kind {
+	case scheduler.KindReminder:
+		d.deliver(job, fmt.Sprintf("Reminder: %s", job.Command))
+		return job.Command, nil
+	case scheduler.KindSkill:
+		out, err = d.runSkillJob(ctx, job)
+	case scheduler.KindShell:
+		out, err = d.runShellJob(ctx, job)
+	case scheduler.KindAgent:
+		out, err = d.runAgentJob(ctx, job)
+	default:
+		err = fmt.Errorf("unknown job kind %q", kind)
+	}
+	if err == nil && ctx.Err() == context.DeadlineExceeded {
+		err = fmt.Errorf("timed out")
+	}
+
+	switch {
+	case err != nil && out != "":
+		d.deliver(job, fmt.Sprintf("Scheduled job %q failed: %v\n%s", job.Name, err, out))
+	case err != nil:
+		d.deliver(job, fmt.Sprintf("Scheduled job %q failed: %v", job.Name, err))
+	case out != "":
+		d.deliver(job, fmt.Sprintf("Scheduled job %q:\n%s", job.Name, out))
+	default:
+		d.deliver(job, fmt.Sprintf("Scheduled job %q finished.", job.Name))
+	}
+	return out, err
+}
+
+func (d *Deps) runSkillJob(ctx context.Context, job scheduler.Job) (string, error) {
+	if d.SkillLoader == nil {
+		return "", fmt.Errorf("skills are not available")
+	}
+	if !tools.ToolAllowed(ctx, "run_skill") {
+		return "", fmt.Errorf("the %s role may not run skills", tools.RoleFrom(ctx))
+	}
+	params := json.RawMessage(job.Params)
+	if len(params) == 0 {
+		params = json.RawMessage("{}")
+	}
+	return d.SkillLoader.Execute(ctx, job.SkillName, params)
+}
+
+// runShellJob runs the command through shell_exec, so the security policy
+// and policy file rules apply, the latter seeing the call come from the
+// "scheduler" channel. Nobody is there to approve a command that needs it,
+// so it fails.
+func (d *Deps) runShellJob(ctx context.Context, job scheduler.Job) (string, error) {
+	tool, ok := d.Registry.Get("shell_exec")
+	if !ok {
+		return "", fmt.Errorf("shell_exec is not available")
+	}
+	if !tools.ToolAllowed(ctx, "shell_exec") {
+		return "", fmt.Errorf("the %s role may not use shell_exec", tools.RoleFrom(ctx))
+	}
+
+	// Called directly rather than through Registry.Execute, whose tool
+	// timeout would cut the job's own timeout short
+	p := map[string]any{"command": job.Command}
+	if deadline, ok := ctx.Deadline(); ok {
+		p["timeout_seconds"] = max(int(time.Until(deadline).Seconds()), 1)
+	}
+	args, _ := json.Marshal(p)
+	start := time.Now()
+	ctx, result := d.Registry.Authorize(tools.WithOrigin(ctx, "scheduler", job.Name), "shell_exec", args)
+	var err error
+	if result == nil {
+		var r tools.ToolResult
+		r, err = tool.Execute(ctx, args)
+		result = &r
+	}
+	d.Registry.Audit(ctx, "shell_exec", args, *result, err, time.Since(start))
+	if err != nil {
+		return "", err
+	}
+	if result.NeedsApproval {
+		return "", fmt.Errorf("command needs approval, which scheduled jobs cannot get: %s", strings.ReplaceAll(result.ApprovalInfo, "\n", "; "))
+	}
+	if result.IsError {
+		line, _, _ := strings.Cut(result.ForLLM, "\n")
+		return result.ForLLM, fmt.Errorf("%s", strings.TrimSuffix(line, ":"))
+	}
+	return result.ForLLM, nil
+}
+
+func (d *Deps) runAgentJob(ctx context.Context, job scheduler.Job) (string, error) {
+	if d.SubMgr == nil {
+		return "", fmt.Errorf("no agent available")
+	}
+	return d.SubMgr.Run(ctx, fmt.Sprintf("cron_%d", job.ID), job.Command)
+}
+
+// deliver sends a job's output to its channel and chat. Jobs without a
+// target, such as those created over MCP or by older versions, go to the
+// Telegram allowed users.
+func (d *Deps) deliver(job scheduler.Job, content string) {
+	if d.SecAdapter != nil {
+		content = d.SecAdapter.ScrubCredentials(content)
+	}
+
+	if job.Channel != "" {
+		d.Bus.Send(bus.OutboundMessage{Channel: job.Channel, ChatID: job.ChatID, Content: content})
+		return
+	}
+	if d.Cfg.Channels.Telegram == nil || len(d.Cfg.Channels.Telegram.AllowedUsers) == 0 {
+		d.Logger.Warn("scheduled job has no delivery target", "job", job.Name)
+		return
+	}
+	for _, uid := range d.Cfg.Channels.Telegram.AllowedUsers {
+		d.Bus.Send(bus.OutboundMessage{
+			Channel: channels.TelegramChannelName,
+			ChatID:  fmt.Sprintf("%d", uid),
+			Content: content,
+		})
+	}
+}
@@ -13,7 +13,9 @@ const (
 	MediaImage MediaType = "image"
 	MediaAudio MediaType = "audio"
 
-	MetaStatus = "status" // metadata key for status update messages
+	MetaStatus   = "status"    // metadata key for status update messages
+	MetaPartial  = "partial"   // metadata key for in-progress streamed replies (Content = text so far)
+	MetaStreamID = "stream_id" // identifies a streamed reply; the final message carries the same ID
 )
 
 type InboundMessage struct {
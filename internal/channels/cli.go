@@ -80,6 +80,9 @@ func (c *CLIChannel) readInput(ctx context.Context, b *bus.MessageBus) {
 }
 
 func (c *CLIChannel) writeOutput(ctx context.Context) {
+	// The streamed reply currently being printed, if any
+	var streamID, printed string
+
 	for {
 		select {
 		case <-ctx.Done():
@@ -99,6 +102,33 @@ func (c *CLIChannel) writeOutput(ctx context.Context) {
 			if msg.Metadata != nil && msg.Metadata[bus.MetaStatus] == "true" {
 				continue
 			}
+
+			// Partial reply — print only what's new since the last update
+			if msg.Metadata != nil && msg.Metadata[bus.MetaPartial] == "true" {
+				id := msg.Metadata[bus.MetaStreamID]
+				if id != streamID || !strings.HasPrefix(msg.Content, printed) {
+					// New stream, or the provider restarted its answer
+					if streamID != "" {
+						fmt.Print("\n")
+					}
+					fmt.Print("\n")
+					streamID, printed = id, ""
+				}
+				fmt.Print(msg.Content[len(printed):])
+				printed = msg.Content
+				continue
+			}
+
+			// Final reply for the stream being printed — finish it off
+			if id := msg.Metadata[bus.MetaStreamID]; id != "" && id == streamID && strings.HasPrefix(msg.Content, printed) {
+				fmt.Printf("%s\n\n> ", msg.Content[len(printed):])
+				streamID, printed = "", ""
+				continue
+			}
+			if streamID != "" {
+				fmt.Print("\n")
+				streamID, printed = "", ""
+			}
 			fmt.Printf("\n%s\n\n> ", msg.Content)
 		}
 	}
@@ -80,7 +80,7 @@ func (d *DiscordChannel) Start(ctx context.Context, msgBus *bus.MessageBus) erro
 				if msg.Channel != DiscordChannelName {
 					continue
 				}
-				if msg.Metadata != nil && msg.Metadata[bus.MetaStatus] == "true" {
+				if msg.Metadata != nil && (msg.Metadata[bus.MetaStatus] == "true" || msg.Metadata[bus.MetaPartial] == "true") {
 					continue
 				}
 				d.sendResponse(msg)
@@ -78,7 +78,7 @@ func (e *EmailChannel) Start(ctx context.Context, msgBus *bus.MessageBus) error
 				if msg.Channel != EmailChannelName {
 					continue
 				}
-				if msg.Metadata != nil && msg.Metadata[bus.MetaStatus] == "true" {
+				if msg.Metadata != nil && (msg.Metadata[bus.MetaStatus] == "true" || msg.Metadata[bus.MetaPartial] == "true") {
 					continue
 				}
 				e.sendReply(msg)
@@ -94,7 +94,7 @@ func (s *SlackChannel) Start(ctx context.Context, msgBus *bus.MessageBus) error
 				if msg.Channel != SlackChannelName {
 					continue
 				}
-				if msg.Metadata != nil && msg.Metadata[bus.MetaStatus] == "true" {
+				if msg.Metadata != nil && (msg.Metadata[bus.MetaStatus] == "true" || msg.Metadata[bus.MetaPartial] == "true") {
 					continue
 				}
 				s.sendResponse(sCtx, msg)
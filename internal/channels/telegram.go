@@ -35,6 +35,16 @@ type chatState struct {
 	phase       int                // 0=grace, 1=typing-only, 2=status-shown
 }
 
+// streamState tracks the message being edited in place while a reply streams.
+type streamState struct {
+	id       string // bus stream ID
+	msgID    int    // Telegram message showing the partial reply
+	lastEdit time.Time
+}
+
+// streamEditInterval keeps edits under Telegram's per-chat rate limit.
+const streamEditInterval = time.Second
+
 // TelegramChannel implements the Channel interface for Telegram Bot API.
 type TelegramChannel struct {
 	token      string
@@ -46,7 +56,8 @@ type TelegramChannel struct {
 	wg         sync.WaitGroup
 
 	stateMu    sync.Mutex
-	chatStates map[string]*chatState // per-chat state
+	chatStates map[string]*chatState   // per-chat state
+	streams    map[string]*streamState // per-chat streamed reply
 
 	transcriber Transcriber
 }
@@ -67,6 +78,7 @@ func NewTelegram(token string, allowedIDs []int64, logger *slog.Logger) *Telegra
 			Timeout: time.Duration(pollTimeout+10) * time.Second,
 		},
 		chatStates: make(map[string]*chatState),
+		streams:    make(map[string]*streamState),
 	}
 }
 
@@ -289,14 +301,21 @@ func (t *TelegramChannel) sendResponse(msg bus.OutboundMessage) {
 		return
 	}
 
+	// Partial reply — edit the streaming message in place
+	if msg.Metadata != nil && msg.Metadata[bus.MetaPartial] == "true" {
+		t.updateStream(msg.ChatID, msg.Metadata[bus.MetaStreamID], msg.Content)
+		return
+	}
+
 	// Real response — stop typing indicator
 	t.stopTyping(msg.ChatID)
 
 	// Approval request — render with inline keyboard buttons
 	if msg.Metadata != nil && msg.Metadata["approval"] == "true" {
+		id := msg.Metadata["approval_id"]
 		buttons := [][]InlineButton{{
-			{Text: "Approve", Data: "/approve"},
-			{Text: "Deny", Data: "/deny"},
+			{Text: "Approve", Data: strings.TrimSpace("/approve " + id)},
+			{Text: "Deny", Data: strings.TrimSpace("/deny " + id)},
 		}}
 		if err := t.SendWithKeyboard(msg.ChatID, msg.Content, buttons); err != nil {
 			t.logger.Error("failed to send approval keyboard", "error", err, "chat_id", msg.ChatID)
@@ -304,6 +323,13 @@ func (t *TelegramChannel) sendResponse(msg bus.OutboundMessage) {
 		return
 	}
 
+	// Completed streamed reply — replace the partial text with the final answer
+	if msg.Metadata != nil && msg.Metadata[bus.MetaStreamID] != "" {
+		if t.finishStream(msg.ChatID, msg.Metadata[bus.MetaStreamID], msg.Content) {
+			return
+		}
+	}
+
 	// Chunk messages that exceed Telegram's limit
 	chunks := chunkMessage(msg.Content, maxMessageLen)
 	for _, chunk := range chunks {
@@ -313,6 +339,63 @@ func (t *TelegramChannel) sendResponse(msg bus.OutboundMessage) {
 	}
 }
 
+// --- Streamed replies ---
+
+// updateStream shows partial reply text, posting a new message for a new
+// stream and editing it (at most once per streamEditInterval) afterwards.
+// Partial text is sent without Markdown since it may end mid-entity.
+func (t *TelegramChannel) updateStream(chatID, streamID, text string) {
+	text = chunkMessage(text, maxMessageLen)[0]
+
+	t.stateMu.Lock()
+	st, ok := t.streams[chatID]
+	if ok && st.id == streamID {
+		if time.Since(st.lastEdit) < streamEditInterval {
+			t.stateMu.Unlock()
+			return
+		}
+		st.lastEdit = time.Now()
+		msgID := st.msgID
+		t.stateMu.Unlock()
+		t.editMessage(chatID, msgID, text, "")
+		return
+	}
+	t.stateMu.Unlock()
+
+	msgID, err := t.sendAndGetID(chatID, text, "")
+	if err != nil {
+		t.logger.Debug("failed to start streamed reply", "error", err, "chat_id", chatID)
+		return
+	}
+	t.stateMu.Lock()
+	t.streams[chatID] = &streamState{id: streamID, msgID: msgID, lastEdit: time.Now()}
+	t.stateMu.Unlock()
+}
+
+// finishStream edits the streamed message into the final reply, sending any
+// overflow as extra messages. Returns false if there is no such stream.
+func (t *TelegramChannel) finishStream(chatID, streamID, text string) bool {
+	t.stateMu.Lock()
+	st, ok := t.streams[chatID]
+	if !ok || st.id != streamID {
+		t.stateMu.Unlock()
+		return false
+	}
+	delete(t.streams, chatID)
+	t.stateMu.Unlock()
+
+	chunks := chunkMessage(text, maxMessageLen)
+	if err := t.editMessage(chatID, st.msgID, chunks[0], "Markdown"); err != nil {
+		t.editMessage(chatID, st.msgID, chunks[0], "")
+	}
+	for _, chunk := range chunks[1:] {
+		if err := t.sendMessage(chatID, chunk); err != nil {
+			t.logger.Error("failed to send telegram message", "error", err, "chat_id", chatID)
+		}
+	}
+	return true
+}
+
 // --- Typing / status indicator ---
 
 const statusGracePeriod = 2 * time.Second
@@ -441,7 +524,7 @@ func (t *TelegramChannel) updateStatus(chatID, text string) {
 		t.editMessageText(chatID, msgID, statusText)
 	} else {
 		// Send new status message
-		if newID, err := t.sendAndGetID(chatID, statusText); err == nil {
+		if newID, err := t.sendAndGetID(chatID, statusText, "Markdown"); err == nil {
 			t.stateMu.Lock()
 			if s, ok := t.chatStates[chatID]; ok && s == state {
 				s.statusMsgID = newID
@@ -456,27 +539,50 @@ func (t *TelegramChannel) updateStatus(chatID, text string) {
 
 // editMessageText edits an existing Telegram message's text.
 func (t *TelegramChannel) editMessageText(chatID string, messageID int, text string) {
-	body, _ := json.Marshal(map[string]interface{}{
+	if err := t.editMessage(chatID, messageID, text, "Markdown"); err != nil {
+		t.logger.Debug("editMessageText failed", "error", err, "chat_id", chatID)
+	}
+}
+
+// editMessage edits a message's text with the given parse mode ("" for plain text).
+func (t *TelegramChannel) editMessage(chatID string, messageID int, text, parseMode string) error {
+	payload := map[string]interface{}{
 		"chat_id":    chatID,
 		"message_id": messageID,
 		"text":       text,
-		"parse_mode": "Markdown",
-	})
+	}
+	if parseMode != "" {
+		payload["parse_mode"] = parseMode
+	}
+	body, _ := json.Marshal(payload)
+
 	resp, err := t.client.Post(t.apiURL("editMessageText"), "application/json", bytes.NewReader(body))
 	if err != nil {
-		t.logger.Debug("editMessageText failed", "error", err, "chat_id", chatID)
-		return
+		return err
 	}
-	resp.Body.Close()
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(resp.Body)
+		// Editing to identical text is not an error for our purposes
+		if strings.Contains(string(data), "message is not modified") {
+			return nil
+		}
+		return fmt.Errorf("editMessageText failed: %s", string(data))
+	}
+	return nil
 }
 
-// sendAndGetID sends a message and returns its message ID.
-func (t *TelegramChannel) sendAndGetID(chatID, text string) (int, error) {
-	body, _ := json.Marshal(map[string]interface{}{
-		"chat_id":    chatID,
-		"text":       text,
-		"parse_mode": "Markdown",
-	})
+// sendAndGetID sends a message with the given parse mode ("" for plain text) and returns its message ID.
+func (t *TelegramChannel) sendAndGetID(chatID, text, parseMode string) (int, error) {
+	payload := map[string]interface{}{
+		"chat_id": chatID,
+		"text":    text,
+	}
+	if parseMode != "" {
+		payload["parse_mode"] = parseMode
+	}
+	body, _ := json.Marshal(payload)
 
 	resp, err := t.client.Post(t.apiURL("sendMessage"), "application/json", bytes.NewReader(body))
 	if err != nil {
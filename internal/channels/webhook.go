@@ -88,7 +88,7 @@ func (w *WebhookChannel) Start(ctx context.Context, msgBus *bus.MessageBus) erro
 				if msg.Channel != WebhookChannelName {
 					continue
 				}
-				if msg.Metadata != nil && msg.Metadata[bus.MetaStatus] == "true" {
+				if msg.Metadata != nil && (msg.Metadata[bus.MetaStatus] == "true" || msg.Metadata[bus.MetaPartial] == "true") {
 					continue
 				}
 				if ch, ok := w.pending.Load(msg.ChatID); ok {
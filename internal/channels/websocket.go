@@ -41,7 +41,9 @@ type wsInbound struct {
 }
 
 type wsOutbound struct {
-	Content string `json:"content"`
+	Content  string `json:"content"`
+	Partial  bool   `json:"partial,omitempty"`   // true while a reply streams; content is the text so far
+	StreamID string `json:"stream_id,omitempty"` // links partial messages to their final reply
 }
 
 func NewWebSocket(listenAddr, authToken string, logger *slog.Logger) *WebSocketChannel {
@@ -93,7 +95,11 @@ func (ws *WebSocketChannel) Start(ctx context.Context, msgBus *bus.MessageBus) e
 				if msg.Metadata != nil && msg.Metadata[bus.MetaStatus] == "true" {
 					continue
 				}
-				ws.sendToClient(msg.ChatID, msg.Content)
+				ws.sendToClient(msg.ChatID, wsOutbound{
+					Content:  msg.Content,
+					Partial:  msg.Metadata[bus.MetaPartial] == "true",
+					StreamID: msg.Metadata[bus.MetaStreamID],
+				})
 			}
 		}
 	}()
@@ -214,7 +220,7 @@ func (ws *WebSocketChannel) handleUpgrade(rw http.ResponseWriter, r *http.Reques
 	}()
 }
 
-func (ws *WebSocketChannel) sendToClient(chatID, content string) {
+func (ws *WebSocketChannel) sendToClient(chatID string, out wsOutbound) {
 	ws.connsMu.RLock()
 	wc, ok := ws.conns[chatID]
 	ws.connsMu.RUnlock()
@@ -222,7 +228,7 @@ func (ws *WebSocketChannel) sendToClient(chatID, content string) {
 		return
 	}
 
-	data, _ := json.Marshal(wsOutbound{Content: content})
+	data, _ := json.Marshal(out)
 	wc.mu.Lock()
 	err := wc.conn.WriteMessage(websocket.TextMessage, data)
 	wc.mu.Unlock()
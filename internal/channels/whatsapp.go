@@ -80,7 +80,7 @@ func (wa *WhatsAppChannel) Start(ctx context.Context, msgBus *bus.MessageBus) er
 				if msg.Channel != WhatsAppChannelName {
 					continue
 				}
-				if msg.Metadata != nil && msg.Metadata[bus.MetaStatus] == "true" {
+				if msg.Metadata != nil && (msg.Metadata[bus.MetaStatus] == "true" || msg.Metadata[bus.MetaPartial] == "true") {
 					continue
 				}
 				wa.sendMessage(msg)
@@ -2,12 +2,17 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
+	"reflect"
 	"regexp"
 	"strings"
 	"time"
+
+	"github.com/ImJafran/aeon/internal/secrets"
 )
 
 type Config struct {
@@ -19,7 +24,18 @@ type Config struct {
 	Scheduler SchedulerConfig `json:"scheduler"`
 	Memory    MemoryConfig    `json:"memory"`
 	Agent     AgentConfig     `json:"agent"`
+	MCP       MCPConfig       `json:"mcp"`
 	Log       LogConfig       `json:"log"`
+	Secrets   SecretsConfig   `json:"secrets"`
+
+	// Pricing overrides the built-in price table, keyed by model name prefix.
+	Pricing map[string]ModelPrice `json:"pricing,omitempty"`
+}
+
+// ModelPrice is the price of a model in USD per million tokens.
+type ModelPrice struct {
+	Input  float64 `json:"input"`
+	Output float64 `json:"output"`
 }
 
 type ProviderConfig struct {
@@ -38,31 +54,35 @@ type ClaudeCLIConfig struct {
 }
 
 type AnthropicConfig struct {
-	Enabled      bool   `json:"enabled"`
-	APIKey       string `json:"api_key"`
-	DefaultModel string `json:"default_model"`
-	FastModel    string `json:"fast_model"`
+	Enabled       bool   `json:"enabled"`
+	APIKey        string `json:"api_key"`
+	DefaultModel  string `json:"default_model"`
+	FastModel     string `json:"fast_model"`
+	ContextWindow int    `json:"context_window,omitempty"` // tokens; 0 = known size for the model
 }
 
 type GeminiConfig struct {
-	Enabled      bool   `json:"enabled"`
-	APIKey       string `json:"api_key"`
-	DefaultModel string `json:"default_model"`
-	AudioModel   string `json:"audio_model"` // native audio (transcription/live)
-	TTSModel     string `json:"tts_model"`   // text-to-speech
+	Enabled       bool   `json:"enabled"`
+	APIKey        string `json:"api_key"`
+	DefaultModel  string `json:"default_model"`
+	AudioModel    string `json:"audio_model"`              // native audio (transcription/live)
+	TTSModel      string `json:"tts_model"`                // text-to-speech
+	ContextWindow int    `json:"context_window,omitempty"` // tokens; 0 = known size for the model
 }
 
 type ZAIConfig struct {
-	Enabled      bool   `json:"enabled"`
-	APIKey       string `json:"api_key"`
-	DefaultModel string `json:"default_model"`
+	Enabled       bool   `json:"enabled"`
+	APIKey        string `json:"api_key"`
+	DefaultModel  string `json:"default_model"`
+	ContextWindow int    `json:"context_window,omitempty"` // tokens; 0 = known size for the model
 }
 
 type OpenAICompatConfig struct {
-	Enabled      bool   `json:"enabled"`
-	BaseURL      string `json:"base_url"`
-	APIKey       string `json:"api_key"`
-	DefaultModel string `json:"default_model"`
+	Enabled       bool   `json:"enabled"`
+	BaseURL       string `json:"base_url"`
+	APIKey        string `json:"api_key"`
+	DefaultModel  string `json:"default_model"`
+	ContextWindow int    `json:"context_window,omitempty"` // tokens; 0 = known size for the model
 }
 
 type RoutingConfig struct {
@@ -133,9 +153,113 @@ type WhatsAppConfig struct {
 }
 
 type SecurityConfig struct {
-	ApprovalTimeout string   `json:"approval_timeout,omitempty"`
-	DenyPatterns    []string `json:"deny_patterns,omitempty"`
-	AllowedPaths    []string `json:"allowed_paths,omitempty"`
+	ApprovalTimeout string        `json:"approval_timeout,omitempty"`
+	Approvers       []string      `json:"approvers,omitempty"`        // channel:userID of who may answer approvals (default: anyone in the chat that asked)
+	ApprovalQuorum  int           `json:"approval_quorum,omitempty"`  // approvers who must approve (default: 1); any one can deny
+	ApprovalChannel string        `json:"approval_channel,omitempty"` // chat every approval request goes to; approvers must answer on this channel
+	ApprovalChatID  string        `json:"approval_chat_id,omitempty"`
+	DenyPatterns    []string      `json:"deny_patterns,omitempty"`
+	AllowedPaths    []string      `json:"allowed_paths,omitempty"`
+	PolicyFile      string        `json:"policy_file,omitempty"` // ordered tool rules, YAML or JSON; reloaded on change
+	Sandbox         SandboxConfig `json:"sandbox,omitempty"`
+	Access          AccessConfig  `json:"access,omitempty"`
+	Scrub           ScrubConfig   `json:"scrub,omitempty"`
+}
+
+// ScrubConfig adds to what credential scrubbing redacts, besides the
+// built-in patterns, the config's own credentials and the vault's secrets.
+type ScrubConfig struct {
+	Env      []string `json:"env,omitempty"`      // env vars whose values are redacted, with * globs
+	Files    []string `json:"files,omitempty"`    // files whose contents, and the values of KEY=VALUE lines, are redacted
+	Patterns []string `json:"patterns,omitempty"` // regexes for credentials of your own, e.g. ACME-[A-Za-z0-9]{16}
+}
+
+// AccessConfig gives channel users roles. Users are keyed channel:userID,
+// channel:* for anyone on a channel, or * for anyone else. With roles set,
+// users without one are refused.
+type AccessConfig struct {
+	Roles map[string]RoleConfig `json:"roles,omitempty"`
+	Users map[string]string     `json:"users,omitempty"` // user -> role name
+}
+
+// RoleConfig is what a role's users may do.
+type RoleConfig struct {
+	Tools    []string `json:"tools,omitempty"`    // tools allowed, with * and ? globs
+	Commands []string `json:"commands,omitempty"` // slash commands allowed, or "*"; /help always is
+	Approve  bool     `json:"approve,omitempty"`  // may answer approval requests
+}
+
+// SandboxConfig runs shell_exec and skills in a Linux sandbox. The profile
+// at the top applies to both; Tools and Skills override it by name, with
+// Skills["*"] applying to every skill before the skill's own entry.
+type SandboxConfig struct {
+	SandboxProfile
+	CgroupParent string                    `json:"cgroup_parent,omitempty"` // cgroup v2 path limits are created under (default: aeon's own cgroup)
+	Tools        map[string]SandboxProfile `json:"tools,omitempty"`
+	Skills       map[string]SandboxProfile `json:"skills,omitempty"`
+}
+
+// SandboxProfile is a set of sandbox settings. Unset fields are inherited.
+type SandboxProfile struct {
+	Enabled  *bool    `json:"enabled,omitempty"`   // run sandboxed (default: false)
+	Network  *bool    `json:"network,omitempty"`   // keep network access (default: true)
+	Writable []string `json:"writable,omitempty"`  // paths left writable; the rest of the filesystem is read-only, /tmp private
+	CPUs     float64  `json:"cpus,omitempty"`      // CPU limit in cores, 0 = unlimited
+	MemoryMB int      `json:"memory_mb,omitempty"` // memory limit, 0 = unlimited
+	Pids     int      `json:"pids,omitempty"`      // process limit, 0 = unlimited
+	Env      []string `json:"env,omitempty"`       // environment variables passed in besides PATH, HOME, locale and the like
+}
+
+// ForTool returns the sandbox profile of a tool and whether it is sandboxed.
+func (c SandboxConfig) ForTool(name string) (SandboxProfile, bool) {
+	return c.resolve(c.Tools[name])
+}
+
+// ForSkill returns the sandbox profile of a skill and whether it is sandboxed.
+func (c SandboxConfig) ForSkill(name string) (SandboxProfile, bool) {
+	all, ok := c.Skills["*"]
+	if !ok {
+		return c.resolve(c.Skills[name])
+	}
+	return c.resolve(all, c.Skills[name])
+}
+
+func (c SandboxConfig) resolve(overrides ...SandboxProfile) (SandboxProfile, bool) {
+	p := c.SandboxProfile
+	for _, o := range overrides {
+		if o.Enabled != nil {
+			p.Enabled = o.Enabled
+		}
+		if o.Network != nil {
+			p.Network = o.Network
+		}
+		if o.Writable != nil {
+			p.Writable = o.Writable
+		}
+		if o.CPUs != 0 {
+			p.CPUs = o.CPUs
+		}
+		if o.MemoryMB != 0 {
+			p.MemoryMB = o.MemoryMB
+		}
+		if o.Pids != 0 {
+			p.Pids = o.Pids
+		}
+		if o.Env != nil {
+			p.Env = o.Env
+		}
+	}
+	writable := make([]string, len(p.Writable))
+	for i, path := range p.Writable {
+		writable[i] = expandHome(path)
+	}
+	p.Writable = writable
+	return p, p.Enabled != nil && *p.Enabled
+}
+
+// HasNetwork reports whether the profile keeps network access.
+func (p SandboxProfile) HasNetwork() bool {
+	return p.Network == nil || *p.Network
 }
 
 type SkillsConfig struct {
@@ -145,26 +269,97 @@ type SkillsConfig struct {
 }
 
 type SchedulerConfig struct {
-	MaxConcurrent          int `json:"max_concurrent,omitempty"`
-	AutoPauseAfterFailures int `json:"auto_pause_after_failures,omitempty"`
+	MaxConcurrent          int    `json:"max_concurrent,omitempty"`
+	AutoPauseAfterFailures int    `json:"auto_pause_after_failures,omitempty"`
+	Timezone               string `json:"timezone,omitempty"` // IANA zone for jobs without their own (default: system local time)
 }
 
 type MemoryConfig struct {
-	AutoSave            bool `json:"auto_save,omitempty"`
-	CompactionThreshold int  `json:"compaction_threshold,omitempty"`
+	AutoSave            bool             `json:"auto_save,omitempty"`            // let scheduled consolidation change memory; false only reports what it would do
+	CompactionThreshold int              `json:"compaction_threshold,omitempty"` // old daily/conversation memories needed before consolidation merges them (default: 10)
+	ConsolidateInterval string           `json:"consolidate_interval,omitempty"` // how often consolidation runs (default: "24h", "off" to disable)
+	AutoExtract         bool             `json:"auto_extract,omitempty"`         // extract memories from each exchange in the background on the fast route
+	DefaultScope        string           `json:"default_scope,omitempty"`        // who new memories are visible to: "user" (default), "chat" or "global"
+	Embeddings          EmbeddingsConfig `json:"embeddings,omitempty"`
+}
+
+// EmbeddingsConfig selects the embedder for semantic memory recall.
+type EmbeddingsConfig struct {
+	Provider      string  `json:"provider,omitempty"`       // "hash" (default, offline), "openai" (any OpenAI-compatible /embeddings), or "none"
+	BaseURL       string  `json:"base_url,omitempty"`       // for openai, e.g. https://api.openai.com/v1
+	APIKey        string  `json:"api_key,omitempty"`        // for openai
+	Model         string  `json:"model,omitempty"`          // for openai, e.g. text-embedding-3-small
+	Dimensions    int     `json:"dimensions,omitempty"`     // hash buckets (default 256), or shortened openai vectors
+	MinSimilarity float64 `json:"min_similarity,omitempty"` // cosine below which meaning-only matches are dropped (default 0.3)
 }
 
 type AgentConfig struct {
-	SystemPrompt       string `json:"system_prompt,omitempty"`
-	MaxHistoryMessages int    `json:"max_history_messages,omitempty"` // max messages to load into context (default: 20)
-	MaxIterations      int    `json:"max_iterations,omitempty"`       // max tool iterations per turn (default: 20)
-	MaxOutputLen       int    `json:"max_output_len,omitempty"`       // max shell output chars (default: 10000)
-	ShellTimeout       string `json:"shell_timeout,omitempty"`        // default shell_exec timeout (default: "30s")
-	ProviderTimeout    string `json:"provider_timeout,omitempty"`     // HTTP timeout for providers (default: "120s")
-	MaxTokens          int    `json:"max_tokens,omitempty"`           // max tokens for LLM response (default: 4096)
-	DailyTokenLimit    int    `json:"daily_token_limit,omitempty"`    // daily token limit, 0=unlimited
-	ToolTimeout        string `json:"tool_timeout,omitempty"`         // default tool execution timeout (default: "60s")
-	HeartbeatInterval  string `json:"heartbeat_interval,omitempty"`   // heartbeat interval (default: "30m", empty to disable)
+	SystemPrompt       string  `json:"system_prompt,omitempty"`
+	MaxHistoryMessages int     `json:"max_history_messages,omitempty"` // max messages to load into context (default: 20)
+	MaxIterations      int     `json:"max_iterations,omitempty"`       // max tool iterations per turn (default: 20)
+	MaxOutputLen       int     `json:"max_output_len,omitempty"`       // max shell output chars (default: 10000)
+	ShellTimeout       string  `json:"shell_timeout,omitempty"`        // default shell_exec timeout (default: "30s")
+	ProviderTimeout    string  `json:"provider_timeout,omitempty"`     // HTTP timeout for providers (default: "120s")
+	MaxTokens          int     `json:"max_tokens,omitempty"`           // max tokens for LLM response (default: 4096)
+	DailyTokenLimit    int     `json:"daily_token_limit,omitempty"`    // daily token limit, 0=unlimited
+	MonthlyTokenLimit  int     `json:"monthly_token_limit,omitempty"`  // monthly token limit, 0=unlimited
+	DailyBudgetUSD     float64 `json:"daily_budget_usd,omitempty"`     // daily spend limit in USD, 0=unlimited
+	MonthlyBudgetUSD   float64 `json:"monthly_budget_usd,omitempty"`   // monthly spend limit in USD, 0=unlimited
+	BudgetWarnPercent  int     `json:"budget_warn_percent,omitempty"`  // warn and use the fast route past this share of a limit (default: 80)
+	ToolTimeout        string  `json:"tool_timeout,omitempty"`         // default tool execution timeout (default: "60s")
+	HeartbeatInterval  string  `json:"heartbeat_interval,omitempty"`   // heartbeat interval (default: "30m", empty to disable)
+	MaxConcurrentTurns int     `json:"max_concurrent_turns,omitempty"` // chats processed in parallel (default: 4)
+	DisableStreaming   bool    `json:"disable_streaming,omitempty"`    // send replies only once complete
+}
+
+// MCPConfig lists Model Context Protocol servers whose tools Aeon imports,
+// and how `aeon mcp` serves Aeon's own tools.
+type MCPConfig struct {
+	Servers map[string]MCPServerConfig `json:"servers,omitempty"` // keyed by server name
+	Serve   MCPServeConfig             `json:"serve,omitempty"`
+}
+
+// MCPServeConfig configures `aeon mcp`. Calls that need approval are sent to
+// ApprovalChannel/ApprovalChatID for /approve or /deny; with no approval
+// channel they are rejected.
+type MCPServeConfig struct {
+	ListenAddr      string   `json:"listen_addr,omitempty"`     // HTTP address for `aeon mcp --http` (default: "127.0.0.1:8765")
+	AuthToken       string   `json:"auth_token,omitempty"`      // Bearer token required over HTTP; needed unless listening on loopback
+	AllowedOrigins  []string `json:"allowed_origins,omitempty"` // browser origins allowed besides local ones, e.g. "https://app.example.com"
+	Tools           []string `json:"tools,omitempty"`           // tools to export; empty exports all but spawn_agent, list_tasks and imported mcp_* tools
+	ApprovalChannel string   `json:"approval_channel,omitempty"`
+	ApprovalChatID  string   `json:"approval_chat_id,omitempty"`
+}
+
+// MCPServerConfig describes one MCP server. Set Command for a stdio server
+// Aeon launches itself, or URL for a streamable HTTP server.
+type MCPServerConfig struct {
+	Disabled bool              `json:"disabled,omitempty"`
+	Command  string            `json:"command,omitempty"`
+	Args     []string          `json:"args,omitempty"`
+	Env      map[string]string `json:"env,omitempty"`
+	Dir      string            `json:"dir,omitempty"`
+	URL      string            `json:"url,omitempty"`
+	Headers  map[string]string `json:"headers,omitempty"`
+}
+
+// SecretsConfig locates the encrypted secrets vault. Config values written
+// "secret://NAME" are replaced by the secret when the config is loaded.
+type SecretsConfig struct {
+	Vault   string              `json:"vault,omitempty"`    // default: ~/.aeon/secrets.vault
+	KeyFile string              `json:"key_file,omitempty"` // default: ~/.aeon/secrets.key; unused for vaults locked with AEON_SECRETS_PASSPHRASE
+	Skills  map[string][]string `json:"skills,omitempty"`   // skill name, or "*" for every skill -> secrets passed to it as env vars
+}
+
+// Open opens the secrets vault, with the passphrase in AEON_SECRETS_PASSPHRASE
+// if it's locked with one.
+func (c SecretsConfig) Open() (*secrets.Vault, error) {
+	return secrets.Open(c.Vault, c.KeyFile, secrets.Passphrase())
+}
+
+// ForSkill returns the names of the secrets a skill may see.
+func (c SecretsConfig) ForSkill(name string) []string {
+	return append(append([]string(nil), c.Skills["*"]...), c.Skills[name]...)
 }
 
 type LogConfig struct {
@@ -174,6 +369,18 @@ type LogConfig struct {
 
 var envVarPattern = regexp.MustCompile(`\$\{([^}]+)\}`)
 
+// credentialField matches the JSON names of config fields holding
+// credentials, and the keys of env and header maps that do.
+var credentialField = regexp.MustCompile(`(?i)(^|_)(api_?key|key|token|password|secret|auth|authorization)$`)
+
+// secretRefPattern matches JSON strings that are a secret reference.
+var secretRefPattern = regexp.MustCompile(`"secret://([^"]*)"`)
+
+var mcpServerName = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// consolidateInterval matches scheduler intervals of whole hours or days.
+var consolidateInterval = regexp.MustCompile(`^[1-9][0-9]*[hd]$`)
+
 func expandEnvVars(s string) string {
 	return envVarPattern.ReplaceAllStringFunc(s, func(match string) string {
 		key := match[2 : len(match)-1]
@@ -188,6 +395,100 @@ func expandEnvInBytes(data []byte) []byte {
 	return []byte(expandEnvVars(string(data)))
 }
 
+// resolveSecrets replaces "secret://NAME" strings with the secret from the
+// vault, which is only opened when the config refers to it.
+func resolveSecrets(data []byte) ([]byte, error) {
+	if !secretRefPattern.Match(data) {
+		return data, nil
+	}
+	c, err := parseSecrets(data)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(c.Vault); err != nil {
+		return nil, fmt.Errorf("config refers to secrets but there is no vault at %s (add them with aeon secrets set)", c.Vault)
+	}
+	vault, err := c.Open()
+	if err != nil {
+		return nil, err
+	}
+
+	var missing []string
+	data = secretRefPattern.ReplaceAllFunc(data, func(ref []byte) []byte {
+		name := string(secretRefPattern.FindSubmatch(ref)[1])
+		value, ok := vault.Get(name)
+		if !ok {
+			missing = append(missing, name)
+			return ref
+		}
+		quoted, _ := json.Marshal(value)
+		return quoted
+	})
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("secrets not in the vault: %s", strings.Join(missing, ", "))
+	}
+	return data, nil
+}
+
+// Credentials returns the API keys, tokens and passwords set in the config,
+// with secret references already resolved, so they can be scrubbed from
+// output: strings in fields named like api_key, bot_token or password, and
+// credential-named entries of env and header maps.
+func (c *Config) Credentials() []string {
+	var values []string
+	var walk func(v reflect.Value, name string)
+	walk = func(v reflect.Value, name string) {
+		switch v.Kind() {
+		case reflect.Pointer:
+			if !v.IsNil() {
+				walk(v.Elem(), name)
+			}
+		case reflect.Struct:
+			for i := 0; i < v.NumField(); i++ {
+				tag, _, _ := strings.Cut(v.Type().Field(i).Tag.Get("json"), ",")
+				walk(v.Field(i), tag)
+			}
+		case reflect.Map:
+			if v.Type().Key().Kind() != reflect.String {
+				return
+			}
+			for _, k := range v.MapKeys() {
+				walk(v.MapIndex(k), k.String())
+			}
+		case reflect.String:
+			if v.String() != "" && credentialField.MatchString(name) {
+				values = append(values, v.String())
+			}
+		}
+	}
+	walk(reflect.ValueOf(c).Elem(), "")
+	return values
+}
+
+// LoadSecrets reads only the secrets section of the config at path, without
+// resolving secret references, so the vault can be managed even while the
+// config refers to secrets it lacks. A missing file gives the defaults.
+func LoadSecrets(path string) (SecretsConfig, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		data = []byte("{}")
+	} else if err != nil {
+		return SecretsConfig{}, fmt.Errorf("reading config: %w", err)
+	}
+	return parseSecrets(expandEnvInBytes(data))
+}
+
+func parseSecrets(data []byte) (SecretsConfig, error) {
+	var raw struct {
+		Secrets SecretsConfig `json:"secrets"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return SecretsConfig{}, fmt.Errorf("parsing config: %w", err)
+	}
+	secretsDefaults(&raw.Secrets)
+	return raw.Secrets, nil
+}
+
 func AeonHome() string {
 	if h := os.Getenv("AEON_HOME"); h != "" {
 		return h
@@ -209,6 +510,9 @@ func Load(path string) (*Config, error) {
 		return nil, fmt.Errorf("reading config: %w", err)
 	}
 	data = expandEnvInBytes(data)
+	if data, err = resolveSecrets(data); err != nil {
+		return nil, err
+	}
 
 	cfg := &Config{}
 	if err := json.Unmarshal(data, cfg); err != nil {
@@ -243,6 +547,15 @@ func applyDefaults(cfg *Config) {
 	if cfg.Memory.CompactionThreshold == 0 {
 		cfg.Memory.CompactionThreshold = 10
 	}
+	if cfg.Memory.ConsolidateInterval == "" {
+		cfg.Memory.ConsolidateInterval = "24h"
+	}
+	if cfg.Memory.DefaultScope == "" {
+		cfg.Memory.DefaultScope = "user"
+	}
+	if cfg.Memory.Embeddings.Provider == "" {
+		cfg.Memory.Embeddings.Provider = "hash"
+	}
 	if cfg.Agent.MaxHistoryMessages == 0 {
 		cfg.Agent.MaxHistoryMessages = 20
 	}
@@ -264,9 +577,18 @@ func applyDefaults(cfg *Config) {
 	if cfg.Agent.ToolTimeout == "" {
 		cfg.Agent.ToolTimeout = "60s"
 	}
+	if cfg.Agent.MaxConcurrentTurns == 0 {
+		cfg.Agent.MaxConcurrentTurns = 4
+	}
+	if cfg.Agent.BudgetWarnPercent == 0 {
+		cfg.Agent.BudgetWarnPercent = 80
+	}
 	if cfg.Agent.HeartbeatInterval == "" {
 		cfg.Agent.HeartbeatInterval = "30m"
 	}
+	if cfg.MCP.Serve.ListenAddr == "" {
+		cfg.MCP.Serve.ListenAddr = "127.0.0.1:8765"
+	}
 	if cfg.Agent.SystemPrompt == "" {
 		cfg.Agent.SystemPrompt = `You are Aeon, a persistent AI assistant on the user's system. You have tools — use them, don't describe them.
 
@@ -283,11 +605,29 @@ Rules:
 	if cfg.Log.File == "" {
 		cfg.Log.File = filepath.Join(AeonHome(), "logs", "aeon.log")
 	}
+	if cfg.Security.PolicyFile == "" {
+		cfg.Security.PolicyFile = filepath.Join(AeonHome(), "policy.yaml")
+	}
+	cfg.Security.PolicyFile = expandHome(cfg.Security.PolicyFile)
+	secretsDefaults(&cfg.Secrets)
+	for i, f := range cfg.Security.Scrub.Files {
+		cfg.Security.Scrub.Files[i] = expandHome(f)
+	}
 	if len(cfg.Skills.BasePackages) == 0 {
 		cfg.Skills.BasePackages = []string{"requests", "httpx", "beautifulsoup4", "pyyaml"}
 	}
 }
 
+func secretsDefaults(c *SecretsConfig) {
+	if c.Vault == "" {
+		c.Vault = filepath.Join(AeonHome(), "secrets.vault")
+	}
+	if c.KeyFile == "" {
+		c.KeyFile = filepath.Join(AeonHome(), "secrets.key")
+	}
+	c.Vault, c.KeyFile = expandHome(c.Vault), expandHome(c.KeyFile)
+}
+
 // NoProvider is returned when no LLM provider is configured.
 // It's a warning, not a fatal error — Aeon runs in echo mode without providers.
 var NoProvider = fmt.Errorf("no LLM provider configured — running in echo mode")
@@ -317,6 +657,97 @@ func validate(cfg *Config) error {
 		}
 	}
 
+	if iv := cfg.Memory.ConsolidateInterval; iv != "off" && !consolidateInterval.MatchString(iv) {
+		return fmt.Errorf("invalid memory.consolidate_interval %q (hours or days like \"24h\" or \"7d\", or \"off\")", iv)
+	}
+	switch cfg.Memory.DefaultScope {
+	case "user", "chat", "global":
+	default:
+		return fmt.Errorf("invalid memory.default_scope %q (must be user, chat or global)", cfg.Memory.DefaultScope)
+	}
+
+	if tz := cfg.Scheduler.Timezone; tz != "" {
+		if _, err := time.LoadLocation(tz); err != nil {
+			return fmt.Errorf("invalid scheduler.timezone %q: %v", tz, err)
+		}
+	}
+
+	switch e := cfg.Memory.Embeddings; e.Provider {
+	case "hash", "none":
+	case "openai":
+		if e.BaseURL == "" || e.Model == "" {
+			return fmt.Errorf("memory.embeddings provider openai needs base_url and model")
+		}
+		if strings.HasPrefix(e.APIKey, "${") {
+			return fmt.Errorf("memory.embeddings api_key contains unexpanded env var: %s", e.APIKey)
+		}
+	default:
+		return fmt.Errorf("unknown memory.embeddings.provider %q (use hash, openai or none)", e.Provider)
+	}
+	if m := cfg.Memory.Embeddings.MinSimilarity; m < 0 || m >= 1 {
+		return fmt.Errorf("memory.embeddings.min_similarity must be between 0 and 1")
+	}
+
+	// Validate MCP servers
+	for name, srv := range cfg.MCP.Servers {
+		if !mcpServerName.MatchString(name) {
+			return fmt.Errorf("invalid mcp server name %q (letters, digits, _ and - only)", name)
+		}
+		if (srv.Command == "") == (srv.URL == "") {
+			return fmt.Errorf("mcp server %q needs exactly one of command or url", name)
+		}
+	}
+	if err := validateApprovalChat("mcp.serve", cfg.MCP.Serve.ApprovalChannel, cfg.MCP.Serve.ApprovalChatID); err != nil {
+		return err
+	}
+
+	// Validate approvals
+	if err := validateApprovalChat("security", cfg.Security.ApprovalChannel, cfg.Security.ApprovalChatID); err != nil {
+		return err
+	}
+	for _, a := range cfg.Security.Approvers {
+		if !strings.Contains(a, ":") {
+			return fmt.Errorf("security.approvers: %q must be channel:userID, since the same ID may be someone else on another channel", a)
+		}
+		if clientChosenID(a) {
+			return fmt.Errorf("security.approvers: %q: WebSocket and webhook clients choose their own IDs, so they can't be approvers", a)
+		}
+	}
+	if cfg.Security.ApprovalQuorum < 0 {
+		return fmt.Errorf("security.approval_quorum must not be negative")
+	}
+	if cfg.Security.ApprovalQuorum > 1 && len(cfg.Security.Approvers) < cfg.Security.ApprovalQuorum && !cfg.Security.Access.approveRole() {
+		return fmt.Errorf("security.approval_quorum is %d but only %d approvers are listed", cfg.Security.ApprovalQuorum, len(cfg.Security.Approvers))
+	}
+
+	if err := validateSandbox(cfg.Security.Sandbox); err != nil {
+		return err
+	}
+	if err := validateAccess(cfg.Security.Access); err != nil {
+		return err
+	}
+
+	// Validate scrubbing
+	for _, pat := range cfg.Security.Scrub.Patterns {
+		if _, err := regexp.Compile(pat); err != nil {
+			return fmt.Errorf("security.scrub.patterns: invalid pattern %q: %v", pat, err)
+		}
+	}
+	for _, name := range cfg.Security.Scrub.Env {
+		if _, err := path.Match(name, ""); err != nil {
+			return fmt.Errorf("security.scrub.env: invalid pattern %q", name)
+		}
+	}
+
+	// Validate secret grants
+	for skill, names := range cfg.Secrets.Skills {
+		for _, name := range names {
+			if err := secrets.ValidName(name); err != nil {
+				return fmt.Errorf("secrets.skills.%s: %v", skill, err)
+			}
+		}
+	}
+
 	// Validate allowed_paths are resolvable
 	for _, p := range cfg.Security.AllowedPaths {
 		expanded := expandHome(p)
@@ -339,6 +770,98 @@ func validate(cfg *Config) error {
 	return nil
 }
 
+// validateApprovalChat checks an approval channel and chat ID, set in section.
+func validateApprovalChat(section, channel, chatID string) error {
+	switch channel {
+	case "", "telegram", "webhook", "websocket", "discord", "slack", "email", "whatsapp":
+	default:
+		return fmt.Errorf("unknown %s.approval_channel %q", section, channel)
+	}
+	if (channel == "") != (chatID == "") {
+		return fmt.Errorf("%s needs both approval_channel and approval_chat_id, or neither", section)
+	}
+	return nil
+}
+
+func validateAccess(c AccessConfig) error {
+	if len(c.Users) > 0 && len(c.Roles) == 0 {
+		return fmt.Errorf("security.access.users needs security.access.roles")
+	}
+	for name, role := range c.Roles {
+		for _, g := range role.Tools {
+			if _, err := path.Match(g, ""); err != nil {
+				return fmt.Errorf("security.access.roles.%s: invalid tool pattern %q", name, g)
+			}
+		}
+		for _, cmd := range role.Commands {
+			if cmd != "*" && !strings.HasPrefix(cmd, "/") {
+				return fmt.Errorf("security.access.roles.%s: command %q must start with /", name, cmd)
+			}
+		}
+	}
+	for user, role := range c.Users {
+		if user != "*" && !strings.Contains(user, ":") {
+			return fmt.Errorf("security.access.users: %q must be channel:userID, channel:* or *", user)
+		}
+		if clientChosenID(user) {
+			return fmt.Errorf("security.access.users: %q: WebSocket and webhook clients choose their own IDs, so map %s:* instead", user, user[:strings.Index(user, ":")])
+		}
+		if _, ok := c.Roles[role]; !ok {
+			return fmt.Errorf("security.access.users: %q has unknown role %q", user, role)
+		}
+	}
+	return nil
+}
+
+// clientChosenID reports whether id names one user of a channel whose user
+// IDs the client picks: WebSocket's come from its ?chat_id= parameter and
+// webhook's from the user_id of the request, so anyone with the shared token
+// can claim them.
+func clientChosenID(id string) bool {
+	channel, rest, ok := strings.Cut(id, ":")
+	return ok && (channel == "websocket" || channel == "webhook") && rest != "*"
+}
+
+// approveRole reports whether any role may answer approvals.
+func (c AccessConfig) approveRole() bool {
+	for _, role := range c.Roles {
+		if role.Approve {
+			return true
+		}
+	}
+	return false
+}
+
+// sandboxTools are the tools that can run sandboxed.
+var sandboxTools = map[string]bool{"shell_exec": true}
+
+func validateSandbox(c SandboxConfig) error {
+	profiles := map[string]SandboxProfile{"security.sandbox": c.SandboxProfile}
+	for name, p := range c.Tools {
+		if !sandboxTools[name] {
+			return fmt.Errorf("security.sandbox.tools: %q can't be sandboxed (only shell_exec)", name)
+		}
+		profiles["security.sandbox.tools."+name] = p
+	}
+	for name, p := range c.Skills {
+		profiles["security.sandbox.skills."+name] = p
+	}
+	for name, p := range profiles {
+		if p.CPUs < 0 || p.MemoryMB < 0 || p.Pids < 0 {
+			return fmt.Errorf("%s: limits can't be negative", name)
+		}
+		if p.CPUs > 0 && p.CPUs < 0.01 {
+			return fmt.Errorf("%s: cpus must be at least 0.01", name)
+		}
+		for _, path := range p.Writable {
+			if !filepath.IsAbs(expandHome(path)) {
+				return fmt.Errorf("%s: writable path %q must be absolute", name, path)
+			}
+		}
+	}
+	return nil
+}
+
 func expandHome(path string) string {
 	if strings.HasPrefix(path, "~") {
 		if home, err := os.UserHomeDir(); err == nil {
@@ -3,7 +3,10 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/ImJafran/aeon/internal/secrets"
 )
 
 func TestLoadValidConfig(t *testing.T) {
@@ -61,6 +64,41 @@ func TestEnvVarExpansion(t *testing.T) {
 	}
 }
 
+func TestSecretReferences(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("AEON_HOME", home)
+	t.Setenv(secrets.PassphraseEnv, "")
+	cfgPath := filepath.Join(home, "config.json")
+	body := `{"provider": {"anthropic": {"enabled": true, "api_key": "secret://ANTHROPIC_KEY"}},
+		"channels": {"telegram": {"enabled": true, "bot_token": "secret://BOT_TOKEN"}}}`
+	os.WriteFile(cfgPath, []byte(body), 0644)
+
+	if _, err := Load(cfgPath); err == nil || !strings.Contains(err.Error(), "no vault") {
+		t.Errorf("expected an error without a vault, got %v", err)
+	}
+
+	sc, err := LoadSecrets(cfgPath)
+	if err != nil || sc.Vault != filepath.Join(home, "secrets.vault") {
+		t.Fatalf("expected the default vault, got %+v, %v", sc, err)
+	}
+	vault, _ := sc.Open()
+	vault.Set("ANTHROPIC_KEY", `sk-ant-"quoted"`)
+	vault.Save()
+	if _, err := Load(cfgPath); err == nil || !strings.Contains(err.Error(), "BOT_TOKEN") {
+		t.Errorf("expected the missing secret named, got %v", err)
+	}
+
+	vault.Set("BOT_TOKEN", "123:abc")
+	vault.Save()
+	cfg, err := Load(cfgPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Provider.Anthropic.APIKey != `sk-ant-"quoted"` || cfg.Channels.Telegram.BotToken != "123:abc" {
+		t.Errorf("expected secrets resolved, got %q and %q", cfg.Provider.Anthropic.APIKey, cfg.Channels.Telegram.BotToken)
+	}
+}
+
 func TestEnvVarNoExpansion(t *testing.T) {
 	result := expandEnvVars("key: ${NONEXISTENT_VAR}")
 	if result != "key: ${NONEXISTENT_VAR}" {
@@ -95,6 +133,9 @@ func TestDefaults(t *testing.T) {
 	if cfg.Log.Level != "info" {
 		t.Errorf("expected default log level info, got %s", cfg.Log.Level)
 	}
+	if cfg.Security.PolicyFile != filepath.Join(AeonHome(), "policy.yaml") {
+		t.Errorf("expected the policy file in the Aeon home, got %s", cfg.Security.PolicyFile)
+	}
 }
 
 func TestEnabledProviderCount(t *testing.T) {
@@ -121,3 +162,233 @@ func TestAeonHome(t *testing.T) {
 		t.Errorf("expected /tmp/test-aeon, got %s", home)
 	}
 }
+
+func TestMCPServerValidation(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.json")
+
+	for _, tc := range []struct {
+		servers string
+		wantErr bool
+	}{
+		{`{"github": {"command": "npx", "args": ["-y", "server-github"]}}`, false},
+		{`{"docs": {"url": "https://example.com/mcp"}}`, false},
+		{`{"both": {"command": "x", "url": "https://example.com/mcp"}}`, true},
+		{`{"neither": {}}`, true},
+		{`{"bad name": {"command": "x"}}`, true},
+	} {
+		os.WriteFile(cfgPath, []byte(`{"mcp": {"servers": `+tc.servers+`}}`), 0644)
+		_, err := Load(cfgPath)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("servers %s: expected error=%v, got %v", tc.servers, tc.wantErr, err)
+		}
+	}
+}
+
+func TestEmbeddingsValidation(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.json")
+
+	for _, tc := range []struct {
+		embeddings string
+		wantErr    bool
+	}{
+		{`{}`, false},
+		{`{"provider": "none"}`, false},
+		{`{"provider": "openai", "base_url": "http://localhost:11434/v1", "model": "nomic-embed-text"}`, false},
+		{`{"provider": "openai", "model": "text-embedding-3-small"}`, true},
+		{`{"provider": "word2vec"}`, true},
+		{`{"min_similarity": 1.5}`, true},
+	} {
+		os.WriteFile(cfgPath, []byte(`{"memory": {"embeddings": `+tc.embeddings+`}}`), 0644)
+		_, err := Load(cfgPath)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("embeddings %s: expected error=%v, got %v", tc.embeddings, tc.wantErr, err)
+		}
+	}
+}
+
+func TestConsolidateIntervalValidation(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.json")
+
+	for interval, wantErr := range map[string]bool{
+		"24h":  false,
+		"7d":   false,
+		"off":  false,
+		"30m":  true,
+		"1d2h": true,
+	} {
+		os.WriteFile(cfgPath, []byte(`{"memory": {"consolidate_interval": "`+interval+`"}}`), 0644)
+		_, err := Load(cfgPath)
+		if (err != nil) != wantErr {
+			t.Errorf("interval %q: expected error=%v, got %v", interval, wantErr, err)
+		}
+	}
+}
+
+func TestMemoryScopeValidation(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.json")
+
+	os.WriteFile(cfgPath, []byte(`{}`), 0644)
+	cfg, err := Load(cfgPath)
+	if err != nil || cfg.Memory.DefaultScope != "user" {
+		t.Fatalf("expected the user scope by default, got %q (%v)", cfg.Memory.DefaultScope, err)
+	}
+
+	for scope, wantErr := range map[string]bool{
+		"chat":   false,
+		"global": false,
+		"team":   true,
+	} {
+		os.WriteFile(cfgPath, []byte(`{"memory": {"default_scope": "`+scope+`"}}`), 0644)
+		_, err := Load(cfgPath)
+		if (err != nil) != wantErr {
+			t.Errorf("scope %q: expected error=%v, got %v", scope, wantErr, err)
+		}
+	}
+}
+
+func TestApprovalValidation(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.json")
+
+	for _, tc := range []struct {
+		security string
+		wantErr  bool
+	}{
+		{`{"approvers": ["telegram:1", "slack:U2"], "approval_quorum": 2}`, false},
+		{`{"approvers": ["telegram:1", "2"], "approval_quorum": 2}`, true},
+		{`{"approval_channel": "telegram", "approval_chat_id": "-100"}`, false},
+		{`{"approvers": ["telegram:1"], "approval_quorum": 2}`, true},
+		{`{"approval_quorum": -1}`, true},
+		{`{"approval_channel": "telegram"}`, true},
+		{`{"approval_channel": "irc", "approval_chat_id": "x"}`, true},
+	} {
+		os.WriteFile(cfgPath, []byte(`{"security": `+tc.security+`}`), 0644)
+		_, err := Load(cfgPath)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("security %s: expected error=%v, got %v", tc.security, tc.wantErr, err)
+		}
+	}
+}
+
+func TestAccessValidation(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.json")
+	roles := `"roles": {"admin": {"tools": ["*"], "commands": ["*"], "approve": true}, "viewer": {"tools": ["memory_*"], "commands": ["/status"]}}`
+
+	for _, tc := range []struct {
+		access  string
+		wantErr bool
+	}{
+		{`{` + roles + `, "users": {"telegram:1": "admin", "telegram:*": "viewer", "*": "viewer"}}`, false},
+		{`{` + roles + `, "users": {"telegram:1": "root"}}`, true},
+		{`{` + roles + `, "users": {"12345": "admin"}}`, true},
+		{`{"users": {"telegram:1": "admin"}}`, true},
+		{`{"roles": {"viewer": {"tools": ["[memory"]}}}`, true},
+		{`{"roles": {"viewer": {"commands": ["status"]}}}`, true},
+		{`{` + roles + `, "users": {"websocket:*": "viewer"}}`, false},
+		{`{` + roles + `, "users": {"websocket:alice": "admin"}}`, true},
+		{`{` + roles + `, "users": {"webhook:*": "viewer"}}`, false},
+		{`{` + roles + `, "users": {"webhook:alice": "admin"}}`, true},
+	} {
+		os.WriteFile(cfgPath, []byte(`{"security": {"access": `+tc.access+`}}`), 0644)
+		_, err := Load(cfgPath)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("access %s: expected error=%v, got %v", tc.access, tc.wantErr, err)
+		}
+	}
+
+	for _, approver := range []string{"websocket:alice", "webhook:alice"} {
+		os.WriteFile(cfgPath, []byte(`{"security": {"approvers": ["`+approver+`"]}}`), 0644)
+		if _, err := Load(cfgPath); err == nil {
+			t.Errorf("expected approver %s to be rejected", approver)
+		}
+	}
+
+	// Roles with approval rights count toward the quorum
+	os.WriteFile(cfgPath, []byte(`{"security": {"approval_quorum": 2, "access": {`+roles+`}}}`), 0644)
+	if _, err := Load(cfgPath); err != nil {
+		t.Errorf("expected a quorum met by approving roles to load, got %v", err)
+	}
+}
+
+func TestScrubConfig(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.json")
+	os.WriteFile(cfgPath, []byte(`{
+		"provider": {"anthropic": {"api_key": "sk-ant-test"}},
+		"channels": {"telegram": {"bot_token": "123:abc"}},
+		"mcp": {"servers": {"gh": {"command": "gh-mcp", "env": {"GITHUB_TOKEN": "ghtok", "LOG_LEVEL": "debug"}}}},
+		"security": {"scrub": {"env": ["DB_*"], "files": ["~/.pgpass"], "patterns": ["ACME-[0-9a-f]{8}"]}}
+	}`), 0644)
+
+	cfg, err := Load(cfgPath)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	creds := strings.Join(cfg.Credentials(), " ")
+	for _, want := range []string{"sk-ant-test", "123:abc", "ghtok"} {
+		if !strings.Contains(creds, want) {
+			t.Errorf("expected %q among the credentials, got %q", want, creds)
+		}
+	}
+	if strings.Contains(creds, "debug") || strings.Contains(creds, "gh-mcp") {
+		t.Errorf("expected only credential fields, got %q", creds)
+	}
+	if f := cfg.Security.Scrub.Files[0]; strings.HasPrefix(f, "~") {
+		t.Errorf("expected ~ expanded in scrub files, got %q", f)
+	}
+
+	for _, scrub := range []string{`{"patterns": ["("]}`, `{"env": ["["]}`} {
+		os.WriteFile(cfgPath, []byte(`{"security": {"scrub": `+scrub+`}}`), 0644)
+		if _, err := Load(cfgPath); err == nil {
+			t.Errorf("expected scrub %s to be rejected", scrub)
+		}
+	}
+}
+
+func TestSandboxProfiles(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.json")
+	os.WriteFile(cfgPath, []byte(`{"security": {"sandbox": {
+		"memory_mb": 512, "writable": ["/srv/work"],
+		"tools": {"shell_exec": {"enabled": true}},
+		"skills": {
+			"*": {"enabled": true, "network": false},
+			"fetch_prices": {"network": true, "pids": 16},
+			"trusted": {"enabled": false}
+		}
+	}}}`), 0644)
+	cfg, err := Load(cfgPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sb := cfg.Security.Sandbox
+
+	if p, ok := sb.ForTool("shell_exec"); !ok || !p.HasNetwork() || p.MemoryMB != 512 || p.Writable[0] != "/srv/work" {
+		t.Errorf("shell_exec: unexpected profile %+v (sandboxed=%v)", p, ok)
+	}
+	if p, ok := sb.ForSkill("summarize"); !ok || p.HasNetwork() || p.MemoryMB != 512 {
+		t.Errorf("summarize: unexpected profile %+v (sandboxed=%v)", p, ok)
+	}
+	if p, ok := sb.ForSkill("fetch_prices"); !ok || !p.HasNetwork() || p.Pids != 16 {
+		t.Errorf("fetch_prices: unexpected profile %+v (sandboxed=%v)", p, ok)
+	}
+	if _, ok := sb.ForSkill("trusted"); ok {
+		t.Error("trusted should run unsandboxed")
+	}
+
+	for name, body := range map[string]string{
+		"tool":     `{"tools": {"file_write": {"enabled": true}}}`,
+		"negative": `{"memory_mb": -1}`,
+		"relative": `{"writable": ["work"]}`,
+	} {
+		os.WriteFile(cfgPath, []byte(`{"security": {"sandbox": `+body+`}}`), 0644)
+		if _, err := Load(cfgPath); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
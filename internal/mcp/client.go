@@ -0,0 +1,547 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// requestTimeout bounds handshake and list requests that have no deadline of their own.
+	requestTimeout = 30 * time.Second
+	// minBackoff and maxBackoff bound the delay between reconnect attempts.
+	minBackoff = time.Second
+	maxBackoff = time.Minute
+	// stableAfter is how long a connection must last before backoff resets,
+	// so a server that crashes on startup isn't restarted in a tight loop.
+	stableAfter = 30 * time.Second
+)
+
+// ClientVersion is reported to servers in clientInfo.
+var ClientVersion = "dev"
+
+// Client is a connection to one MCP server. It reconnects (restarting stdio
+// servers) when the connection is lost.
+type Client struct {
+	name         string
+	newTransport func() transport
+	logger       *slog.Logger
+
+	nextID atomic.Int64
+
+	mu             sync.Mutex
+	t              transport
+	connected      bool
+	pending        map[string]chan rpcMessage
+	caps           ServerCapabilities
+	info           Implementation
+	instructions   string
+	onToolsChanged func()
+	closed         bool
+	closeCh        chan struct{}
+}
+
+// NewStdioClient returns a client for a server launched as a child process.
+func NewStdioClient(name, command string, args []string, env map[string]string, dir string, logger *slog.Logger) *Client {
+	logger = logger.With("mcp_server", name)
+	return newClient(name, logger, func() transport {
+		return &stdioTransport{command: command, args: args, env: env, dir: dir, logger: logger}
+	})
+}
+
+// NewHTTPClient returns a client for a server reached over streamable HTTP.
+func NewHTTPClient(name, url string, headers map[string]string, logger *slog.Logger) *Client {
+	logger = logger.With("mcp_server", name)
+	t := &httpTransport{url: url, headers: headers, client: &http.Client{}, logger: logger}
+	return newClient(name, logger, func() transport { return t })
+}
+
+func newClient(name string, logger *slog.Logger, newTransport func() transport) *Client {
+	return &Client{
+		name:         name,
+		newTransport: newTransport,
+		logger:       logger,
+		pending:      make(map[string]chan rpcMessage),
+		closeCh:      make(chan struct{}),
+	}
+}
+
+// Name returns the server name from config.
+func (c *Client) Name() string { return c.name }
+
+// OnToolsChanged sets a function called after every (re)connect and whenever
+// the server reports that its tool list changed.
+func (c *Client) OnToolsChanged(fn func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onToolsChanged = fn
+}
+
+// Connected reports whether the server is currently connected.
+func (c *Client) Connected() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.connected
+}
+
+// Capabilities returns what the server said it supports when it connected.
+func (c *Client) Capabilities() ServerCapabilities {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.caps
+}
+
+// Instructions returns the server's usage instructions, if it sent any.
+func (c *Client) Instructions() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.instructions
+}
+
+// Connect connects to the server and performs the initialize handshake, then
+// keeps the connection alive in the background.
+func (c *Client) Connect(ctx context.Context) error {
+	done, err := c.connect(ctx)
+	if err != nil {
+		return err
+	}
+	c.notifyToolsChanged()
+	go c.supervise(done)
+	return nil
+}
+
+// Start connects in the background, retrying with backoff until it succeeds
+// or the client is closed.
+func (c *Client) Start() {
+	go c.supervise(nil)
+}
+
+// Close disconnects and stops reconnecting. A stdio server is shut down.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.closed = true
+	close(c.closeCh)
+	t := c.t
+	c.mu.Unlock()
+
+	if t != nil {
+		return t.close()
+	}
+	return nil
+}
+
+// supervise waits for the connection to drop and reconnects. A nil done
+// means not connected yet.
+func (c *Client) supervise(done <-chan struct{}) {
+	backoff := minBackoff
+	retry := false
+	for {
+		if done != nil {
+			connectedAt := time.Now()
+			select {
+			case <-done:
+			case <-c.closeCh:
+				return
+			}
+			c.disconnected()
+			if c.isClosed() {
+				return
+			}
+			c.logger.Warn("mcp server disconnected, reconnecting")
+			if time.Since(connectedAt) > stableAfter {
+				backoff = minBackoff
+			}
+		}
+
+		if retry || done != nil {
+			select {
+			case <-c.closeCh:
+				return
+			case <-time.After(backoff):
+			}
+		}
+		retry = true
+
+		ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+		var err error
+		done, err = c.connect(ctx)
+		cancel()
+		if err != nil {
+			done = nil
+			c.logger.Warn("mcp server connect failed", "error", err, "retry_in", backoff)
+			backoff = min(backoff*2, maxBackoff)
+			continue
+		}
+		c.mu.Lock()
+		info := c.info
+		c.mu.Unlock()
+		c.logger.Info("mcp server connected", "server", info.Name, "version", info.Version)
+		c.notifyToolsChanged()
+	}
+}
+
+func (c *Client) isClosed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closed
+}
+
+// connect starts a transport and runs the initialize handshake on it.
+func (c *Client) connect(ctx context.Context) (<-chan struct{}, error) {
+	t := c.newTransport()
+	done, err := t.start(c.handle)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		t.close()
+		return nil, errors.New("client closed")
+	}
+	c.t = t
+	c.mu.Unlock()
+
+	if err := c.handshake(ctx); err != nil {
+		c.mu.Lock()
+		c.t = nil
+		c.mu.Unlock()
+		t.close()
+		return nil, fmt.Errorf("initialize: %w", err)
+	}
+
+	c.mu.Lock()
+	c.connected = true
+	c.mu.Unlock()
+
+	if ht, ok := t.(*httpTransport); ok {
+		go ht.listen()
+	}
+	return done, nil
+}
+
+// handshake sends initialize and notifications/initialized on the current transport.
+func (c *Client) handshake(ctx context.Context) error {
+	var res initializeResult
+	params := initializeParams{
+		ProtocolVersion: ProtocolVersion,
+		ClientInfo:      Implementation{Name: "aeon", Version: ClientVersion},
+	}
+	if err := c.request(ctx, "initialize", params, &res); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.caps, c.info, c.instructions = res.Capabilities, res.ServerInfo, res.Instructions
+	t := c.t
+	c.mu.Unlock()
+
+	if ht, ok := t.(*httpTransport); ok {
+		ht.setVersion(res.ProtocolVersion)
+	}
+	return c.notify(ctx, "notifications/initialized", nil)
+}
+
+// disconnected fails all in-flight requests after the connection drops.
+func (c *Client) disconnected() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.connected = false
+	c.t = nil
+	for id, ch := range c.pending {
+		ch <- rpcMessage{Error: &RPCError{Code: -1, Message: "connection to server lost"}}
+		delete(c.pending, id)
+	}
+}
+
+func (c *Client) notifyToolsChanged() {
+	c.mu.Lock()
+	fn := c.onToolsChanged
+	c.mu.Unlock()
+	if fn != nil {
+		fn()
+	}
+}
+
+// call sends a request on a connected client, re-initializing once if an
+// HTTP server has dropped the session.
+func (c *Client) call(ctx context.Context, method string, params, result any) error {
+	if !c.Connected() {
+		return fmt.Errorf("mcp server %q is not connected", c.name)
+	}
+	err := c.request(ctx, method, params, result)
+	if errors.Is(err, errSessionExpired) {
+		c.logger.Info("mcp session expired, initializing again")
+		if err = c.handshake(ctx); err == nil {
+			err = c.request(ctx, method, params, result)
+		}
+	}
+	return err
+}
+
+// request sends a JSON-RPC request and waits for its response.
+func (c *Client) request(ctx context.Context, method string, params, result any) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, requestTimeout)
+		defer cancel()
+	}
+
+	id := c.nextID.Add(1)
+	key := strconv.FormatInt(id, 10)
+	data, err := marshalMessage(json.RawMessage(key), method, params)
+	if err != nil {
+		return err
+	}
+
+	ch := make(chan rpcMessage, 1)
+	c.mu.Lock()
+	t := c.t
+	if t == nil {
+		c.mu.Unlock()
+		return fmt.Errorf("mcp server %q is not connected", c.name)
+	}
+	c.pending[key] = ch
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, key)
+		c.mu.Unlock()
+	}()
+
+	if err := t.send(ctx, data); err != nil {
+		return err
+	}
+
+	select {
+	case resp := <-ch:
+		if resp.Error != nil {
+			return resp.Error
+		}
+		if result != nil && len(resp.Result) > 0 {
+			return json.Unmarshal(resp.Result, result)
+		}
+		return nil
+	case <-ctx.Done():
+		// Tell the server to stop working on it; best effort
+		c.notify(context.Background(), "notifications/cancelled", map[string]any{"requestId": id, "reason": ctx.Err().Error()})
+		return fmt.Errorf("%s: %w", method, ctx.Err())
+	}
+}
+
+// notify sends a JSON-RPC notification.
+func (c *Client) notify(ctx context.Context, method string, params any) error {
+	data, err := marshalMessage(nil, method, params)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	t := c.t
+	c.mu.Unlock()
+	if t == nil {
+		return fmt.Errorf("mcp server %q is not connected", c.name)
+	}
+	return t.send(ctx, data)
+}
+
+func marshalMessage(id json.RawMessage, method string, params any) ([]byte, error) {
+	msg := rpcMessage{JSONRPC: "2.0", Method: method}
+	if id != nil {
+		msg.ID = &id
+	}
+	if params != nil {
+		p, err := json.Marshal(params)
+		if err != nil {
+			return nil, err
+		}
+		msg.Params = p
+	}
+	return json.Marshal(msg)
+}
+
+// handle processes one incoming message or batch from the transport.
+func (c *Client) handle(data []byte) {
+	if len(data) > 0 && data[0] == '[' {
+		var batch []json.RawMessage
+		if err := json.Unmarshal(data, &batch); err == nil {
+			for _, m := range batch {
+				c.handle(m)
+			}
+		}
+		return
+	}
+
+	var msg rpcMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		c.logger.Debug("ignoring malformed mcp message", "error", err)
+		return
+	}
+
+	switch {
+	case msg.Method != "" && msg.ID != nil:
+		go c.answerServerRequest(msg)
+	case msg.Method != "":
+		c.handleNotification(msg)
+	case msg.ID != nil:
+		c.mu.Lock()
+		ch, ok := c.pending[string(*msg.ID)]
+		delete(c.pending, string(*msg.ID))
+		c.mu.Unlock()
+		if ok {
+			ch <- msg
+		}
+	}
+}
+
+// answerServerRequest replies to requests the server sends to the client.
+// Aeon offers no client features (sampling, roots, elicitation), so only ping succeeds.
+func (c *Client) answerServerRequest(req rpcMessage) {
+	resp := rpcMessage{JSONRPC: "2.0", ID: req.ID}
+	if req.Method == "ping" {
+		resp.Result = json.RawMessage(`{}`)
+	} else {
+		resp.Error = &RPCError{Code: codeMethodNotFound, Message: "method not supported: " + req.Method}
+	}
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return
+	}
+
+	c.mu.Lock()
+	t := c.t
+	c.mu.Unlock()
+	if t != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+		defer cancel()
+		t.send(ctx, data)
+	}
+}
+
+func (c *Client) handleNotification(msg rpcMessage) {
+	switch msg.Method {
+	case "notifications/tools/list_changed":
+		c.logger.Info("mcp tool list changed")
+		go c.notifyToolsChanged()
+	case "notifications/message":
+		var p struct {
+			Level string          `json:"level"`
+			Data  json.RawMessage `json:"data"`
+		}
+		json.Unmarshal(msg.Params, &p)
+		c.logger.Debug("mcp server log", "level", p.Level, "data", string(p.Data))
+	}
+}
+
+// ListTools returns all tools the server offers.
+func (c *Client) ListTools(ctx context.Context) ([]Tool, error) {
+	var all []Tool
+	cursor := ""
+	for {
+		var res struct {
+			Tools      []Tool `json:"tools"`
+			NextCursor string `json:"nextCursor"`
+		}
+		if err := c.call(ctx, "tools/list", cursorParams(cursor), &res); err != nil {
+			return nil, err
+		}
+		all = append(all, res.Tools...)
+		if res.NextCursor == "" {
+			return all, nil
+		}
+		cursor = res.NextCursor
+	}
+}
+
+// CallTool invokes a tool with JSON-encoded arguments.
+func (c *Client) CallTool(ctx context.Context, name string, args json.RawMessage) (*CallToolResult, error) {
+	if len(args) == 0 {
+		args = json.RawMessage(`{}`)
+	}
+	var res CallToolResult
+	params := map[string]any{"name": name, "arguments": args}
+	if err := c.call(ctx, "tools/call", params, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// ListResources returns the resources the server exposes.
+func (c *Client) ListResources(ctx context.Context) ([]Resource, error) {
+	var all []Resource
+	cursor := ""
+	for {
+		var res struct {
+			Resources  []Resource `json:"resources"`
+			NextCursor string     `json:"nextCursor"`
+		}
+		if err := c.call(ctx, "resources/list", cursorParams(cursor), &res); err != nil {
+			return nil, err
+		}
+		all = append(all, res.Resources...)
+		if res.NextCursor == "" {
+			return all, nil
+		}
+		cursor = res.NextCursor
+	}
+}
+
+// ReadResource returns the contents of the resource at uri.
+func (c *Client) ReadResource(ctx context.Context, uri string) ([]ResourceContents, error) {
+	var res struct {
+		Contents []ResourceContents `json:"contents"`
+	}
+	if err := c.call(ctx, "resources/read", map[string]string{"uri": uri}, &res); err != nil {
+		return nil, err
+	}
+	return res.Contents, nil
+}
+
+// ListPrompts returns the prompt templates the server offers.
+func (c *Client) ListPrompts(ctx context.Context) ([]Prompt, error) {
+	var all []Prompt
+	cursor := ""
+	for {
+		var res struct {
+			Prompts    []Prompt `json:"prompts"`
+			NextCursor string   `json:"nextCursor"`
+		}
+		if err := c.call(ctx, "prompts/list", cursorParams(cursor), &res); err != nil {
+			return nil, err
+		}
+		all = append(all, res.Prompts...)
+		if res.NextCursor == "" {
+			return all, nil
+		}
+		cursor = res.NextCursor
+	}
+}
+
+// GetPrompt renders a prompt template with the given arguments.
+func (c *Client) GetPrompt(ctx context.Context, name string, args map[string]string) (*GetPromptResult, error) {
+	var res GetPromptResult
+	params := map[string]any{"name": name, "arguments": args}
+	if err := c.call(ctx, "prompts/get", params, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func cursorParams(cursor string) any {
+	if cursor == "" {
+		return nil
+	}
+	return map[string]string{"cursor": cursor}
+}
@@ -0,0 +1,305 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestMain doubles as a fake stdio MCP server when re-executed by the tests.
+func TestMain(m *testing.M) {
+	if os.Getenv("AEON_FAKE_MCP_SERVER") == "1" {
+		runFakeStdioServer()
+		return
+	}
+	os.Exit(m.Run())
+}
+
+func testLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+// fakeServer answers MCP requests. Calling the "change" tool adds a tool and
+// reports list_changed; "crash" makes a stdio server exit.
+type fakeServer struct {
+	mu     sync.Mutex
+	extra  bool
+	notify func(method string)
+}
+
+func (s *fakeServer) handle(msg rpcMessage) (any, *RPCError) {
+	switch msg.Method {
+	case "initialize":
+		return map[string]any{
+			"protocolVersion": ProtocolVersion,
+			"capabilities":    map[string]any{"tools": map[string]any{"listChanged": true}, "resources": map[string]any{}, "prompts": map[string]any{}},
+			"serverInfo":      map[string]any{"name": "fake", "version": "1.0"},
+		}, nil
+	case "tools/list":
+		tools := []map[string]any{
+			{"name": "echo", "description": "Echo text", "inputSchema": map[string]any{"type": "object", "properties": map[string]any{"text": map[string]any{"type": "string"}}}},
+		}
+		s.mu.Lock()
+		if s.extra {
+			tools = append(tools, map[string]any{"name": "extra", "inputSchema": map[string]any{"type": "object"}})
+		}
+		s.mu.Unlock()
+		return map[string]any{"tools": tools}, nil
+	case "tools/call":
+		var p struct {
+			Name      string          `json:"name"`
+			Arguments json.RawMessage `json:"arguments"`
+		}
+		json.Unmarshal(msg.Params, &p)
+		switch p.Name {
+		case "echo":
+			var args struct{ Text string }
+			json.Unmarshal(p.Arguments, &args)
+			return map[string]any{"content": []map[string]any{{"type": "text", "text": "echo: " + args.Text}}}, nil
+		case "change":
+			s.mu.Lock()
+			s.extra = true
+			s.mu.Unlock()
+			if s.notify != nil {
+				s.notify("notifications/tools/list_changed")
+			}
+			return map[string]any{"content": []map[string]any{{"type": "text", "text": "changed"}}}, nil
+		case "crash":
+			os.Exit(1)
+		}
+		return map[string]any{"content": []map[string]any{{"type": "text", "text": "no such tool"}}, "isError": true}, nil
+	case "resources/list":
+		return map[string]any{"resources": []map[string]any{{"uri": "mem://notes", "name": "notes"}}}, nil
+	case "resources/read":
+		return map[string]any{"contents": []map[string]any{{"uri": "mem://notes", "text": "remember the milk"}}}, nil
+	case "prompts/get":
+		return map[string]any{"messages": []map[string]any{{"role": "user", "content": map[string]any{"type": "text", "text": "review this"}}}}, nil
+	}
+	return nil, &RPCError{Code: codeMethodNotFound, Message: "unknown method " + msg.Method}
+}
+
+func reply(msg rpcMessage, result any, rpcErr *RPCError) []byte {
+	resp := rpcMessage{JSONRPC: "2.0", ID: msg.ID, Error: rpcErr}
+	if result != nil {
+		resp.Result, _ = json.Marshal(result)
+	}
+	data, _ := json.Marshal(resp)
+	return data
+}
+
+func runFakeStdioServer() {
+	var mu sync.Mutex
+	write := func(data []byte) {
+		mu.Lock()
+		defer mu.Unlock()
+		os.Stdout.Write(append(data, '\n'))
+	}
+	srv := &fakeServer{notify: func(method string) {
+		write([]byte(fmt.Sprintf(`{"jsonrpc":"2.0","method":%q}`, method)))
+	}}
+
+	sc := bufio.NewScanner(os.Stdin)
+	for sc.Scan() {
+		var msg rpcMessage
+		if json.Unmarshal(sc.Bytes(), &msg) != nil || msg.ID == nil {
+			continue
+		}
+		result, rpcErr := srv.handle(msg)
+		write(reply(msg, result, rpcErr))
+	}
+}
+
+func newFakeStdioClient(t *testing.T) *Client {
+	t.Helper()
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewStdioClient("fake", exe, nil, map[string]string{"AEON_FAKE_MCP_SERVER": "1"}, "", testLogger())
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func TestStdioToolsAndResources(t *testing.T) {
+	c := newFakeStdioClient(t)
+	ctx := context.Background()
+	if err := c.Connect(ctx); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+
+	tools, err := c.ListTools(ctx)
+	if err != nil || len(tools) != 1 || tools[0].Name != "echo" {
+		t.Fatalf("unexpected tools %+v, err %v", tools, err)
+	}
+	if !strings.Contains(string(tools[0].InputSchema), `"text"`) {
+		t.Errorf("input schema not passed through: %s", tools[0].InputSchema)
+	}
+
+	res, err := c.CallTool(ctx, "echo", json.RawMessage(`{"text":"hi"}`))
+	if err != nil || len(res.Content) != 1 || res.Content[0].Text != "echo: hi" {
+		t.Fatalf("unexpected call result %+v, err %v", res, err)
+	}
+
+	contents, err := c.ReadResource(ctx, "mem://notes")
+	if err != nil || len(contents) != 1 || contents[0].Text != "remember the milk" {
+		t.Fatalf("unexpected resource %+v, err %v", contents, err)
+	}
+
+	prompt, err := c.GetPrompt(ctx, "review", nil)
+	if err != nil || len(prompt.Messages) != 1 || prompt.Messages[0].Content.Text != "review this" {
+		t.Fatalf("unexpected prompt %+v, err %v", prompt, err)
+	}
+}
+
+func TestStdioListChangedNotification(t *testing.T) {
+	c := newFakeStdioClient(t)
+	changed := make(chan struct{}, 4)
+	c.OnToolsChanged(func() { changed <- struct{}{} })
+
+	ctx := context.Background()
+	if err := c.Connect(ctx); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	<-changed // initial sync
+
+	if _, err := c.CallTool(ctx, "change", nil); err != nil {
+		t.Fatalf("call: %v", err)
+	}
+	select {
+	case <-changed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("list_changed notification not delivered")
+	}
+	tools, _ := c.ListTools(ctx)
+	if len(tools) != 2 {
+		t.Fatalf("expected the new tool after list_changed, got %+v", tools)
+	}
+}
+
+func TestStdioServerRestart(t *testing.T) {
+	c := newFakeStdioClient(t)
+	reconnected := make(chan struct{}, 4)
+	c.OnToolsChanged(func() { reconnected <- struct{}{} })
+
+	ctx := context.Background()
+	if err := c.Connect(ctx); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	<-reconnected
+
+	if _, err := c.CallTool(ctx, "crash", nil); err == nil {
+		t.Fatal("expected the in-flight call to fail when the server exits")
+	}
+
+	select {
+	case <-reconnected:
+	case <-time.After(5 * time.Second):
+		t.Fatal("server was not restarted")
+	}
+	res, err := c.CallTool(ctx, "echo", json.RawMessage(`{"text":"back"}`))
+	if err != nil || res.Content[0].Text != "echo: back" {
+		t.Fatalf("call after restart: %+v, %v", res, err)
+	}
+}
+
+// newFakeHTTPServer serves the streamable HTTP transport. tools/call replies
+// as an SSE stream, everything else as plain JSON.
+func newFakeHTTPServer(t *testing.T) (*httptest.Server, *atomic.Int32) {
+	srv := &fakeServer{}
+	var sessions atomic.Int32
+	var current atomic.Value
+	current.Store("")
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		case http.MethodDelete:
+			return
+		}
+
+		var msg rpcMessage
+		json.NewDecoder(r.Body).Decode(&msg)
+
+		if msg.Method == "initialize" {
+			id := fmt.Sprintf("session-%d", sessions.Add(1))
+			current.Store(id)
+			w.Header().Set("Mcp-Session-Id", id)
+		} else if r.Header.Get("Mcp-Session-Id") != current.Load().(string) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if msg.ID == nil {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+
+		result, rpcErr := srv.handle(msg)
+		if msg.Method == "tools/call" {
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprintf(w, "event: message\ndata: %s\n\n", reply(msg, result, rpcErr))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(reply(msg, result, rpcErr))
+	}))
+	t.Cleanup(ts.Close)
+
+	return ts, &sessions
+}
+
+func TestHTTPClient(t *testing.T) {
+	ts, sessions := newFakeHTTPServer(t)
+	c := NewHTTPClient("remote", ts.URL, map[string]string{"Authorization": "Bearer x"}, testLogger())
+	defer c.Close()
+
+	ctx := context.Background()
+	if err := c.Connect(ctx); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	res, err := c.CallTool(ctx, "echo", json.RawMessage(`{"text":"over http"}`))
+	if err != nil || res.Content[0].Text != "echo: over http" {
+		t.Fatalf("unexpected call result %+v, err %v", res, err)
+	}
+	if sessions.Load() != 1 {
+		t.Fatalf("expected one session, got %d", sessions.Load())
+	}
+}
+
+func TestHTTPSessionExpiry(t *testing.T) {
+	ts, sessions := newFakeHTTPServer(t)
+	c := NewHTTPClient("remote", ts.URL, nil, testLogger())
+	defer c.Close()
+
+	ctx := context.Background()
+	if err := c.Connect(ctx); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+
+	// Another client initializing replaces the only session the fake keeps
+	other := NewHTTPClient("other", ts.URL, nil, testLogger())
+	if err := other.Connect(ctx); err != nil {
+		t.Fatalf("connect other: %v", err)
+	}
+	defer other.Close()
+
+	res, err := c.CallTool(ctx, "echo", json.RawMessage(`{"text":"again"}`))
+	if err != nil || res.Content[0].Text != "echo: again" {
+		t.Fatalf("expected transparent re-initialize, got %+v, %v", res, err)
+	}
+	if sessions.Load() != 3 {
+		t.Fatalf("expected a third session after expiry, got %d", sessions.Load())
+	}
+}
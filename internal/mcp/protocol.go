@@ -0,0 +1,143 @@
+// Package mcp implements the Model Context Protocol. The client connects to
+// MCP servers over stdio or streamable HTTP so their tools, resources and
+// prompts can be used by the agent; the server exposes Aeon's own tools to
+// other agents and IDEs.
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ProtocolVersion is the MCP revision Aeon speaks.
+const ProtocolVersion = "2025-06-18"
+
+// rpcMessage is any JSON-RPC 2.0 message: request, notification or response.
+type rpcMessage struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+	Result  json.RawMessage  `json:"result,omitempty"`
+	Error   *RPCError        `json:"error,omitempty"`
+}
+
+// RPCError is an error returned by an MCP server.
+type RPCError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("mcp error %d: %s", e.Code, e.Message)
+}
+
+// JSON-RPC error codes.
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+)
+
+// Implementation identifies a client or server.
+type Implementation struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+// Capability describes one feature a server supports.
+type Capability struct {
+	ListChanged bool `json:"listChanged,omitempty"`
+	Subscribe   bool `json:"subscribe,omitempty"`
+}
+
+// ServerCapabilities lists the features a server offers. Nil means unsupported.
+type ServerCapabilities struct {
+	Tools     *Capability `json:"tools,omitempty"`
+	Resources *Capability `json:"resources,omitempty"`
+	Prompts   *Capability `json:"prompts,omitempty"`
+}
+
+type initializeParams struct {
+	ProtocolVersion string         `json:"protocolVersion"`
+	Capabilities    struct{}       `json:"capabilities"`
+	ClientInfo      Implementation `json:"clientInfo"`
+}
+
+type initializeResult struct {
+	ProtocolVersion string             `json:"protocolVersion"`
+	Capabilities    ServerCapabilities `json:"capabilities"`
+	ServerInfo      Implementation     `json:"serverInfo"`
+	Instructions    string             `json:"instructions,omitempty"`
+}
+
+// Tool is a tool offered by a server. InputSchema is a JSON schema object.
+type Tool struct {
+	Name        string          `json:"name"`
+	Title       string          `json:"title,omitempty"`
+	Description string          `json:"description,omitempty"`
+	InputSchema json.RawMessage `json:"inputSchema,omitempty"`
+}
+
+// Content is one item of tool output or prompt message content.
+type Content struct {
+	Type     string            `json:"type"` // text, image, audio, resource, resource_link
+	Text     string            `json:"text,omitempty"`
+	Data     string            `json:"data,omitempty"` // base64 for image and audio
+	MimeType string            `json:"mimeType,omitempty"`
+	URI      string            `json:"uri,omitempty"` // resource_link
+	Name     string            `json:"name,omitempty"`
+	Resource *ResourceContents `json:"resource,omitempty"` // embedded resource
+}
+
+// CallToolResult is the result of tools/call. IsError marks a tool-level
+// failure the model should see, as opposed to a protocol error.
+type CallToolResult struct {
+	Content           []Content       `json:"content"`
+	StructuredContent json.RawMessage `json:"structuredContent,omitempty"`
+	IsError           bool            `json:"isError,omitempty"`
+}
+
+// Resource is a piece of context a server exposes by URI.
+type Resource struct {
+	URI         string `json:"uri"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	MimeType    string `json:"mimeType,omitempty"`
+}
+
+// ResourceContents is the content of a resource. Exactly one of Text and Blob is set.
+type ResourceContents struct {
+	URI      string `json:"uri"`
+	MimeType string `json:"mimeType,omitempty"`
+	Text     string `json:"text,omitempty"`
+	Blob     string `json:"blob,omitempty"` // base64
+}
+
+// Prompt is a prompt template offered by a server.
+type Prompt struct {
+	Name        string           `json:"name"`
+	Description string           `json:"description,omitempty"`
+	Arguments   []PromptArgument `json:"arguments,omitempty"`
+}
+
+// PromptArgument is a named argument to a prompt template.
+type PromptArgument struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+}
+
+// PromptMessage is one message of a rendered prompt.
+type PromptMessage struct {
+	Role    string  `json:"role"`
+	Content Content `json:"content"`
+}
+
+// GetPromptResult is a rendered prompt.
+type GetPromptResult struct {
+	Description string          `json:"description,omitempty"`
+	Messages    []PromptMessage `json:"messages"`
+}
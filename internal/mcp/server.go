@@ -0,0 +1,351 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// ServerHandler supplies the tools a Server exposes.
+type ServerHandler interface {
+	Tools() []Tool
+	CallTool(ctx context.Context, name string, args json.RawMessage) (*CallToolResult, error)
+}
+
+// Server answers MCP requests from other agents and IDEs over stdio or
+// streamable HTTP. It offers tools only.
+type Server struct {
+	info         Implementation
+	instructions string
+	handler      ServerHandler
+	logger       *slog.Logger
+}
+
+// NewServer creates a server that identifies itself as name/version.
+func NewServer(name, version string, handler ServerHandler, logger *slog.Logger) *Server {
+	return &Server{info: Implementation{Name: name, Version: version}, handler: handler, logger: logger}
+}
+
+// SetInstructions sets the usage hint sent to clients on initialize.
+func (s *Server) SetInstructions(text string) {
+	s.instructions = text
+}
+
+// ServeStdio reads newline-delimited JSON-RPC from r and writes replies to w
+// until r is closed or ctx is cancelled. Requests run concurrently so a slow
+// tool doesn't block pings or cancellations.
+func (s *Server) ServeStdio(ctx context.Context, r io.Reader, w io.Writer) error {
+	var (
+		writeMu sync.Mutex
+		wg      sync.WaitGroup
+		cancels sync.Map // request ID -> context.CancelFunc
+	)
+	write := func(data []byte) {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		w.Write(append(data, '\n'))
+	}
+	defer wg.Wait()
+
+	lines := make(chan []byte)
+	readErr := make(chan error, 1)
+	go func() {
+		br := bufio.NewReader(r)
+		for {
+			line, err := br.ReadBytes('\n')
+			if line = bytes.TrimSpace(line); len(line) > 0 {
+				select {
+				case lines <- line:
+				case <-ctx.Done():
+					return
+				}
+			}
+			if err != nil {
+				if err == io.EOF {
+					err = nil
+				}
+				readErr <- err
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-readErr:
+			return err
+		case line := <-lines:
+			if cancelled, ok := cancelledRequest(line); ok {
+				if cancel, found := cancels.Load(cancelled); found {
+					cancel.(context.CancelFunc)()
+				}
+				continue
+			}
+
+			reqCtx, cancel := context.WithCancel(ctx)
+			id := requestID(line)
+			if id != "" {
+				cancels.Store(id, cancel)
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer cancel()
+				if id != "" {
+					defer cancels.Delete(id)
+				}
+				if reply := s.handle(reqCtx, line); reply != nil {
+					write(reply)
+				}
+			}()
+		}
+	}
+}
+
+// requestID returns the ID of a single request, or "" for notifications and batches.
+func requestID(data []byte) string {
+	var msg rpcMessage
+	if json.Unmarshal(data, &msg) != nil || msg.ID == nil {
+		return ""
+	}
+	return string(*msg.ID)
+}
+
+// cancelledRequest reports the request ID named by a notifications/cancelled message.
+func cancelledRequest(data []byte) (string, bool) {
+	var msg rpcMessage
+	if json.Unmarshal(data, &msg) != nil || msg.Method != "notifications/cancelled" {
+		return "", false
+	}
+	var p struct {
+		RequestID json.RawMessage `json:"requestId"`
+	}
+	json.Unmarshal(msg.Params, &p)
+	return string(p.RequestID), true
+}
+
+// handle processes one message or batch and returns the encoded reply, or
+// nil when there is nothing to send back.
+func (s *Server) handle(ctx context.Context, data []byte) []byte {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		var batch []json.RawMessage
+		if err := json.Unmarshal(trimmed, &batch); err != nil {
+			return errorReply(nil, codeParseError, "parse error")
+		}
+		var replies []json.RawMessage
+		for _, item := range batch {
+			if reply := s.handle(ctx, item); reply != nil {
+				replies = append(replies, reply)
+			}
+		}
+		if len(replies) == 0 {
+			return nil
+		}
+		out, _ := json.Marshal(replies)
+		return out
+	}
+
+	var msg rpcMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return errorReply(nil, codeParseError, "parse error")
+	}
+	if msg.ID == nil {
+		// Notifications (initialized, cancelled handled by the transport) need no reply
+		return nil
+	}
+	if msg.Method == "" {
+		return errorReply(msg.ID, codeInvalidRequest, "missing method")
+	}
+
+	result, rpcErr := s.dispatch(ctx, msg)
+	if rpcErr != nil {
+		return errorReply(msg.ID, rpcErr.Code, rpcErr.Message)
+	}
+	raw, err := json.Marshal(result)
+	if err != nil {
+		return errorReply(msg.ID, codeInvalidRequest, err.Error())
+	}
+	out, _ := json.Marshal(rpcMessage{JSONRPC: "2.0", ID: msg.ID, Result: raw})
+	return out
+}
+
+func (s *Server) dispatch(ctx context.Context, msg rpcMessage) (any, *RPCError) {
+	switch msg.Method {
+	case "initialize":
+		var p initializeParams
+		json.Unmarshal(msg.Params, &p)
+		s.logger.Info("mcp client connected", "client", p.ClientInfo.Name, "client_version", p.ClientInfo.Version, "protocol", p.ProtocolVersion)
+		return initializeResult{
+			ProtocolVersion: negotiateVersion(p.ProtocolVersion),
+			Capabilities:    ServerCapabilities{Tools: &Capability{}},
+			ServerInfo:      s.info,
+			Instructions:    s.instructions,
+		}, nil
+
+	case "ping":
+		return struct{}{}, nil
+
+	case "tools/list":
+		tools := s.handler.Tools()
+		if tools == nil {
+			tools = []Tool{}
+		}
+		return map[string]any{"tools": tools}, nil
+
+	case "tools/call":
+		var p struct {
+			Name      string          `json:"name"`
+			Arguments json.RawMessage `json:"arguments"`
+		}
+		if err := json.Unmarshal(msg.Params, &p); err != nil || p.Name == "" {
+			return nil, &RPCError{Code: codeInvalidParams, Message: "tools/call needs a tool name"}
+		}
+		if len(p.Arguments) == 0 || string(p.Arguments) == "null" {
+			p.Arguments = json.RawMessage(`{}`)
+		}
+		res, err := s.handler.CallTool(ctx, p.Name, p.Arguments)
+		if err != nil {
+			// Execution failures go back as tool errors so the caller's model sees them
+			return CallToolResult{Content: []Content{{Type: "text", Text: err.Error()}}, IsError: true}, nil
+		}
+		if res.Content == nil {
+			res.Content = []Content{}
+		}
+		return res, nil
+	}
+	return nil, &RPCError{Code: codeMethodNotFound, Message: "method not found: " + msg.Method}
+}
+
+// negotiateVersion answers with the client's version when it is one we
+// speak, and with ours otherwise, leaving the client to decide.
+func negotiateVersion(requested string) string {
+	switch requested {
+	case ProtocolVersion, "2025-03-26", "2024-11-05":
+		return requested
+	}
+	return ProtocolVersion
+}
+
+func errorReply(id *json.RawMessage, code int, message string) []byte {
+	out, _ := json.Marshal(rpcMessage{JSONRPC: "2.0", ID: id, Error: &RPCError{Code: code, Message: message}})
+	return out
+}
+
+// ---- streamable HTTP ----
+
+// HTTPHandler serves the streamable HTTP transport on a single endpoint.
+// Replies are plain JSON; the server never opens an SSE stream. When
+// authToken is set, requests must carry it as a Bearer token. Requests from
+// web pages must come from a local origin or one in origins, so a page can't
+// reach a local server through DNS rebinding.
+func (s *Server) HTTPHandler(authToken string, origins []string) http.Handler {
+	var (
+		mu       sync.Mutex
+		sessions = make(map[string]bool)
+	)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if origin := r.Header.Get("Origin"); origin != "" && !allowedOrigin(origin, origins) {
+			http.Error(w, "origin not allowed", http.StatusForbidden)
+			return
+		}
+		if authToken != "" {
+			auth := r.Header.Get("Authorization")
+			if subtle.ConstantTimeCompare([]byte(auth), []byte("Bearer "+authToken)) != 1 {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+		}
+
+		sessionID := r.Header.Get("Mcp-Session-Id")
+		switch r.Method {
+		case http.MethodPost:
+		case http.MethodDelete:
+			mu.Lock()
+			delete(sessions, sessionID)
+			mu.Unlock()
+			w.WriteHeader(http.StatusNoContent)
+			return
+		default:
+			// No server-initiated stream
+			w.Header().Set("Allow", "POST, DELETE")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, 4<<20))
+		if err != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+
+		var msg rpcMessage
+		isInit := json.Unmarshal(body, &msg) == nil && msg.Method == "initialize"
+		if isInit {
+			sessionID = newSessionID()
+			mu.Lock()
+			sessions[sessionID] = true
+			mu.Unlock()
+			w.Header().Set("Mcp-Session-Id", sessionID)
+		} else {
+			mu.Lock()
+			known := sessions[sessionID]
+			mu.Unlock()
+			if !known {
+				http.Error(w, "unknown session", http.StatusNotFound)
+				return
+			}
+		}
+
+		reply := s.handle(r.Context(), body)
+		if reply == nil {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(reply)
+	})
+}
+
+// allowedOrigin reports whether a browser Origin is localhost, a loopback
+// address, or listed in origins.
+func allowedOrigin(origin string, origins []string) bool {
+	for _, o := range origins {
+		if strings.EqualFold(strings.TrimSuffix(o, "/"), origin) {
+			return true
+		}
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return IsLoopback(u.Hostname())
+}
+
+// IsLoopback reports whether host is localhost or a loopback address.
+func IsLoopback(host string) bool {
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func newSessionID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
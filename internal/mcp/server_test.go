@@ -0,0 +1,147 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type echoHandler struct{}
+
+func (echoHandler) Tools() []Tool {
+	return []Tool{{Name: "echo", Description: "Echo text", InputSchema: json.RawMessage(`{"type":"object"}`)}}
+}
+
+func (echoHandler) CallTool(ctx context.Context, name string, args json.RawMessage) (*CallToolResult, error) {
+	switch name {
+	case "echo":
+		var p struct{ Text string }
+		json.Unmarshal(args, &p)
+		return &CallToolResult{Content: []Content{{Type: "text", Text: p.Text}}}, nil
+	case "slow":
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return nil, errors.New("unknown tool")
+}
+
+func TestServerOverHTTP(t *testing.T) {
+	srv := NewServer("aeon", "test", echoHandler{}, testLogger())
+	ts := httptest.NewServer(srv.HTTPHandler("secret", []string{"https://ide.example.com"}))
+	defer ts.Close()
+
+	c := NewHTTPClient("aeon", ts.URL, map[string]string{"Authorization": "Bearer secret"}, testLogger())
+	defer c.Close()
+	ctx := context.Background()
+	if err := c.Connect(ctx); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+
+	tools, err := c.ListTools(ctx)
+	if err != nil || len(tools) != 1 || tools[0].Name != "echo" {
+		t.Fatalf("unexpected tools %+v, err %v", tools, err)
+	}
+	res, err := c.CallTool(ctx, "echo", json.RawMessage(`{"text":"hello"}`))
+	if err != nil || res.Content[0].Text != "hello" {
+		t.Fatalf("unexpected result %+v, err %v", res, err)
+	}
+
+	res, err = c.CallTool(ctx, "missing", nil)
+	if err != nil || !res.IsError {
+		t.Fatalf("expected a tool error result, got %+v, err %v", res, err)
+	}
+
+	resp, err := http.Post(ts.URL, "application/json", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"initialize"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected 401 without the token, got %d", resp.StatusCode)
+	}
+
+	for origin, want := range map[string]int{
+		"https://evil.example.com": http.StatusForbidden,
+		"http://localhost:3000":    http.StatusOK,
+		"http://127.0.0.1":         http.StatusOK,
+		"https://ide.example.com":  http.StatusOK,
+	} {
+		req, _ := http.NewRequest(http.MethodPost, ts.URL, strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"initialize"}`))
+		req.Header.Set("Authorization", "Bearer secret")
+		req.Header.Set("Origin", origin)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("origin %s: expected %d, got %d", origin, want, resp.StatusCode)
+		}
+	}
+}
+
+func TestServeStdio(t *testing.T) {
+	srv := NewServer("aeon", "test", echoHandler{}, testLogger())
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- srv.ServeStdio(ctx, inR, outW) }()
+
+	replies := bufio.NewScanner(outR)
+	send := func(line string) {
+		io.WriteString(inW, line+"\n")
+	}
+	next := func() rpcMessage {
+		t.Helper()
+		if !replies.Scan() {
+			t.Fatal("no reply")
+		}
+		var msg rpcMessage
+		json.Unmarshal(replies.Bytes(), &msg)
+		return msg
+	}
+
+	send(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05","clientInfo":{"name":"ide"}}}`)
+	var init initializeResult
+	json.Unmarshal(next().Result, &init)
+	if init.ProtocolVersion != "2024-11-05" || init.Capabilities.Tools == nil || init.ServerInfo.Name != "aeon" {
+		t.Fatalf("unexpected initialize result %+v", init)
+	}
+	send(`{"jsonrpc":"2.0","method":"notifications/initialized"}`)
+
+	// A slow call must not block later requests, and cancelling it ends it
+	send(`{"jsonrpc":"2.0","id":"slow","method":"tools/call","params":{"name":"slow"}}`)
+	send(`{"jsonrpc":"2.0","id":2,"method":"ping"}`)
+	if msg := next(); string(*msg.ID) != "2" {
+		t.Fatalf("expected the ping reply first, got id %s", *msg.ID)
+	}
+	send(`{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":"slow"}}`)
+	if msg := next(); string(*msg.ID) != `"slow"` {
+		t.Fatalf("expected the cancelled call to finish, got id %s", *msg.ID)
+	}
+
+	send(`{"jsonrpc":"2.0","id":3,"method":"resources/list"}`)
+	if msg := next(); msg.Error == nil || msg.Error.Code != codeMethodNotFound {
+		t.Fatalf("expected method not found, got %+v", msg)
+	}
+
+	inW.Close()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("serve: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("server did not stop at EOF")
+	}
+}
@@ -0,0 +1,326 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// transport moves JSON-RPC messages between the client and one server connection.
+type transport interface {
+	// start opens the connection and delivers incoming messages to handle.
+	// The returned channel is closed when the connection is lost.
+	start(handle func([]byte)) (<-chan struct{}, error)
+	// send delivers one message. Responses arrive through handle.
+	send(ctx context.Context, msg []byte) error
+	close() error
+}
+
+// errSessionExpired is returned by the HTTP transport when the server no
+// longer knows the session, so the client must initialize again.
+var errSessionExpired = errors.New("mcp session expired")
+
+// ---- stdio ----
+
+// stdioTransport runs the server as a child process speaking newline-delimited
+// JSON-RPC on stdin and stdout. Stderr is logged.
+type stdioTransport struct {
+	command string
+	args    []string
+	env     map[string]string
+	dir     string
+	logger  *slog.Logger
+
+	mu    sync.Mutex
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+	done  chan struct{}
+}
+
+func (t *stdioTransport) start(handle func([]byte)) (<-chan struct{}, error) {
+	cmd := exec.Command(t.command, t.args...)
+	cmd.Dir = t.dir
+	cmd.Env = os.Environ()
+	for k, v := range t.env {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+	// Own process group so the whole server tree can be stopped
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("starting %s: %w", t.command, err)
+	}
+
+	done := make(chan struct{})
+	t.mu.Lock()
+	t.cmd, t.stdin, t.done = cmd, stdin, done
+	t.mu.Unlock()
+
+	go func() {
+		sc := bufio.NewScanner(stderr)
+		for sc.Scan() {
+			t.logger.Debug("mcp server stderr", "line", sc.Text())
+		}
+	}()
+
+	go func() {
+		defer close(done)
+		r := bufio.NewReader(stdout)
+		for {
+			line, err := r.ReadBytes('\n')
+			if line = bytes.TrimSpace(line); len(line) > 0 {
+				handle(line)
+			}
+			if err != nil {
+				break
+			}
+		}
+		cmd.Wait()
+	}()
+
+	return done, nil
+}
+
+func (t *stdioTransport) send(_ context.Context, msg []byte) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.stdin == nil {
+		return errors.New("server not running")
+	}
+	_, err := t.stdin.Write(append(msg, '\n'))
+	return err
+}
+
+// close asks the server to exit by closing stdin, then kills its process
+// group if it hasn't exited after a grace period.
+func (t *stdioTransport) close() error {
+	t.mu.Lock()
+	cmd, stdin, done := t.cmd, t.stdin, t.done
+	t.stdin = nil
+	t.mu.Unlock()
+	if cmd == nil {
+		return nil
+	}
+
+	if stdin != nil {
+		stdin.Close()
+	}
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+		<-done
+	}
+	return nil
+}
+
+// ---- streamable HTTP ----
+
+// httpTransport speaks the streamable HTTP transport: each message is POSTed
+// and the reply comes back as JSON or as an SSE stream. Server-initiated
+// notifications arrive on an optional long-lived GET stream.
+type httpTransport struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+	logger  *slog.Logger
+
+	mu        sync.Mutex
+	sessionID string
+	version   string // negotiated protocol version, sent once known
+	handle    func([]byte)
+	done      chan struct{}
+	cancel    context.CancelFunc
+	ctx       context.Context
+}
+
+func (t *httpTransport) start(handle func([]byte)) (<-chan struct{}, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.mu.Lock()
+	t.handle, t.done, t.ctx, t.cancel = handle, make(chan struct{}), ctx, cancel
+	t.sessionID, t.version = "", ""
+	done := t.done
+	t.mu.Unlock()
+	return done, nil
+}
+
+func (t *httpTransport) setHeaders(req *http.Request) {
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+	t.mu.Lock()
+	if t.sessionID != "" {
+		req.Header.Set("Mcp-Session-Id", t.sessionID)
+	}
+	if t.version != "" {
+		req.Header.Set("MCP-Protocol-Version", t.version)
+	}
+	t.mu.Unlock()
+}
+
+func (t *httpTransport) send(ctx context.Context, msg []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url, bytes.NewReader(msg))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	t.setHeaders(req)
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if id := resp.Header.Get("Mcp-Session-Id"); id != "" {
+		t.mu.Lock()
+		t.sessionID = id
+		t.mu.Unlock()
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusAccepted:
+		return nil
+	case resp.StatusCode == http.StatusNotFound && req.Header.Get("Mcp-Session-Id") != "":
+		t.mu.Lock()
+		t.sessionID = ""
+		t.mu.Unlock()
+		return errSessionExpired
+	case resp.StatusCode >= 300:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		return readSSE(resp.Body, t.handle)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if len(bytes.TrimSpace(body)) > 0 {
+		t.handle(body)
+	}
+	return nil
+}
+
+// listen holds a GET stream open for server notifications such as
+// tools/list_changed, reconnecting until the transport is closed. Servers
+// that don't offer the stream answer 405, and listen gives up.
+func (t *httpTransport) listen() {
+	t.mu.Lock()
+	ctx := t.ctx
+	t.mu.Unlock()
+
+	for ctx.Err() == nil {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, t.url, nil)
+		if err != nil {
+			return
+		}
+		req.Header.Set("Accept", "text/event-stream")
+		t.setHeaders(req)
+
+		resp, err := t.client.Do(req)
+		if err == nil {
+			if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+				resp.Body.Close()
+				return
+			}
+			readSSE(resp.Body, t.handle)
+			resp.Body.Close()
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(5 * time.Second):
+		}
+	}
+}
+
+func (t *httpTransport) setVersion(v string) {
+	t.mu.Lock()
+	t.version = v
+	t.mu.Unlock()
+}
+
+// close ends the session on the server and stops the notification stream.
+func (t *httpTransport) close() error {
+	t.mu.Lock()
+	cancel, done, sessionID := t.cancel, t.done, t.sessionID
+	t.cancel, t.done = nil, nil
+	t.mu.Unlock()
+	if cancel == nil {
+		return nil
+	}
+
+	if sessionID != "" {
+		ctx, stop := context.WithTimeout(context.Background(), 2*time.Second)
+		if req, err := http.NewRequestWithContext(ctx, http.MethodDelete, t.url, nil); err == nil {
+			t.setHeaders(req)
+			if resp, err := t.client.Do(req); err == nil {
+				resp.Body.Close()
+			}
+		}
+		stop()
+	}
+	cancel()
+	close(done)
+	return nil
+}
+
+// readSSE delivers the data of each server-sent event to handle.
+func readSSE(r io.Reader, handle func([]byte)) error {
+	br := bufio.NewReader(r)
+	var data bytes.Buffer
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		switch {
+		case line == "":
+			if data.Len() > 0 {
+				handle(append([]byte(nil), data.Bytes()...))
+				data.Reset()
+			}
+		case strings.HasPrefix(line, "data:"):
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+
+		if err == io.EOF {
+			if data.Len() > 0 {
+				handle(data.Bytes())
+			}
+			return nil
+		}
+	}
+}
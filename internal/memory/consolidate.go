@@ -2,16 +2,27 @@ package memory
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 )
 
-// Consolidator merges old memories into concise summaries.
+// Consolidation actions recorded in memory_consolidations.
+const (
+	ActionMerge = "merge" // an original folded into a consolidated core memory
+	ActionPrune = "prune" // a stale memory deleted outright
+)
+
+// Consolidator merges old memories into concise summaries and prunes stale
+// ones. Every original it removes is kept in memory_consolidations, so a bad
+// merge can be undone with UndoConsolidation.
 type Consolidator struct {
-	store     *Store
-	summarize func(ctx context.Context, memories []Entry) (string, error)
-	maxAge    time.Duration
+	store         *Store
+	summarize     func(ctx context.Context, memories []Entry) (string, error)
+	maxAge        time.Duration
+	minCandidates int
 }
 
 // SummarizeFunc takes a batch of memories and returns a consolidated summary.
@@ -31,52 +42,157 @@ func NewConsolidator(store *Store, summarize SummarizeFunc) *Consolidator {
 	}
 }
 
+// SetMinCandidates makes merging wait until at least n old daily and
+// conversation memories have piled up. Pruning is not affected.
+func (c *Consolidator) SetMinCandidates(n int) {
+	c.minCandidates = n
+}
+
+// Merge is one group of memories folded into a core memory.
+type Merge struct {
+	Tag       string
+	Scope     Scope // memories are only merged within a scope
+	Originals []Entry
+	Summary   string // empty in a dry run
+	MemoryID  int64  // the new core memory, 0 in a dry run
+}
+
+// ConsolidationReport describes what a consolidation run merged and pruned,
+// or would have, in a dry run.
+type ConsolidationReport struct {
+	DryRun  bool
+	Merges  []Merge
+	Pruned  []Entry
+	Waiting int // old memories left alone below the minimum
+	Errors  []string
+}
+
+// MergedCount returns how many original memories were merged.
+func (r ConsolidationReport) MergedCount() int {
+	n := 0
+	for _, m := range r.Merges {
+		n += len(m.Originals)
+	}
+	return n
+}
+
+func (r ConsolidationReport) String() string {
+	var b strings.Builder
+	verb, pruneVerb := "Merged", "Pruned"
+	if r.DryRun {
+		verb, pruneVerb = "Would merge", "Would prune"
+	}
+	if len(r.Merges) == 0 && len(r.Pruned) == 0 {
+		b.WriteString("Nothing to consolidate.")
+		if r.Waiting > 0 {
+			fmt.Fprintf(&b, " %d old memories are waiting for the compaction threshold.", r.Waiting)
+		}
+	}
+	for _, m := range r.Merges {
+		ids := make([]string, len(m.Originals))
+		for i, e := range m.Originals {
+			ids[i] = fmt.Sprintf("#%d", e.ID)
+		}
+		fmt.Fprintf(&b, "%s %d memories tagged %q (%s)", verb, len(m.Originals), m.Tag, strings.Join(ids, ", "))
+		if m.MemoryID > 0 {
+			fmt.Fprintf(&b, " into #%d: %s", m.MemoryID, truncate(m.Summary, 200))
+		}
+		b.WriteString("\n")
+	}
+	for _, e := range r.Pruned {
+		fmt.Fprintf(&b, "%s #%d [%s] %s\n", pruneVerb, e.ID, e.Category, truncate(e.Content, 100))
+	}
+	for _, e := range r.Errors {
+		fmt.Fprintf(&b, "Error: %s\n", e)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
 // Consolidate finds old daily/conversation memories, groups by tags, summarizes, and replaces.
+// It returns how many originals were merged.
 func (c *Consolidator) Consolidate(ctx context.Context) (int, error) {
+	report, err := c.Run(ctx, false)
+	return report.MergedCount(), err
+}
+
+// Run merges groups of old daily and conversation memories into core
+// summaries, then prunes stale memories (see Store.Consolidate). With dryRun
+// it only reports what it would do; nothing is summarized or changed.
+func (c *Consolidator) Run(ctx context.Context, dryRun bool) (ConsolidationReport, error) {
+	report := ConsolidationReport{DryRun: dryRun}
 	cutoff := time.Now().Add(-c.maxAge)
 
 	// Find old daily and conversation memories
 	old, err := c.store.ListOlderThan(ctx, cutoff, 100)
 	if err != nil {
-		return 0, fmt.Errorf("listing old memories: %w", err)
+		return report, fmt.Errorf("listing old memories: %w", err)
 	}
 
-	if len(old) == 0 {
-		return 0, nil
+	if len(old) > 0 && len(old) < c.minCandidates {
+		report.Waiting = len(old)
+		old = nil
 	}
 
-	// Group by primary tag
-	groups := make(map[string][]Entry)
+	// Group by scope and primary tag, so one user's notes never end up
+	// in a summary another can recall
+	type groupKey struct {
+		scope Scope
+		tag   string
+	}
+	groups := make(map[groupKey][]Entry)
 	for _, e := range old {
-		key := primaryTag(e.Tags)
+		key := groupKey{e.Scope, primaryTag(e.Tags)}
 		groups[key] = append(groups[key], e)
 	}
+	keys := make([]groupKey, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].tag != keys[j].tag {
+			return keys[i].tag < keys[j].tag
+		}
+		return keys[i].scope < keys[j].scope
+	})
 
-	consolidated := 0
-	for tag, entries := range groups {
+	for _, key := range keys {
+		entries, tag := groups[key], key.tag
 		if len(entries) < 2 {
 			continue // don't consolidate singles
 		}
+		merge := Merge{Tag: tag, Scope: key.scope, Originals: entries}
+		if dryRun {
+			report.Merges = append(report.Merges, merge)
+			continue
+		}
 
 		summary, err := c.summarize(ctx, entries)
 		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("summarizing %q: %v", tag, err))
 			continue // skip this group on error
 		}
+		merge.Summary = summary
 
-		// Store consolidated memory as core
-		_, err = c.store.MemStore(ctx, CategoryCore, summary, tag, 0.8)
+		// Store consolidated memory as core, replacing the originals
+		merge.MemoryID, err = c.store.merge(ctx, entries, summary, tag, key.scope)
 		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("merging %q: %v", tag, err))
 			continue
 		}
+		report.Merges = append(report.Merges, merge)
+	}
 
-		// Delete originals
-		for _, e := range entries {
-			c.store.Forget(ctx, e.ID)
+	stale, err := c.store.staleMemories(ctx)
+	if err != nil {
+		return report, fmt.Errorf("listing stale memories: %w", err)
+	}
+	if !dryRun {
+		if err := c.store.prune(ctx, stale); err != nil {
+			return report, fmt.Errorf("pruning: %w", err)
 		}
-		consolidated += len(entries)
 	}
-
-	return consolidated, nil
+	report.Pruned = stale
+	return report, nil
 }
 
 // ListOlderThan returns daily/conversation memories older than the given time.
@@ -86,19 +202,20 @@ func (s *Store) ListOlderThan(ctx context.Context, olderThan time.Time, limit in
 	}
 
 	rows, err := s.db.QueryContext(ctx, `
-		SELECT id, category, content, tags, created_at, accessed_at
+		SELECT id, category, content, tags, COALESCE(importance, 0.5),
+		       COALESCE(access_count, 0), created_at, accessed_at, COALESCE(scope, 'global')
 		FROM memories
 		WHERE category IN ('daily', 'conversation')
 		AND created_at < ?
 		ORDER BY created_at ASC
 		LIMIT ?
-	`, olderThan.Format(time.DateTime), limit)
+	`, olderThan.UTC().Format(time.DateTime), limit)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	return scanEntries(rows)
+	return scanEntriesFull(rows)
 }
 
 func primaryTag(tags string) string {
@@ -119,3 +236,195 @@ func defaultSummarize(_ context.Context, memories []Entry) (string, error) {
 	}
 	return fmt.Sprintf("[Consolidated from %d memories] %s", len(memories), strings.Join(parts, "; ")), nil
 }
+
+func initConsolidationSchema(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS memory_consolidations (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			action TEXT NOT NULL,
+			memory_id INTEGER NOT NULL DEFAULT 0,
+			original_id INTEGER NOT NULL,
+			category TEXT NOT NULL,
+			content TEXT NOT NULL,
+			tags TEXT DEFAULT '',
+			importance REAL DEFAULT 0.5,
+			access_count INTEGER DEFAULT 0,
+			created_at DATETIME,
+			accessed_at DATETIME,
+			consolidated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			undone_at DATETIME
+		);
+		CREATE INDEX IF NOT EXISTS idx_consolidations_memory ON memory_consolidations(memory_id);
+		CREATE INDEX IF NOT EXISTS idx_consolidations_original ON memory_consolidations(original_id);
+	`)
+	return err
+}
+
+// merge stores summary as a core memory and replaces the originals with it,
+// keeping them in the audit trail. It returns the new memory's ID.
+func (s *Store) merge(ctx context.Context, originals []Entry, summary, tag string, scope Scope) (int64, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if scope == "" {
+		scope = ScopeGlobal
+	}
+	res, err := tx.Exec("INSERT INTO memories (category, content, tags, importance, scope) VALUES (?, ?, ?, ?, ?)",
+		string(CategoryCore), summary, tag, 0.8, string(scope))
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	if err := archive(tx, ActionMerge, id, originals); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	s.embedMemory(ctx, id, summary, tag)
+	return id, nil
+}
+
+// prune deletes memories, keeping them in the audit trail.
+func (s *Store) prune(ctx context.Context, entries []Entry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := archive(tx, ActionPrune, 0, entries); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// archive copies entries into memory_consolidations and deletes them.
+func archive(tx *sql.Tx, action string, memoryID int64, entries []Entry) error {
+	for _, e := range entries {
+		if _, err := tx.Exec(`
+			INSERT INTO memory_consolidations
+				(action, memory_id, original_id, category, content, tags, importance, access_count, created_at, accessed_at, scope)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, action, memoryID, e.ID, string(e.Category), e.Content, e.Tags, e.Importance, e.AccessCount,
+			e.CreatedAt.UTC().Format(time.DateTime), e.AccessedAt.UTC().Format(time.DateTime), string(e.Scope)); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("DELETE FROM memories WHERE id = ?", e.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ConsolidationRecord is one original memory removed by consolidation.
+type ConsolidationRecord struct {
+	ID             int64
+	Action         string
+	MemoryID       int64 // the consolidated core memory, 0 for prunes
+	Original       Entry
+	ConsolidatedAt time.Time
+	UndoneAt       *time.Time
+}
+
+// ConsolidationLog returns the most recent consolidation records, newest first.
+func (s *Store) ConsolidationLog(ctx context.Context, limit int) ([]ConsolidationRecord, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, action, memory_id, original_id, category, content, tags, importance,
+		       access_count, created_at, accessed_at, consolidated_at, undone_at, COALESCE(scope, 'global')
+		FROM memory_consolidations ORDER BY id DESC LIMIT ?
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanConsolidations(rows)
+}
+
+// UndoConsolidation reverses consolidation for id: if id is a consolidated
+// core memory, its originals are restored and it is deleted; if id is a
+// pruned memory, it is restored. Restored memories keep their original IDs.
+// It returns how many memories were restored.
+func (s *Store) UndoConsolidation(ctx context.Context, id int64) (int, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, action, memory_id, original_id, category, content, tags, importance,
+		       access_count, created_at, accessed_at, consolidated_at, undone_at, COALESCE(scope, 'global')
+		FROM memory_consolidations
+		WHERE undone_at IS NULL
+		  AND ((action = ? AND memory_id = ?) OR (action = ? AND original_id = ?))
+		ORDER BY id
+	`, ActionMerge, id, ActionPrune, id)
+	if err != nil {
+		return 0, err
+	}
+	records, err := scanConsolidations(rows)
+	rows.Close()
+	if err != nil {
+		return 0, err
+	}
+	if len(records) == 0 {
+		return 0, fmt.Errorf("memory %d was not consolidated, or was already restored", id)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	for _, r := range records {
+		e := r.Original
+		if _, err := tx.Exec(`
+			INSERT INTO memories (id, category, content, tags, importance, access_count, created_at, accessed_at, scope)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, e.ID, string(e.Category), e.Content, e.Tags, e.Importance, e.AccessCount,
+			e.CreatedAt.UTC().Format(time.DateTime), e.AccessedAt.UTC().Format(time.DateTime), string(e.Scope)); err != nil {
+			return 0, fmt.Errorf("restoring memory %d: %w", e.ID, err)
+		}
+		if _, err := tx.Exec("UPDATE memory_consolidations SET undone_at = CURRENT_TIMESTAMP WHERE id = ?", r.ID); err != nil {
+			return 0, err
+		}
+	}
+	if records[0].Action == ActionMerge {
+		if _, err := tx.Exec("DELETE FROM memories WHERE id = ?", id); err != nil {
+			return 0, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	for _, r := range records {
+		s.embedMemory(ctx, r.Original.ID, r.Original.Content, r.Original.Tags)
+	}
+	return len(records), nil
+}
+
+func scanConsolidations(rows *sql.Rows) ([]ConsolidationRecord, error) {
+	var records []ConsolidationRecord
+	for rows.Next() {
+		var r ConsolidationRecord
+		var undone sql.NullTime
+		if err := rows.Scan(&r.ID, &r.Action, &r.MemoryID, &r.Original.ID, &r.Original.Category,
+			&r.Original.Content, &r.Original.Tags, &r.Original.Importance, &r.Original.AccessCount,
+			&r.Original.CreatedAt, &r.Original.AccessedAt, &r.ConsolidatedAt, &undone, &r.Original.Scope); err != nil {
+			return nil, err
+		}
+		if undone.Valid {
+			r.UndoneAt = &undone.Time
+		}
+		records = append(records, r)
+	}
+	return records, rows.Err()
+}
@@ -0,0 +1,139 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// storeAged stores a memory and backdates it by days.
+func storeAged(t *testing.T, s *Store, category Category, content, tags string, importance float64, days int) int64 {
+	t.Helper()
+	id, err := s.MemStore(context.Background(), category, content, tags, importance)
+	if err != nil {
+		t.Fatal(err)
+	}
+	age := fmt.Sprintf("-%d days", days)
+	s.db.Exec("UPDATE memories SET created_at = datetime('now', ?), accessed_at = datetime('now', ?) WHERE id = ?", age, age, id)
+	return id
+}
+
+func TestConsolidatorMergeAndUndo(t *testing.T) {
+	store := setupTestStore(t)
+	ctx := context.Background()
+
+	a := storeAged(t, store, CategoryDaily, "Deployed v1.2 to staging", "deploy", 0.6, 10)
+	b := storeAged(t, store, CategoryDaily, "Deployed v1.2 to production", "deploy", 0.6, 9)
+	single := storeAged(t, store, CategoryDaily, "Bought coffee beans", "errands", 0.6, 9)
+	fresh := storeAged(t, store, CategoryDaily, "Deployed v1.3 to staging", "deploy", 0.6, 1)
+
+	var calls int
+	c := NewConsolidator(store, func(_ context.Context, memories []Entry) (string, error) {
+		calls++
+		return fmt.Sprintf("v1.2 shipped (%d notes)", len(memories)), nil
+	})
+
+	// A dry run reports the merge but changes nothing
+	report, err := c.Run(ctx, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 0 || report.MergedCount() != 2 || report.Merges[0].MemoryID != 0 {
+		t.Fatalf("unexpected dry run: calls=%d report=%+v", calls, report)
+	}
+	if !strings.Contains(report.String(), "Would merge 2 memories tagged \"deploy\"") {
+		t.Errorf("unexpected dry run report: %s", report)
+	}
+	if n, _ := store.Count(ctx); n != 4 {
+		t.Fatalf("dry run changed memory: %d entries", n)
+	}
+
+	report, err = c.Run(ctx, false)
+	if err != nil || report.MergedCount() != 2 {
+		t.Fatalf("expected 2 merged, got %+v (%v)", report, err)
+	}
+	merged := report.Merges[0].MemoryID
+	e, err := store.Get(ctx, merged)
+	if err != nil || e.Category != CategoryCore || e.Content != "v1.2 shipped (2 notes)" {
+		t.Fatalf("unexpected consolidated memory %+v (%v)", e, err)
+	}
+	for _, id := range []int64{a, b} {
+		if _, err := store.Get(ctx, id); err == nil {
+			t.Errorf("original %d should be gone", id)
+		}
+	}
+	for _, id := range []int64{single, fresh} {
+		if _, err := store.Get(ctx, id); err != nil {
+			t.Errorf("memory %d should be untouched", id)
+		}
+	}
+
+	log, _ := store.ConsolidationLog(ctx, 10)
+	if len(log) != 2 || log[0].Action != ActionMerge || log[0].MemoryID != merged {
+		t.Fatalf("unexpected audit trail %+v", log)
+	}
+
+	// Undo restores the originals under their IDs and drops the summary
+	n, err := store.UndoConsolidation(ctx, merged)
+	if err != nil || n != 2 {
+		t.Fatalf("undo: restored %d (%v)", n, err)
+	}
+	if _, err := store.Get(ctx, merged); err == nil {
+		t.Error("consolidated memory should be deleted")
+	}
+	if e, err := store.Get(ctx, a); err != nil || e.Content != "Deployed v1.2 to staging" {
+		t.Errorf("original not restored: %+v (%v)", e, err)
+	}
+	if entries, _ := store.Recall(ctx, "production", 5); len(entries) != 1 || entries[0].ID != b {
+		t.Errorf("restored memory not searchable: %+v", entries)
+	}
+	if _, err := store.UndoConsolidation(ctx, merged); err == nil {
+		t.Error("expected an error undoing twice")
+	}
+}
+
+func TestConsolidatorThreshold(t *testing.T) {
+	store := setupTestStore(t)
+	ctx := context.Background()
+	storeAged(t, store, CategoryConversation, "Asked about the weather", "chat", 0.6, 10)
+	storeAged(t, store, CategoryConversation, "Asked about the news", "chat", 0.6, 10)
+
+	c := NewConsolidator(store, nil)
+	c.SetMinCandidates(3)
+	report, err := c.Run(ctx, false)
+	if err != nil || len(report.Merges) != 0 || report.Waiting != 2 {
+		t.Fatalf("expected merging to wait, got %+v (%v)", report, err)
+	}
+	if n, _ := store.Count(ctx); n != 2 {
+		t.Errorf("expected memory untouched, got %d entries", n)
+	}
+}
+
+func TestConsolidatePrunesWithAudit(t *testing.T) {
+	store := setupTestStore(t)
+	ctx := context.Background()
+	stale := storeAged(t, store, CategoryCustom, "Temporary wifi password guest123", "", 0.3, 40)
+	storeAged(t, store, CategoryCore, "User's name is Sam", "", 0.3, 40)
+	storeAged(t, store, CategoryCustom, "Important custom note", "", 0.7, 40)
+
+	report, err := NewConsolidator(store, nil).Run(ctx, true)
+	if err != nil || len(report.Pruned) != 1 || report.Pruned[0].ID != stale {
+		t.Fatalf("expected one prune candidate, got %+v (%v)", report, err)
+	}
+
+	n, err := store.Consolidate(ctx)
+	if err != nil || n != 1 {
+		t.Fatalf("expected 1 pruned, got %d (%v)", n, err)
+	}
+	if _, err := store.Get(ctx, stale); err == nil {
+		t.Fatal("stale memory should be pruned")
+	}
+
+	if n, err := store.UndoConsolidation(ctx, stale); err != nil || n != 1 {
+		t.Fatalf("undo prune: %d (%v)", n, err)
+	}
+	if _, err := store.Get(ctx, stale); err != nil {
+		t.Error("pruned memory should be restored")
+	}
+}
@@ -0,0 +1,206 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Embedder turns text into vectors for semantic recall. Name identifies the
+// model and dimensions; vectors from different embedders are never compared.
+type Embedder interface {
+	Name() string
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// HashEmbedder is an offline embedder that hashes words and character
+// trigrams into a fixed number of buckets. It catches shared words and
+// word forms ("rotate", "rotation") but not synonyms.
+type HashEmbedder struct {
+	dims int
+}
+
+func NewHashEmbedder(dims int) *HashEmbedder {
+	if dims <= 0 {
+		dims = 256
+	}
+	return &HashEmbedder{dims: dims}
+}
+
+func (h *HashEmbedder) Name() string { return fmt.Sprintf("hash-ngram-%d", h.dims) }
+
+func (h *HashEmbedder) Embed(_ context.Context, texts []string) ([][]float32, error) {
+	out := make([][]float32, len(texts))
+	for i, text := range texts {
+		v := make([]float32, h.dims)
+		for _, word := range extractKeywords(text) {
+			h.add(v, "w:"+word, 1)
+			padded := "#" + word + "#"
+			for j := 0; j+3 <= len(padded); j++ {
+				h.add(v, padded[j:j+3], 0.5)
+			}
+		}
+		out[i] = normalize(v)
+	}
+	return out, nil
+}
+
+// add hashes a feature into a bucket, with a sign bit so collisions cancel
+// out rather than pile up.
+func (h *HashEmbedder) add(v []float32, feature string, weight float32) {
+	f := fnv.New64a()
+	f.Write([]byte(feature))
+	sum := f.Sum64()
+	if sum>>63 == 1 {
+		weight = -weight
+	}
+	v[sum%uint64(h.dims)] += weight
+}
+
+// OpenAIEmbedder calls an OpenAI-compatible /embeddings endpoint: OpenAI,
+// Ollama, LM Studio, vLLM and most hosted gateways.
+type OpenAIEmbedder struct {
+	baseURL string
+	apiKey  string
+	model   string
+	dims    int
+	client  *http.Client
+}
+
+// NewOpenAIEmbedder creates an embedder for baseURL (e.g. https://api.openai.com/v1).
+// dims asks the model for shorter vectors where supported; 0 uses the model default.
+func NewOpenAIEmbedder(baseURL, apiKey, model string, dims int) *OpenAIEmbedder {
+	return &OpenAIEmbedder{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		apiKey:  apiKey,
+		model:   model,
+		dims:    dims,
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (e *OpenAIEmbedder) Name() string {
+	if e.dims > 0 {
+		return fmt.Sprintf("openai:%s:%d", e.model, e.dims)
+	}
+	return "openai:" + e.model
+}
+
+func (e *OpenAIEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	req := map[string]any{"model": e.model, "input": texts}
+	if e.dims > 0 {
+		req["dimensions"] = e.dims
+	}
+	body, _ := json.Marshal(req)
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, e.baseURL+"/embeddings", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if e.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+e.apiKey)
+	}
+
+	resp, err := e.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("embeddings request: %w", err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<20))
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("embeddings API error (status %d): %s", resp.StatusCode, truncate(string(data), 200))
+	}
+
+	var parsed struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return nil, fmt.Errorf("parsing embeddings response: %w", err)
+	}
+	if len(parsed.Data) != len(texts) {
+		return nil, fmt.Errorf("embeddings API returned %d vectors for %d inputs", len(parsed.Data), len(texts))
+	}
+
+	out := make([][]float32, len(texts))
+	for _, d := range parsed.Data {
+		if d.Index < 0 || d.Index >= len(out) {
+			return nil, fmt.Errorf("embeddings API returned index %d out of range", d.Index)
+		}
+		out[d.Index] = normalize(d.Embedding)
+	}
+	return out, nil
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
+
+// normalize scales v to unit length in place, so cosine similarity is a dot product.
+func normalize(v []float32) []float32 {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	if sum == 0 {
+		return v
+	}
+	scale := float32(1 / math.Sqrt(sum))
+	for i := range v {
+		v[i] *= scale
+	}
+	return v
+}
+
+// dot returns the dot product of two unit vectors, their cosine similarity.
+// Vectors of different lengths are unrelated.
+func dot(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var sum float64
+	for i := range a {
+		sum += float64(a[i]) * float64(b[i])
+	}
+	return sum
+}
+
+func isZero(v []float32) bool {
+	for _, x := range v {
+		if x != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// encodeVector packs a vector as little-endian float32s for the embedding column.
+func encodeVector(v []float32) []byte {
+	buf := make([]byte, 4*len(v))
+	for i, x := range v {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(x))
+	}
+	return buf
+}
+
+func decodeVector(buf []byte) []float32 {
+	v := make([]float32, len(buf)/4)
+	for i := range v {
+		v[i] = math.Float32frombits(binary.LittleEndian.Uint32(buf[4*i:]))
+	}
+	return v
+}
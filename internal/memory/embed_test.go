@@ -0,0 +1,157 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// topicEmbedder maps text onto hand-picked topic axes, standing in for a
+// real model that knows "password" and "credential" are related.
+type topicEmbedder struct{}
+
+var topics = [][]string{
+	{"password", "credential", "secret"},
+	{"postgres", "db", "database"},
+	{"pizza", "food", "lunch"},
+}
+
+func (e *topicEmbedder) Name() string { return "topics" }
+
+func (e *topicEmbedder) Embed(_ context.Context, texts []string) ([][]float32, error) {
+	out := make([][]float32, len(texts))
+	for i, text := range texts {
+		v := make([]float32, len(topics))
+		lower := strings.ToLower(text)
+		for axis, words := range topics {
+			for _, w := range words {
+				if strings.Contains(lower, w) {
+					v[axis]++
+				}
+			}
+		}
+		out[i] = normalize(v)
+	}
+	return out, nil
+}
+
+func TestHybridRecallFindsSemanticMatches(t *testing.T) {
+	store := setupTestStore(t)
+	ctx := context.Background()
+	store.SetEmbedder(&topicEmbedder{}, 0.5)
+
+	store.MemStore(ctx, CategoryCore, "Credential rotation for Postgres happens every 90 days", "", 0)
+	store.MemStore(ctx, CategoryDaily, "Had pizza for lunch", "", 0)
+
+	// No keyword in common with the stored memory
+	entries, err := store.Recall(ctx, "what's my DB password policy", 5)
+	if err != nil {
+		t.Fatalf("recall error: %v", err)
+	}
+	if len(entries) != 1 || !strings.Contains(entries[0].Content, "Credential rotation") {
+		t.Fatalf("expected only the credential memory, got %+v", entries)
+	}
+
+	result := store.BuildContextFromMemory(ctx, "which database secret do we use?")
+	if !strings.Contains(result, "Credential rotation") || strings.Contains(result, "pizza") {
+		t.Errorf("unexpected context: %s", result)
+	}
+}
+
+func TestHybridRecallRanksKeywordAndMeaning(t *testing.T) {
+	store := setupTestStore(t)
+	ctx := context.Background()
+	store.SetEmbedder(&topicEmbedder{}, 0.5)
+
+	store.MemStore(ctx, CategoryCustom, "The postgres password lives in the vault", "", 0.5)
+	store.MemStore(ctx, CategoryCustom, "Postgres runs on port 5432", "", 0.5)
+
+	// Both match "postgres"; only the first also matches in meaning
+	entries, _ := store.Recall(ctx, "postgres secret", 5)
+	if len(entries) != 2 || !strings.Contains(entries[0].Content, "vault") {
+		t.Fatalf("expected the vault memory first, got %+v", entries)
+	}
+}
+
+func TestBackfillEmbeddings(t *testing.T) {
+	store := setupTestStore(t)
+	ctx := context.Background()
+
+	// Stored before semantic recall was enabled
+	store.MemStore(ctx, CategoryCore, "The database password is rotated monthly", "", 0)
+	store.MemStore(ctx, CategoryCore, "Lunch is at noon", "", 0)
+
+	store.SetEmbedder(&topicEmbedder{}, 0.5)
+	if entries, _ := store.Recall(ctx, "credential", 5); len(entries) != 0 {
+		t.Fatalf("expected no semantic matches before backfill, got %+v", entries)
+	}
+
+	n, err := store.BackfillEmbeddings(ctx)
+	if err != nil || n != 2 {
+		t.Fatalf("expected 2 memories embedded, got %d (%v)", n, err)
+	}
+	if entries, _ := store.Recall(ctx, "credential", 5); len(entries) != 1 {
+		t.Fatalf("expected a semantic match after backfill, got %+v", entries)
+	}
+	if n, _ := store.BackfillEmbeddings(ctx); n != 0 {
+		t.Errorf("expected nothing left to backfill, got %d", n)
+	}
+}
+
+func TestHashEmbedder(t *testing.T) {
+	h := NewHashEmbedder(256)
+	vecs, _ := h.Embed(context.Background(), []string{
+		"credential rotation for Postgres",
+		"rotating credentials",
+		"I like pizza with pineapple",
+	})
+	related, unrelated := dot(vecs[0], vecs[1]), dot(vecs[0], vecs[2])
+	if related < defaultMinSimilarity || unrelated >= defaultMinSimilarity {
+		t.Errorf("expected word forms to match and unrelated text not to: related %.2f, unrelated %.2f", related, unrelated)
+	}
+	if got := dot(vecs[0], vecs[0]); got < 0.999 || got > 1.001 {
+		t.Errorf("expected unit vectors, got |v|² = %f", got)
+	}
+}
+
+func TestOpenAIEmbedder(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/embeddings" || r.Header.Get("Authorization") != "Bearer key" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		var req struct {
+			Model      string   `json:"model"`
+			Input      []string `json:"input"`
+			Dimensions int      `json:"dimensions"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Model != "text-embedding-3-small" || req.Dimensions != 2 || len(req.Input) != 2 {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		// Out of order, as the API allows
+		w.Write([]byte(`{"data":[{"index":1,"embedding":[0,2]},{"index":0,"embedding":[3,4]}]}`))
+	}))
+	defer srv.Close()
+
+	e := NewOpenAIEmbedder(srv.URL+"/v1/", "key", "text-embedding-3-small", 2)
+	if e.Name() != "openai:text-embedding-3-small:2" {
+		t.Errorf("unexpected name %s", e.Name())
+	}
+	vecs, err := e.Embed(context.Background(), []string{"a", "b"})
+	if err != nil {
+		t.Fatalf("embed: %v", err)
+	}
+	if vecs[0][0] != 0.6 || vecs[0][1] != 0.8 || vecs[1][1] != 1 {
+		t.Errorf("expected normalized vectors in input order, got %v", vecs)
+	}
+
+	bad := NewOpenAIEmbedder(srv.URL, "wrong", "text-embedding-3-small", 2)
+	if _, err := bad.Embed(context.Background(), []string{"a", "b"}); err == nil {
+		t.Error("expected an error for a rejected request")
+	}
+}
@@ -0,0 +1,292 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Hybrid ranking weights. Keyword and vector relevance share relevanceWeight;
+// without an embedder the keyword score takes all of it.
+const (
+	relevanceWeight  = 0.7
+	decayWeight      = 0.15
+	importanceWeight = 0.15
+
+	candidateFactor      = 4  // candidates fetched per result from each search
+	minCandidates        = 20 // so small limits still rank a useful pool
+	embedBatchSize       = 32
+	defaultMinSimilarity = 0.3
+)
+
+// SetEmbedder enables semantic recall. Vector-only matches with cosine
+// similarity below minSimilarity are dropped (0 uses the default).
+func (s *Store) SetEmbedder(e Embedder, minSimilarity float64) {
+	if minSimilarity <= 0 {
+		minSimilarity = defaultMinSimilarity
+	}
+	s.embedder = e
+	s.minSimilarity = minSimilarity
+}
+
+// recall ranks memories matching ftsQuery (FTS5 syntax; empty skips keyword
+// search) or semantically close to text, merging BM25, cosine similarity,
+// access decay and importance:
+//
+//	score = relevance * 0.7 + decay * 0.15 + importance * 0.15
+//	relevance = (bm25 + cosine) / 2, or bm25 alone without an embedder
+//	decay = min(1, exp(-0.05 * days_since_access) * (1 + 0.02 * access_count))
+//
+// bm25 is normalized to [0, 1] within the candidates.
+func (s *Store) recall(ctx context.Context, ftsQuery, text string, limit int) ([]Entry, error) {
+	if limit <= 0 {
+		limit = 5
+	}
+	n := max(limit*candidateFactor, minCandidates)
+
+	keyword, err := s.keywordCandidates(ctx, ftsQuery, n)
+	if err != nil {
+		return nil, err
+	}
+	similarity, vectorIDs := s.vectorCandidates(ctx, text, n)
+
+	ids := make([]int64, 0, len(keyword)+len(vectorIDs))
+	seen := map[int64]bool{}
+	for id := range keyword {
+		seen[id] = true
+		ids = append(ids, id)
+	}
+	for _, id := range vectorIDs {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	entries, err := s.entriesByID(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	scores := make(map[int64]float64, len(entries))
+	for _, e := range entries {
+		relevance := keyword[e.ID]
+		if similarity != nil {
+			relevance = (keyword[e.ID] + math.Max(similarity[e.ID], 0)) / 2
+		}
+		scores[e.ID] = relevance*relevanceWeight + decayScore(e)*decayWeight + e.Importance*importanceWeight
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return scores[entries[i].ID] > scores[entries[j].ID] })
+	if len(entries) > limit {
+		entries = entries[:limit]
+	}
+
+	// Increment access_count for returned entries
+	for _, e := range entries {
+		s.db.Exec("UPDATE memories SET access_count = COALESCE(access_count, 0) + 1, accessed_at = CURRENT_TIMESTAMP WHERE id = ?", e.ID)
+	}
+	return entries, nil
+}
+
+func decayScore(e Entry) float64 {
+	days := math.Max(0, time.Since(e.AccessedAt).Hours()/24)
+	return math.Min(1, math.Exp(-0.05*days)*(1+0.02*float64(e.AccessCount)))
+}
+
+// keywordCandidates returns FTS5 matches with BM25 normalized to [0, 1]. If
+// the query isn't valid FTS5 syntax, it falls back to LIKE matching and
+// scores by the fraction of keywords matched.
+func (s *Store) keywordCandidates(ctx context.Context, query string, n int) (map[int64]float64, error) {
+	out := map[int64]float64{}
+	if strings.TrimSpace(query) == "" {
+		return out, nil
+	}
+
+	scope, args := scopeFilter(ctx, "m.scope")
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT memories_fts.rowid, -memories_fts.rank FROM memories_fts
+		JOIN memories m ON m.id = memories_fts.rowid
+		WHERE memories_fts MATCH ? AND `+scope+`
+		ORDER BY memories_fts.rank LIMIT ?
+	`, append(append([]any{query}, args...), n)...)
+	if err != nil {
+		return s.likeCandidates(ctx, query, n)
+	}
+	defer rows.Close()
+
+	var best float64
+	for rows.Next() {
+		var id int64
+		var score float64
+		if err := rows.Scan(&id, &score); err != nil {
+			continue
+		}
+		out[id] = score
+		best = math.Max(best, score)
+	}
+	for id, score := range out {
+		if best > 0 {
+			out[id] = score / best
+		} else {
+			out[id] = 1
+		}
+	}
+	return out, rows.Err()
+}
+
+func (s *Store) likeCandidates(ctx context.Context, query string, n int) (map[int64]float64, error) {
+	rows, err := s.recallLike(ctx, query, n)
+	if err != nil {
+		return nil, err
+	}
+	keywords := extractKeywords(query)
+	out := make(map[int64]float64, len(rows))
+	for _, e := range rows {
+		text := strings.ToLower(e.Content + " " + e.Tags)
+		matched := 0
+		for _, kw := range keywords {
+			if strings.Contains(text, kw) {
+				matched++
+			}
+		}
+		out[e.ID] = 1
+		if len(keywords) > 0 {
+			out[e.ID] = float64(matched) / float64(len(keywords))
+		}
+	}
+	return out, nil
+}
+
+// vectorCandidates embeds text and compares it with every stored vector from
+// the same embedder. It returns the similarity of each compared memory and
+// the IDs of the top n at or above the minimum similarity. Both are nil
+// without an embedder or if embedding fails; recall then uses keywords alone.
+func (s *Store) vectorCandidates(ctx context.Context, text string, n int) (map[int64]float64, []int64) {
+	if s.embedder == nil || strings.TrimSpace(text) == "" {
+		return nil, nil
+	}
+	vecs, err := s.embedder.Embed(ctx, []string{text})
+	if err != nil || len(vecs) != 1 || isZero(vecs[0]) {
+		return nil, nil
+	}
+	query := vecs[0]
+
+	scope, args := scopeFilter(ctx, "scope")
+	rows, err := s.db.QueryContext(ctx, "SELECT id, embedding FROM memories WHERE embedding_model = ? AND "+scope,
+		append([]any{s.embedder.Name()}, args...)...)
+	if err != nil {
+		return nil, nil
+	}
+	defer rows.Close()
+
+	similarity := map[int64]float64{}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		var blob []byte
+		if err := rows.Scan(&id, &blob); err != nil {
+			continue
+		}
+		sim := dot(query, decodeVector(blob))
+		similarity[id] = sim
+		if sim >= s.minSimilarity {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return similarity[ids[i]] > similarity[ids[j]] })
+	if len(ids) > n {
+		ids = ids[:n]
+	}
+	return similarity, ids
+}
+
+func (s *Store) entriesByID(ids []int64) ([]Entry, error) {
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",")
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	rows, err := s.db.Query(fmt.Sprintf(`
+		SELECT id, category, content, tags, COALESCE(importance, 0.5),
+		       COALESCE(access_count, 0), created_at, accessed_at, COALESCE(scope, 'global')
+		FROM memories WHERE id IN (%s)`, placeholders), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanEntriesFull(rows)
+}
+
+// embedMemory stores the vector for one memory. Failures are left for
+// BackfillEmbeddings to retry.
+func (s *Store) embedMemory(ctx context.Context, id int64, content, tags string) {
+	if s.embedder == nil {
+		return
+	}
+	vecs, err := s.embedder.Embed(ctx, []string{embeddingText(content, tags)})
+	if err != nil || len(vecs) != 1 {
+		return
+	}
+	s.db.Exec("UPDATE memories SET embedding = ?, embedding_model = ? WHERE id = ?",
+		encodeVector(vecs[0]), s.embedder.Name(), id)
+}
+
+func embeddingText(content, tags string) string {
+	if tags == "" {
+		return content
+	}
+	return content + "\n" + tags
+}
+
+// BackfillEmbeddings embeds memories that have no vector from the current
+// embedder, in batches, until none are left or ctx is done. It returns how
+// many were embedded.
+func (s *Store) BackfillEmbeddings(ctx context.Context) (int, error) {
+	if s.embedder == nil {
+		return 0, nil
+	}
+	name := s.embedder.Name()
+	done := 0
+	for ctx.Err() == nil {
+		rows, err := s.db.Query(`
+			SELECT id, content, tags FROM memories
+			WHERE COALESCE(embedding_model, '') != ?
+			ORDER BY id LIMIT ?`, name, embedBatchSize)
+		if err != nil {
+			return done, err
+		}
+		var ids []int64
+		var texts []string
+		for rows.Next() {
+			var id int64
+			var content, tags string
+			if err := rows.Scan(&id, &content, &tags); err == nil {
+				ids = append(ids, id)
+				texts = append(texts, embeddingText(content, tags))
+			}
+		}
+		rows.Close()
+		if len(ids) == 0 {
+			return done, nil
+		}
+
+		vecs, err := s.embedder.Embed(ctx, texts)
+		if err != nil {
+			return done, err
+		}
+		for i, id := range ids {
+			if _, err := s.db.Exec("UPDATE memories SET embedding = ?, embedding_model = ? WHERE id = ?",
+				encodeVector(vecs[i]), name, id); err != nil {
+				return done, err
+			}
+		}
+		done += len(ids)
+	}
+	return done, ctx.Err()
+}
@@ -0,0 +1,155 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// Scope says who can recall a memory: everyone (ScopeGlobal), one user on
+// one channel ("user:<channel>:<user id>"), or everyone in one chat, such as
+// a team's Slack channel ("chat:<channel>:<chat id>").
+type Scope string
+
+const ScopeGlobal Scope = "global"
+
+// Scope kinds, as named in config and by the memory tools.
+const (
+	KindUser   = "user"
+	KindChat   = "chat"
+	KindGlobal = "global"
+)
+
+func UserScope(channel, userID string) Scope {
+	return Scope(KindUser + ":" + channel + ":" + userID)
+}
+
+func ChatScope(channel, chatID string) Scope {
+	return Scope(KindChat + ":" + channel + ":" + chatID)
+}
+
+// Kind returns "user", "chat" or "global".
+func (s Scope) Kind() string {
+	kind, _, _ := strings.Cut(string(s), ":")
+	if kind == "" {
+		return KindGlobal
+	}
+	return kind
+}
+
+// ParseScope checks a scope written out in full, as in exports and on the
+// command line: "global", "user:<channel>:<user id>" or "chat:<channel>:<chat id>".
+func ParseScope(s string) (Scope, error) {
+	if s == "" || s == KindGlobal {
+		return ScopeGlobal, nil
+	}
+	kind, rest, _ := strings.Cut(s, ":")
+	channel, id, _ := strings.Cut(rest, ":")
+	if (kind != KindUser && kind != KindChat) || channel == "" || id == "" {
+		return "", fmt.Errorf("invalid scope %q (global, user:<channel>:<user id> or chat:<channel>:<chat id>)", s)
+	}
+	return Scope(s), nil
+}
+
+// ValidKind reports whether kind names a scope kind.
+func ValidKind(kind string) bool {
+	return kind == KindUser || kind == KindChat || kind == KindGlobal
+}
+
+// Audience is who a turn is for. Memory calls whose context carries one
+// only see global memories and those of that user and chat; without one,
+// as from the CLI commands, every memory is visible.
+type Audience struct {
+	Channel string
+	ChatID  string
+	UserID  string
+}
+
+// Scopes returns the scopes the audience can see.
+func (a Audience) Scopes() []Scope {
+	scopes := []Scope{ScopeGlobal}
+	if a.UserID != "" {
+		scopes = append(scopes, UserScope(a.Channel, a.UserID))
+	}
+	if a.ChatID != "" {
+		scopes = append(scopes, ChatScope(a.Channel, a.ChatID))
+	}
+	return scopes
+}
+
+// Scope returns the audience's scope of the given kind, falling back from
+// user to chat to global when the message doesn't say who or where.
+func (a Audience) Scope(kind string) Scope {
+	switch {
+	case kind == KindUser && a.UserID != "":
+		return UserScope(a.Channel, a.UserID)
+	case (kind == KindUser || kind == KindChat) && a.ChatID != "":
+		return ChatScope(a.Channel, a.ChatID)
+	}
+	return ScopeGlobal
+}
+
+type audienceContextKey struct{}
+
+// WithAudience limits memory recall through ctx to what a sees.
+func WithAudience(ctx context.Context, a Audience) context.Context {
+	return context.WithValue(ctx, audienceContextKey{}, a)
+}
+
+// AudienceFrom returns the audience recorded by WithAudience.
+func AudienceFrom(ctx context.Context) (Audience, bool) {
+	a, ok := ctx.Value(audienceContextKey{}).(Audience)
+	return a, ok
+}
+
+// SetDefaultScope sets the kind of scope ScopeFor uses when none is asked
+// for: "user" (the default), "chat" or "global".
+func (s *Store) SetDefaultScope(kind string) {
+	if ValidKind(kind) {
+		s.defaultScope = kind
+	}
+}
+
+// ScopeFor returns the scope of the given kind ("" for the default) for the
+// audience in ctx. Without an audience it is global.
+func (s *Store) ScopeFor(ctx context.Context, kind string) Scope {
+	a, ok := AudienceFrom(ctx)
+	if !ok {
+		return ScopeGlobal
+	}
+	if kind == "" {
+		kind = s.defaultScope
+	}
+	if kind == "" {
+		kind = KindUser
+	}
+	return a.Scope(kind)
+}
+
+// scopeFilter returns an SQL condition on column limiting rows to the scopes
+// visible from ctx, and its arguments. Without an audience it matches all.
+func scopeFilter(ctx context.Context, column string) (string, []any) {
+	a, ok := AudienceFrom(ctx)
+	if !ok {
+		return "1 = 1", nil
+	}
+	scopes := a.Scopes()
+	args := make([]any, len(scopes))
+	for i, sc := range scopes {
+		args[i] = string(sc)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(scopes)), ",")
+	return fmt.Sprintf("COALESCE(%s, 'global') IN (%s)", column, placeholders), args
+}
+
+func initScopeSchema(db *sql.DB) error {
+	if err := addColumnIfMissing(db, "memories", "scope", "TEXT DEFAULT 'global'"); err != nil {
+		return err
+	}
+	if err := addColumnIfMissing(db, "memory_consolidations", "scope", "TEXT DEFAULT 'global'"); err != nil {
+		return err
+	}
+	_, err := db.Exec("CREATE INDEX IF NOT EXISTS idx_memories_scope ON memories(scope)")
+	return err
+}
@@ -0,0 +1,138 @@
+package memory
+
+import (
+	"context"
+	"testing"
+)
+
+func TestRecallFilteredByScope(t *testing.T) {
+	store := setupTestStore(t)
+	store.SetEmbedder(NewHashEmbedder(0), 0)
+	bg := context.Background()
+
+	alice := WithAudience(bg, Audience{Channel: "slack", ChatID: "C1", UserID: "U-alice"})
+	bob := WithAudience(bg, Audience{Channel: "slack", ChatID: "C1", UserID: "U-bob"})
+	other := WithAudience(bg, Audience{Channel: "slack", ChatID: "C2", UserID: "U-carol"})
+
+	global, _ := store.Save(bg, Entry{Category: CategoryCore, Content: "The staging cluster runs Kubernetes 1.30"})
+	private, _ := store.Save(bg, Entry{Category: CategoryCore, Content: "Prefers answers in Kubernetes YAML", Scope: store.ScopeFor(alice, "")})
+	team, _ := store.Save(bg, Entry{Category: CategoryCore, Content: "This team owns the Kubernetes ingress", Scope: store.ScopeFor(alice, KindChat)})
+
+	visible := func(ctx context.Context) map[int64]bool {
+		t.Helper()
+		entries, err := store.Recall(ctx, "Kubernetes", 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids := map[int64]bool{}
+		for _, e := range entries {
+			ids[e.ID] = true
+		}
+		listed, _ := store.List(ctx, CategoryCore, 10)
+		if len(listed) != len(ids) {
+			t.Errorf("List and Recall disagree: %d vs %d", len(listed), len(ids))
+		}
+		return ids
+	}
+
+	if ids := visible(alice); !ids[global] || !ids[private] || !ids[team] {
+		t.Errorf("alice should see all three, got %v", ids)
+	}
+	if ids := visible(bob); !ids[global] || ids[private] || !ids[team] {
+		t.Errorf("bob should see the global and team memories, got %v", ids)
+	}
+	if ids := visible(other); !ids[global] || ids[private] || ids[team] {
+		t.Errorf("another chat should only see the global memory, got %v", ids)
+	}
+	if ids := visible(bg); len(ids) != 3 {
+		t.Errorf("without an audience every memory is visible, got %v", ids)
+	}
+
+	if _, err := store.Get(bob, private); err == nil {
+		t.Error("bob should not get alice's memory by ID")
+	}
+	if e, err := store.Get(alice, private); err != nil || e.Scope != UserScope("slack", "U-alice") {
+		t.Errorf("unexpected scope %+v (%v)", e, err)
+	}
+}
+
+func TestScopeFor(t *testing.T) {
+	store := setupTestStore(t)
+	ctx := WithAudience(context.Background(), Audience{Channel: "telegram", ChatID: "100", UserID: "42"})
+
+	if s := store.ScopeFor(ctx, ""); s != "user:telegram:42" {
+		t.Errorf("expected the user scope by default, got %q", s)
+	}
+	store.SetDefaultScope(KindChat)
+	if s := store.ScopeFor(ctx, ""); s != "chat:telegram:100" {
+		t.Errorf("expected the chat scope, got %q", s)
+	}
+	if s := store.ScopeFor(ctx, KindGlobal); s != ScopeGlobal {
+		t.Errorf("expected global, got %q", s)
+	}
+	if s := store.ScopeFor(context.Background(), KindUser); s != ScopeGlobal {
+		t.Errorf("expected global without an audience, got %q", s)
+	}
+	noUser := WithAudience(context.Background(), Audience{Channel: "webhook", ChatID: "hook"})
+	if s := store.ScopeFor(noUser, KindUser); s != "chat:webhook:hook" {
+		t.Errorf("expected the chat scope without a user, got %q", s)
+	}
+}
+
+func TestConsolidationKeepsScopes(t *testing.T) {
+	store := setupTestStore(t)
+	ctx := context.Background()
+	alice := UserScope("slack", "U-alice")
+
+	a := storeAged(t, store, CategoryDaily, "Deployed the API", "deploy", 0.6, 10)
+	b := storeAged(t, store, CategoryDaily, "Deployed the worker", "deploy", 0.6, 10)
+	c := storeAged(t, store, CategoryDaily, "Deployed my side project", "deploy", 0.6, 10)
+	d := storeAged(t, store, CategoryDaily, "Deployed my blog", "deploy", 0.6, 10)
+	store.db.Exec("UPDATE memories SET scope = ? WHERE id IN (?, ?)", string(alice), c, d)
+
+	report, err := NewConsolidator(store, nil).Run(ctx, false)
+	if err != nil || len(report.Merges) != 2 {
+		t.Fatalf("expected one merge per scope, got %+v (%v)", report, err)
+	}
+	for _, m := range report.Merges {
+		e, err := store.Get(ctx, m.MemoryID)
+		if err != nil || e.Scope != m.Scope {
+			t.Fatalf("merged memory lost its scope: %+v (%v)", e, err)
+		}
+		for _, o := range m.Originals {
+			if o.Scope != m.Scope {
+				t.Errorf("memory %d merged across scopes", o.ID)
+			}
+		}
+		if _, err := store.UndoConsolidation(ctx, m.MemoryID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for id, want := range map[int64]Scope{a: ScopeGlobal, b: ScopeGlobal, c: alice, d: alice} {
+		if e, err := store.Get(ctx, id); err != nil || e.Scope != want {
+			t.Errorf("memory %d restored as %+v (%v), want scope %q", id, e, err, want)
+		}
+	}
+}
+
+func TestSupersedeKeepsScope(t *testing.T) {
+	store := setupTestStore(t)
+	bg := context.Background()
+	alice := WithAudience(bg, Audience{Channel: "slack", ChatID: "C1", UserID: "U-alice"})
+	bob := WithAudience(bg, Audience{Channel: "slack", ChatID: "C1", UserID: "U-bob"})
+
+	shared, _ := store.Save(bg, Entry{Category: CategoryCore, Content: "Deploys go out on Fridays"})
+	fixed, err := store.Save(alice, Entry{Category: CategoryCorrection, Content: "Deploys go out on Thursdays", Scope: store.ScopeFor(alice, ""), Supersedes: shared})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e, err := store.Get(bob, fixed)
+	if err != nil {
+		t.Fatalf("a correction to a global memory should stay visible to everyone: %v", err)
+	}
+	if e.Scope != ScopeGlobal {
+		t.Errorf("expected the global scope, got %q", e.Scope)
+	}
+}
@@ -30,14 +30,44 @@ type Entry struct {
 	AccessCount int
 	CreatedAt   time.Time
 	AccessedAt  time.Time
+	Version     int        // 1 until edited with Update
+	Supersedes  int64      // the memory this one replaced, if any
+	Source      Provenance // set by Save
+	Scope       Scope      // who can recall it, global if empty
+}
+
+// Session is a persisted conversation bound to a single channel chat.
+type Session struct {
+	ID        string
+	Channel   string
+	ChatID    string
+	Model     string // provider override chosen with /model, empty = default routing
+	Summary   string // rolling summary of turns evicted from the context window
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// HistoryEntry is one persisted conversation message.
+type HistoryEntry struct {
+	Role       string
+	Content    string
+	ToolCalls  string // assistant tool calls as a JSON array, empty if none
+	ToolCallID string // for role "tool": the call this result answers
+	Provider   string // provider that produced an assistant message
+	CreatedAt  time.Time
 }
 
 type Store struct {
-	db *sql.DB
+	db            *sql.DB
+	embedder      Embedder // nil disables semantic recall
+	minSimilarity float64
+	defaultScope  string // scope kind for ScopeFor, "user" if empty
 }
 
 func NewStore(dbPath string) (*Store, error) {
-	db, err := sql.Open("sqlite", dbPath)
+	// busy_timeout is per-connection, so it goes in the DSN: concurrent chats
+	// write history at the same time and should wait rather than fail.
+	db, err := sql.Open("sqlite", dbPath+"?_pragma=busy_timeout(5000)")
 	if err != nil {
 		return nil, fmt.Errorf("opening database: %w", err)
 	}
@@ -122,25 +152,87 @@ func initSchema(db *sql.DB) error {
 		);
 
 		CREATE INDEX IF NOT EXISTS idx_history_session ON conversation_history(session_id);
+
+		CREATE TABLE IF NOT EXISTS sessions (
+			id TEXT PRIMARY KEY,
+			channel TEXT NOT NULL,
+			chat_id TEXT NOT NULL DEFAULT '',
+			model TEXT DEFAULT '',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_sessions_chat ON sessions(channel, chat_id);
+
+		CREATE TABLE IF NOT EXISTS token_usage (
+			day TEXT NOT NULL,
+			provider TEXT NOT NULL,
+			model TEXT NOT NULL DEFAULT '',
+			session_id TEXT NOT NULL DEFAULT '',
+			input_tokens INTEGER NOT NULL DEFAULT 0,
+			output_tokens INTEGER NOT NULL DEFAULT 0,
+			cost_usd REAL NOT NULL DEFAULT 0,
+			requests INTEGER NOT NULL DEFAULT 0,
+			PRIMARY KEY (day, provider, model, session_id)
+		);
 	`
-	_, err = db.Exec(rest)
+	if _, err = db.Exec(rest); err != nil {
+		return err
+	}
+
+	// Migration: tool transcript columns on conversation_history
+	for col, def := range map[string]string{
+		"tool_calls":   "TEXT DEFAULT ''",
+		"tool_call_id": "TEXT DEFAULT ''",
+		"provider":     "TEXT DEFAULT ''",
+	} {
+		if err := addColumnIfMissing(db, "conversation_history", col, def); err != nil {
+			return err
+		}
+	}
+
+	// Migration: rolling context summary on sessions. summarized_through is the
+	// last conversation_history row folded into the summary.
+	if err := addColumnIfMissing(db, "sessions", "summary", "TEXT DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := addColumnIfMissing(db, "sessions", "summarized_through", "INTEGER DEFAULT 0"); err != nil {
+		return err
+	}
+
+	// Migration: embedding vectors for semantic recall, tagged with the
+	// embedder that produced them
+	if err := addColumnIfMissing(db, "memories", "embedding", "BLOB"); err != nil {
+		return err
+	}
+	if err := addColumnIfMissing(db, "memories", "embedding_model", "TEXT DEFAULT ''"); err != nil {
+		return err
+	}
+
+	if err := initVersionsSchema(db); err != nil {
+		return err
+	}
+	if err := initConsolidationSchema(db); err != nil {
+		return err
+	}
+	return initScopeSchema(db)
+}
+
+// addColumnIfMissing adds a column to an existing table, for databases created by older versions.
+func addColumnIfMissing(db *sql.DB, table, column, def string) error {
+	var n int
+	err := db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`, table, column).Scan(&n)
+	if err != nil || n > 0 {
+		return err
+	}
+	_, err = db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, def))
 	return err
 }
 
 // MemStore stores a memory entry with an importance score.
 // Importance ranges from 0.0 to 1.0 (default 0.5).
-func (s *Store) MemStore(_ context.Context, category Category, content, tags string, importance float64) (int64, error) {
-	if importance <= 0 {
-		importance = defaultImportance(category)
-	}
-	result, err := s.db.Exec(
-		"INSERT INTO memories (category, content, tags, importance) VALUES (?, ?, ?, ?)",
-		string(category), content, tags, importance,
-	)
-	if err != nil {
-		return 0, err
-	}
-	return result.LastInsertId()
+func (s *Store) MemStore(ctx context.Context, category Category, content, tags string, importance float64) (int64, error) {
+	return s.Save(ctx, Entry{Category: category, Content: content, Tags: tags, Importance: importance})
 }
 
 // defaultImportance returns a sensible default importance for a category.
@@ -161,48 +253,20 @@ func defaultImportance(category Category) float64 {
 	}
 }
 
-// Recall searches memories using FTS5 keyword search with composite relevance scoring.
-// Score = fts5_rank * 0.4 + decay_score * 0.3 + importance * 0.3
-// where decay_score = exp(-0.05 * days_since_access) * (1 + 0.02 * access_count)
-func (s *Store) Recall(_ context.Context, query string, limit int) ([]Entry, error) {
-	if limit <= 0 {
-		limit = 5
-	}
-
-	rows, err := s.db.Query(`
-		SELECT m.id, m.category, m.content, m.tags, COALESCE(m.importance, 0.5),
-		       COALESCE(m.access_count, 0), m.created_at, m.accessed_at
-		FROM memories_fts fts
-		JOIN memories m ON m.id = fts.rowid
-		WHERE memories_fts MATCH ?
-		ORDER BY (
-			rank * 0.4
-			+ (EXP(-0.05 * (julianday('now') - julianday(m.accessed_at)))
-			   * (1.0 + 0.02 * COALESCE(m.access_count, 0))) * 0.3
-			+ COALESCE(m.importance, 0.5) * 0.3
-		)
-		LIMIT ?
-	`, query, limit)
-	if err != nil {
-		// If FTS query fails (bad syntax), fall back to LIKE search
-		return s.recallLike(query, limit)
-	}
-	defer rows.Close()
-
-	entries, err := scanEntriesFull(rows)
-	if err != nil {
-		return nil, err
-	}
-
-	// Increment access_count for returned entries
-	for _, e := range entries {
-		s.db.Exec("UPDATE memories SET access_count = COALESCE(access_count, 0) + 1, accessed_at = CURRENT_TIMESTAMP WHERE id = ?", e.ID)
-	}
+// Recall searches memories by keyword (FTS5, falling back to LIKE on bad
+// syntax) and, with an embedder, by meaning, ranking the merged results by
+// relevance, recency of access and importance. See recall for the formula.
+func (s *Store) Recall(ctx context.Context, query string, limit int) ([]Entry, error) {
+	return s.recall(ctx, query, query, limit)
+}
 
-	return entries, nil
+// RecallText is Recall for natural-language text rather than an FTS5 query:
+// any of its keywords match, and the whole text is used for semantic search.
+func (s *Store) RecallText(ctx context.Context, text string, limit int) ([]Entry, error) {
+	return s.recall(ctx, strings.Join(extractKeywords(text), " OR "), text, limit)
 }
 
-func (s *Store) recallLike(query string, limit int) ([]Entry, error) {
+func (s *Store) recallLike(ctx context.Context, query string, limit int) ([]Entry, error) {
 	// Build OR-ed LIKE clauses for each keyword
 	keywords := extractKeywords(query)
 	if len(keywords) == 0 {
@@ -217,17 +281,19 @@ func (s *Store) recallLike(query string, limit int) ([]Entry, error) {
 		conditions = append(conditions, "(content LIKE ? OR tags LIKE ?)")
 		args = append(args, pattern, pattern)
 	}
+	scope, scopeArgs := scopeFilter(ctx, "scope")
+	args = append(args, scopeArgs...)
 	args = append(args, limit)
 
 	q := fmt.Sprintf(`
-		SELECT id, category, content, tags, created_at, accessed_at
+		SELECT id, category, content, tags, created_at, accessed_at, COALESCE(scope, 'global')
 		FROM memories
-		WHERE %s
+		WHERE (%s) AND %s
 		ORDER BY accessed_at DESC
 		LIMIT ?
-	`, strings.Join(conditions, " OR "))
+	`, strings.Join(conditions, " OR "), scope)
 
-	rows, err := s.db.Query(q, args...)
+	rows, err := s.db.QueryContext(ctx, q, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -236,14 +302,22 @@ func (s *Store) recallLike(query string, limit int) ([]Entry, error) {
 	return scanEntries(rows)
 }
 
-// Get retrieves a single memory by ID.
-func (s *Store) Get(_ context.Context, id int64) (*Entry, error) {
-	row := s.db.QueryRow(
-		"SELECT id, category, content, tags, created_at, accessed_at FROM memories WHERE id = ?", id,
+// Get retrieves a single memory by ID, with its version and provenance. Like
+// recall, it only finds memories visible to the audience in ctx.
+func (s *Store) Get(ctx context.Context, id int64) (*Entry, error) {
+	scope, args := scopeFilter(ctx, "scope")
+	row := s.db.QueryRowContext(ctx, `
+		SELECT id, category, content, tags, COALESCE(importance, 0.5), COALESCE(access_count, 0),
+		       created_at, accessed_at, COALESCE(version, 1), COALESCE(supersedes, 0),
+		       COALESCE(source_session, ''), COALESCE(source_channel, ''), COALESCE(source_user, ''),
+		       COALESCE(source_message, ''), COALESCE(scope, 'global')
+		FROM memories WHERE id = ? AND `+scope, append([]any{id}, args...)...,
 	)
 
 	var e Entry
-	if err := row.Scan(&e.ID, &e.Category, &e.Content, &e.Tags, &e.CreatedAt, &e.AccessedAt); err != nil {
+	if err := row.Scan(&e.ID, &e.Category, &e.Content, &e.Tags, &e.Importance, &e.AccessCount,
+		&e.CreatedAt, &e.AccessedAt, &e.Version, &e.Supersedes,
+		&e.Source.Session, &e.Source.Channel, &e.Source.UserID, &e.Source.Message, &e.Scope); err != nil {
 		return nil, err
 	}
 
@@ -253,23 +327,24 @@ func (s *Store) Get(_ context.Context, id int64) (*Entry, error) {
 	return &e, nil
 }
 
-// List returns memories filtered by category.
-func (s *Store) List(_ context.Context, category Category, limit int) ([]Entry, error) {
+// List returns memories filtered by category and by the audience in ctx.
+func (s *Store) List(ctx context.Context, category Category, limit int) ([]Entry, error) {
 	if limit <= 0 {
 		limit = 20
 	}
 
+	scope, args := scopeFilter(ctx, "scope")
 	var rows *sql.Rows
 	var err error
 	if category == "" {
-		rows, err = s.db.Query(
-			"SELECT id, category, content, tags, created_at, accessed_at FROM memories ORDER BY created_at DESC LIMIT ?",
-			limit,
+		rows, err = s.db.QueryContext(ctx,
+			"SELECT id, category, content, tags, created_at, accessed_at, COALESCE(scope, 'global') FROM memories WHERE "+scope+" ORDER BY created_at DESC LIMIT ?",
+			append(args, limit)...,
 		)
 	} else {
-		rows, err = s.db.Query(
-			"SELECT id, category, content, tags, created_at, accessed_at FROM memories WHERE category = ? ORDER BY created_at DESC LIMIT ?",
-			string(category), limit,
+		rows, err = s.db.QueryContext(ctx,
+			"SELECT id, category, content, tags, created_at, accessed_at, COALESCE(scope, 'global') FROM memories WHERE category = ? AND "+scope+" ORDER BY created_at DESC LIMIT ?",
+			append(append([]any{string(category)}, args...), limit)...,
 		)
 	}
 	if err != nil {
@@ -293,51 +368,103 @@ func (s *Store) Count(_ context.Context) (int, error) {
 	return count, err
 }
 
-// SaveHistory saves a conversation turn.
-func (s *Store) SaveHistory(_ context.Context, sessionID, role, content string) error {
-	_, err := s.db.Exec(
-		"INSERT INTO conversation_history (session_id, role, content) VALUES (?, ?, ?)",
-		sessionID, role, truncate(content, 2000),
-	)
-	return err
+// Stats summarizes what is in memory.
+type Stats struct {
+	Total        int
+	ByCategory   map[Category]int
+	ByScope      map[string]int // by scope kind: user, chat, global
+	Embedded     int            // memories with a vector from the current embedder
+	Versions     int            // earlier versions kept by updates and supersedes
+	Consolidated int            // originals merged or pruned and not restored
+	Oldest       time.Time
+	Newest       time.Time
 }
 
-// GetLatestSessionID returns the most recent session ID from conversation history.
-func (s *Store) GetLatestSessionID() (string, error) {
-	var sessionID string
-	err := s.db.QueryRow(`
-		SELECT session_id FROM conversation_history
-		ORDER BY id DESC LIMIT 1
-	`).Scan(&sessionID)
+// Stats counts memories by category and scope, and reports their history.
+func (s *Store) Stats(ctx context.Context) (Stats, error) {
+	st := Stats{ByCategory: map[Category]int{}, ByScope: map[string]int{}}
+	rows, err := s.db.QueryContext(ctx, "SELECT category, COALESCE(scope, 'global'), COUNT(*) FROM memories GROUP BY 1, 2")
 	if err != nil {
-		return "", err
+		return st, err
 	}
-	return sessionID, nil
+	defer rows.Close()
+	for rows.Next() {
+		var category Category
+		var scope Scope
+		var n int
+		if err := rows.Scan(&category, &scope, &n); err != nil {
+			return st, err
+		}
+		st.Total += n
+		st.ByCategory[category] += n
+		st.ByScope[scope.Kind()] += n
+	}
+	if err := rows.Err(); err != nil {
+		return st, err
+	}
+
+	model := ""
+	if s.embedder != nil {
+		model = s.embedder.Name()
+	}
+	var oldest, newest sql.NullString
+	err = s.db.QueryRowContext(ctx, `
+		SELECT (SELECT COUNT(*) FROM memories WHERE embedding_model = ? AND embedding_model != ''),
+		       (SELECT COUNT(*) FROM memory_versions),
+		       (SELECT COUNT(*) FROM memory_consolidations WHERE undone_at IS NULL),
+		       (SELECT MIN(created_at) FROM memories),
+		       (SELECT MAX(created_at) FROM memories)
+	`, model).Scan(&st.Embedded, &st.Versions, &st.Consolidated, &oldest, &newest)
+	if err != nil {
+		return st, err
+	}
+	st.Oldest, _ = time.Parse(time.DateTime, oldest.String)
+	st.Newest, _ = time.Parse(time.DateTime, newest.String)
+	return st, nil
+}
+
+// SaveHistory saves a conversation turn.
+func (s *Store) SaveHistory(ctx context.Context, sessionID, role, content string) error {
+	return s.AppendHistory(ctx, sessionID, HistoryEntry{Role: role, Content: content})
+}
+
+// AppendHistory persists one conversation message, including any tool calls
+// or tool result linkage, so a resumed session can replay the full transcript.
+func (s *Store) AppendHistory(_ context.Context, sessionID string, e HistoryEntry) error {
+	_, err := s.db.Exec(
+		"INSERT INTO conversation_history (session_id, role, content, tool_calls, tool_call_id, provider) VALUES (?, ?, ?, ?, ?, ?)",
+		sessionID, e.Role, e.Content, e.ToolCalls, e.ToolCallID, e.Provider,
+	)
+	return err
 }
 
-// GetHistory returns conversation history for a session.
-func (s *Store) GetHistory(_ context.Context, sessionID string, limit int) ([]map[string]string, error) {
+// GetHistory returns the last limit messages of a session in chronological order,
+// skipping messages already folded into the session summary.
+func (s *Store) GetHistory(_ context.Context, sessionID string, limit int) ([]HistoryEntry, error) {
 	if limit <= 0 {
 		limit = 50
 	}
 
 	rows, err := s.db.Query(`
-		SELECT role, content FROM conversation_history
+		SELECT role, content, COALESCE(tool_calls, ''), COALESCE(tool_call_id, ''),
+		       COALESCE(provider, ''), created_at
+		FROM conversation_history
 		WHERE session_id = ?
+		  AND id > COALESCE((SELECT summarized_through FROM sessions WHERE id = ?), 0)
 		ORDER BY id DESC LIMIT ?
-	`, sessionID, limit)
+	`, sessionID, sessionID, limit)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var history []map[string]string
+	var history []HistoryEntry
 	for rows.Next() {
-		var role, content string
-		if err := rows.Scan(&role, &content); err != nil {
+		var e HistoryEntry
+		if err := rows.Scan(&e.Role, &e.Content, &e.ToolCalls, &e.ToolCallID, &e.Provider, &e.CreatedAt); err != nil {
 			continue
 		}
-		history = append(history, map[string]string{"role": role, "content": content})
+		history = append(history, e)
 	}
 
 	// Reverse to chronological order
@@ -348,9 +475,59 @@ func (s *Store) GetHistory(_ context.Context, sessionID string, limit int) ([]ma
 	return history, nil
 }
 
-// ClearHistory deletes all history for a session.
+// ClearHistory deletes all history for a session, along with its session row.
 func (s *Store) ClearHistory(_ context.Context, sessionID string) error {
-	_, err := s.db.Exec("DELETE FROM conversation_history WHERE session_id = ?", sessionID)
+	if _, err := s.db.Exec("DELETE FROM conversation_history WHERE session_id = ?", sessionID); err != nil {
+		return err
+	}
+	_, err := s.db.Exec("DELETE FROM sessions WHERE id = ?", sessionID)
+	return err
+}
+
+// SaveSession creates or updates the session row binding a session ID to its chat.
+func (s *Store) SaveSession(_ context.Context, sess Session) error {
+	_, err := s.db.Exec(`
+		INSERT INTO sessions (id, channel, chat_id, model) VALUES (?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET model = excluded.model, updated_at = CURRENT_TIMESTAMP
+	`, sess.ID, sess.Channel, sess.ChatID, sess.Model)
+	return err
+}
+
+// GetChatSession returns the most recent session for a channel chat.
+// Returns sql.ErrNoRows if the chat has never been seen.
+func (s *Store) GetChatSession(_ context.Context, channel, chatID string) (*Session, error) {
+	row := s.db.QueryRow(`
+		SELECT id, channel, chat_id, COALESCE(model, ''), COALESCE(summary, ''), created_at, updated_at
+		FROM sessions
+		WHERE channel = ? AND chat_id = ?
+		ORDER BY rowid DESC LIMIT 1
+	`, channel, chatID)
+
+	var sess Session
+	if err := row.Scan(&sess.ID, &sess.Channel, &sess.ChatID, &sess.Model, &sess.Summary, &sess.CreatedAt, &sess.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &sess, nil
+}
+
+// SaveSummary stores a session's rolling summary and marks every message except
+// the newest keepLast as folded into it, so they aren't reloaded on resume.
+func (s *Store) SaveSummary(_ context.Context, sessionID, summary string, keepLast int) error {
+	var through int64
+	err := s.db.QueryRow(`
+		SELECT id FROM conversation_history
+		WHERE session_id = ?
+		ORDER BY id DESC LIMIT 1 OFFSET ?
+	`, sessionID, keepLast).Scan(&through)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+
+	_, err = s.db.Exec(`
+		UPDATE sessions SET summary = ?, summarized_through = MAX(COALESCE(summarized_through, 0), ?),
+		       updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?
+	`, summary, through, sessionID)
 	return err
 }
 
@@ -361,19 +538,36 @@ func (s *Store) DB() *sql.DB {
 
 // Consolidate removes old, low-importance memories that haven't been accessed recently.
 // Keeps core, lesson, and correction memories. Removes daily/conversation/custom memories
-// older than 30 days with low access counts and importance.
-func (s *Store) Consolidate(_ context.Context) (int64, error) {
-	result, err := s.db.Exec(`
-		DELETE FROM memories
+// older than 30 days with low access counts and importance. Removed memories are kept in
+// memory_consolidations and can be restored with UndoConsolidation.
+func (s *Store) Consolidate(ctx context.Context) (int64, error) {
+	stale, err := s.staleMemories(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if err := s.prune(ctx, stale); err != nil {
+		return 0, err
+	}
+	return int64(len(stale)), nil
+}
+
+// staleMemories returns the memories Consolidate would remove.
+func (s *Store) staleMemories(ctx context.Context) ([]Entry, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, category, content, tags, COALESCE(importance, 0.5),
+		       COALESCE(access_count, 0), created_at, accessed_at, COALESCE(scope, 'global')
+		FROM memories
 		WHERE category NOT IN ('core', 'lesson', 'correction')
 		  AND julianday('now') - julianday(accessed_at) > 30
 		  AND COALESCE(access_count, 0) < 2
 		  AND COALESCE(importance, 0.5) < 0.5
+		ORDER BY id
 	`)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
-	return result.RowsAffected()
+	defer rows.Close()
+	return scanEntriesFull(rows)
 }
 
 // Close closes the database connection.
@@ -385,7 +579,7 @@ func scanEntries(rows *sql.Rows) ([]Entry, error) {
 	var entries []Entry
 	for rows.Next() {
 		var e Entry
-		if err := rows.Scan(&e.ID, &e.Category, &e.Content, &e.Tags, &e.CreatedAt, &e.AccessedAt); err != nil {
+		if err := rows.Scan(&e.ID, &e.Category, &e.Content, &e.Tags, &e.CreatedAt, &e.AccessedAt, &e.Scope); err != nil {
 			continue
 		}
 		entries = append(entries, e)
@@ -393,13 +587,13 @@ func scanEntries(rows *sql.Rows) ([]Entry, error) {
 	return entries, nil
 }
 
-// scanEntriesFull scans rows with importance and access_count fields.
+// scanEntriesFull scans rows with importance, access_count and scope fields.
 func scanEntriesFull(rows *sql.Rows) ([]Entry, error) {
 	var entries []Entry
 	for rows.Next() {
 		var e Entry
 		if err := rows.Scan(&e.ID, &e.Category, &e.Content, &e.Tags, &e.Importance,
-			&e.AccessCount, &e.CreatedAt, &e.AccessedAt); err != nil {
+			&e.AccessCount, &e.CreatedAt, &e.AccessedAt, &e.Scope); err != nil {
 			continue
 		}
 		entries = append(entries, e)
@@ -407,15 +601,9 @@ func scanEntriesFull(rows *sql.Rows) ([]Entry, error) {
 	return entries, nil
 }
 
-func truncate(s string, maxLen int) string {
-	if len(s) <= maxLen {
-		return s
-	}
-	return s[:maxLen]
-}
-
 // BuildContextFromMemory retrieves relevant memories for the current query and formats them for the system prompt.
-// Core memories are ALWAYS included. Additional memories are keyword-matched from the query.
+// Core memories are ALWAYS included. Additional memories are matched by the query's keywords and, with an
+// embedder, its meaning.
 func (s *Store) BuildContextFromMemory(ctx context.Context, query string) string {
 	seen := map[int64]bool{}
 
@@ -425,11 +613,7 @@ func (s *Store) BuildContextFromMemory(ctx context.Context, query string) string
 	// Search for query-relevant memories if we have keywords
 	var relevantEntries []Entry
 	if query != "" {
-		keywords := extractKeywords(query)
-		if len(keywords) > 0 {
-			ftsQuery := strings.Join(keywords, " OR ")
-			relevantEntries, _ = s.Recall(ctx, ftsQuery, 5)
-		}
+		relevantEntries, _ = s.RecallText(ctx, query, 5)
 	}
 
 	// If no keywords matched and no core memories, load recent memories as fallback
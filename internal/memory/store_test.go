@@ -3,6 +3,7 @@ package memory
 import (
 	"context"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -108,11 +109,68 @@ func TestConversationHistory(t *testing.T) {
 	if len(history) != 2 {
 		t.Fatalf("expected 2 history entries, got %d", len(history))
 	}
-	if history[0]["role"] != "user" || history[0]["content"] != "hello" {
+	if history[0].Role != "user" || history[0].Content != "hello" {
 		t.Errorf("unexpected first entry: %v", history[0])
 	}
 }
 
+func TestSaveSummarySkipsFoldedHistory(t *testing.T) {
+	store := setupTestStore(t)
+	ctx := context.Background()
+
+	store.SaveSession(ctx, Session{ID: "s1", Channel: "telegram", ChatID: "1"})
+	for _, content := range []string{"one", "two", "three", "four"} {
+		store.SaveHistory(ctx, "s1", "user", content)
+	}
+	if err := store.SaveSummary(ctx, "s1", "talked about one and two", 2); err != nil {
+		t.Fatalf("save summary error: %v", err)
+	}
+
+	history, err := store.GetHistory(ctx, "s1", 10)
+	if err != nil {
+		t.Fatalf("get history error: %v", err)
+	}
+	if len(history) != 2 || history[0].Content != "three" {
+		t.Fatalf("expected only unsummarized entries, got %+v", history)
+	}
+
+	sess, err := store.GetChatSession(ctx, "telegram", "1")
+	if err != nil {
+		t.Fatalf("get chat session error: %v", err)
+	}
+	if sess.Summary != "talked about one and two" {
+		t.Errorf("unexpected summary: %q", sess.Summary)
+	}
+}
+
+func TestHistoryToolTranscript(t *testing.T) {
+	store := setupTestStore(t)
+	ctx := context.Background()
+
+	long := strings.Repeat("x", 5000)
+	store.AppendHistory(ctx, "s1", HistoryEntry{Role: "user", Content: "list files"})
+	store.AppendHistory(ctx, "s1", HistoryEntry{
+		Role:      "assistant",
+		ToolCalls: `[{"id":"call_1","name":"shell_exec","arguments":"{}"}]`,
+		Provider:  "anthropic",
+	})
+	store.AppendHistory(ctx, "s1", HistoryEntry{Role: "tool", Content: long, ToolCallID: "call_1"})
+
+	history, err := store.GetHistory(ctx, "s1", 10)
+	if err != nil {
+		t.Fatalf("get history error: %v", err)
+	}
+	if len(history) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(history))
+	}
+	if history[1].ToolCalls == "" || history[1].Provider != "anthropic" {
+		t.Errorf("assistant tool calls not persisted: %+v", history[1])
+	}
+	if history[2].ToolCallID != "call_1" || len(history[2].Content) != len(long) {
+		t.Errorf("tool result not persisted in full: id=%q len=%d", history[2].ToolCallID, len(history[2].Content))
+	}
+}
+
 func TestClearHistory(t *testing.T) {
 	store := setupTestStore(t)
 	ctx := context.Background()
@@ -200,3 +258,64 @@ func containsSubstr(s, substr string) bool {
 	}
 	return false
 }
+
+func TestChatSessions(t *testing.T) {
+	store := setupTestStore(t)
+	ctx := context.Background()
+
+	if _, err := store.GetChatSession(ctx, "telegram", "42"); err == nil {
+		t.Fatal("expected error for unknown chat")
+	}
+
+	store.SaveSession(ctx, Session{ID: "s1", Channel: "telegram", ChatID: "42"})
+	store.SaveSession(ctx, Session{ID: "s2", Channel: "slack", ChatID: "42"})
+	store.SaveSession(ctx, Session{ID: "s1", Channel: "telegram", ChatID: "42", Model: "gemini"})
+
+	sess, err := store.GetChatSession(ctx, "telegram", "42")
+	if err != nil {
+		t.Fatalf("get session error: %v", err)
+	}
+	if sess.ID != "s1" || sess.Model != "gemini" {
+		t.Errorf("unexpected session: %+v", sess)
+	}
+
+	// A newer session for the same chat takes precedence
+	store.SaveSession(ctx, Session{ID: "s3", Channel: "telegram", ChatID: "42"})
+	sess, _ = store.GetChatSession(ctx, "telegram", "42")
+	if sess.ID != "s3" {
+		t.Errorf("expected latest session s3, got %s", sess.ID)
+	}
+
+	// Clearing the session drops it, so the chat falls back to the previous one
+	store.ClearHistory(ctx, "s3")
+	sess, _ = store.GetChatSession(ctx, "telegram", "42")
+	if sess.ID != "s1" {
+		t.Errorf("expected s1 after clearing s3, got %s", sess.ID)
+	}
+}
+
+func TestUsageAggregation(t *testing.T) {
+	store := setupTestStore(t)
+	ctx := context.Background()
+
+	store.RecordUsage(ctx, Usage{Day: "2026-01-30", Provider: "anthropic", Model: "claude-sonnet-4", SessionID: "s1", InputTokens: 100, OutputTokens: 10, CostUSD: 0.5, Requests: 1})
+	store.RecordUsage(ctx, Usage{Day: "2026-02-01", Provider: "anthropic", Model: "claude-sonnet-4", SessionID: "s1", InputTokens: 100, OutputTokens: 10, CostUSD: 0.5, Requests: 1})
+	store.RecordUsage(ctx, Usage{Day: "2026-02-01", Provider: "anthropic", Model: "claude-sonnet-4", SessionID: "s1", InputTokens: 200, OutputTokens: 20, CostUSD: 1, Requests: 1})
+	store.RecordUsage(ctx, Usage{Day: "2026-02-02", Provider: "gemini", Model: "gemini-2.5-flash", SessionID: "s2", InputTokens: 50, OutputTokens: 5, CostUSD: 0.1, Requests: 1})
+
+	total, err := store.UsageSince(ctx, "2026-02-01")
+	if err != nil {
+		t.Fatalf("usage since error: %v", err)
+	}
+	if total.Tokens() != 385 || total.Requests != 3 {
+		t.Errorf("unexpected totals: %+v", total)
+	}
+
+	byModel, err := store.UsageByModelSince(ctx, "2026-02-01")
+	if err != nil {
+		t.Fatalf("usage by model error: %v", err)
+	}
+	if len(byModel) != 2 || byModel[0].Model != "claude-sonnet-4" || byModel[0].Requests != 2 || byModel[0].CostUSD != 1.5 {
+		t.Errorf("unexpected per-model usage: %+v", byModel)
+	}
+}
@@ -0,0 +1,324 @@
+package memory
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Export and import formats.
+const (
+	FormatJSONL    = "jsonl"
+	FormatMarkdown = "markdown"
+)
+
+// Record is a memory as exported: one JSON object per line in JSONL.
+// Imports ignore ID; zero timestamps mean the time of import.
+type Record struct {
+	ID         int64     `json:"id,omitempty"`
+	Category   Category  `json:"category"`
+	Content    string    `json:"content"`
+	Tags       string    `json:"tags,omitempty"`
+	Importance float64   `json:"importance,omitempty"`
+	Scope      Scope     `json:"scope,omitempty"`
+	CreatedAt  time.Time `json:"created_at,omitzero"`
+	AccessedAt time.Time `json:"accessed_at,omitzero"`
+}
+
+// ImportResult counts what an import did.
+type ImportResult struct {
+	Added      int
+	Duplicates int // already stored, or repeated in the input
+}
+
+// categoryOrder is the order categories appear in in Markdown exports.
+var categoryOrder = []Category{
+	CategoryCore, CategoryCorrection, CategoryLesson, CategoryDaily, CategoryConversation, CategoryCustom,
+}
+
+func validCategory(c Category) bool {
+	for _, known := range categoryOrder {
+		if c == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Export writes every memory visible from ctx, optionally only those in
+// category, as JSONL or Markdown. It returns how many were written.
+func (s *Store) Export(ctx context.Context, w io.Writer, format string, category Category) (int, error) {
+	records, err := s.exportRecords(ctx, category)
+	if err != nil {
+		return 0, err
+	}
+	switch format {
+	case FormatJSONL:
+		enc := json.NewEncoder(w)
+		for _, r := range records {
+			if err := enc.Encode(r); err != nil {
+				return 0, err
+			}
+		}
+	case FormatMarkdown:
+		if err := writeMarkdown(w, records); err != nil {
+			return 0, err
+		}
+	default:
+		return 0, fmt.Errorf("unknown format %q (jsonl or markdown)", format)
+	}
+	return len(records), nil
+}
+
+func (s *Store) exportRecords(ctx context.Context, category Category) ([]Record, error) {
+	scope, args := scopeFilter(ctx, "scope")
+	q := `
+		SELECT id, category, content, tags, COALESCE(importance, 0.5), COALESCE(scope, 'global'),
+		       created_at, accessed_at
+		FROM memories WHERE ` + scope
+	if category != "" {
+		q += " AND category = ?"
+		args = append(args, string(category))
+	}
+	rows, err := s.db.QueryContext(ctx, q+" ORDER BY id", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []Record
+	for rows.Next() {
+		var r Record
+		if err := rows.Scan(&r.ID, &r.Category, &r.Content, &r.Tags, &r.Importance, &r.Scope,
+			&r.CreatedAt, &r.AccessedAt); err != nil {
+			return nil, err
+		}
+		r.CreatedAt, r.AccessedAt = r.CreatedAt.UTC(), r.AccessedAt.UTC()
+		records = append(records, r)
+	}
+	return records, rows.Err()
+}
+
+// writeMarkdown groups memories by category. Each memory is a list item; a
+// last indented line in italics carries its tags, importance, scope and
+// timestamps, so the file reads well and imports back unchanged:
+//
+//	## core
+//
+//	- The team's database is Postgres 16
+//	  _tags: postgres; importance: 0.8; scope: global; created: 2026-10-01T09:00:00Z; accessed: 2026-10-14T16:20:00Z_
+func writeMarkdown(w io.Writer, records []Record) error {
+	byCategory := map[Category][]Record{}
+	var extra []Category
+	for _, r := range records {
+		if !validCategory(r.Category) && byCategory[r.Category] == nil {
+			extra = append(extra, r.Category)
+		}
+		byCategory[r.Category] = append(byCategory[r.Category], r)
+	}
+	sort.Slice(extra, func(i, j int) bool { return extra[i] < extra[j] })
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "# Aeon memories\n\n%d memories, exported %s.\n", len(records), time.Now().UTC().Format(time.RFC3339))
+	for _, category := range append(categoryOrder, extra...) {
+		if len(byCategory[category]) == 0 {
+			continue
+		}
+		fmt.Fprintf(bw, "\n## %s\n\n", category)
+		for _, r := range byCategory[category] {
+			lines := strings.Split(strings.TrimSpace(r.Content), "\n")
+			fmt.Fprintf(bw, "- %s\n", lines[0])
+			for _, line := range lines[1:] {
+				fmt.Fprintf(bw, "  %s\n", line)
+			}
+			meta := []string{}
+			if r.Tags != "" {
+				meta = append(meta, "tags: "+r.Tags)
+			}
+			meta = append(meta,
+				"importance: "+strconv.FormatFloat(r.Importance, 'f', -1, 64),
+				"scope: "+string(r.Scope),
+				"created: "+r.CreatedAt.Format(time.RFC3339),
+				"accessed: "+r.AccessedAt.Format(time.RFC3339))
+			fmt.Fprintf(bw, "  _%s_\n", strings.Join(meta, "; "))
+		}
+	}
+	return bw.Flush()
+}
+
+// Import reads memories in JSONL or Markdown and stores those not already
+// stored in the same scope. Content is compared ignoring case and spacing.
+// Markdown items without a metadata line take the defaults of their
+// category, so a hand-written list of facts under "## core" imports too.
+func (s *Store) Import(ctx context.Context, r io.Reader, format string) (ImportResult, error) {
+	var result ImportResult
+	var records []Record
+	var err error
+	switch format {
+	case FormatJSONL:
+		records, err = readJSONL(r)
+	case FormatMarkdown:
+		records, err = readMarkdown(r)
+	default:
+		return result, fmt.Errorf("unknown format %q (jsonl or markdown)", format)
+	}
+	if err != nil {
+		return result, err
+	}
+
+	for i := range records {
+		rec := &records[i]
+		if strings.TrimSpace(rec.Content) == "" {
+			return result, fmt.Errorf("memory %d: empty content", i+1)
+		}
+		if rec.Category == "" {
+			rec.Category = CategoryCustom
+		}
+		if !validCategory(rec.Category) {
+			return result, fmt.Errorf("memory %d: unknown category %q", i+1, rec.Category)
+		}
+		if rec.Importance < 0 || rec.Importance > 1 {
+			return result, fmt.Errorf("memory %d: importance %v is outside 0-1", i+1, rec.Importance)
+		}
+		if rec.Scope, err = ParseScope(string(rec.Scope)); err != nil {
+			return result, fmt.Errorf("memory %d: %w", i+1, err)
+		}
+	}
+
+	seen, err := s.contentKeys(ctx)
+	if err != nil {
+		return result, err
+	}
+	for _, rec := range records {
+		key := contentKey(rec.Scope, rec.Content)
+		if seen[key] {
+			result.Duplicates++
+			continue
+		}
+		if _, err := s.Save(ctx, Entry{
+			Category:   rec.Category,
+			Content:    strings.TrimSpace(rec.Content),
+			Tags:       rec.Tags,
+			Importance: rec.Importance,
+			Scope:      rec.Scope,
+			CreatedAt:  rec.CreatedAt,
+			AccessedAt: rec.AccessedAt,
+		}); err != nil {
+			return result, err
+		}
+		seen[key] = true
+		result.Added++
+	}
+	return result, nil
+}
+
+// contentKeys returns the dedup key of every stored memory.
+func (s *Store) contentKeys(ctx context.Context) (map[string]bool, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT COALESCE(scope, 'global'), content FROM memories")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	keys := map[string]bool{}
+	for rows.Next() {
+		var scope, content string
+		if err := rows.Scan(&scope, &content); err != nil {
+			return nil, err
+		}
+		keys[contentKey(Scope(scope), content)] = true
+	}
+	return keys, rows.Err()
+}
+
+func contentKey(scope Scope, content string) string {
+	return string(scope) + "\x00" + strings.ToLower(strings.Join(strings.Fields(content), " "))
+}
+
+func readJSONL(r io.Reader) ([]Record, error) {
+	var records []Record
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for line := 1; sc.Scan(); line++ {
+		text := strings.TrimSpace(sc.Text())
+		if text == "" {
+			continue
+		}
+		var rec Record
+		if err := json.Unmarshal([]byte(text), &rec); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		records = append(records, rec)
+	}
+	return records, sc.Err()
+}
+
+func readMarkdown(r io.Reader) ([]Record, error) {
+	var records []Record
+	var current *Record
+	category := CategoryCustom
+	flush := func() {
+		if current != nil {
+			records = append(records, *current)
+			current = nil
+		}
+	}
+
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for line := 1; sc.Scan(); line++ {
+		text := sc.Text()
+		switch {
+		case strings.HasPrefix(text, "## "):
+			flush()
+			category = Category(strings.ToLower(strings.TrimSpace(text[3:])))
+		case strings.HasPrefix(text, "- "):
+			flush()
+			current = &Record{Category: category, Content: strings.TrimSpace(text[2:])}
+		case current != nil && strings.HasPrefix(text, "  "):
+			body := strings.TrimSpace(text)
+			if strings.HasPrefix(body, "_") && strings.HasSuffix(body, "_") && strings.Contains(body, "importance: ") {
+				if err := parseMarkdownMeta(current, body[1:len(body)-1]); err != nil {
+					return nil, fmt.Errorf("line %d: %w", line, err)
+				}
+				continue
+			}
+			current.Content += "\n" + text[2:]
+		default:
+			flush()
+		}
+	}
+	flush()
+	return records, sc.Err()
+}
+
+func parseMarkdownMeta(r *Record, meta string) error {
+	for _, field := range strings.Split(meta, "; ") {
+		key, value, ok := strings.Cut(field, ": ")
+		if !ok {
+			return fmt.Errorf("bad metadata %q", field)
+		}
+		var err error
+		switch key {
+		case "tags":
+			r.Tags = value
+		case "importance":
+			r.Importance, err = strconv.ParseFloat(value, 64)
+		case "scope":
+			r.Scope = Scope(value)
+		case "created":
+			r.CreatedAt, err = time.Parse(time.RFC3339, value)
+		case "accessed":
+			r.AccessedAt, err = time.Parse(time.RFC3339, value)
+		}
+		if err != nil {
+			return fmt.Errorf("bad %s %q", key, value)
+		}
+	}
+	return nil
+}
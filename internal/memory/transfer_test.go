@@ -0,0 +1,137 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func seedExport(t *testing.T, s *Store) {
+	t.Helper()
+	ctx := context.Background()
+	created := time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)
+	for _, e := range []Entry{
+		{Category: CategoryCore, Content: "The team's database is Postgres 16", Tags: "postgres,db", Importance: 0.9, CreatedAt: created, AccessedAt: created},
+		{Category: CategoryLesson, Content: "Run migrations before deploying\nthe worker", Tags: "deploy", Scope: UserScope("slack", "U1"), CreatedAt: created},
+		{Category: CategoryDaily, Content: "Standup moved to 10:00"},
+	} {
+		if _, err := s.Save(ctx, e); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	for _, format := range []string{FormatJSONL, FormatMarkdown} {
+		t.Run(format, func(t *testing.T) {
+			src := setupTestStore(t)
+			seedExport(t, src)
+			ctx := context.Background()
+
+			var buf bytes.Buffer
+			n, err := src.Export(ctx, &buf, format, "")
+			if err != nil || n != 3 {
+				t.Fatalf("exported %d (%v)", n, err)
+			}
+
+			dst := setupTestStore(t)
+			result, err := dst.Import(ctx, bytes.NewReader(buf.Bytes()), format)
+			if err != nil || result.Added != 3 || result.Duplicates != 0 {
+				t.Fatalf("import: %+v (%v)\n%s", result, err, buf.String())
+			}
+
+			want, _ := src.exportRecords(ctx, "")
+			got, _ := dst.exportRecords(ctx, "")
+			for i := range want {
+				w, g := want[i], got[i]
+				if g.Category != w.Category || g.Content != w.Content || g.Tags != w.Tags ||
+					g.Importance != w.Importance || g.Scope != w.Scope ||
+					!g.CreatedAt.Equal(w.CreatedAt) || !g.AccessedAt.Equal(w.AccessedAt) {
+					t.Errorf("memory %d changed:\nwant %+v\ngot  %+v", i, w, g)
+				}
+			}
+
+			// Importing again adds nothing
+			result, err = dst.Import(ctx, bytes.NewReader(buf.Bytes()), format)
+			if err != nil || result.Added != 0 || result.Duplicates != 3 {
+				t.Errorf("expected only duplicates, got %+v (%v)", result, err)
+			}
+		})
+	}
+}
+
+func TestImportDeduplicates(t *testing.T) {
+	store := setupTestStore(t)
+	ctx := context.Background()
+	store.Save(ctx, Entry{Category: CategoryCore, Content: "Deploys happen on Fridays"})
+
+	input := `# Team memory
+
+## core
+
+- deploys  happen on FRIDAYS
+- The on-call rotation changes on Mondays
+- The on-call rotation changes on Mondays
+
+## lesson
+
+- Never restart the primary during business hours
+  _tags: db; importance: 0.95; scope: chat:slack:C1; created: 2026-01-02T03:04:05Z; accessed: 2026-01-02T03:04:05Z_
+`
+	result, err := store.Import(ctx, strings.NewReader(input), FormatMarkdown)
+	if err != nil || result.Added != 2 || result.Duplicates != 2 {
+		t.Fatalf("expected 2 added and 2 duplicates, got %+v (%v)", result, err)
+	}
+
+	lessons, _ := store.List(ctx, CategoryLesson, 5)
+	if len(lessons) != 1 || lessons[0].Scope != "chat:slack:C1" || lessons[0].Tags != "db" {
+		t.Fatalf("unexpected lessons %+v", lessons)
+	}
+	if e, _ := store.Get(ctx, lessons[0].ID); e.Importance != 0.95 || e.CreatedAt.Year() != 2026 || e.CreatedAt.Month() != time.January {
+		t.Errorf("metadata not kept: %+v", e)
+	}
+	core, _ := store.List(ctx, CategoryCore, 5)
+	if len(core) != 2 {
+		t.Errorf("expected 2 core memories, got %+v", core)
+	}
+}
+
+func TestImportRejectsBadRecords(t *testing.T) {
+	store := setupTestStore(t)
+	ctx := context.Background()
+
+	for name, input := range map[string]string{
+		"json":       `{"category": "core", "content": "ok"}` + "\n{not json",
+		"category":   `{"category": "secret", "content": "x"}`,
+		"scope":      `{"category": "core", "content": "x", "scope": "user:U1"}`,
+		"importance": `{"category": "core", "content": "x", "importance": 5}`,
+		"empty":      `{"category": "core", "content": "  "}`,
+	} {
+		if _, err := store.Import(ctx, strings.NewReader(input), FormatJSONL); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	if n, _ := store.Count(ctx); n != 0 {
+		t.Errorf("a rejected import stored %d memories", n)
+	}
+}
+
+func TestStats(t *testing.T) {
+	store := setupTestStore(t)
+	seedExport(t, store)
+	ctx := context.Background()
+	store.Update(ctx, 1, Change{Content: "The team's database is Postgres 17"})
+
+	st, err := store.Stats(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Total != 3 || st.ByCategory[CategoryCore] != 1 || st.ByScope[KindUser] != 1 || st.ByScope[KindGlobal] != 2 {
+		t.Errorf("unexpected counts %+v", st)
+	}
+	if st.Versions != 1 || st.Oldest.Year() != 2026 || st.Oldest.Month() != time.March {
+		t.Errorf("unexpected history %+v", st)
+	}
+}
@@ -0,0 +1,70 @@
+package memory
+
+import "context"
+
+// Usage is token usage and cost, aggregated per day, provider, model and session.
+type Usage struct {
+	Day          string // YYYY-MM-DD in local time
+	Provider     string
+	Model        string
+	SessionID    string
+	InputTokens  int
+	OutputTokens int
+	CostUSD      float64
+	Requests     int
+}
+
+// Tokens returns input plus output tokens.
+func (u Usage) Tokens() int {
+	return u.InputTokens + u.OutputTokens
+}
+
+// RecordUsage adds u to the running totals for its day, provider, model and session.
+func (s *Store) RecordUsage(_ context.Context, u Usage) error {
+	_, err := s.db.Exec(`
+		INSERT INTO token_usage (day, provider, model, session_id, input_tokens, output_tokens, cost_usd, requests)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(day, provider, model, session_id) DO UPDATE SET
+			input_tokens = input_tokens + excluded.input_tokens,
+			output_tokens = output_tokens + excluded.output_tokens,
+			cost_usd = cost_usd + excluded.cost_usd,
+			requests = requests + excluded.requests
+	`, u.Day, u.Provider, u.Model, u.SessionID, u.InputTokens, u.OutputTokens, u.CostUSD, u.Requests)
+	return err
+}
+
+// UsageSince returns total usage from day (YYYY-MM-DD) onwards.
+func (s *Store) UsageSince(_ context.Context, day string) (Usage, error) {
+	var u Usage
+	err := s.db.QueryRow(`
+		SELECT COALESCE(SUM(input_tokens), 0), COALESCE(SUM(output_tokens), 0),
+			COALESCE(SUM(cost_usd), 0), COALESCE(SUM(requests), 0)
+		FROM token_usage WHERE day >= ?
+	`, day).Scan(&u.InputTokens, &u.OutputTokens, &u.CostUSD, &u.Requests)
+	return u, err
+}
+
+// UsageByModelSince returns usage from day onwards grouped by provider and
+// model, most expensive first.
+func (s *Store) UsageByModelSince(_ context.Context, day string) ([]Usage, error) {
+	rows, err := s.db.Query(`
+		SELECT provider, model, SUM(input_tokens), SUM(output_tokens), SUM(cost_usd), SUM(requests)
+		FROM token_usage WHERE day >= ?
+		GROUP BY provider, model
+		ORDER BY SUM(cost_usd) DESC, SUM(input_tokens + output_tokens) DESC
+	`, day)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Usage
+	for rows.Next() {
+		var u Usage
+		if err := rows.Scan(&u.Provider, &u.Model, &u.InputTokens, &u.OutputTokens, &u.CostUSD, &u.Requests); err != nil {
+			return nil, err
+		}
+		out = append(out, u)
+	}
+	return out, rows.Err()
+}
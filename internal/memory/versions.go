@@ -0,0 +1,285 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// maxSourceMessage is how much of the originating message is kept.
+const maxSourceMessage = 500
+
+// Provenance records where a memory came from.
+type Provenance struct {
+	Session string // conversation session ID
+	Channel string
+	UserID  string
+	Message string // the message that led to it, truncated
+}
+
+// Version is an earlier state of a memory, kept when it was edited or superseded.
+type Version struct {
+	MemoryID   int64
+	Version    int
+	Category   Category
+	Content    string
+	Tags       string
+	Importance float64
+	Source     Provenance
+	CreatedAt  time.Time // when this version was written
+	ReplacedAt time.Time
+	ReplacedBy int64 // the memory that superseded it, 0 if edited in place
+}
+
+// Change is an edit to a memory. Empty fields keep their current value.
+type Change struct {
+	Category   Category
+	Content    string
+	Tags       string
+	Importance float64
+	Source     Provenance // who made the edit
+}
+
+func initVersionsSchema(db *sql.DB) error {
+	for col, def := range map[string]string{
+		"version":        "INTEGER DEFAULT 1",
+		"supersedes":     "INTEGER DEFAULT 0",
+		"updated_at":     "DATETIME",
+		"source_session": "TEXT DEFAULT ''",
+		"source_channel": "TEXT DEFAULT ''",
+		"source_user":    "TEXT DEFAULT ''",
+		"source_message": "TEXT DEFAULT ''",
+	} {
+		if err := addColumnIfMissing(db, "memories", col, def); err != nil {
+			return err
+		}
+	}
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS memory_versions (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			memory_id INTEGER NOT NULL,
+			version INTEGER NOT NULL,
+			category TEXT NOT NULL,
+			content TEXT NOT NULL,
+			tags TEXT DEFAULT '',
+			importance REAL DEFAULT 0.5,
+			supersedes INTEGER DEFAULT 0,
+			source_session TEXT DEFAULT '',
+			source_channel TEXT DEFAULT '',
+			source_user TEXT DEFAULT '',
+			source_message TEXT DEFAULT '',
+			created_at DATETIME,
+			replaced_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			replaced_by INTEGER DEFAULT 0
+		);
+		CREATE INDEX IF NOT EXISTS idx_memory_versions ON memory_versions(memory_id, version);
+	`)
+	return err
+}
+
+// Save stores a new memory with its provenance. Zero timestamps mean now;
+// imports set them to keep a memory's age. If e.Supersedes is set, the
+// memory it names is retired into the version history and stops being
+// recalled, so a correction replaces the stale fact instead of sitting
+// beside it. The correction takes the scope of the memory it replaces, so
+// fixing a shared memory doesn't turn it into one user's private copy. It
+// returns the new memory's ID.
+func (s *Store) Save(ctx context.Context, e Entry) (int64, error) {
+	if e.Importance <= 0 {
+		e.Importance = defaultImportance(e.Category)
+	}
+	if e.Category == "" {
+		e.Category = CategoryCustom
+	}
+	if e.Scope == "" {
+		e.Scope = ScopeGlobal
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if e.Supersedes > 0 {
+		var scope string
+		err := tx.QueryRow("SELECT COALESCE(scope, '') FROM memories WHERE id = ?", e.Supersedes).Scan(&scope)
+		if err != nil {
+			return 0, fmt.Errorf("memory %d not found", e.Supersedes)
+		}
+		e.Scope = Scope(scope)
+		if e.Scope == "" {
+			e.Scope = ScopeGlobal
+		}
+	}
+
+	res, err := tx.Exec(`
+		INSERT INTO memories (category, content, tags, importance, supersedes, scope,
+		                      source_session, source_channel, source_user, source_message,
+		                      access_count, created_at, accessed_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, COALESCE(?, CURRENT_TIMESTAMP), COALESCE(?, CURRENT_TIMESTAMP))
+	`, string(e.Category), e.Content, e.Tags, e.Importance, e.Supersedes, string(e.Scope),
+		e.Source.Session, e.Source.Channel, e.Source.UserID, clipMessage(e.Source.Message),
+		e.AccessCount, sqlTime(e.CreatedAt), sqlTime(e.AccessedAt))
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	if e.Supersedes > 0 {
+		if err := snapshot(tx, e.Supersedes, id); err != nil {
+			return 0, err
+		}
+		if _, err := tx.Exec("DELETE FROM memories WHERE id = ?", e.Supersedes); err != nil {
+			return 0, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	s.embedMemory(ctx, id, e.Content, e.Tags)
+	return id, nil
+}
+
+// Update edits a memory in place, keeping the previous version in its
+// history. It returns the new version number.
+func (s *Store) Update(ctx context.Context, id int64, c Change) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if err := snapshot(tx, id, 0); err != nil {
+		return 0, err
+	}
+	_, err = tx.Exec(`
+		UPDATE memories SET
+			category = COALESCE(NULLIF(?, ''), category),
+			content = COALESCE(NULLIF(?, ''), content),
+			tags = COALESCE(NULLIF(?, ''), tags),
+			importance = CASE WHEN ? > 0 THEN ? ELSE importance END,
+			version = COALESCE(version, 1) + 1,
+			updated_at = CURRENT_TIMESTAMP,
+			source_session = ?, source_channel = ?, source_user = ?, source_message = ?
+		WHERE id = ?
+	`, string(c.Category), c.Content, c.Tags, c.Importance, c.Importance,
+		c.Source.Session, c.Source.Channel, c.Source.UserID, clipMessage(c.Source.Message), id)
+	if err != nil {
+		return 0, err
+	}
+
+	var version int
+	var content, tags string
+	if err := tx.QueryRow("SELECT version, content, tags FROM memories WHERE id = ?", id).Scan(&version, &content, &tags); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	if c.Content != "" || c.Tags != "" {
+		s.embedMemory(ctx, id, content, tags)
+	}
+	return version, nil
+}
+
+// snapshot copies a memory's current state into memory_versions.
+func snapshot(tx *sql.Tx, id, replacedBy int64) error {
+	res, err := tx.Exec(`
+		INSERT INTO memory_versions (memory_id, version, category, content, tags, importance, supersedes,
+		                             source_session, source_channel, source_user, source_message,
+		                             created_at, replaced_by)
+		SELECT id, COALESCE(version, 1), category, content, tags, COALESCE(importance, 0.5),
+		       COALESCE(supersedes, 0), COALESCE(source_session, ''), COALESCE(source_channel, ''), COALESCE(source_user, ''),
+		       COALESCE(source_message, ''), COALESCE(updated_at, created_at), ?
+		FROM memories WHERE id = ?
+	`, replacedBy, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("memory %d not found", id)
+	}
+	return nil
+}
+
+// Versions returns the earlier versions of a memory, oldest first. It
+// follows supersedes links, so a correction's history includes the memories
+// it replaced.
+func (s *Store) Versions(ctx context.Context, id int64) ([]Version, error) {
+	var chain []int64
+	seen := map[int64]bool{}
+	for cur := id; cur > 0 && !seen[cur]; {
+		seen[cur] = true
+		chain = append(chain, cur)
+		// The link lives on the memory, or on its last version once it was superseded itself
+		var next int64
+		err := s.db.QueryRowContext(ctx, `
+			SELECT COALESCE(supersedes, 0) FROM memories WHERE id = ?
+			UNION ALL
+			SELECT * FROM (SELECT supersedes FROM memory_versions WHERE memory_id = ? ORDER BY id DESC LIMIT 1)
+			LIMIT 1
+		`, cur, cur).Scan(&next)
+		if err == sql.ErrNoRows {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		cur = next
+	}
+
+	var versions []Version
+	for i := len(chain) - 1; i >= 0; i-- {
+		rows, err := s.db.QueryContext(ctx, `
+			SELECT memory_id, version, category, content, tags, importance,
+			       source_session, source_channel, source_user, source_message,
+			       created_at, replaced_at, replaced_by
+			FROM memory_versions WHERE memory_id = ? ORDER BY version, id
+		`, chain[i])
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var v Version
+			var created sql.NullTime
+			if err := rows.Scan(&v.MemoryID, &v.Version, &v.Category, &v.Content, &v.Tags, &v.Importance,
+				&v.Source.Session, &v.Source.Channel, &v.Source.UserID, &v.Source.Message,
+				&created, &v.ReplacedAt, &v.ReplacedBy); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			v.CreatedAt = created.Time
+			versions = append(versions, v)
+		}
+		rows.Close()
+	}
+	return versions, nil
+}
+
+// sqlTime formats t for a DATETIME column, or returns nil for the zero time.
+func sqlTime(t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+	return t.UTC().Format(time.DateTime)
+}
+
+// clipMessage keeps the start of an originating message.
+func clipMessage(msg string) string {
+	msg = strings.TrimSpace(msg)
+	if len(msg) <= maxSourceMessage {
+		return msg
+	}
+	cut := maxSourceMessage
+	for cut > 0 && !utf8.RuneStart(msg[cut]) {
+		cut--
+	}
+	return msg[:cut] + "..."
+}
@@ -0,0 +1,95 @@
+package memory
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestUpdateKeepsHistory(t *testing.T) {
+	store := setupTestStore(t)
+	ctx := context.Background()
+
+	id, err := store.Save(ctx, Entry{
+		Category: CategoryCore,
+		Content:  "Deploys go out on Fridays",
+		Tags:     "deploy",
+		Source:   Provenance{Session: "s1", Channel: "telegram", UserID: "42", Message: "we deploy on fridays"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := store.Update(ctx, id, Change{Content: "Deploys go out on Thursdays", Source: Provenance{Session: "s2", Channel: "cli"}})
+	if err != nil || v != 2 {
+		t.Fatalf("expected version 2, got %d (%v)", v, err)
+	}
+	e, _ := store.Get(ctx, id)
+	if e.Content != "Deploys go out on Thursdays" || e.Tags != "deploy" || e.Version != 2 || e.Source.Channel != "cli" {
+		t.Fatalf("unexpected updated memory %+v", e)
+	}
+	if entries, _ := store.Recall(ctx, "Thursdays", 5); len(entries) != 1 {
+		t.Errorf("updated content not searchable: %+v", entries)
+	}
+
+	versions, err := store.Versions(ctx, id)
+	if err != nil || len(versions) != 1 {
+		t.Fatalf("expected one earlier version, got %+v (%v)", versions, err)
+	}
+	old := versions[0]
+	if old.Version != 1 || old.Content != "Deploys go out on Fridays" || old.Source.UserID != "42" || old.ReplacedBy != 0 {
+		t.Errorf("unexpected version %+v", old)
+	}
+
+	if _, err := store.Update(ctx, 999, Change{Content: "x"}); err == nil {
+		t.Error("expected an error updating a missing memory")
+	}
+}
+
+func TestSaveSupersedes(t *testing.T) {
+	store := setupTestStore(t)
+	ctx := context.Background()
+
+	first, _ := store.Save(ctx, Entry{Category: CategoryCore, Content: "Editor is Vim"})
+	second, err := store.Save(ctx, Entry{Category: CategoryCorrection, Content: "Editor is Helix", Supersedes: first})
+	if err != nil {
+		t.Fatal(err)
+	}
+	third, err := store.Save(ctx, Entry{Category: CategoryCorrection, Content: "Editor is Zed", Supersedes: second})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := store.Get(ctx, first); err == nil {
+		t.Error("superseded memory should no longer be recalled")
+	}
+	if e, _ := store.Get(ctx, third); e.Supersedes != second {
+		t.Errorf("expected a supersedes link to %d, got %+v", second, e)
+	}
+
+	versions, err := store.Versions(ctx, third)
+	if err != nil || len(versions) != 2 {
+		t.Fatalf("expected the whole chain, got %+v (%v)", versions, err)
+	}
+	if !strings.Contains(versions[0].Content, "Vim") || versions[0].ReplacedBy != second {
+		t.Errorf("unexpected oldest version %+v", versions[0])
+	}
+	if !strings.Contains(versions[1].Content, "Helix") || versions[1].ReplacedBy != third {
+		t.Errorf("unexpected second version %+v", versions[1])
+	}
+
+	if _, err := store.Save(ctx, Entry{Content: "orphan", Supersedes: 999}); err == nil {
+		t.Error("expected an error superseding a missing memory")
+	}
+}
+
+func TestClipMessage(t *testing.T) {
+	long := strings.Repeat("é", maxSourceMessage)
+	clipped := clipMessage(long)
+	if len(clipped) > maxSourceMessage+3 || !strings.HasSuffix(clipped, "...") {
+		t.Errorf("unexpected clip length %d", len(clipped))
+	}
+	if !strings.HasPrefix(long, strings.TrimSuffix(clipped, "...")) {
+		t.Error("clip split a rune")
+	}
+}
@@ -7,16 +7,18 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"time"
+	"strings"
 )
 
 const anthropicAPIURL = "https://api.anthropic.com/v1/messages"
 const anthropicAPIVersion = "2023-06-01"
 
 type AnthropicProvider struct {
-	apiKey string
-	model  string
-	client *http.Client
+	apiKey        string
+	model         string
+	apiURL        string
+	contextWindow int // 0 = derive from model name
+	client        *http.Client
 }
 
 func NewAnthropic(apiKey, model string) *AnthropicProvider {
@@ -26,7 +28,8 @@ func NewAnthropic(apiKey, model string) *AnthropicProvider {
 	return &AnthropicProvider{
 		apiKey: apiKey,
 		model:  model,
-		client: &http.Client{Timeout: 120 * time.Second},
+		apiURL: anthropicAPIURL,
+		client: newHTTPClient(),
 	}
 }
 
@@ -35,13 +38,16 @@ func (p *AnthropicProvider) Available() bool   { return p.apiKey != "" }
 
 func (p *AnthropicProvider) Complete(ctx context.Context, req CompletionRequest) (CompletionResponse, error) {
 	body := p.buildRequest(req)
+	body.Stream = req.OnStream != nil
 
 	jsonBody, err := json.Marshal(body)
 	if err != nil {
 		return CompletionResponse{}, fmt.Errorf("marshaling request: %w", err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", anthropicAPIURL, bytes.NewReader(jsonBody))
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.apiURL, bytes.NewReader(jsonBody))
 	if err != nil {
 		return CompletionResponse{}, fmt.Errorf("creating request: %w", err)
 	}
@@ -55,8 +61,14 @@ func (p *AnthropicProvider) Complete(ctx context.Context, req CompletionRequest)
 		return CompletionResponse{}, fmt.Errorf("API request: %w", err)
 	}
 	defer resp.Body.Close()
+	stream := newIdleReader(resp.Body, cancel)
+	defer stream.Stop()
 
-	respBody, err := io.ReadAll(resp.Body)
+	if resp.StatusCode == http.StatusOK && body.Stream {
+		return p.parseStream(stream, req.OnStream)
+	}
+
+	respBody, err := io.ReadAll(stream)
 	if err != nil {
 		return CompletionResponse{}, fmt.Errorf("reading response: %w", err)
 	}
@@ -74,6 +86,7 @@ type anthropicRequest struct {
 	System    string              `json:"system,omitempty"`
 	Messages  []anthropicMessage  `json:"messages"`
 	Tools     []anthropicTool     `json:"tools,omitempty"`
+	Stream    bool                `json:"stream,omitempty"`
 }
 
 type anthropicMessage struct {
@@ -189,6 +202,7 @@ func (p *AnthropicProvider) parseResponse(body []byte) (CompletionResponse, erro
 
 	var result CompletionResponse
 	result.Provider = p.Name()
+	result.Model = p.model
 	result.Usage = TokenUsage{
 		InputTokens:  resp.Usage.InputTokens,
 		OutputTokens: resp.Usage.OutputTokens,
@@ -211,6 +225,111 @@ func (p *AnthropicProvider) parseResponse(body []byte) (CompletionResponse, erro
 	return result, nil
 }
 
+// parseStream assembles a response from Anthropic's SSE stream, forwarding
+// text deltas and tool call starts to onStream as they arrive.
+func (p *AnthropicProvider) parseStream(body io.Reader, onStream func(StreamEvent)) (CompletionResponse, error) {
+	result := CompletionResponse{Provider: p.Name(), Model: p.model}
+
+	type toolBlock struct {
+		id, name string
+		args     strings.Builder
+	}
+	var text strings.Builder
+	tools := make(map[int]*toolBlock)
+	var order []int
+	done := false
+
+	err := readSSE(body, func(event, data string) error {
+		switch event {
+		case "message_stop":
+			done = true
+
+		case "message_start":
+			var ev struct {
+				Message anthropicResponse `json:"message"`
+			}
+			if err := json.Unmarshal([]byte(data), &ev); err == nil {
+				result.Usage.InputTokens = ev.Message.Usage.InputTokens
+				result.Usage.OutputTokens = ev.Message.Usage.OutputTokens
+			}
+
+		case "content_block_start":
+			var ev struct {
+				Index        int                   `json:"index"`
+				ContentBlock anthropicContentBlock `json:"content_block"`
+			}
+			if err := json.Unmarshal([]byte(data), &ev); err != nil {
+				return fmt.Errorf("parsing stream event: %w", err)
+			}
+			if ev.ContentBlock.Type == "tool_use" {
+				tools[ev.Index] = &toolBlock{id: ev.ContentBlock.ID, name: ev.ContentBlock.Name}
+				order = append(order, ev.Index)
+				onStream(StreamEvent{ToolName: ev.ContentBlock.Name})
+			}
+
+		case "content_block_delta":
+			var ev struct {
+				Index int `json:"index"`
+				Delta struct {
+					Type        string `json:"type"`
+					Text        string `json:"text"`
+					PartialJSON string `json:"partial_json"`
+				} `json:"delta"`
+			}
+			if err := json.Unmarshal([]byte(data), &ev); err != nil {
+				return fmt.Errorf("parsing stream event: %w", err)
+			}
+			switch ev.Delta.Type {
+			case "text_delta":
+				text.WriteString(ev.Delta.Text)
+				onStream(StreamEvent{Text: ev.Delta.Text})
+			case "input_json_delta":
+				if tb, ok := tools[ev.Index]; ok {
+					tb.args.WriteString(ev.Delta.PartialJSON)
+				}
+			}
+
+		case "message_delta":
+			var ev struct {
+				Usage struct {
+					OutputTokens int `json:"output_tokens"`
+				} `json:"usage"`
+			}
+			if err := json.Unmarshal([]byte(data), &ev); err == nil && ev.Usage.OutputTokens > 0 {
+				result.Usage.OutputTokens = ev.Usage.OutputTokens
+			}
+
+		case "error":
+			var ev struct {
+				Error struct {
+					Type    string `json:"type"`
+					Message string `json:"message"`
+				} `json:"error"`
+			}
+			json.Unmarshal([]byte(data), &ev)
+			return fmt.Errorf("API stream error: %s: %s", ev.Error.Type, ev.Error.Message)
+		}
+		return nil
+	})
+	if err != nil {
+		return CompletionResponse{}, err
+	}
+	if !done {
+		return CompletionResponse{}, errIncompleteStream
+	}
+
+	result.Content = text.String()
+	for _, idx := range order {
+		tb := tools[idx]
+		args := tb.args.String()
+		if args == "" {
+			args = "{}"
+		}
+		result.ToolCalls = append(result.ToolCalls, ToolCall{ID: tb.id, Name: tb.name, Arguments: args})
+	}
+	return result, nil
+}
+
 // sanitizeToolMessages removes tool-result messages whose tool_use_id
 // doesn't appear in a preceding assistant message's ToolCalls. This
 // prevents Anthropic API errors when history trimming or provider
@@ -113,9 +113,14 @@ func (c *ProviderChain) Complete(ctx context.Context, req CompletionRequest) (Co
 		lastErr = err
 
 		// Notify about failover if a next candidate exists
-		if c.onRetry != nil && i+1 < len(candidates) {
-			nextName := candidates[i+1].Name()
-			c.onRetry(name, nextName)
+		if i+1 < len(candidates) {
+			if c.onRetry != nil {
+				c.onRetry(name, candidates[i+1].Name())
+			}
+			// Partial output from the failed provider is stale now
+			if req.OnStream != nil {
+				req.OnStream(StreamEvent{Reset: true})
+			}
 		}
 	}
 
@@ -172,6 +177,27 @@ func (c *ProviderChain) SwitchTo(name string) error {
 	return fmt.Errorf("unknown provider %q, available: %v", name, c.AvailableNames())
 }
 
+// WithPrimary returns a copy of the chain that uses the named provider as primary.
+// The copy shares cooldown state with the original, so failures are tracked globally.
+func (c *ProviderChain) WithPrimary(name string) (*ProviderChain, error) {
+	p, ok := c.all[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown provider %q, available: %v", name, c.AvailableNames())
+	}
+	cp := *c
+	cp.primary = p
+	cp.onRetry = nil
+	return &cp, nil
+}
+
+// WithRetryCallback returns a copy of the chain that calls fn on failover.
+// Use this instead of SetRetryCallback when the chain is shared by concurrent turns.
+func (c *ProviderChain) WithRetryCallback(fn func(failed, next string)) *ProviderChain {
+	cp := *c
+	cp.onRetry = fn
+	return &cp
+}
+
 // AvailableNames returns the names of all configured providers.
 func (c *ProviderChain) AvailableNames() []string {
 	var names []string
@@ -91,3 +91,27 @@ func TestChainNoProvider(t *testing.T) {
 		t.Fatal("expected error with no providers")
 	}
 }
+
+func TestChainWithPrimary(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	primary := &mockProvider{name: "primary", available: true}
+	other := &mockProvider{name: "other", available: true}
+
+	chain := NewChain(ChainConfig{Primary: primary}, logger)
+	chain.SetAll(map[string]Provider{"primary": primary, "other": other})
+
+	switched, err := chain.WithPrimary("other")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if switched.PrimaryName() != "other" {
+		t.Errorf("expected switched primary 'other', got %q", switched.PrimaryName())
+	}
+	if chain.PrimaryName() != "primary" {
+		t.Errorf("original chain must keep its primary, got %q", chain.PrimaryName())
+	}
+
+	if _, err := chain.WithPrimary("missing"); err == nil {
+		t.Error("expected error for unknown provider")
+	}
+}
@@ -0,0 +1,94 @@
+package providers
+
+import "strings"
+
+// defaultContextWindow is assumed for models we know nothing about.
+const defaultContextWindow = 32000
+
+// contextWindows maps model name prefixes to context window sizes in tokens.
+// More specific prefixes come first.
+var contextWindows = []struct {
+	prefix string
+	tokens int
+}{
+	{"claude", 200000},
+	{"gpt-4.1", 1000000},
+	{"gpt-4o", 128000},
+	{"gpt-5", 400000},
+	{"o1", 200000},
+	{"o3", 200000},
+	{"o4", 200000},
+	{"gemini", 1000000},
+	{"glm-4.6", 200000},
+	{"glm-4.7", 200000},
+	{"glm", 128000},
+	{"llama3", 128000},
+	{"qwen", 32000},
+	{"mistral", 32000},
+}
+
+// lookupContextWindow returns the known context window for a model name, or the default.
+func lookupContextWindow(model string) int {
+	model = strings.ToLower(model)
+	// Strip vendor prefixes like "anthropic/claude-..." (OpenRouter style)
+	if i := strings.LastIndex(model, "/"); i >= 0 {
+		model = model[i+1:]
+	}
+	for _, cw := range contextWindows {
+		if strings.HasPrefix(model, cw.prefix) {
+			return cw.tokens
+		}
+	}
+	return defaultContextWindow
+}
+
+// ContextWindow returns the context window of the model behind p, in tokens.
+func ContextWindow(p Provider) int {
+	if cw, ok := p.(interface{ ContextWindow() int }); ok {
+		if n := cw.ContextWindow(); n > 0 {
+			return n
+		}
+	}
+	return defaultContextWindow
+}
+
+func (p *AnthropicProvider) ContextWindow() int {
+	if p.contextWindow > 0 {
+		return p.contextWindow
+	}
+	return lookupContextWindow(p.model)
+}
+
+// SetContextWindow overrides the context window derived from the model name.
+func (p *AnthropicProvider) SetContextWindow(tokens int) {
+	p.contextWindow = tokens
+}
+
+func (p *OpenAICompatProvider) ContextWindow() int {
+	if p.contextWindow > 0 {
+		return p.contextWindow
+	}
+	return lookupContextWindow(p.model)
+}
+
+// SetContextWindow overrides the context window derived from the model name.
+func (p *OpenAICompatProvider) SetContextWindow(tokens int) {
+	p.contextWindow = tokens
+}
+
+func (p *ClaudeCLIProvider) ContextWindow() int { return 200000 }
+
+// ContextWindow returns the smaller of the primary and fallback windows, so a
+// prompt sized for the chain still fits after a failover.
+func (c *ProviderChain) ContextWindow() int {
+	if c.primary == nil {
+		return defaultContextWindow
+	}
+	n := ContextWindow(c.primary)
+	if c.fallback != nil && c.fallback != c.primary {
+		if fb := ContextWindow(c.fallback); fb < n {
+			n = fb
+		}
+	}
+	return n
+}
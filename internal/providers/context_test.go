@@ -0,0 +1,39 @@
+package providers
+
+import (
+	"log/slog"
+	"os"
+	"testing"
+)
+
+func TestLookupContextWindow(t *testing.T) {
+	cases := map[string]int{
+		"claude-sonnet-4-20250514":   200000,
+		"anthropic/claude-3.5-haiku": 200000,
+		"gpt-4o-mini":                128000,
+		"glm-4.7":                    200000,
+		"glm-4-flash":                128000,
+		"some-local-model":           defaultContextWindow,
+	}
+	for model, want := range cases {
+		if got := lookupContextWindow(model); got != want {
+			t.Errorf("lookupContextWindow(%q) = %d, want %d", model, got, want)
+		}
+	}
+}
+
+func TestChainContextWindowUsesSmallest(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	primary := NewAnthropic("key", "claude-sonnet-4-20250514")
+	fallback := NewOpenAICompat("http://localhost:11434/v1", "", "qwen2.5")
+
+	chain := NewChain(ChainConfig{Primary: primary, Fallback: fallback}, logger)
+	if got := ContextWindow(chain); got != 32000 {
+		t.Fatalf("expected the fallback's 32000 window, got %d", got)
+	}
+
+	fallback.SetContextWindow(64000)
+	if got := ContextWindow(chain); got != 64000 {
+		t.Fatalf("expected configured override to apply, got %d", got)
+	}
+}
@@ -31,6 +31,7 @@ func FromConfig(cfg *config.Config, logger *slog.Logger) (Provider, error) {
 
 	if c := cfg.Provider.Anthropic; c != nil && c.Enabled && c.APIKey != "" {
 		p := NewAnthropic(c.APIKey, c.DefaultModel)
+		p.SetContextWindow(c.ContextWindow)
 		available["anthropic"] = p
 		logger.Info("provider enabled", "name", "anthropic", "model", c.DefaultModel)
 
@@ -48,6 +49,7 @@ func FromConfig(cfg *config.Config, logger *slog.Logger) (Provider, error) {
 			c.APIKey,
 			c.DefaultModel,
 		)
+		p.SetContextWindow(c.ContextWindow)
 		available["gemini"] = p
 		logger.Info("provider enabled", "name", "gemini", "model", c.DefaultModel)
 	}
@@ -58,12 +60,14 @@ func FromConfig(cfg *config.Config, logger *slog.Logger) (Provider, error) {
 			c.APIKey,
 			c.DefaultModel,
 		)
+		p.SetContextWindow(c.ContextWindow)
 		available["zai"] = p
 		logger.Info("provider enabled", "name", "zai", "model", c.DefaultModel)
 	}
 
 	if c := cfg.Provider.OpenAICompat; c != nil && c.Enabled && c.BaseURL != "" {
 		p := NewOpenAICompat(c.BaseURL, c.APIKey, c.DefaultModel)
+		p.SetContextWindow(c.ContextWindow)
 		available["openai_compat"] = p
 		logger.Info("provider enabled", "name", "openai_compat", "model", c.DefaultModel)
 	}
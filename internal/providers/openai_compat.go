@@ -8,14 +8,14 @@ import (
 	"io"
 	"net/http"
 	"strings"
-	"time"
 )
 
 type OpenAICompatProvider struct {
-	baseURL string
-	apiKey  string
-	model   string
-	client  *http.Client
+	baseURL       string
+	apiKey        string
+	model         string
+	contextWindow int // 0 = derive from model name
+	client        *http.Client
 }
 
 func NewOpenAICompat(baseURL, apiKey, model string) *OpenAICompatProvider {
@@ -24,7 +24,7 @@ func NewOpenAICompat(baseURL, apiKey, model string) *OpenAICompatProvider {
 		baseURL: baseURL,
 		apiKey:  apiKey,
 		model:   model,
-		client:  &http.Client{Timeout: 120 * time.Second},
+		client:  newHTTPClient(),
 	}
 }
 
@@ -33,6 +33,10 @@ func (p *OpenAICompatProvider) Available() bool { return p.baseURL != "" }
 
 func (p *OpenAICompatProvider) Complete(ctx context.Context, req CompletionRequest) (CompletionResponse, error) {
 	body := p.buildRequest(req)
+	if req.OnStream != nil {
+		body.Stream = true
+		body.StreamOptions = &openaiStreamOptions{IncludeUsage: true}
+	}
 
 	jsonBody, err := json.Marshal(body)
 	if err != nil {
@@ -40,6 +44,8 @@ func (p *OpenAICompatProvider) Complete(ctx context.Context, req CompletionReque
 	}
 
 	url := p.baseURL + "/chat/completions"
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(jsonBody))
 	if err != nil {
 		return CompletionResponse{}, fmt.Errorf("creating request: %w", err)
@@ -55,8 +61,14 @@ func (p *OpenAICompatProvider) Complete(ctx context.Context, req CompletionReque
 		return CompletionResponse{}, fmt.Errorf("API request: %w", err)
 	}
 	defer resp.Body.Close()
+	stream := newIdleReader(resp.Body, cancel)
+	defer stream.Stop()
+
+	if resp.StatusCode == http.StatusOK && body.Stream {
+		return p.parseStream(stream, req.OnStream)
+	}
 
-	respBody, err := io.ReadAll(resp.Body)
+	respBody, err := io.ReadAll(stream)
 	if err != nil {
 		return CompletionResponse{}, fmt.Errorf("reading response: %w", err)
 	}
@@ -69,9 +81,15 @@ func (p *OpenAICompatProvider) Complete(ctx context.Context, req CompletionReque
 }
 
 type openaiRequest struct {
-	Model    string          `json:"model"`
-	Messages []openaiMessage `json:"messages"`
-	Tools    []openaiTool    `json:"tools,omitempty"`
+	Model         string               `json:"model"`
+	Messages      []openaiMessage      `json:"messages"`
+	Tools         []openaiTool         `json:"tools,omitempty"`
+	Stream        bool                 `json:"stream,omitempty"`
+	StreamOptions *openaiStreamOptions `json:"stream_options,omitempty"`
+}
+
+type openaiStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
 }
 
 type openaiMessage struct {
@@ -180,6 +198,7 @@ func (p *OpenAICompatProvider) parseResponse(body []byte) (CompletionResponse, e
 	result := CompletionResponse{
 		Content:  content,
 		Provider: p.Name(),
+		Model:    p.model,
 		Usage: TokenUsage{
 			InputTokens:  resp.Usage.PromptTokens,
 			OutputTokens: resp.Usage.CompletionTokens,
@@ -196,3 +215,114 @@ func (p *OpenAICompatProvider) parseResponse(body []byte) (CompletionResponse, e
 
 	return result, nil
 }
+
+// openaiStreamChunk is one "data:" payload of a chat completions stream.
+type openaiStreamChunk struct {
+	Choices []struct {
+		FinishReason string `json:"finish_reason"`
+		Delta        struct {
+			Content          string `json:"content"`
+			ReasoningContent string `json:"reasoning_content,omitempty"`
+			ToolCalls        []struct {
+				Index    int    `json:"index"`
+				ID       string `json:"id"`
+				Function struct {
+					Name      string `json:"name"`
+					Arguments string `json:"arguments"`
+				} `json:"function"`
+			} `json:"tool_calls,omitempty"`
+		} `json:"delta"`
+	} `json:"choices"`
+	Usage *struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage,omitempty"`
+	Error *struct {
+		Message string `json:"message"`
+		Type    string `json:"type"`
+		Code    any    `json:"code"`
+	} `json:"error,omitempty"`
+}
+
+// parseStream assembles a response from a chunked chat completions stream.
+// Tool calls arrive as fragments keyed by index: the first carries the ID and
+// name, later ones append to the arguments string. The stream is complete
+// once a choice has a finish reason or [DONE] arrives.
+func (p *OpenAICompatProvider) parseStream(body io.Reader, onStream func(StreamEvent)) (CompletionResponse, error) {
+	result := CompletionResponse{Provider: p.Name(), Model: p.model}
+
+	var content, reasoning strings.Builder
+	calls := make(map[int]*ToolCall)
+	var order []int
+	done := false
+
+	err := readSSE(body, func(_, data string) error {
+		if data == "[DONE]" {
+			done = true
+			return nil
+		}
+		var chunk openaiStreamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return fmt.Errorf("parsing stream chunk: %w", err)
+		}
+		if e := chunk.Error; e != nil {
+			kind := e.Type
+			if e.Code != nil {
+				kind = fmt.Sprint(e.Code)
+			}
+			return fmt.Errorf("API stream error: %s: %s", kind, e.Message)
+		}
+		if chunk.Usage != nil {
+			result.Usage = TokenUsage{
+				InputTokens:  chunk.Usage.PromptTokens,
+				OutputTokens: chunk.Usage.CompletionTokens,
+			}
+		}
+		if len(chunk.Choices) == 0 {
+			return nil
+		}
+
+		if chunk.Choices[0].FinishReason != "" {
+			done = true
+		}
+		delta := chunk.Choices[0].Delta
+		if delta.Content != "" {
+			content.WriteString(delta.Content)
+			onStream(StreamEvent{Text: delta.Content})
+		}
+		reasoning.WriteString(delta.ReasoningContent)
+
+		for _, tc := range delta.ToolCalls {
+			call, ok := calls[tc.Index]
+			if !ok {
+				call = &ToolCall{}
+				calls[tc.Index] = call
+				order = append(order, tc.Index)
+			}
+			if tc.ID != "" {
+				call.ID = tc.ID
+			}
+			if tc.Function.Name != "" && call.Name == "" {
+				call.Name = tc.Function.Name
+				onStream(StreamEvent{ToolName: call.Name})
+			}
+			call.Arguments += tc.Function.Arguments
+		}
+		return nil
+	})
+	if err != nil {
+		return CompletionResponse{}, err
+	}
+	if !done {
+		return CompletionResponse{}, errIncompleteStream
+	}
+
+	result.Content = content.String()
+	if result.Content == "" {
+		result.Content = reasoning.String()
+	}
+	for _, idx := range order {
+		result.ToolCalls = append(result.ToolCalls, *calls[idx])
+	}
+	return result, nil
+}
@@ -26,6 +26,11 @@ type CompletionRequest struct {
 	Messages     []Message
 	Tools        []ToolDef
 	Hint         string // "fast", "normal", "complex"
+
+	// OnStream, if set, asks the provider to stream its answer and receives
+	// partial output as it arrives. Complete still returns the full response.
+	// Providers that can't stream ignore it.
+	OnStream func(StreamEvent)
 }
 
 type CompletionResponse struct {
@@ -33,6 +38,7 @@ type CompletionResponse struct {
 	ToolCalls []ToolCall
 	Usage     TokenUsage
 	Provider  string
+	Model     string // model that produced the response, empty if unknown
 }
 
 type TokenUsage struct {
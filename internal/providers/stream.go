@@ -0,0 +1,119 @@
+package providers
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// responseTimeout bounds the wait for a response to start, and idleTimeout
+// the silence within one. Neither limits how long a streamed reply takes.
+var (
+	responseTimeout = 120 * time.Second
+	idleTimeout     = 60 * time.Second
+)
+
+// newHTTPClient returns a client for provider APIs. It has no overall
+// timeout, which would cut long streamed replies off mid-stream; wrap
+// response bodies in newIdleReader instead.
+func newHTTPClient() *http.Client {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.ResponseHeaderTimeout = responseTimeout
+	return &http.Client{Transport: t}
+}
+
+// idleReader cancels a request whose body goes idleTimeout without data.
+type idleReader struct {
+	r     io.Reader
+	timer *time.Timer
+	fired atomic.Bool
+}
+
+// newIdleReader watches r, the body of a request made with a context that
+// cancel cancels. Call Stop when done reading.
+func newIdleReader(r io.Reader, cancel context.CancelFunc) *idleReader {
+	ir := &idleReader{r: r}
+	ir.timer = time.AfterFunc(idleTimeout, func() {
+		ir.fired.Store(true)
+		cancel()
+	})
+	return ir
+}
+
+func (ir *idleReader) Read(p []byte) (int, error) {
+	n, err := ir.r.Read(p)
+	if err != nil && !errors.Is(err, io.EOF) && ir.fired.Load() {
+		return n, fmt.Errorf("timeout: no data from the API for %s", idleTimeout)
+	}
+	ir.timer.Reset(idleTimeout)
+	return n, err
+}
+
+// Stop stops watching.
+func (ir *idleReader) Stop() {
+	ir.timer.Stop()
+}
+
+// errIncompleteStream means a stream ended without its final event, as when
+// the connection drops or a proxy cuts it: the reply may be truncated.
+var errIncompleteStream = errors.New("API stream ended before the response was complete")
+
+// StreamEvent is partial output delivered while a completion is still running.
+type StreamEvent struct {
+	Text     string // text delta to append to what has been streamed so far
+	ToolName string // set when the model starts a tool call
+	Reset    bool   // discard everything streamed so far (the chain is failing over)
+}
+
+// readSSE reads a server-sent event stream and calls fn for each event.
+// Events without an "event:" line get an empty event name (OpenAI style).
+func readSSE(r io.Reader, fn func(event, data string) error) error {
+	br := bufio.NewReader(r)
+	var event string
+	var data strings.Builder
+
+	dispatch := func() error {
+		if data.Len() == 0 {
+			event = ""
+			return nil
+		}
+		err := fn(event, data.String())
+		event = ""
+		data.Reset()
+		return err
+	}
+
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		switch {
+		case line == "":
+			if derr := dispatch(); derr != nil {
+				return derr
+			}
+		case strings.HasPrefix(line, ":"):
+			// comment / keep-alive
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+
+		if err == io.EOF {
+			return dispatch()
+		}
+	}
+}
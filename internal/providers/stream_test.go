@@ -0,0 +1,267 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sseServer replies to every request with the given SSE events and records the request body.
+func sseServer(t *testing.T, events []string, gotBody *map[string]any) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if gotBody != nil {
+			json.NewDecoder(r.Body).Decode(gotBody)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		flusher := w.(http.Flusher)
+		for _, ev := range events {
+			fmt.Fprint(w, ev)
+			flusher.Flush()
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func collectStream(events *[]StreamEvent) func(StreamEvent) {
+	return func(ev StreamEvent) { *events = append(*events, ev) }
+}
+
+func TestAnthropicStream(t *testing.T) {
+	var body map[string]any
+	srv := sseServer(t, []string{
+		"event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"usage\":{\"input_tokens\":12,\"output_tokens\":1}}}\n\n",
+		"event: content_block_start\ndata: {\"type\":\"content_block_start\",\"index\":0,\"content_block\":{\"type\":\"text\",\"text\":\"\"}}\n\n",
+		"event: ping\ndata: {\"type\":\"ping\"}\n\n",
+		"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"Let me \"}}\n\n",
+		"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"check.\"}}\n\n",
+		"event: content_block_stop\ndata: {\"type\":\"content_block_stop\",\"index\":0}\n\n",
+		"event: content_block_start\ndata: {\"type\":\"content_block_start\",\"index\":1,\"content_block\":{\"type\":\"tool_use\",\"id\":\"toolu_1\",\"name\":\"shell_exec\",\"input\":{}}}\n\n",
+		"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":1,\"delta\":{\"type\":\"input_json_delta\",\"partial_json\":\"{\\\"command\\\": \"}}\n\n",
+		"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":1,\"delta\":{\"type\":\"input_json_delta\",\"partial_json\":\"\\\"ls\\\"}\"}}\n\n",
+		"event: content_block_stop\ndata: {\"type\":\"content_block_stop\",\"index\":1}\n\n",
+		"event: message_delta\ndata: {\"type\":\"message_delta\",\"delta\":{\"stop_reason\":\"tool_use\"},\"usage\":{\"output_tokens\":30}}\n\n",
+		"event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n",
+	}, &body)
+
+	p := NewAnthropic("test-key", "claude-test")
+	p.apiURL = srv.URL
+
+	var events []StreamEvent
+	resp, err := p.Complete(context.Background(), CompletionRequest{
+		Messages: []Message{{Role: "user", Content: "list files"}},
+		OnStream: collectStream(&events),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if body["stream"] != true {
+		t.Errorf("expected stream=true in request, got %v", body["stream"])
+	}
+	if resp.Content != "Let me check." {
+		t.Errorf("unexpected content %q", resp.Content)
+	}
+	if len(resp.ToolCalls) != 1 || resp.ToolCalls[0].ID != "toolu_1" || resp.ToolCalls[0].Arguments != `{"command": "ls"}` {
+		t.Errorf("unexpected tool calls %+v", resp.ToolCalls)
+	}
+	if resp.Usage.InputTokens != 12 || resp.Usage.OutputTokens != 30 {
+		t.Errorf("unexpected usage %+v", resp.Usage)
+	}
+	if len(events) != 3 || events[0].Text != "Let me " || events[1].Text != "check." || events[2].ToolName != "shell_exec" {
+		t.Errorf("unexpected stream events %+v", events)
+	}
+}
+
+func TestAnthropicStreamError(t *testing.T) {
+	srv := sseServer(t, []string{
+		"event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"usage\":{\"input_tokens\":1}}}\n\n",
+		"event: error\ndata: {\"type\":\"error\",\"error\":{\"type\":\"overloaded_error\",\"message\":\"Overloaded\"}}\n\n",
+	}, nil)
+
+	p := NewAnthropic("test-key", "claude-test")
+	p.apiURL = srv.URL
+
+	_, err := p.Complete(context.Background(), CompletionRequest{
+		Messages: []Message{{Role: "user", Content: "hi"}},
+		OnStream: func(StreamEvent) {},
+	})
+	if err == nil {
+		t.Fatal("expected stream error")
+	}
+	if reason := ClassifyError(err); reason != ReasonOverloaded {
+		t.Errorf("expected overloaded classification, got %s", reason)
+	}
+}
+
+func TestOpenAICompatStream(t *testing.T) {
+	var body map[string]any
+	srv := sseServer(t, []string{
+		"data: {\"choices\":[{\"delta\":{\"role\":\"assistant\",\"content\":\"Run\"}}]}\n\n",
+		"data: {\"choices\":[{\"delta\":{\"content\":\"ning.\"}}]}\n\n",
+		"data: {\"choices\":[{\"delta\":{\"tool_calls\":[{\"index\":0,\"id\":\"call_a\",\"type\":\"function\",\"function\":{\"name\":\"shell_exec\",\"arguments\":\"\"}}]}}]}\n\n",
+		"data: {\"choices\":[{\"delta\":{\"tool_calls\":[{\"index\":0,\"function\":{\"arguments\":\"{\\\"command\\\":\"}}]}}]}\n\n",
+		"data: {\"choices\":[{\"delta\":{\"tool_calls\":[{\"index\":1,\"id\":\"call_b\",\"type\":\"function\",\"function\":{\"name\":\"file_read\",\"arguments\":\"{}\"}}]}}]}\n\n",
+		"data: {\"choices\":[{\"delta\":{\"tool_calls\":[{\"index\":0,\"function\":{\"arguments\":\"\\\"ls\\\"}\"}}]}}]}\n\n",
+		"data: {\"choices\":[],\"usage\":{\"prompt_tokens\":9,\"completion_tokens\":4}}\n\n",
+		"data: [DONE]\n\n",
+	}, &body)
+
+	p := NewOpenAICompat(srv.URL, "key", "test-model")
+
+	var events []StreamEvent
+	resp, err := p.Complete(context.Background(), CompletionRequest{
+		Messages: []Message{{Role: "user", Content: "hi"}},
+		OnStream: collectStream(&events),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if body["stream"] != true || body["stream_options"] == nil {
+		t.Errorf("expected streaming request, got %v", body)
+	}
+	if resp.Content != "Running." {
+		t.Errorf("unexpected content %q", resp.Content)
+	}
+	if len(resp.ToolCalls) != 2 {
+		t.Fatalf("expected 2 tool calls, got %+v", resp.ToolCalls)
+	}
+	if tc := resp.ToolCalls[0]; tc.ID != "call_a" || tc.Name != "shell_exec" || tc.Arguments != `{"command":"ls"}` {
+		t.Errorf("unexpected first tool call %+v", tc)
+	}
+	if tc := resp.ToolCalls[1]; tc.ID != "call_b" || tc.Arguments != "{}" {
+		t.Errorf("unexpected second tool call %+v", tc)
+	}
+	if resp.Usage.InputTokens != 9 || resp.Usage.OutputTokens != 4 {
+		t.Errorf("unexpected usage %+v", resp.Usage)
+	}
+
+	var text strings.Builder
+	var toolNames []string
+	for _, ev := range events {
+		text.WriteString(ev.Text)
+		if ev.ToolName != "" {
+			toolNames = append(toolNames, ev.ToolName)
+		}
+	}
+	if text.String() != "Running." || strings.Join(toolNames, ",") != "shell_exec,file_read" {
+		t.Errorf("unexpected stream events %+v", events)
+	}
+}
+
+func TestStreamCutShort(t *testing.T) {
+	anthropic := sseServer(t, []string{
+		"event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"usage\":{\"input_tokens\":1}}}\n\n",
+		"event: content_block_start\ndata: {\"type\":\"content_block_start\",\"index\":0,\"content_block\":{\"type\":\"tool_use\",\"id\":\"toolu_1\",\"name\":\"shell_exec\",\"input\":{}}}\n\n",
+		"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"input_json_delta\",\"partial_json\":\"{\\\"command\\\": \"}}\n\n",
+	}, nil)
+	openai := sseServer(t, []string{
+		"data: {\"choices\":[{\"delta\":{\"content\":\"Half a sen\"}}]}\n\n",
+	}, nil)
+
+	a := NewAnthropic("test-key", "claude-test")
+	a.apiURL = anthropic.URL
+	for _, p := range []Provider{a, NewOpenAICompat(openai.URL, "key", "test-model")} {
+		_, err := p.Complete(context.Background(), CompletionRequest{
+			Messages: []Message{{Role: "user", Content: "hi"}},
+			OnStream: func(StreamEvent) {},
+		})
+		if err == nil || !ClassifyError(err).Retriable() {
+			t.Errorf("%s: expected a retriable error for a cut stream, got %v", p.Name(), err)
+		}
+	}
+}
+
+func TestOpenAICompatStreamErrorChunk(t *testing.T) {
+	srv := sseServer(t, []string{
+		"data: {\"choices\":[{\"delta\":{\"content\":\"Hi\"}}]}\n\n",
+		"data: {\"error\":{\"message\":\"Rate limit reached\",\"type\":\"requests\",\"code\":\"rate_limit_exceeded\"}}\n\n",
+		"data: [DONE]\n\n",
+	}, nil)
+
+	p := NewOpenAICompat(srv.URL, "key", "test-model")
+	_, err := p.Complete(context.Background(), CompletionRequest{
+		Messages: []Message{{Role: "user", Content: "hi"}},
+		OnStream: func(StreamEvent) {},
+	})
+	if err == nil || ClassifyError(err) != ReasonRateLimit {
+		t.Errorf("expected the error chunk as a rate limit error, got %v", err)
+	}
+}
+
+func TestStreamIdleTimeout(t *testing.T) {
+	defer func(d time.Duration) { idleTimeout = d }(idleTimeout)
+	idleTimeout = 200 * time.Millisecond
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"slow\"}}]}\n\n")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	p := NewOpenAICompat(srv.URL, "key", "test-model")
+	start := time.Now()
+	_, err := p.Complete(context.Background(), CompletionRequest{
+		Messages: []Message{{Role: "user", Content: "hi"}},
+		OnStream: func(StreamEvent) {},
+	})
+	if err == nil || ClassifyError(err) != ReasonTimeout {
+		t.Errorf("expected an idle timeout, got %v", err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Errorf("idle stream ran for %v", time.Since(start))
+	}
+}
+
+func TestOpenAICompatNoStreamWithoutCallback(t *testing.T) {
+	var body map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&body)
+		fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"plain"}}]}`)
+	}))
+	defer srv.Close()
+
+	p := NewOpenAICompat(srv.URL, "key", "test-model")
+	resp, err := p.Complete(context.Background(), CompletionRequest{
+		Messages: []Message{{Role: "user", Content: "hi"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := body["stream"]; ok {
+		t.Errorf("stream should be omitted without OnStream, got %v", body)
+	}
+	if resp.Content != "plain" {
+		t.Errorf("unexpected content %q", resp.Content)
+	}
+}
+
+func TestChainStreamResetOnFailover(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	primary := &mockProvider{name: "primary", available: true, fail: true}
+	fallback := &mockProvider{name: "fallback", available: true}
+	chain := NewChain(ChainConfig{Primary: primary, Fallback: fallback}, logger)
+
+	var events []StreamEvent
+	_, err := chain.Complete(context.Background(), CompletionRequest{
+		Messages: []Message{{Role: "user", Content: "hello"}},
+		OnStream: collectStream(&events),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(events) != 1 || !events[0].Reset {
+		t.Errorf("expected a single reset event on failover, got %+v", events)
+	}
+}
@@ -0,0 +1,226 @@
+//go:build linux
+
+package sandbox
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+var cgroupSeq atomic.Int64
+
+// cpuPeriod is the cpu.max period in microseconds.
+const cpuPeriod = 100000
+
+// createCgroup makes a cgroup with the configured limits under the parent
+// cgroup and opens it, so the command starts inside it (clone3 with
+// CLONE_INTO_CGROUP) and never runs unlimited.
+func (s *Sandbox) createCgroup() error {
+	root, own, err := cgroupPaths()
+	if err != nil {
+		return err
+	}
+	parent := filepath.Join(root, own)
+	if s.opts.CgroupParent != "" {
+		parent = filepath.Join(root, s.opts.CgroupParent)
+	} else if filepath.Base(own) == daemonLeaf {
+		parent = filepath.Dir(parent) // moved there by vacate
+	}
+
+	limits := map[string]string{}
+	var controllers []string
+	if s.opts.CPUs > 0 {
+		controllers = append(controllers, "cpu")
+		quota := max(int(s.opts.CPUs*cpuPeriod), 1000)
+		limits["cpu.max"] = fmt.Sprintf("%d %d", quota, cpuPeriod)
+	}
+	if s.opts.MemoryMB > 0 {
+		controllers = append(controllers, "memory")
+		limits["memory.max"] = strconv.Itoa(s.opts.MemoryMB << 20)
+		limits["memory.swap.max"] = "0"
+	}
+	if s.opts.Pids > 0 {
+		controllers = append(controllers, "pids")
+		limits["pids.max"] = strconv.Itoa(s.opts.Pids)
+	}
+	err = enableControllers(parent, controllers)
+	if errors.Is(err, syscall.EBUSY) && s.opts.CgroupParent == "" {
+		// Only cgroups without processes of their own can have limited
+		// children, so make room in the daemon's (systemd Delegate=yes).
+		if err = vacate(parent); err == nil {
+			err = enableControllers(parent, controllers)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Join(parent, fmt.Sprintf("aeon-sandbox-%d-%d", os.Getpid(), cgroupSeq.Add(1)))
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		return fmt.Errorf("creating cgroup: %w", err)
+	}
+	s.cgroup = dir
+	for file, value := range limits {
+		if err := os.WriteFile(filepath.Join(dir, file), []byte(value), 0); err != nil {
+			if file == "memory.swap.max" && errors.Is(err, os.ErrNotExist) {
+				continue // no swap accounting
+			}
+			s.removeCgroup()
+			return fmt.Errorf("setting %s: %w", file, err)
+		}
+	}
+	fd, err := syscall.Open(dir, syscall.O_RDONLY|syscall.O_DIRECTORY|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		s.removeCgroup()
+		return fmt.Errorf("opening cgroup: %w", err)
+	}
+	s.cgroupFD = fd
+	return nil
+}
+
+// daemonLeaf is the cgroup vacate moves the daemon's processes into.
+const daemonLeaf = "aeon-daemon"
+
+var vacateMu sync.Mutex
+
+// vacate moves the processes in cgroup dir into a child cgroup.
+func vacate(dir string) error {
+	vacateMu.Lock()
+	defer vacateMu.Unlock()
+	leaf := filepath.Join(dir, daemonLeaf)
+	if err := os.Mkdir(leaf, 0o755); err != nil && !errors.Is(err, os.ErrExist) {
+		return fmt.Errorf("creating cgroup: %w", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "cgroup.procs"))
+	if err != nil {
+		return err
+	}
+	for _, pid := range strings.Fields(string(data)) {
+		if err := os.WriteFile(filepath.Join(leaf, "cgroup.procs"), []byte(pid), 0); err != nil && !errors.Is(err, syscall.ESRCH) {
+			return fmt.Errorf("moving process %s to cgroup %s: %w", pid, leaf, err)
+		}
+	}
+	return nil
+}
+
+// cgroupPaths returns where cgroup v2 is mounted and the daemon's own cgroup.
+func cgroupPaths() (root, own string, err error) {
+	mounts, err := mountInfo()
+	if err != nil {
+		return "", "", err
+	}
+	for _, m := range mounts {
+		if m.fstype == "cgroup2" {
+			root = m.point
+			break
+		}
+	}
+	if root == "" {
+		return "", "", errors.New("cgroup v2 is not mounted; resource limits need it")
+	}
+
+	data, err := os.ReadFile("/proc/self/cgroup")
+	if err != nil {
+		return "", "", err
+	}
+	sc := bufio.NewScanner(strings.NewReader(string(data)))
+	for sc.Scan() {
+		if path, ok := strings.CutPrefix(sc.Text(), "0::"); ok {
+			return root, path, nil
+		}
+	}
+	return "", "", errors.New("not in a cgroup v2 hierarchy")
+}
+
+// enableControllers makes the controllers available to parent's children.
+func enableControllers(parent string, controllers []string) error {
+	available, err := os.ReadFile(filepath.Join(parent, "cgroup.controllers"))
+	if err != nil {
+		return fmt.Errorf("reading cgroup %s: %w", parent, err)
+	}
+	enabled, err := os.ReadFile(filepath.Join(parent, "cgroup.subtree_control"))
+	if err != nil {
+		return fmt.Errorf("reading cgroup %s: %w", parent, err)
+	}
+	for _, c := range controllers {
+		if !hasField(string(available), c) {
+			return fmt.Errorf("the %s controller is not delegated to cgroup %s; set sandbox.cgroup_parent to a cgroup aeon may manage", c, parent)
+		}
+		if hasField(string(enabled), c) {
+			continue
+		}
+		if err := os.WriteFile(filepath.Join(parent, "cgroup.subtree_control"), []byte("+"+c), 0); err != nil {
+			return fmt.Errorf("enabling the %s controller in cgroup %s: %w", c, parent, err)
+		}
+	}
+	return nil
+}
+
+func hasField(s, field string) bool {
+	for _, f := range strings.Fields(s) {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// Finish releases the sandbox's cgroup once its command has exited, and
+// returns a note when a limit stopped the command, or "".
+func (s *Sandbox) Finish() string {
+	if s == nil || s.cgroup == "" {
+		return ""
+	}
+	if s.cgroupFD >= 0 {
+		syscall.Close(s.cgroupFD)
+		s.cgroupFD = -1
+	}
+	var notes []string
+	if eventCount(s.cgroup, "memory.events", "oom_kill") > 0 {
+		notes = append(notes, fmt.Sprintf("killed for exceeding the %d MB memory limit", s.opts.MemoryMB))
+	}
+	if eventCount(s.cgroup, "pids.events", "max") > 0 {
+		notes = append(notes, fmt.Sprintf("hit the limit of %d processes", s.opts.Pids))
+	}
+	s.removeCgroup()
+	return strings.Join(notes, "; ")
+}
+
+// removeCgroup deletes the cgroup, killing anything the command left behind.
+func (s *Sandbox) removeCgroup() {
+	for i := 0; i < 50; i++ {
+		err := syscall.Rmdir(s.cgroup)
+		if err == nil || errors.Is(err, syscall.ENOENT) {
+			break
+		}
+		if i == 0 {
+			os.WriteFile(filepath.Join(s.cgroup, "cgroup.kill"), []byte("1"), 0)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	s.cgroup = ""
+}
+
+// eventCount reads a counter from a cgroup events file such as memory.events.
+func eventCount(dir, file, key string) int {
+	data, err := os.ReadFile(filepath.Join(dir, file))
+	if err != nil {
+		return 0
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if name, value, ok := strings.Cut(line, " "); ok && name == key {
+			n, _ := strconv.Atoi(value)
+			return n
+		}
+	}
+	return 0
+}
@@ -0,0 +1,58 @@
+// Package sandbox runs commands isolated from the host: in their own user,
+// mount, PID, IPC and UTS namespaces, optionally without network, on a
+// read-only view of the host filesystem with chosen paths writable, and under
+// cgroup v2 CPU, memory and process limits. It needs Linux; elsewhere Wrap
+// and Check return an error, so sandboxed tools fail closed.
+package sandbox
+
+import (
+	"strings"
+)
+
+// Options configures a sandbox.
+type Options struct {
+	Network      bool     // keep the host network; false gives an isolated network with only loopback
+	Writable     []string // host paths mounted read-write; everything else is read-only
+	Hidden       []string // host directories covered by an empty one, like aeon's own home
+	Visible      []string // paths under Hidden ones mounted back read-only
+	CPUs         float64  // CPU limit in cores, 0 = unlimited
+	MemoryMB     int      // memory limit, 0 = unlimited
+	Pids         int      // process limit, 0 = unlimited
+	Env          []string // environment variables passed through, besides baseEnv
+	CgroupParent string   // cgroup v2 path limits are created under, default the daemon's own cgroup
+}
+
+// baseEnv are the variables every sandboxed command keeps. The rest of the
+// daemon's environment, with its API keys, stays outside.
+var baseEnv = []string{"PATH", "HOME", "USER", "LOGNAME", "LANG", "LC_ALL", "LC_CTYPE", "TERM", "TZ"}
+
+func (o Options) limited() bool {
+	return o.CPUs > 0 || o.MemoryMB > 0 || o.Pids > 0
+}
+
+// filterEnv keeps the entries of env named in baseEnv or o.Env. Like
+// os/exec, the last of repeated entries wins.
+func (o Options) filterEnv(env []string) []string {
+	keep := map[string]bool{}
+	for _, name := range baseEnv {
+		keep[name] = true
+	}
+	for _, name := range o.Env {
+		keep[name] = true
+	}
+	var out []string
+	index := map[string]int{}
+	for _, kv := range env {
+		name, _, _ := strings.Cut(kv, "=")
+		if !keep[name] {
+			continue
+		}
+		if i, ok := index[name]; ok {
+			out[i] = kv
+			continue
+		}
+		index[name] = len(out)
+		out = append(out, kv)
+	}
+	return out
+}
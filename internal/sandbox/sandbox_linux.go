@@ -0,0 +1,514 @@
+//go:build linux
+
+package sandbox
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+// A sandboxed command starts as the aeon binary itself, re-executed under
+// initArg in fresh namespaces. Init sets up the mounts from the spec passed
+// in specEnv, then execs the real command.
+const (
+	initArg = "aeon-sandbox-init"
+	specEnv = "AEON_SANDBOX_SPEC"
+)
+
+// spec is what the daemon hands to Init.
+type spec struct {
+	Path     string   `json:"path"`
+	Args     []string `json:"args"`
+	Dir      string   `json:"dir"`
+	Env      []string `json:"env"`
+	Network  bool     `json:"network"`
+	Writable []string `json:"writable"`
+	Hidden   []string `json:"hidden,omitempty"`
+	Visible  []string `json:"visible,omitempty"`
+	Probe    bool     `json:"probe,omitempty"` // set up, then exit 0 without running anything
+}
+
+// devices are the /dev entries bound into the sandbox's own /dev.
+var devices = []string{"null", "zero", "full", "random", "urandom", "tty"}
+
+// Sandbox is a command prepared by Wrap. Call Finish once it has exited.
+type Sandbox struct {
+	opts     Options
+	cgroup   string // empty without limits
+	cgroupFD int
+}
+
+// Init runs the inside of a sandbox when the process was started as one, and
+// never returns then. It must be called first thing in main.
+func Init() {
+	if len(os.Args) == 0 || os.Args[0] != initArg {
+		return
+	}
+	var sp spec
+	if err := json.Unmarshal([]byte(os.Getenv(specEnv)), &sp); err != nil {
+		initFailed(fmt.Errorf("reading spec: %w", err))
+	}
+	if err := setup(sp); err != nil {
+		initFailed(err)
+	}
+	if sp.Probe {
+		os.Exit(0)
+	}
+	err := syscall.Exec(sp.Path, sp.Args, sp.Env)
+	initFailed(fmt.Errorf("exec %s: %w", sp.Path, err))
+}
+
+func initFailed(err error) {
+	fmt.Fprintf(os.Stderr, "sandbox: %v\n", err)
+	os.Exit(126)
+}
+
+// Check starts an empty sandbox with opts and reports why it failed, if it
+// did: no user namespaces, no cgroup v2 delegation, a missing writable path.
+func Check(opts Options) error {
+	cmd := exec.Command("/proc/self/exe")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	sb, err := wrap(cmd, opts, true)
+	if err != nil {
+		return err
+	}
+	err = cmd.Run()
+	sb.Finish()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return errors.New(msg)
+		}
+		return fmt.Errorf("starting sandbox: %w", err)
+	}
+	return nil
+}
+
+// Wrap changes cmd, not yet started, to run inside a sandbox configured by
+// opts. The environment is reduced to baseEnv and opts.Env.
+func Wrap(cmd *exec.Cmd, opts Options) (*Sandbox, error) {
+	return wrap(cmd, opts, false)
+}
+
+func wrap(cmd *exec.Cmd, opts Options, probe bool) (*Sandbox, error) {
+	if cmd.Err != nil {
+		return nil, cmd.Err
+	}
+	sp, err := newSpec(cmd, opts)
+	if err != nil {
+		return nil, err
+	}
+	sp.Probe = probe
+	data, err := json.Marshal(sp)
+	if err != nil {
+		return nil, err
+	}
+
+	sb := &Sandbox{opts: opts, cgroupFD: -1}
+	if opts.limited() {
+		if err := sb.createCgroup(); err != nil {
+			return nil, err
+		}
+	}
+
+	cmd.Path = "/proc/self/exe"
+	cmd.Args = []string{initArg}
+	cmd.Env = []string{specEnv + "=" + string(data)}
+	cmd.Dir = ""
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	attr := cmd.SysProcAttr
+	attr.Cloneflags |= syscall.CLONE_NEWUSER | syscall.CLONE_NEWNS | syscall.CLONE_NEWPID |
+		syscall.CLONE_NEWIPC | syscall.CLONE_NEWUTS
+	if !opts.Network {
+		attr.Cloneflags |= syscall.CLONE_NEWNET
+	}
+	attr.UidMappings = []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Geteuid(), Size: 1}}
+	attr.GidMappings = []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getegid(), Size: 1}}
+	attr.GidMappingsEnableSetgroups = false
+	if sb.cgroupFD >= 0 {
+		attr.UseCgroupFD = true
+		attr.CgroupFD = sb.cgroupFD
+	}
+	return sb, nil
+}
+
+func newSpec(cmd *exec.Cmd, opts Options) (spec, error) {
+	sp := spec{Path: cmd.Path, Args: cmd.Args, Dir: cmd.Dir, Network: opts.Network}
+	if sp.Dir == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return sp, err
+		}
+		sp.Dir = wd
+	}
+	if !filepath.IsAbs(sp.Path) {
+		sp.Path = filepath.Join(sp.Dir, sp.Path)
+	}
+	env := cmd.Env
+	if env == nil {
+		env = os.Environ()
+	}
+	sp.Env = opts.filterEnv(env)
+
+	for _, p := range opts.Writable {
+		abs, err := filepath.Abs(p)
+		if err == nil {
+			abs, err = filepath.EvalSymlinks(abs)
+		}
+		if err != nil {
+			return sp, fmt.Errorf("writable path %s: %w", p, err)
+		}
+		sp.Writable = append(sp.Writable, abs)
+	}
+	// Hidden and visible paths that don't exist have nothing to hide or show
+	sp.Hidden = existing(opts.Hidden)
+	sp.Visible = existing(opts.Visible)
+	return sp, nil
+}
+
+// existing resolves the paths that exist.
+func existing(paths []string) []string {
+	var out []string
+	for _, p := range paths {
+		abs, err := filepath.Abs(p)
+		if err == nil {
+			abs, err = filepath.EvalSymlinks(abs)
+		}
+		if err == nil {
+			out = append(out, abs)
+		}
+	}
+	return out
+}
+
+// setup runs inside the new namespaces, as root of the user namespace.
+func setup(sp spec) error {
+	syscall.Sethostname([]byte("sandbox"))
+	if err := syscall.Mount("", "/", "", syscall.MS_REC|syscall.MS_PRIVATE, ""); err != nil {
+		return fmt.Errorf("making mounts private: %w", err)
+	}
+
+	// Keep handles on what the new /dev, /tmp and hidden paths are about to cover.
+	writable, err := openPaths(sp.Writable)
+	if err != nil {
+		return err
+	}
+	visible, err := openPaths(sp.Visible)
+	if err != nil {
+		return err
+	}
+	devFDs := map[string]int{}
+	for _, name := range devices {
+		if fd, err := syscall.Open("/dev/"+name, oPath|syscall.O_CLOEXEC, 0); err == nil {
+			devFDs[name] = fd
+		}
+	}
+
+	if err := readOnlyMounts(); err != nil {
+		return err
+	}
+	if err := mountDev(devFDs); err != nil {
+		return err
+	}
+	if err := syscall.Mount("tmpfs", "/tmp", "tmpfs", syscall.MS_NOSUID|syscall.MS_NODEV, "mode=1777"); err != nil {
+		return fmt.Errorf("mounting /tmp: %w", err)
+	}
+	for _, p := range sp.Hidden {
+		if err := os.MkdirAll(p, 0o755); err != nil {
+			return fmt.Errorf("creating mount point %s: %w", p, err)
+		}
+		if err := syscall.Mount("tmpfs", p, "tmpfs", syscall.MS_NOSUID|syscall.MS_NODEV, "mode=755"); err != nil {
+			return fmt.Errorf("hiding %s: %w", p, err)
+		}
+	}
+	for i, p := range sp.Visible {
+		if err := bind(visible[i], p, false); err != nil {
+			return err
+		}
+	}
+	for i, p := range sp.Writable {
+		if err := bind(writable[i], p, true); err != nil {
+			return err
+		}
+	}
+	// Mount points are in place, so the hiding tmpfs can go read-only
+	for _, p := range sp.Hidden {
+		flags := uintptr(syscall.MS_BIND | syscall.MS_REMOUNT | syscall.MS_RDONLY | syscall.MS_NOSUID | syscall.MS_NODEV)
+		if err := syscall.Mount("", p, "", flags, ""); err != nil {
+			return fmt.Errorf("remounting %s: %w", p, err)
+		}
+	}
+	if err := syscall.Mount("proc", "/proc", "proc", syscall.MS_NOSUID|syscall.MS_NODEV|syscall.MS_NOEXEC, ""); err != nil {
+		return fmt.Errorf("mounting /proc: %w", err)
+	}
+	if !sp.Network {
+		if err := loopbackUp(); err != nil {
+			return fmt.Errorf("bringing up loopback: %w", err)
+		}
+	}
+	if err := os.Chdir(sp.Dir); err != nil {
+		return err
+	}
+	return nil
+}
+
+// readOnlyMounts remounts every mount read-only and nosuid, then checks that
+// it worked: a mount left writable fails the sandbox rather than the command
+// running with more access than configured. /proc is replaced later and
+// /dev covered, so both are left alone.
+func readOnlyMounts() error {
+	points, err := mountPoints()
+	if err != nil {
+		return err
+	}
+	var kept []string
+	for _, p := range points {
+		if under(p, "/proc") || under(p, "/dev") {
+			continue
+		}
+		kept = append(kept, p)
+		flags := mountFlags(p) | syscall.MS_RDONLY | syscall.MS_NOSUID
+		syscall.Mount("", p, "", syscall.MS_BIND|syscall.MS_REMOUNT|flags, "")
+	}
+	for _, p := range kept {
+		var st syscall.Statfs_t
+		if err := syscall.Statfs(p, &st); err != nil {
+			continue // covered by another mount
+		}
+		if st.Flags&stReadOnly == 0 {
+			return fmt.Errorf("could not make %s read-only", p)
+		}
+	}
+	return nil
+}
+
+// mountDev replaces /dev with a read-only tmpfs holding only the harmless
+// devices, bound from the host, and a private /dev/shm.
+func mountDev(fds map[string]int) error {
+	if err := syscall.Mount("tmpfs", "/dev", "tmpfs", syscall.MS_NOSUID|syscall.MS_NOEXEC, "mode=755"); err != nil {
+		return fmt.Errorf("mounting /dev: %w", err)
+	}
+	for _, name := range devices {
+		fd, ok := fds[name]
+		if !ok {
+			continue
+		}
+		target := "/dev/" + name
+		if err := os.WriteFile(target, nil, 0o666); err != nil {
+			return err
+		}
+		if err := syscall.Mount(fdPath(fd), target, "", syscall.MS_BIND, ""); err != nil {
+			return fmt.Errorf("binding %s: %w", target, err)
+		}
+		flags := mountFlags(target) | syscall.MS_RDONLY | syscall.MS_NOSUID | syscall.MS_NOEXEC
+		if err := syscall.Mount("", target, "", syscall.MS_BIND|syscall.MS_REMOUNT|flags, ""); err != nil {
+			return fmt.Errorf("remounting %s: %w", target, err)
+		}
+	}
+	for name, target := range map[string]string{
+		"fd": "/proc/self/fd", "stdin": "/proc/self/fd/0", "stdout": "/proc/self/fd/1", "stderr": "/proc/self/fd/2",
+	} {
+		if err := os.Symlink(target, "/dev/"+name); err != nil {
+			return err
+		}
+	}
+	if err := os.Mkdir("/dev/shm", 0o1777); err != nil {
+		return err
+	}
+	if err := syscall.Mount("tmpfs", "/dev/shm", "tmpfs", syscall.MS_NOSUID|syscall.MS_NODEV, "mode=1777"); err != nil {
+		return fmt.Errorf("mounting /dev/shm: %w", err)
+	}
+	flags := uintptr(syscall.MS_BIND | syscall.MS_REMOUNT | syscall.MS_RDONLY | syscall.MS_NOSUID | syscall.MS_NOEXEC)
+	if err := syscall.Mount("", "/dev", "", flags, ""); err != nil {
+		return fmt.Errorf("remounting /dev: %w", err)
+	}
+	return nil
+}
+
+// openPaths opens paths with O_PATH, to bind them once covered.
+func openPaths(paths []string) ([]int, error) {
+	fds := make([]int, len(paths))
+	for i, p := range paths {
+		fd, err := syscall.Open(p, oPath|syscall.O_CLOEXEC, 0)
+		if err != nil {
+			return nil, fmt.Errorf("opening %s: %w", p, err)
+		}
+		fds[i] = fd
+	}
+	return fds, nil
+}
+
+// bind mounts the host path held open by fd back at target, read-write or
+// read-only. Paths under /tmp or a hidden path need their mount point
+// created first.
+func bind(fd int, target string, writable bool) error {
+	if _, err := os.Lstat(target); os.IsNotExist(err) {
+		var st syscall.Stat_t
+		if err := syscall.Fstat(fd, &st); err != nil {
+			return err
+		}
+		if st.Mode&syscall.S_IFMT == syscall.S_IFDIR {
+			err = os.MkdirAll(target, 0o755)
+		} else if err = os.MkdirAll(filepath.Dir(target), 0o755); err == nil {
+			err = os.WriteFile(target, nil, 0o644)
+		}
+		if err != nil {
+			return fmt.Errorf("creating mount point %s: %w", target, err)
+		}
+	}
+	if err := syscall.Mount(fdPath(fd), target, "", syscall.MS_BIND|syscall.MS_REC, ""); err != nil {
+		return fmt.Errorf("binding %s: %w", target, err)
+	}
+	flags := mountFlags(target) | syscall.MS_RDONLY | syscall.MS_NOSUID
+	if writable {
+		flags = mountFlags(target) &^ syscall.MS_RDONLY
+	}
+	if err := syscall.Mount("", target, "", syscall.MS_BIND|syscall.MS_REMOUNT|flags, ""); err != nil {
+		return fmt.Errorf("remounting %s: %w", target, err)
+	}
+	return nil
+}
+
+func fdPath(fd int) string {
+	return "/proc/self/fd/" + strconv.Itoa(fd)
+}
+
+func under(path, dir string) bool {
+	return path == dir || strings.HasPrefix(path, dir+"/")
+}
+
+// oPath is O_PATH, which package syscall lacks.
+const oPath = 0x200000
+
+// statfs(2) flags.
+const (
+	stReadOnly   = 0x1
+	stNoSuid     = 0x2
+	stNoDev      = 0x4
+	stNoExec     = 0x8
+	stNoAtime    = 0x400
+	stNoDirAtime = 0x800
+	stRelAtime   = 0x1000
+)
+
+// mountFlags returns the flags path's mount has, as mount(2) flags. A bind
+// remount in a user namespace must repeat flags the host set, or fail.
+func mountFlags(path string) uintptr {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return 0
+	}
+	var flags uintptr
+	for stFlag, msFlag := range map[int64]uintptr{
+		stReadOnly:   syscall.MS_RDONLY,
+		stNoSuid:     syscall.MS_NOSUID,
+		stNoDev:      syscall.MS_NODEV,
+		stNoExec:     syscall.MS_NOEXEC,
+		stNoAtime:    syscall.MS_NOATIME,
+		stNoDirAtime: syscall.MS_NODIRATIME,
+		stRelAtime:   syscall.MS_RELATIME,
+	} {
+		if int64(st.Flags)&stFlag != 0 {
+			flags |= msFlag
+		}
+	}
+	return flags
+}
+
+// mount is a line of /proc/self/mountinfo.
+type mount struct {
+	point  string
+	fstype string
+}
+
+func mountInfo() ([]mount, error) {
+	f, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var mounts []mount
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		sep := -1
+		for i, field := range fields {
+			if field == "-" {
+				sep = i
+				break
+			}
+		}
+		if len(fields) < 5 || sep < 0 || sep+1 >= len(fields) {
+			continue
+		}
+		mounts = append(mounts, mount{point: unescape(fields[4]), fstype: fields[sep+1]})
+	}
+	return mounts, sc.Err()
+}
+
+func mountPoints() ([]string, error) {
+	mounts, err := mountInfo()
+	if err != nil {
+		return nil, err
+	}
+	points := make([]string, len(mounts))
+	for i, m := range mounts {
+		points[i] = m.point
+	}
+	return points, nil
+}
+
+// unescape decodes the octal escapes mountinfo uses for spaces and the like.
+func unescape(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) {
+			if n, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(n))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// loopbackUp brings up lo, the only interface in a fresh network namespace.
+func loopbackUp() error {
+	fd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_DGRAM|syscall.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return err
+	}
+	defer syscall.Close(fd)
+
+	var req struct {
+		name  [syscall.IFNAMSIZ]byte
+		flags uint16
+		_     [22]byte
+	}
+	copy(req.name[:], "lo")
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), syscall.SIOCGIFFLAGS, uintptr(unsafe.Pointer(&req))); errno != 0 {
+		return errno
+	}
+	req.flags |= syscall.IFF_UP
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), syscall.SIOCSIFFLAGS, uintptr(unsafe.Pointer(&req))); errno != 0 {
+		return errno
+	}
+	return nil
+}
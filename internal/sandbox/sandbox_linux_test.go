@@ -0,0 +1,150 @@
+//go:build linux
+
+package sandbox
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// The test binary re-executes itself as the sandbox's init, like aeon does.
+func TestMain(m *testing.M) {
+	Init()
+	os.Exit(m.Run())
+}
+
+func run(t *testing.T, opts Options, script string) (string, error) {
+	t.Helper()
+	if err := Check(Options{}); err != nil {
+		t.Skipf("sandbox unavailable: %v", err)
+	}
+	cmd := exec.Command("sh", "-c", script)
+	sb, err := Wrap(cmd, opts)
+	if err != nil {
+		return "", err
+	}
+	out, err := cmd.CombinedOutput()
+	if note := sb.Finish(); note != "" {
+		out = append(out, "\n"+note...)
+	}
+	return strings.TrimSpace(string(out)), err
+}
+
+func TestReadOnlyRootWithWritablePaths(t *testing.T) {
+	work := t.TempDir()
+	home, _ := os.UserHomeDir()
+
+	out, err := run(t, Options{Writable: []string{work}}, `
+		echo hi > `+work+`/out && echo wrote-work
+		touch `+home+`/.aeon-sandbox-test 2>/dev/null || echo home-read-only
+		touch /etc/aeon-sandbox-test 2>/dev/null || echo etc-read-only
+		echo discard > /dev/null && echo dev-null
+		echo scratch > /tmp/x && echo tmp-writable`)
+	if err != nil {
+		t.Fatalf("%v: %s", err, out)
+	}
+	for _, want := range []string{"wrote-work", "home-read-only", "etc-read-only", "dev-null", "tmp-writable"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+	if data, _ := os.ReadFile(filepath.Join(work, "out")); string(data) != "hi\n" {
+		t.Errorf("write to the writable path was lost: %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(os.TempDir(), "x")); err == nil && !strings.HasPrefix(work, os.TempDir()) {
+		t.Error("the sandbox's /tmp leaked to the host")
+	}
+}
+
+func TestHiddenPaths(t *testing.T) {
+	// Under home, since the sandbox's private /tmp would hide a temp dir anyway
+	home, _ := os.UserHomeDir()
+	hidden, err := os.MkdirTemp(home, ".aeon-sandbox-test")
+	if err != nil {
+		t.Skip(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(hidden) })
+	for _, dir := range []string{"skills", "workspace"} {
+		os.Mkdir(filepath.Join(hidden, dir), 0o755)
+	}
+	os.WriteFile(filepath.Join(hidden, "secrets.key"), []byte("key"), 0o600)
+	os.WriteFile(filepath.Join(hidden, "skills", "run.py"), []byte("skill"), 0o644)
+
+	opts := Options{
+		Hidden:   []string{hidden},
+		Visible:  []string{filepath.Join(hidden, "skills"), filepath.Join(hidden, "missing")},
+		Writable: []string{filepath.Join(hidden, "workspace")},
+	}
+	out, err := run(t, opts, `
+		cat `+hidden+`/secrets.key 2>/dev/null || echo key-hidden
+		cat `+hidden+`/skills/run.py
+		touch `+hidden+`/skills/x 2>/dev/null || echo skills-read-only
+		touch `+hidden+`/x 2>/dev/null || echo hidden-read-only
+		echo hi > `+hidden+`/workspace/out && echo wrote-workspace`)
+	if err != nil {
+		t.Fatalf("%v: %s", err, out)
+	}
+	for _, want := range []string{"key-hidden", "skill", "skills-read-only", "hidden-read-only", "wrote-workspace"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+	if data, _ := os.ReadFile(filepath.Join(hidden, "workspace", "out")); string(data) != "hi\n" {
+		t.Errorf("write to the writable path was lost: %q", data)
+	}
+}
+
+func TestNamespaces(t *testing.T) {
+	out, err := run(t, Options{}, `echo pid=$$; hostname; cat /proc/net/dev | tail -n +3 | cut -d: -f1 | tr -d ' '`)
+	if err != nil {
+		t.Fatalf("%v: %s", err, out)
+	}
+	lines := strings.Fields(out)
+	if len(lines) != 3 || lines[0] != "pid=1" || lines[1] != "sandbox" || lines[2] != "lo" {
+		t.Errorf("expected own PID, UTS and network namespaces, got:\n%s", out)
+	}
+
+	out, err = run(t, Options{Network: true}, `cat /proc/net/dev | tail -n +3 | wc -l`)
+	if err != nil {
+		t.Fatalf("%v: %s", err, out)
+	}
+	hostIfaces, _ := os.ReadFile("/proc/net/dev")
+	if want := len(strings.Split(strings.TrimSpace(string(hostIfaces)), "\n")) - 2; out != strconv.Itoa(want) {
+		t.Errorf("with network on, expected the host's %d interfaces, got %s", want, out)
+	}
+}
+
+func TestEnvironmentFiltered(t *testing.T) {
+	t.Setenv("AEON_TEST_SECRET", "s3cret")
+	t.Setenv("AEON_TEST_ALLOWED", "yes")
+
+	out, err := run(t, Options{Env: []string{"AEON_TEST_ALLOWED"}}, `echo "[$AEON_TEST_SECRET][$AEON_TEST_ALLOWED][$PATH]"`)
+	if err != nil {
+		t.Fatalf("%v: %s", err, out)
+	}
+	if !strings.HasPrefix(out, "[][yes][/") {
+		t.Errorf("unexpected environment: %s", out)
+	}
+}
+
+func TestMissingWritablePath(t *testing.T) {
+	cmd := exec.Command("true")
+	if _, err := Wrap(cmd, Options{Writable: []string{"/nonexistent/aeon"}}); err == nil {
+		t.Error("expected an error for a missing writable path")
+	}
+}
+
+func TestLimits(t *testing.T) {
+	opts := Options{MemoryMB: 32, Pids: 8, CPUs: 0.5}
+	if err := Check(opts); err != nil {
+		t.Skipf("cgroup limits unavailable: %v", err)
+	}
+	out, _ := run(t, opts, `for i in 1 2 3 4 5 6 7 8 9 10; do sleep 1 & done; wait`)
+	if !strings.Contains(out, "limit of 8 processes") {
+		t.Errorf("expected the process limit to be reported, got:\n%s", out)
+	}
+}
@@ -0,0 +1,25 @@
+//go:build !linux
+
+package sandbox
+
+import (
+	"errors"
+	"os/exec"
+)
+
+var errUnsupported = errors.New("sandbox needs Linux")
+
+// Sandbox is a command prepared by Wrap.
+type Sandbox struct{}
+
+// Init does nothing outside Linux.
+func Init() {}
+
+// Check reports that sandboxing is unavailable.
+func Check(Options) error { return errUnsupported }
+
+// Wrap fails outside Linux, so sandboxed commands never run unconfined.
+func Wrap(*exec.Cmd, Options) (*Sandbox, error) { return nil, errUnsupported }
+
+// Finish does nothing outside Linux.
+func (s *Sandbox) Finish() string { return "" }
@@ -0,0 +1,200 @@
+package scheduler
+
+import (
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronSpec is a parsed 5-field cron expression: minute hour day-of-month
+// month day-of-week. Each field is a bitset of allowed values.
+type cronSpec struct {
+	minute, hour, dom, month, dow uint64
+	domAny, dowAny                bool // field was "*", for the day-matching rule
+}
+
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var monthNames = map[string]int{
+	"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+	"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+}
+
+var dayNames = map[string]int{"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6}
+
+// isCronExpr reports whether schedule looks like a cron expression rather
+// than one of the natural-language forms.
+func isCronExpr(schedule string) bool {
+	return strings.HasPrefix(schedule, "@") || len(strings.Fields(schedule)) == 5
+}
+
+// parseCron parses a standard 5-field expression or an @macro. Fields accept
+// *, lists (1,15), ranges (9-17), steps (*/15, 0-30/10) and, for month and
+// day-of-week, names (jan, mon). Day-of-week 7 is Sunday.
+func parseCron(expr string) (*cronSpec, error) {
+	expr = strings.TrimSpace(strings.ToLower(expr))
+	if m, ok := cronMacros[expr]; ok {
+		expr = m
+	}
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron expression needs 5 fields (minute hour day month weekday), got %d", len(fields))
+	}
+
+	var spec cronSpec
+	var err error
+	if spec.minute, err = parseCronField(fields[0], 0, 59, nil); err != nil {
+		return nil, fmt.Errorf("minute: %w", err)
+	}
+	if spec.hour, err = parseCronField(fields[1], 0, 23, nil); err != nil {
+		return nil, fmt.Errorf("hour: %w", err)
+	}
+	if spec.dom, err = parseCronField(fields[2], 1, 31, nil); err != nil {
+		return nil, fmt.Errorf("day of month: %w", err)
+	}
+	if spec.month, err = parseCronField(fields[3], 1, 12, monthNames); err != nil {
+		return nil, fmt.Errorf("month: %w", err)
+	}
+	if spec.dow, err = parseCronField(fields[4], 0, 7, dayNames); err != nil {
+		return nil, fmt.Errorf("day of week: %w", err)
+	}
+	if spec.dow&(1<<7) != 0 {
+		spec.dow = spec.dow&^(1<<7) | 1
+	}
+	spec.domAny = fields[2] == "*" || strings.HasPrefix(fields[2], "*/")
+	spec.dowAny = fields[4] == "*" || strings.HasPrefix(fields[4], "*/")
+	return &spec, nil
+}
+
+func parseCronField(field string, min, max int, names map[string]int) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		if i := strings.IndexByte(part, '/'); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			rangePart, step = part[:i], n
+		}
+
+		lo, hi := min, max
+		switch {
+		case rangePart == "*":
+		case strings.Contains(rangePart, "-"):
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if lo, err = cronValue(bounds[0], names); err != nil {
+				return 0, err
+			}
+			if hi, err = cronValue(bounds[1], names); err != nil {
+				return 0, err
+			}
+		default:
+			v, err := cronValue(rangePart, names)
+			if err != nil {
+				return 0, err
+			}
+			lo, hi = v, v
+			if step > 1 {
+				hi = max // "5/15" means from 5 every 15
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("%q out of range %d-%d", part, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			set |= 1 << uint(v)
+		}
+	}
+	return set, nil
+}
+
+func cronValue(s string, names map[string]int) (int, error) {
+	if v, ok := names[s]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", s)
+	}
+	return v, nil
+}
+
+// dayMatches applies the cron rule: if both day fields are restricted, a day
+// matches when either does; otherwise both must.
+func (c *cronSpec) dayMatches(t time.Time) bool {
+	if c.month&(1<<uint(t.Month())) == 0 {
+		return false
+	}
+	domOK := c.dom&(1<<uint(t.Day())) != 0
+	dowOK := c.dow&(1<<uint(t.Weekday())) != 0
+	if !c.domAny && !c.dowAny {
+		return domOK || dowOK
+	}
+	return domOK && dowOK
+}
+
+// cronSearchDays bounds the search for expressions that rarely or never
+// match, such as "0 0 30 2 *".
+const cronSearchDays = 366 * 5
+
+// next returns the first fire time strictly after from, evaluated in loc's
+// wall clock. A time skipped by a DST jump fires at the equivalent instant
+// after the jump when the hour is fixed (so a 02:30 daily job still runs
+// that day) and is dropped when the hour is a wildcard. A time repeated by a
+// DST fallback fires once.
+func (c *cronSpec) next(from time.Time, loc *time.Location) (time.Time, error) {
+	from = from.In(loc)
+	day := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, loc)
+	fixedHour := bits.OnesCount64(c.hour) < 24
+
+	for i := 0; i < cronSearchDays; i++ {
+		d := day.AddDate(0, 0, i)
+		// Use noon to read the date; midnight may not exist on DST days
+		date := time.Date(d.Year(), d.Month(), d.Day(), 12, 0, 0, 0, loc)
+		if !c.dayMatches(date) {
+			continue
+		}
+		for h := 0; h < 24; h++ {
+			if c.hour&(1<<uint(h)) == 0 {
+				continue
+			}
+			for m := 0; m < 60; m++ {
+				if c.minute&(1<<uint(m)) == 0 {
+					continue
+				}
+				t := time.Date(date.Year(), date.Month(), date.Day(), h, m, 0, 0, loc)
+				if t.Hour() != h || t.Minute() != m {
+					// Wall time doesn't exist today (DST gap)
+					if !fixedHour {
+						continue
+					}
+					t = afterGap(date, h, m, loc)
+				}
+				if t.After(from) {
+					return t, nil
+				}
+			}
+		}
+	}
+	return time.Time{}, fmt.Errorf("cron expression never fires")
+}
+
+// afterGap maps a wall time skipped by a DST jump to the same instant read
+// with the offset in force before the jump: 02:30 EST is 03:30 EDT.
+func afterGap(date time.Time, h, m int, loc *time.Location) time.Time {
+	wall := time.Date(date.Year(), date.Month(), date.Day(), h, m, 0, 0, time.UTC)
+	_, offset := wall.Add(-12 * time.Hour).In(loc).Zone()
+	return wall.Add(-time.Duration(offset) * time.Second).In(loc)
+}
@@ -0,0 +1,99 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCron(t *testing.T) {
+	valid := []string{
+		"* * * * *", "*/15 9-17 * * 1-5", "0 0 1,15 * *", "0 12 * jan-mar mon-fri",
+		"5/10 * * * *", "0 0 * * 7", "@daily", "@weekly", "@yearly",
+	}
+	for _, expr := range valid {
+		if _, err := parseCron(expr); err != nil {
+			t.Errorf("%q: unexpected error: %v", expr, err)
+		}
+	}
+
+	invalid := []string{"* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "*/0 * * * *", "5-1 * * * *", "@often"}
+	for _, expr := range invalid {
+		if _, err := parseCron(expr); err == nil {
+			t.Errorf("%q: expected error", expr)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	utc := time.UTC
+	tests := []struct {
+		expr string
+		from time.Time
+		want time.Time
+	}{
+		// Friday evening rolls to Monday morning
+		{"*/15 9-17 * * 1-5", time.Date(2024, 1, 5, 17, 50, 0, 0, utc), time.Date(2024, 1, 8, 9, 0, 0, 0, utc)},
+		// Day-of-month and day-of-week both restricted: either matches
+		{"0 0 1 * mon", time.Date(2024, 1, 2, 0, 0, 0, 0, utc), time.Date(2024, 1, 8, 0, 0, 0, 0, utc)},
+		// Sunday as 7
+		{"0 8 * * 7", time.Date(2024, 1, 1, 0, 0, 0, 0, utc), time.Date(2024, 1, 7, 8, 0, 0, 0, utc)},
+		// Step from an offset
+		{"5/20 * * * *", time.Date(2024, 1, 1, 10, 26, 0, 0, utc), time.Date(2024, 1, 1, 10, 45, 0, 0, utc)},
+		// Leap day
+		{"0 0 29 2 *", time.Date(2024, 3, 1, 0, 0, 0, 0, utc), time.Date(2028, 2, 29, 0, 0, 0, 0, utc)},
+	}
+	for _, tt := range tests {
+		spec, err := parseCron(tt.expr)
+		if err != nil {
+			t.Fatalf("%q: %v", tt.expr, err)
+		}
+		got, err := spec.next(tt.from, utc)
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("%q from %v: got %v (%v), want %v", tt.expr, tt.from, got, err, tt.want)
+		}
+	}
+
+	spec, _ := parseCron("0 0 30 2 *")
+	if _, err := spec.next(time.Now(), utc); err == nil {
+		t.Error("expected an error for an expression that never fires")
+	}
+}
+
+func TestCronNextDST(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	next := func(expr string, from time.Time) time.Time {
+		t.Helper()
+		spec, err := parseCron(expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := spec.next(from, ny)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+
+	// 2024-03-10: clocks jump from 02:00 to 03:00
+	springDay := time.Date(2024, 3, 10, 0, 0, 0, 0, ny)
+	if got := next("30 2 * * *", springDay); got.Day() != 10 || got.Hour() != 3 || got.Minute() != 30 {
+		t.Errorf("fixed-hour job in the gap should run after the jump, got %v", got)
+	}
+	if got := next("*/30 * * * *", time.Date(2024, 3, 10, 1, 45, 0, 0, ny)); got.Hour() != 3 || got.Minute() != 0 {
+		t.Errorf("wildcard-hour job should skip the missing hour, got %v", got)
+	}
+	if got := next("0 9 * * *", springDay); got.Hour() != 9 || got.Sub(springDay) != 8*time.Hour {
+		t.Errorf("09:00 on the short day should be 8 hours after midnight, got %v", got)
+	}
+
+	// 2024-11-03: 01:00-02:00 happens twice; the job runs once
+	fallDay := time.Date(2024, 11, 3, 0, 0, 0, 0, ny)
+	first := next("30 1 * * *", fallDay)
+	second := next("30 1 * * *", first)
+	if first.Day() != 3 || second.Day() != 4 || second.Hour() != 1 {
+		t.Errorf("expected one run on Nov 3 then Nov 4, got %v and %v", first, second)
+	}
+}
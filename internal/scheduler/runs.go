@@ -0,0 +1,173 @@
+package scheduler
+
+import (
+	"database/sql"
+	"time"
+	"unicode/utf8"
+)
+
+// Run statuses.
+const (
+	RunRunning     = "running"
+	RunOK          = "ok"
+	RunFailed      = "failed"
+	RunTimeout     = "timeout"
+	RunCancelled   = "cancelled"   // stopped by shutdown or StopAll
+	RunInterrupted = "interrupted" // the process exited mid-run
+	RunSkipped     = "skipped"     // missed while down, dropped by the misfire policy
+)
+
+// Run triggers.
+const (
+	TriggerSchedule = "schedule" // fired on time
+	TriggerCatchUp  = "catchup"  // fired late, for runs missed while the scheduler was down
+)
+
+const (
+	maxRunOutput  = 2000 // bytes of output kept per run
+	maxRunsPerJob = 100  // older runs are pruned
+)
+
+// Run is one execution of a job, kept in cron_runs.
+type Run struct {
+	ID        int64
+	JobID     int64
+	JobName   string
+	Trigger   string
+	Status    string
+	StartedAt time.Time
+	EndedAt   *time.Time
+	Output    string // excerpt
+	Error     string
+}
+
+// Duration returns how long the run took, or zero if it hasn't ended.
+func (r Run) Duration() time.Duration {
+	if r.EndedAt == nil {
+		return 0
+	}
+	return r.EndedAt.Sub(r.StartedAt)
+}
+
+func initRunsSchema(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS cron_runs (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			job_id INTEGER NOT NULL,
+			trigger TEXT NOT NULL,
+			status TEXT NOT NULL,
+			started_at DATETIME NOT NULL,
+			ended_at DATETIME,
+			output TEXT DEFAULT '',
+			error TEXT DEFAULT ''
+		);
+		CREATE INDEX IF NOT EXISTS idx_cron_runs_job ON cron_runs(job_id, id);
+	`)
+	return err
+}
+
+// startRun records a run as running and returns its ID.
+func (s *Scheduler) startRun(jobID int64, trigger string, start time.Time) int64 {
+	res, err := s.db.Exec(
+		"INSERT INTO cron_runs (job_id, trigger, status, started_at) VALUES (?, ?, ?, ?)",
+		jobID, trigger, RunRunning, start.UTC(),
+	)
+	if err != nil {
+		s.logger.Warn("recording cron run", "job_id", jobID, "error", err)
+		return 0
+	}
+	id, _ := res.LastInsertId()
+	return id
+}
+
+// finishRun stores a run's outcome and prunes the job's old runs.
+func (s *Scheduler) finishRun(runID, jobID int64, status, output string, runErr error) {
+	if runID == 0 {
+		return
+	}
+	var errText string
+	if runErr != nil {
+		errText = runErr.Error()
+	}
+	_, err := s.db.Exec(
+		"UPDATE cron_runs SET status = ?, ended_at = ?, output = ?, error = ? WHERE id = ?",
+		status, time.Now().UTC(), excerpt(s.scrub(output)), excerpt(s.scrub(errText)), runID,
+	)
+	if err != nil {
+		s.logger.Warn("recording cron run", "job_id", jobID, "error", err)
+		return
+	}
+	s.db.Exec(`DELETE FROM cron_runs WHERE job_id = ? AND id NOT IN
+		(SELECT id FROM cron_runs WHERE job_id = ? ORDER BY id DESC LIMIT ?)`, jobID, jobID, maxRunsPerJob)
+}
+
+// recordSkipped records runs dropped by the skip misfire policy.
+func (s *Scheduler) recordSkipped(jobID int64, note string) {
+	now := time.Now().UTC()
+	s.db.Exec(
+		"INSERT INTO cron_runs (job_id, trigger, status, started_at, ended_at, output) VALUES (?, ?, ?, ?, ?, ?)",
+		jobID, TriggerCatchUp, RunSkipped, now, now, note,
+	)
+}
+
+// markInterrupted closes runs left running by a process that exited.
+func (s *Scheduler) markInterrupted() {
+	s.db.Exec("UPDATE cron_runs SET status = ?, ended_at = ? WHERE status = ?",
+		RunInterrupted, time.Now().UTC(), RunRunning)
+}
+
+// History returns the most recent runs, newest first: of one job, or of
+// all jobs when jobID is 0.
+func (s *Scheduler) History(jobID int64, limit int) ([]Run, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+	query := `SELECT r.id, r.job_id, COALESCE(j.name, ''), r.trigger, r.status, r.started_at, r.ended_at, r.output, r.error
+		FROM cron_runs r LEFT JOIN cron_jobs j ON j.id = r.job_id`
+	args := []any{}
+	if jobID > 0 {
+		query += " WHERE r.job_id = ?"
+		args = append(args, jobID)
+	}
+	query += " ORDER BY r.id DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var runs []Run
+	for rows.Next() {
+		var r Run
+		var ended sql.NullTime
+		if err := rows.Scan(&r.ID, &r.JobID, &r.JobName, &r.Trigger, &r.Status, &r.StartedAt, &ended, &r.Output, &r.Error); err != nil {
+			return nil, err
+		}
+		if ended.Valid {
+			r.EndedAt = &ended.Time
+		}
+		runs = append(runs, r)
+	}
+	return runs, rows.Err()
+}
+
+func (s *Scheduler) scrub(text string) string {
+	if s.scrubber == nil || text == "" {
+		return text
+	}
+	return s.scrubber.ScrubCredentials(text)
+}
+
+// excerpt truncates text to maxRunOutput bytes on a rune boundary.
+func excerpt(text string) string {
+	if len(text) <= maxRunOutput {
+		return text
+	}
+	cut := maxRunOutput
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	return text[:cut] + "\n... [truncated]"
+}
@@ -9,16 +9,32 @@ import (
 	"strings"
 	"sync"
 	"time"
+	_ "time/tzdata" // per-job timezones work without system zoneinfo
+)
+
+// Job kinds: what a job does when it fires.
+const (
+	KindReminder = "reminder" // send Command as a reminder message
+	KindSkill    = "skill"    // run SkillName with Params
+	KindShell    = "shell"    // run Command through shell_exec and its security policy
+	KindAgent    = "agent"    // run Command as a prompt for a background agent
 )
 
 // Job represents a scheduled task.
 type Job struct {
 	ID        int64
 	Name      string
-	Schedule  string // cron expression or "every Xm/Xh/Xd"
-	SkillName string // skill to run, or empty for shell command
-	Command   string // shell command if no skill
+	Schedule  string // cron expression, "every Xm/Xh/Xd", "in X" or "at [date] time"
+	Timezone  string // IANA zone the schedule is evaluated in; empty uses the scheduler default
+	Kind      string // one of the Kind constants; empty is inferred, see JobKind
+	SkillName string // skill to run for KindSkill
+	Command   string // reminder text, shell command or agent prompt
 	Params    string // JSON params for skill
+	Channel   string // channel results are delivered to; empty uses the default targets
+	ChatID    string // chat within Channel
+	Timeout   time.Duration
+	Misfire   string // one of the Misfire constants; empty means MisfireOnce
+	Role      string // role of the user who created it, whose tool limits apply when it runs; empty for none
 	Enabled   bool
 	LastRun   *time.Time
 	NextRun   time.Time
@@ -26,16 +42,70 @@ type Job struct {
 	CreatedAt time.Time
 }
 
-const maxConsecutiveFailures = 5
+// JobKind returns the job's kind. Jobs created before kinds existed have
+// none: a skill name means a skill, a one-shot schedule a reminder, and
+// anything else an agent prompt, which is how they used to run.
+func (j Job) JobKind() string {
+	switch {
+	case j.Kind != "":
+		return j.Kind
+	case j.SkillName != "":
+		return KindSkill
+	case IsOneShot(j.Schedule):
+		return KindReminder
+	default:
+		return KindAgent
+	}
+}
+
+// MisfirePolicy returns what happens to runs missed while the scheduler was down.
+func (j Job) MisfirePolicy() string {
+	if j.Misfire == "" {
+		return MisfireOnce
+	}
+	return j.Misfire
+}
+
+// AutoPaused reports whether the job was paused for failing too often.
+func (j Job) AutoPaused() bool {
+	return !j.Enabled && j.FailCount >= maxConsecutiveFailures
+}
+
+// TriggerFunc runs a job. The context carries the job's timeout; the
+// returned output is kept in the run history and the error marks the run
+// as failed.
+type TriggerFunc func(ctx context.Context, job Job) (string, error)
+
+// Misfire policies: what happens to runs missed while the scheduler was down.
+const (
+	MisfireSkip = "skip" // drop them and wait for the next occurrence
+	MisfireOnce = "once" // run once, late
+	MisfireAll  = "all"  // run each missed occurrence, up to maxCatchUpRuns
+)
+
+const (
+	maxConsecutiveFailures = 5
+	maxCatchUpRuns         = 10
+	misfireGrace           = 2 * time.Minute // later than this counts as missed
+)
+
+// DefaultJobTimeout bounds a run of a job without its own timeout.
+const DefaultJobTimeout = 5 * time.Minute
+
+// jobColumns is the column list scanJob and scanJobs expect.
+const jobColumns = "id, name, schedule, timezone, kind, skill_name, command, params, channel, chat_id, timeout_seconds, " +
+	"misfire, role, enabled, last_run, next_run, fail_count, created_at"
 
 // Scheduler manages cron-like scheduled jobs.
 type Scheduler struct {
 	db        *sql.DB
 	logger    *slog.Logger
+	loc       *time.Location // default timezone for jobs without one
 	mu        sync.Mutex
 	running   map[int64]context.CancelFunc // active job cancellers
 	maxConc   int                          // max concurrent jobs
-	onTrigger func(job Job)                // callback when job fires
+	onTrigger TriggerFunc                  // runs a job when it fires
+	scrubber  interface{ ScrubCredentials(string) string }
 }
 
 // New creates a scheduler backed by the given SQLite database.
@@ -47,6 +117,7 @@ func New(db *sql.DB, logger *slog.Logger) (*Scheduler, error) {
 	return &Scheduler{
 		db:      db,
 		logger:  logger,
+		loc:     time.Local,
 		running: make(map[int64]context.CancelFunc),
 		maxConc: 3,
 	}, nil
@@ -68,30 +139,149 @@ func initCronSchema(db *sql.DB) error {
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
 		);
 	`)
+	if err != nil {
+		return err
+	}
+	for _, col := range []struct{ name, def string }{
+		{"timezone", "TEXT DEFAULT ''"},
+		{"kind", "TEXT DEFAULT ''"},
+		{"channel", "TEXT DEFAULT ''"},
+		{"chat_id", "TEXT DEFAULT ''"},
+		{"timeout_seconds", "INTEGER DEFAULT 0"},
+		{"misfire", "TEXT DEFAULT ''"},
+		{"role", "TEXT DEFAULT ''"},
+	} {
+		if err := addColumnIfMissing(db, "cron_jobs", col.name, col.def); err != nil {
+			return err
+		}
+	}
+	if err := initRunsSchema(db); err != nil {
+		return err
+	}
+	return normalizeNextRuns(db)
+}
+
+// addColumnIfMissing adds a column to an existing table, for databases created by older versions.
+func addColumnIfMissing(db *sql.DB, table, column, def string) error {
+	var n int
+	err := db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`, table, column).Scan(&n)
+	if err != nil || n > 0 {
+		return err
+	}
+	_, err = db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, def))
 	return err
 }
 
+// normalizeNextRuns rewrites next_run in UTC. Times are compared as text in
+// SQL, so every row must use the same offset; older versions stored local time.
+func normalizeNextRuns(db *sql.DB) error {
+	rows, err := db.Query("SELECT id, next_run FROM cron_jobs")
+	if err != nil {
+		return err
+	}
+	type row struct {
+		id   int64
+		next time.Time
+	}
+	var stale []row
+	for rows.Next() {
+		var r row
+		if err := rows.Scan(&r.id, &r.next); err == nil && r.next.Location() != time.UTC {
+			stale = append(stale, r)
+		}
+	}
+	rows.Close()
+
+	for _, r := range stale {
+		if _, err := db.Exec("UPDATE cron_jobs SET next_run = ? WHERE id = ?", r.next.UTC(), r.id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // SetMaxConcurrent sets the max number of concurrent cron jobs.
 func (s *Scheduler) SetMaxConcurrent(n int) {
 	s.maxConc = n
 }
 
-// OnTrigger sets the callback invoked when a job fires.
-func (s *Scheduler) OnTrigger(fn func(Job)) {
+// SetScrubber sets the credential scrubber for output kept in run history.
+func (s *Scheduler) SetScrubber(sc interface{ ScrubCredentials(string) string }) {
+	s.scrubber = sc
+}
+
+// SetLocation sets the timezone for jobs created without one.
+func (s *Scheduler) SetLocation(loc *time.Location) {
+	if loc != nil {
+		s.loc = loc
+	}
+}
+
+// location resolves the timezone a job's schedule is evaluated in.
+func (s *Scheduler) location(tz string) (*time.Location, error) {
+	if tz == "" {
+		return s.loc, nil
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil, fmt.Errorf("unknown timezone %q", tz)
+	}
+	return loc, nil
+}
+
+// OnTrigger sets the function that runs a job when it fires.
+func (s *Scheduler) OnTrigger(fn TriggerFunc) {
 	s.onTrigger = fn
 }
 
-// Create adds a new scheduled job.
+// Create adds a new scheduled job in the default timezone.
 func (s *Scheduler) Create(name, schedule, skillName, command, params string) (int64, error) {
-	nextRun, err := computeNextRun(schedule, time.Now())
+	return s.CreateJob(Job{Name: name, Schedule: schedule, SkillName: skillName, Command: command, Params: params})
+}
+
+// CreateJob adds a new scheduled job from its name, schedule, timezone, kind,
+// skill, command, params, target, timeout, misfire policy and role. Other fields
+// are ignored.
+func (s *Scheduler) CreateJob(job Job) (int64, error) {
+	job.Kind = job.JobKind()
+	switch job.Kind {
+	case KindSkill:
+		if job.SkillName == "" {
+			return 0, fmt.Errorf("skill jobs need a skill name")
+		}
+	case KindReminder, KindShell, KindAgent:
+		if job.Command == "" {
+			return 0, fmt.Errorf("%s jobs need a command", job.Kind)
+		}
+	default:
+		return 0, fmt.Errorf("unknown job kind %q (use %s, %s, %s or %s)", job.Kind, KindReminder, KindSkill, KindShell, KindAgent)
+	}
+	if job.Timeout < 0 {
+		return 0, fmt.Errorf("timeout must not be negative")
+	}
+	switch job.Misfire {
+	case "", MisfireSkip, MisfireOnce, MisfireAll:
+	default:
+		return 0, fmt.Errorf("unknown misfire policy %q (use %s, %s or %s)", job.Misfire, MisfireSkip, MisfireOnce, MisfireAll)
+	}
+
+	loc, err := s.location(job.Timezone)
+	if err != nil {
+		return 0, err
+	}
+	nextRun, err := computeNextRun(job.Schedule, time.Now(), loc)
 	if err != nil {
 		return 0, fmt.Errorf("invalid schedule: %w", err)
 	}
+	if job.Params == "" {
+		job.Params = "{}"
+	}
 
 	result, err := s.db.Exec(
-		`INSERT INTO cron_jobs (name, schedule, skill_name, command, params, next_run)
-		 VALUES (?, ?, ?, ?, ?, ?)`,
-		name, schedule, skillName, command, params, nextRun,
+		`INSERT INTO cron_jobs (name, schedule, timezone, kind, skill_name, command, params, channel, chat_id, timeout_seconds, misfire, role, next_run)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		job.Name, job.Schedule, job.Timezone, job.Kind, job.SkillName, job.Command, job.Params,
+		job.Channel, job.ChatID, int64(job.Timeout/time.Second), job.Misfire, job.Role, nextRun.UTC(),
 	)
 	if err != nil {
 		return 0, err
@@ -100,9 +290,32 @@ func (s *Scheduler) Create(name, schedule, skillName, command, params string) (i
 	return result.LastInsertId()
 }
 
+// NextRuns returns the next n fire times of a schedule in the given timezone
+// (empty for the default), for previews. One-shot schedules return one time.
+func (s *Scheduler) NextRuns(schedule, tz string, n int) ([]time.Time, error) {
+	loc, err := s.location(tz)
+	if err != nil {
+		return nil, err
+	}
+	var runs []time.Time
+	from := time.Now()
+	for i := 0; i < n; i++ {
+		next, err := computeNextRun(schedule, from, loc)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, next)
+		if IsOneShot(schedule) {
+			break
+		}
+		from = next
+	}
+	return runs, nil
+}
+
 // List returns all jobs, optionally filtered by enabled status.
 func (s *Scheduler) List(enabledOnly bool) ([]Job, error) {
-	query := "SELECT id, name, schedule, skill_name, command, params, enabled, last_run, next_run, fail_count, created_at FROM cron_jobs"
+	query := "SELECT " + jobColumns + " FROM cron_jobs"
 	if enabledOnly {
 		query += " WHERE enabled = 1"
 	}
@@ -120,7 +333,7 @@ func (s *Scheduler) List(enabledOnly bool) ([]Job, error) {
 // Get returns a job by ID.
 func (s *Scheduler) Get(id int64) (*Job, error) {
 	row := s.db.QueryRow(
-		"SELECT id, name, schedule, skill_name, command, params, enabled, last_run, next_run, fail_count, created_at FROM cron_jobs WHERE id = ?",
+		"SELECT "+jobColumns+" FROM cron_jobs WHERE id = ?",
 		id,
 	)
 	return scanJob(row)
@@ -132,16 +345,35 @@ func (s *Scheduler) Pause(id int64) error {
 	return err
 }
 
-// Resume enables a job and resets fail count.
+// Resume enables a job, resets fail count and schedules its next run from now.
 func (s *Scheduler) Resume(id int64) error {
-	nextRun := time.Now()
-	_, err := s.db.Exec("UPDATE cron_jobs SET enabled = 1, fail_count = 0, next_run = ? WHERE id = ?", nextRun, id)
+	job, err := s.Get(id)
+	if err != nil {
+		return err
+	}
+	nextRun, err := s.nextRun(job, time.Now())
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec("UPDATE cron_jobs SET enabled = 1, fail_count = 0, next_run = ? WHERE id = ?", nextRun.UTC(), id)
 	return err
 }
 
+// nextRun computes a job's next fire time after from in its timezone.
+func (s *Scheduler) nextRun(job *Job, from time.Time) (time.Time, error) {
+	loc, err := s.location(job.Timezone)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return computeNextRun(job.Schedule, from, loc)
+}
+
 // Delete removes a job.
 func (s *Scheduler) Delete(id int64) error {
-	_, err := s.db.Exec("DELETE FROM cron_jobs WHERE id = ?", id)
+	if _, err := s.db.Exec("DELETE FROM cron_jobs WHERE id = ?", id); err != nil {
+		return err
+	}
+	_, err := s.db.Exec("DELETE FROM cron_runs WHERE job_id = ?", id)
 	return err
 }
 
@@ -153,10 +385,10 @@ func (s *Scheduler) RecordSuccess(id int64) error {
 		return err
 	}
 
-	nextRun, _ := computeNextRun(job.Schedule, now)
+	nextRun, _ := s.nextRun(job, now)
 	_, err = s.db.Exec(
 		"UPDATE cron_jobs SET last_run = ?, next_run = ?, fail_count = 0 WHERE id = ?",
-		now, nextRun, id,
+		now.UTC(), nextRun.UTC(), id,
 	)
 	return err
 }
@@ -177,10 +409,10 @@ func (s *Scheduler) RecordFailure(id int64) error {
 			"job_id", id, "name", job.Name, "failures", newFails)
 	}
 
-	nextRun, _ := computeNextRun(job.Schedule, now)
+	nextRun, _ := s.nextRun(job, now)
 	_, err = s.db.Exec(
 		"UPDATE cron_jobs SET last_run = ?, next_run = ?, fail_count = ?, enabled = ? WHERE id = ?",
-		now, nextRun, newFails, enabled, id,
+		now.UTC(), nextRun.UTC(), newFails, enabled, id,
 	)
 	return err
 }
@@ -192,8 +424,10 @@ func (s *Scheduler) Count() (int, error) {
 	return count, err
 }
 
-// Start begins the scheduler tick loop.
+// Start begins the scheduler tick loop. Runs left running by a previous
+// process are marked interrupted first.
 func (s *Scheduler) Start(ctx context.Context) {
+	s.markInterrupted()
 	go s.tickLoop(ctx)
 }
 
@@ -218,8 +452,8 @@ func (s *Scheduler) checkAndFire(ctx context.Context) {
 	now := time.Now()
 
 	rows, err := s.db.Query(
-		"SELECT id, name, schedule, skill_name, command, params, enabled, last_run, next_run, fail_count, created_at FROM cron_jobs WHERE enabled = 1 AND next_run <= ?",
-		now,
+		"SELECT "+jobColumns+" FROM cron_jobs WHERE enabled = 1 AND next_run <= ?",
+		now.UTC(),
 	)
 	if err != nil {
 		s.logger.Error("checking cron jobs", "error", err)
@@ -253,13 +487,29 @@ func (s *Scheduler) checkAndFire(ctx context.Context) {
 }
 
 func (s *Scheduler) fireJob(ctx context.Context, job Job) {
-	jobCtx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	fireCtx, cancel := context.WithCancel(ctx)
 
 	s.mu.Lock()
 	s.running[job.ID] = cancel
 	s.mu.Unlock()
 
-	s.logger.Info("firing cron job", "id", job.ID, "name", job.Name)
+	runs, trigger := 1, TriggerSchedule
+	if late := time.Since(job.NextRun); late > misfireGrace {
+		trigger = TriggerCatchUp
+		missed := s.missedRuns(job, time.Now())
+		s.logger.Warn("cron job misfired", "id", job.ID, "name", job.Name,
+			"due", job.NextRun, "missed", missed, "policy", job.MisfirePolicy())
+		switch job.MisfirePolicy() {
+		case MisfireSkip:
+			runs = 0
+			s.recordSkipped(job.ID, fmt.Sprintf("missed %d run(s) since %s; skipped by misfire policy",
+				missed, job.NextRun.Format(time.RFC3339)))
+		case MisfireAll:
+			runs = missed
+		}
+	}
+
+	s.logger.Info("firing cron job", "id", job.ID, "name", job.Name, "kind", job.JobKind(), "trigger", trigger, "runs", runs)
 
 	go func() {
 		defer func() {
@@ -269,23 +519,105 @@ func (s *Scheduler) fireJob(ctx context.Context, job Job) {
 			s.mu.Unlock()
 		}()
 
-		if s.onTrigger != nil {
-			s.onTrigger(job)
+		if runs == 0 {
+			s.skipTo(job, time.Now())
+		}
+		for i := 0; i < runs; i++ {
+			if !s.runOnce(fireCtx, job, trigger) {
+				return
+			}
 		}
 
-		// Auto-disable one-shot reminders after they fire
+		// Auto-disable one-shot jobs after they fire
 		if IsOneShot(job.Schedule) {
-			s.logger.Info("one-shot reminder fired, disabling", "id", job.ID, "name", job.Name)
 			s.Pause(job.ID)
-		} else {
-			// Update next_run so the job doesn't re-fire on next tick
-			s.RecordSuccess(job.ID)
 		}
-
-		_ = jobCtx // context passed to trigger if needed
 	}()
 }
 
+// runOnce runs a job with its timeout, records the run and the job's
+// outcome, and reports whether more catch-up runs may follow.
+func (s *Scheduler) runOnce(ctx context.Context, job Job, trigger string) bool {
+	timeout := job.Timeout
+	if timeout <= 0 {
+		timeout = DefaultJobTimeout
+	}
+	runCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	runID := s.startRun(job.ID, trigger, start)
+	out, err := s.run(runCtx, job)
+
+	if ctx.Err() != nil {
+		// Stopped by shutdown or StopAll; it runs again when next due
+		s.logger.Info("cron job cancelled", "id", job.ID, "name", job.Name)
+		s.finishRun(runID, job.ID, RunCancelled, out, err)
+		return false
+	}
+	if err != nil {
+		status := RunFailed
+		if runCtx.Err() == context.DeadlineExceeded {
+			status = RunTimeout
+		}
+		s.logger.Warn("cron job failed", "id", job.ID, "name", job.Name, "status", status,
+			"duration_ms", time.Since(start).Milliseconds(), "error", err)
+		s.finishRun(runID, job.ID, status, out, err)
+		s.RecordFailure(job.ID)
+		if j, err := s.Get(job.ID); err != nil || !j.Enabled {
+			return false
+		}
+		return true
+	}
+
+	s.logger.Info("cron job finished", "id", job.ID, "name", job.Name,
+		"duration_ms", time.Since(start).Milliseconds())
+	s.finishRun(runID, job.ID, RunOK, out, nil)
+	s.RecordSuccess(job.ID)
+	return true
+}
+
+// run invokes the trigger and reports a run that outlived its timeout as a
+// failure even if the trigger returned nil.
+func (s *Scheduler) run(ctx context.Context, job Job) (string, error) {
+	if s.onTrigger == nil {
+		return "", fmt.Errorf("no trigger configured")
+	}
+	out, err := s.onTrigger(ctx, job)
+	if err == nil && ctx.Err() == context.DeadlineExceeded {
+		err = fmt.Errorf("timed out")
+	}
+	return out, err
+}
+
+// missedRuns counts the occurrences due between the job's next_run and now,
+// up to maxCatchUpRuns. One-shot jobs have one.
+func (s *Scheduler) missedRuns(job Job, now time.Time) int {
+	if IsOneShot(job.Schedule) {
+		return 1
+	}
+	n, t := 1, job.NextRun
+	for n < maxCatchUpRuns {
+		next, err := s.nextRun(&job, t)
+		if err != nil || next.After(now) {
+			break
+		}
+		n, t = n+1, next
+	}
+	return n
+}
+
+// skipTo moves a job past the runs it missed without running it.
+func (s *Scheduler) skipTo(job Job, now time.Time) {
+	if IsOneShot(job.Schedule) {
+		s.Pause(job.ID)
+		return
+	}
+	if next, err := s.nextRun(&job, now); err == nil {
+		s.db.Exec("UPDATE cron_jobs SET next_run = ? WHERE id = ?", next.UTC(), job.ID)
+	}
+}
+
 // StopAll cancels all running jobs.
 func (s *Scheduler) StopAll() {
 	s.mu.Lock()
@@ -310,18 +642,20 @@ func IsOneShot(schedule string) bool {
 	return strings.HasPrefix(s, "in ") || strings.HasPrefix(s, "at ")
 }
 
-// computeNextRun calculates the next run time from a schedule expression.
-// Supports: "every Xm", "every Xh", "every Xd", "in Xm/Xh" (one-shot), "at HH:MM" (one-shot),
-// or simple interval strings like "hourly", "daily", "weekly".
-func computeNextRun(schedule string, from time.Time) (time.Time, error) {
+// computeNextRun calculates the next run time after from, evaluated in loc.
+// Supports: 5-field cron expressions and @macros ("*/15 9-17 * * 1-5", "@daily"),
+// "every Xm/Xh/Xd", "in Xm/Xh" (one-shot), "at [date] HH:MM" (one-shot),
+// and "hourly", "daily", "weekly" as aliases for @hourly, @daily, @weekly.
+func computeNextRun(schedule string, from time.Time, loc *time.Location) (time.Time, error) {
 	schedule = strings.TrimSpace(strings.ToLower(schedule))
+	from = from.In(loc)
 
 	// One-shot: "in 10m", "in 2h", "in 30s"
 	if strings.HasPrefix(schedule, "in ") {
 		return parseInterval(strings.TrimPrefix(schedule, "in "), from)
 	}
 
-	// One-shot: "at 16:50", "at 4:50pm", "at 4:50 pm"
+	// One-shot: "at 16:50", "at 4:50pm", "at 2025-03-01 09:00", "at tomorrow 9am"
 	if strings.HasPrefix(schedule, "at ") {
 		return parseAtTime(strings.TrimPrefix(schedule, "at "), from)
 	}
@@ -331,17 +665,21 @@ func computeNextRun(schedule string, from time.Time) (time.Time, error) {
 		return parseInterval(strings.TrimPrefix(schedule, "every "), from)
 	}
 
-	// Simple cron-like: "hourly", "daily", "weekly"
+	// Fixed-time aliases
 	switch schedule {
-	case "hourly":
-		return from.Add(1 * time.Hour), nil
-	case "daily":
-		return from.Add(24 * time.Hour), nil
-	case "weekly":
-		return from.Add(7 * 24 * time.Hour), nil
+	case "hourly", "daily", "weekly":
+		schedule = "@" + schedule
 	}
 
-	return time.Time{}, fmt.Errorf("unsupported schedule format: %s (use 'in Xm', 'at HH:MM', 'every Xm/Xh/Xd', or 'hourly/daily/weekly')", schedule)
+	if isCronExpr(schedule) {
+		spec, err := parseCron(schedule)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return spec.next(from, loc)
+	}
+
+	return time.Time{}, fmt.Errorf("unsupported schedule format: %s (use a cron expression like '0 9 * * 1-5', '@daily', 'in Xm', 'at HH:MM', 'at YYYY-MM-DD HH:MM', or 'every Xm/Xh/Xd')", schedule)
 }
 
 // parseInterval parses a duration like "10m", "2h", "1d", "30s".
@@ -373,51 +711,90 @@ func parseInterval(interval string, from time.Time) (time.Time, error) {
 	}
 }
 
-// parseAtTime parses a time-of-day like "16:50", "4:50pm", "4:50 pm".
-// If the time has already passed today, schedules for tomorrow.
+// parseAtTime parses "[date] time" where date is YYYY-MM-DD, "today" or
+// "tomorrow", and time is like "16:50", "4:50pm", "4:50 pm". A date without
+// a time means midnight. Without a date, a time that has already passed
+// today is scheduled for tomorrow.
 func parseAtTime(timeStr string, from time.Time) (time.Time, error) {
 	timeStr = strings.TrimSpace(timeStr)
-	timeStr = strings.ReplaceAll(timeStr, " ", "") // "4:50 pm" → "4:50pm"
-
-	// Try 24h format first: "16:50"
-	layouts := []string{"15:04", "3:04pm", "3:04PM", "3pm", "3PM", "15"}
-
-	var parsed time.Time
-	var err error
-	for _, layout := range layouts {
-		parsed, err = time.Parse(layout, timeStr)
-		if err == nil {
-			break
+	if len(timeStr) > 10 && timeStr[10] == 't' && looksLikeDate(timeStr[:10]) {
+		timeStr = timeStr[:10] + " " + timeStr[11:] // ISO "2025-03-01T09:00"
+	}
+	fields := strings.Fields(timeStr)
+
+	var date time.Time
+	hasDate := false
+	if len(fields) > 0 {
+		switch fields[0] {
+		case "today":
+			date, hasDate = from, true
+		case "tomorrow":
+			date, hasDate = from.AddDate(0, 0, 1), true
+		default:
+			if d, err := time.ParseInLocation("2006-01-02", fields[0], from.Location()); err == nil {
+				date, hasDate = d, true
+			}
 		}
 	}
-	if err != nil {
-		return time.Time{}, fmt.Errorf("invalid time: %s (use HH:MM, H:MMpm, or Hpm)", timeStr)
+	if hasDate {
+		fields = fields[1:]
 	}
 
-	// Build target time today
-	target := time.Date(from.Year(), from.Month(), from.Day(),
-		parsed.Hour(), parsed.Minute(), 0, 0, from.Location())
+	hour, minute := 0, 0
+	if clock := strings.Join(fields, ""); clock != "" {
+		parsed, err := parseClock(clock)
+		if err != nil {
+			return time.Time{}, err
+		}
+		hour, minute = parsed.Hour(), parsed.Minute()
+	} else if !hasDate {
+		return time.Time{}, fmt.Errorf("invalid time: %s (use HH:MM, H:MMpm, or YYYY-MM-DD HH:MM)", timeStr)
+	}
 
-	// If target has already passed, schedule for tomorrow
-	if target.Before(from) {
-		target = target.Add(24 * time.Hour)
+	if !hasDate {
+		// Build target time today; if it has already passed, schedule for tomorrow
+		target := time.Date(from.Year(), from.Month(), from.Day(), hour, minute, 0, 0, from.Location())
+		if target.Before(from) {
+			target = time.Date(from.Year(), from.Month(), from.Day()+1, hour, minute, 0, 0, from.Location())
+		}
+		return target, nil
 	}
 
+	target := time.Date(date.Year(), date.Month(), date.Day(), hour, minute, 0, 0, from.Location())
+	if !target.After(from) {
+		return time.Time{}, fmt.Errorf("%s is in the past", target.Format("2006-01-02 15:04 MST"))
+	}
 	return target, nil
 }
 
+func looksLikeDate(s string) bool {
+	return len(s) == 10 && s[4] == '-' && s[7] == '-'
+}
+
+// parseClock parses a time of day like "16:50", "4:50pm" or "3pm".
+func parseClock(clock string) (time.Time, error) {
+	for _, layout := range []string{"15:04", "3:04pm", "3pm", "15"} {
+		if parsed, err := time.Parse(layout, clock); err == nil {
+			return parsed, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time: %s (use HH:MM, H:MMpm, or Hpm)", clock)
+}
+
 func scanJobs(rows *sql.Rows) ([]Job, error) {
 	var jobs []Job
 	for rows.Next() {
 		var j Job
 		var lastRun sql.NullTime
-		if err := rows.Scan(&j.ID, &j.Name, &j.Schedule, &j.SkillName, &j.Command,
-			&j.Params, &j.Enabled, &lastRun, &j.NextRun, &j.FailCount, &j.CreatedAt); err != nil {
+		var timeout int64
+		if err := rows.Scan(&j.ID, &j.Name, &j.Schedule, &j.Timezone, &j.Kind, &j.SkillName, &j.Command,
+			&j.Params, &j.Channel, &j.ChatID, &timeout, &j.Misfire, &j.Role, &j.Enabled, &lastRun, &j.NextRun, &j.FailCount, &j.CreatedAt); err != nil {
 			continue
 		}
 		if lastRun.Valid {
 			j.LastRun = &lastRun.Time
 		}
+		j.Timeout = time.Duration(timeout) * time.Second
 		jobs = append(jobs, j)
 	}
 	return jobs, nil
@@ -426,12 +803,14 @@ func scanJobs(rows *sql.Rows) ([]Job, error) {
 func scanJob(row *sql.Row) (*Job, error) {
 	var j Job
 	var lastRun sql.NullTime
-	if err := row.Scan(&j.ID, &j.Name, &j.Schedule, &j.SkillName, &j.Command,
-		&j.Params, &j.Enabled, &lastRun, &j.NextRun, &j.FailCount, &j.CreatedAt); err != nil {
+	var timeout int64
+	if err := row.Scan(&j.ID, &j.Name, &j.Schedule, &j.Timezone, &j.Kind, &j.SkillName, &j.Command,
+		&j.Params, &j.Channel, &j.ChatID, &timeout, &j.Misfire, &j.Role, &j.Enabled, &lastRun, &j.NextRun, &j.FailCount, &j.CreatedAt); err != nil {
 		return nil, err
 	}
 	if lastRun.Valid {
 		j.LastRun = &lastRun.Time
 	}
+	j.Timeout = time.Duration(timeout) * time.Second
 	return &j, nil
 }
@@ -1,10 +1,13 @@
 package scheduler
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -51,6 +54,18 @@ func TestCreateAndList(t *testing.T) {
 	}
 }
 
+func TestJobRole(t *testing.T) {
+	sched := setupTestScheduler(t)
+
+	id, err := sched.CreateJob(Job{Name: "disk", Schedule: "every 1h", Kind: KindShell, Command: "df -h", Role: "viewer"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job, err := sched.Get(id); err != nil || job.Role != "viewer" {
+		t.Errorf("expected the creator's role stored, got %+v, %v", job, err)
+	}
+}
+
 func TestPauseResume(t *testing.T) {
 	sched := setupTestScheduler(t)
 
@@ -123,6 +138,178 @@ func TestRecordSuccessAndFailure(t *testing.T) {
 	}
 }
 
+// fireAndWait runs a job through fireJob and waits for it to finish.
+func fireAndWait(t *testing.T, sched *Scheduler, id int64) {
+	t.Helper()
+	job, err := sched.Get(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sched.fireJob(context.Background(), *job)
+	deadline := time.Now().Add(3 * time.Second)
+	for sched.RunningCount() > 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("job did not finish")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestFireJobRecordsOutcome(t *testing.T) {
+	sched := setupTestScheduler(t)
+	id, _ := sched.CreateJob(Job{Name: "disk", Schedule: "every 1h", Kind: KindShell, Command: "df -h", Timeout: time.Second})
+
+	var fail bool
+	sched.OnTrigger(func(ctx context.Context, job Job) (string, error) {
+		if job.Kind != KindShell || job.Timeout != time.Second {
+			t.Errorf("job fields not stored: %+v", job)
+		}
+		if fail {
+			return "", errors.New("exit code 1")
+		}
+		return "ok", nil
+	})
+
+	fail = true
+	fireAndWait(t, sched, id)
+	if job, _ := sched.Get(id); job.FailCount != 1 || job.LastRun == nil {
+		t.Fatalf("expected one recorded failure, got %+v", job)
+	}
+
+	fail = false
+	fireAndWait(t, sched, id)
+	if job, _ := sched.Get(id); job.FailCount != 0 {
+		t.Fatalf("expected success to reset failures, got %d", job.FailCount)
+	}
+
+	// A run that outlives its timeout fails even if the trigger returns nil
+	sched.OnTrigger(func(ctx context.Context, job Job) (string, error) {
+		<-ctx.Done()
+		return "", nil
+	})
+	fireAndWait(t, sched, id)
+	if job, _ := sched.Get(id); job.FailCount != 1 {
+		t.Fatalf("expected the timeout to count as a failure, got %d", job.FailCount)
+	}
+
+	runs, err := sched.History(id, 10)
+	if err != nil || len(runs) != 3 {
+		t.Fatalf("expected 3 runs, got %d (%v)", len(runs), err)
+	}
+	want := []struct{ status, output, err string }{
+		{RunTimeout, "", "timed out"},
+		{RunOK, "ok", ""},
+		{RunFailed, "", "exit code 1"},
+	}
+	for i, w := range want {
+		r := runs[i]
+		if r.Status != w.status || r.Output != w.output || r.Error != w.err || r.Trigger != TriggerSchedule || r.EndedAt == nil || r.JobName != "disk" {
+			t.Errorf("run %d: got %+v, want %+v", i, r, w)
+		}
+	}
+}
+
+type maskScrubber struct{}
+
+func (maskScrubber) ScrubCredentials(s string) string {
+	return strings.ReplaceAll(s, "sk-secret", "[REDACTED]")
+}
+
+func TestRunHistoryScrubbedAndTruncated(t *testing.T) {
+	sched := setupTestScheduler(t)
+	sched.SetScrubber(maskScrubber{})
+	id, _ := sched.Create("dump", "every 1h", "", "env", "{}")
+	sched.OnTrigger(func(ctx context.Context, job Job) (string, error) {
+		return "TOKEN=sk-secret\n" + strings.Repeat("x", 3*maxRunOutput), nil
+	})
+	fireAndWait(t, sched, id)
+
+	runs, _ := sched.History(id, 1)
+	if len(runs) != 1 || strings.Contains(runs[0].Output, "sk-secret") || len(runs[0].Output) > maxRunOutput+50 {
+		t.Fatalf("output not scrubbed or truncated: %d bytes", len(runs[0].Output))
+	}
+
+	sched.Delete(id)
+	if runs, _ := sched.History(id, 1); len(runs) != 0 {
+		t.Error("deleting a job should delete its runs")
+	}
+}
+
+func TestMisfirePolicy(t *testing.T) {
+	tests := []struct {
+		policy   string
+		wantRuns int
+		status   string
+	}{
+		{MisfireSkip, 0, RunSkipped},
+		{MisfireOnce, 1, RunOK},
+		{MisfireAll, 3, RunOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			sched := setupTestScheduler(t)
+			id, err := sched.CreateJob(Job{Name: "report", Schedule: "every 1h", Kind: KindReminder, Command: "hi", Misfire: tt.policy})
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Down for 2.5 hours: due now-2.5h, now-1.5h and now-0.5h
+			sched.db.Exec("UPDATE cron_jobs SET next_run = ? WHERE id = ?", time.Now().Add(-150*time.Minute).UTC(), id)
+
+			var runs int
+			sched.OnTrigger(func(ctx context.Context, job Job) (string, error) {
+				runs++
+				return "", nil
+			})
+			fireAndWait(t, sched, id)
+
+			if runs != tt.wantRuns {
+				t.Errorf("expected %d runs, got %d", tt.wantRuns, runs)
+			}
+			history, _ := sched.History(id, 10)
+			if len(history) == 0 || history[0].Status != tt.status || history[0].Trigger != TriggerCatchUp {
+				t.Fatalf("unexpected history %+v", history)
+			}
+			if job, _ := sched.Get(id); !job.NextRun.After(time.Now()) || !job.Enabled {
+				t.Errorf("expected the job to wait for its next occurrence, got %+v", job)
+			}
+		})
+	}
+
+	sched := setupTestScheduler(t)
+	if _, err := sched.CreateJob(Job{Name: "x", Schedule: "@daily", Command: "x", Misfire: "later"}); err == nil {
+		t.Error("expected an unknown misfire policy to be rejected")
+	}
+}
+
+func TestJobKind(t *testing.T) {
+	sched := setupTestScheduler(t)
+
+	tests := []struct {
+		job  Job
+		want string
+	}{
+		{Job{Schedule: "in 10m", Command: "stretch"}, KindReminder},
+		{Job{Schedule: "@daily", SkillName: "backup"}, KindSkill},
+		{Job{Schedule: "@daily", Command: "summarize my inbox"}, KindAgent},
+		{Job{Schedule: "in 10m", Kind: KindShell, Command: "uptime"}, KindShell},
+	}
+	for _, tt := range tests {
+		if got := tt.job.JobKind(); got != tt.want {
+			t.Errorf("JobKind(%+v) = %s, want %s", tt.job, got, tt.want)
+		}
+	}
+
+	for _, job := range []Job{
+		{Name: "x", Schedule: "@daily", Kind: "webhook", Command: "x"},
+		{Name: "x", Schedule: "@daily", Kind: KindSkill, Command: "x"},
+		{Name: "x", Schedule: "@daily", Kind: KindShell},
+	} {
+		if _, err := sched.CreateJob(job); err == nil {
+			t.Errorf("expected %+v to be rejected", job)
+		}
+	}
+}
+
 func TestComputeNextRun(t *testing.T) {
 	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
 
@@ -139,16 +326,24 @@ func TestComputeNextRun(t *testing.T) {
 		{"in 2h", now.Add(2 * time.Hour), false},
 		{"at 14:00", time.Date(2024, 1, 1, 14, 0, 0, 0, time.UTC), false},
 		{"at 10:00", time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC), false}, // already past noon, so tomorrow
+		{"at 2024-01-03 09:30", time.Date(2024, 1, 3, 9, 30, 0, 0, time.UTC), false},
+		{"at 2024-01-03T9:30am", time.Date(2024, 1, 3, 9, 30, 0, 0, time.UTC), false},
+		{"at 2024-01-05", time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC), false},
+		{"at tomorrow 9am", time.Date(2024, 1, 2, 9, 0, 0, 0, time.UTC), false},
+		{"at 2023-12-31 09:00", time.Time{}, true}, // in the past
 		{"hourly", now.Add(1 * time.Hour), false},
-		{"daily", now.Add(24 * time.Hour), false},
-		{"weekly", now.Add(7 * 24 * time.Hour), false},
+		{"daily", time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), false},  // next midnight, not now+24h
+		{"weekly", time.Date(2024, 1, 7, 0, 0, 0, 0, time.UTC), false}, // next Sunday midnight
+		{"*/15 9-17 * * 1-5", time.Date(2024, 1, 1, 12, 15, 0, 0, time.UTC), false},
+		{"@monthly", time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), false},
+		{"0 9 * * 8", time.Time{}, true},
 		{"invalid", time.Time{}, true},
 		{"every abc", time.Time{}, true},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.schedule, func(t *testing.T) {
-			next, err := computeNextRun(tt.schedule, now)
+			next, err := computeNextRun(tt.schedule, now, time.UTC)
 			if tt.wantErr {
 				if err == nil {
 					t.Error("expected error")
@@ -178,6 +373,9 @@ func TestIsOneShot(t *testing.T) {
 	if IsOneShot("daily") {
 		t.Error("'daily' should not be one-shot")
 	}
+	if IsOneShot("0 9 * * *") {
+		t.Error("cron expressions should not be one-shot")
+	}
 }
 
 func TestListEnabledOnly(t *testing.T) {
@@ -216,3 +414,32 @@ func TestCount(t *testing.T) {
 		t.Errorf("expected 2, got %d", count)
 	}
 }
+
+func TestJobTimezone(t *testing.T) {
+	sched := setupTestScheduler(t)
+
+	if _, err := sched.CreateJob(Job{Name: "bad", Schedule: "@daily", Timezone: "Mars/Olympus"}); err == nil {
+		t.Error("expected an error for an unknown timezone")
+	}
+
+	id, err := sched.CreateJob(Job{Name: "tokyo", Schedule: "0 9 * * *", Timezone: "Asia/Tokyo", Command: "standup"})
+	if err != nil {
+		t.Fatalf("create error: %v", err)
+	}
+	job, _ := sched.Get(id)
+	if job.Timezone != "Asia/Tokyo" {
+		t.Errorf("timezone not stored, got %q", job.Timezone)
+	}
+	// 09:00 in Tokyo is always 00:00 UTC
+	if next := job.NextRun.UTC(); next.Hour() != 0 || next.Minute() != 0 {
+		t.Errorf("expected next run at 00:00 UTC, got %v", next)
+	}
+
+	runs, err := sched.NextRuns("0 9 * * *", "Asia/Tokyo", 3)
+	if err != nil || len(runs) != 3 {
+		t.Fatalf("expected 3 runs, got %v, %v", runs, err)
+	}
+	if runs[1].Sub(runs[0]) != 24*time.Hour {
+		t.Errorf("expected daily runs, got %v", runs)
+	}
+}
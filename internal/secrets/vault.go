@@ -0,0 +1,277 @@
+// Package secrets keeps API keys, tokens and passwords in an encrypted file
+// instead of config.json.
+package secrets
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/nacl/secretbox"
+	"golang.org/x/crypto/scrypt"
+)
+
+// PassphraseEnv holds the passphrase of vaults locked with one instead of a
+// key file.
+const PassphraseEnv = "AEON_SECRETS_PASSPHRASE"
+
+// Scheme prefixes config values that name a secret, as in secret://OPENAI_KEY.
+const Scheme = "secret://"
+
+// scrypt parameters for passphrases, as recommended for interactive logins.
+const (
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+// namePattern is what secret names look like, so they work as env vars.
+var namePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+var (
+	passphraseOnce sync.Once
+	passphrase     string
+)
+
+// Passphrase returns the passphrase in AEON_SECRETS_PASSPHRASE and removes
+// it from the environment, so the skills, MCP servers and shell commands
+// Aeon starts never inherit it. Later calls return the same value.
+func Passphrase() string {
+	passphraseOnce.Do(func() {
+		passphrase = os.Getenv(PassphraseEnv)
+		os.Unsetenv(PassphraseEnv)
+	})
+	return passphrase
+}
+
+// ErrLocked means the vault needs a passphrase that wasn't given.
+var ErrLocked = errors.New("the secrets vault is locked with a passphrase; set " + PassphraseEnv)
+
+// Vault is a set of named secrets, stored encrypted with NaCl secretbox
+// (XSalsa20-Poly1305) under a random key kept in a key file, or a key
+// derived from a passphrase with scrypt. Changes are kept in memory until
+// Save.
+type Vault struct {
+	path    string
+	keyFile string // empty when a passphrase unlocks the vault
+	key     [32]byte
+	salt    []byte // scrypt salt for passphrases
+	newKey  bool   // key generated by Open, written to keyFile on Save
+	secrets map[string]secret
+}
+
+type secret struct {
+	Value   string    `json:"value"`
+	Updated time.Time `json:"updated"`
+}
+
+// vaultFile is the vault on disk. Only Box is secret.
+type vaultFile struct {
+	Version int    `json:"version"`
+	KDF     string `json:"kdf"`            // "keyfile" or "scrypt"
+	Salt    []byte `json:"salt,omitempty"` // for scrypt
+	Nonce   []byte `json:"nonce"`
+	Box     []byte `json:"box"` // the secrets as JSON, sealed
+}
+
+// Open reads the vault at path. It is unlocked with passphrase if it was
+// created with one, or else with the key in keyFile. A vault that doesn't
+// exist yet opens empty, and Save creates it: locked with passphrase if one
+// is given, otherwise with keyFile, which gets a new key if it's missing.
+func Open(path, keyFile, passphrase string) (*Vault, error) {
+	v := &Vault{path: path, secrets: make(map[string]secret)}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return v, v.newLock(keyFile, passphrase)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading secrets vault: %w", err)
+	}
+
+	var f vaultFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("parsing secrets vault %s: %w", path, err)
+	}
+	if f.Version != 1 || len(f.Nonce) != 24 {
+		return nil, fmt.Errorf("secrets vault %s: unsupported format", path)
+	}
+	switch f.KDF {
+	case "scrypt":
+		if passphrase == "" {
+			return nil, ErrLocked
+		}
+		v.salt = f.Salt
+		if err := v.derive(passphrase); err != nil {
+			return nil, err
+		}
+	case "keyfile":
+		v.keyFile = keyFile
+		if err := v.readKey(); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("secrets vault %s: unknown kdf %q", path, f.KDF)
+	}
+
+	var nonce [24]byte
+	copy(nonce[:], f.Nonce)
+	plain, ok := secretbox.Open(nil, f.Box, &nonce, &v.key)
+	if !ok {
+		if f.KDF == "scrypt" {
+			return nil, fmt.Errorf("cannot decrypt secrets vault %s: wrong passphrase", path)
+		}
+		return nil, fmt.Errorf("cannot decrypt secrets vault %s: wrong key in %s", path, keyFile)
+	}
+	if err := json.Unmarshal(plain, &v.secrets); err != nil {
+		return nil, fmt.Errorf("secrets vault %s: %w", path, err)
+	}
+	return v, nil
+}
+
+// newLock picks the key for a new vault.
+func (v *Vault) newLock(keyFile, passphrase string) error {
+	if passphrase != "" {
+		v.salt = make([]byte, 16)
+		rand.Read(v.salt)
+		return v.derive(passphrase)
+	}
+	v.keyFile = keyFile
+	err := v.readKey()
+	if errors.Is(err, os.ErrNotExist) {
+		rand.Read(v.key[:])
+		v.newKey = true
+		return nil
+	}
+	return err
+}
+
+func (v *Vault) derive(passphrase string) error {
+	key, err := scrypt.Key([]byte(passphrase), v.salt, scryptN, scryptR, scryptP, 32)
+	if err != nil {
+		return err
+	}
+	copy(v.key[:], key)
+	return nil
+}
+
+// readKey loads the base64 key in v.keyFile.
+func (v *Vault) readKey() error {
+	data, err := os.ReadFile(v.keyFile)
+	if err != nil {
+		return fmt.Errorf("reading secrets key: %w", err)
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(key) != 32 {
+		return fmt.Errorf("secrets key %s is not a base64 32-byte key", v.keyFile)
+	}
+	copy(v.key[:], key)
+	return nil
+}
+
+// Save encrypts the vault and replaces the file, creating the key file for
+// a new vault. Both are readable by the owner only.
+func (v *Vault) Save() error {
+	if err := os.MkdirAll(filepath.Dir(v.path), 0700); err != nil {
+		return err
+	}
+	if v.newKey {
+		if err := os.MkdirAll(filepath.Dir(v.keyFile), 0700); err != nil {
+			return err
+		}
+		key := base64.StdEncoding.EncodeToString(v.key[:]) + "\n"
+		if err := os.WriteFile(v.keyFile, []byte(key), 0600); err != nil {
+			return fmt.Errorf("writing secrets key: %w", err)
+		}
+		v.newKey = false
+	}
+
+	plain, err := json.Marshal(v.secrets)
+	if err != nil {
+		return err
+	}
+	var nonce [24]byte
+	rand.Read(nonce[:])
+	f := vaultFile{Version: 1, KDF: "keyfile", Nonce: nonce[:], Box: secretbox.Seal(nil, plain, &nonce, &v.key)}
+	if v.keyFile == "" {
+		f.KDF, f.Salt = "scrypt", v.salt
+	}
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := v.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("writing secrets vault: %w", err)
+	}
+	return os.Rename(tmp, v.path)
+}
+
+// Get returns the value of a secret.
+func (v *Vault) Get(name string) (string, bool) {
+	s, ok := v.secrets[name]
+	return s.Value, ok
+}
+
+// Set adds or replaces a secret. Names are letters, digits and _, not
+// starting with a digit, so they can be passed on as env vars.
+func (v *Vault) Set(name, value string) error {
+	if err := ValidName(name); err != nil {
+		return err
+	}
+	if value == "" {
+		return fmt.Errorf("secret %s is empty", name)
+	}
+	v.secrets[name] = secret{Value: value, Updated: time.Now().UTC()}
+	return nil
+}
+
+// Delete removes a secret, reporting whether it existed.
+func (v *Vault) Delete(name string) bool {
+	_, ok := v.secrets[name]
+	delete(v.secrets, name)
+	return ok
+}
+
+// Info describes a secret without its value.
+type Info struct {
+	Name    string
+	Updated time.Time
+}
+
+// List returns the secrets' names, sorted, and when each was last set.
+func (v *Vault) List() []Info {
+	list := make([]Info, 0, len(v.secrets))
+	for name, s := range v.secrets {
+		list = append(list, Info{Name: name, Updated: s.Updated})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Values returns every secret value, for scrubbing them from output.
+func (v *Vault) Values() []string {
+	values := make([]string, 0, len(v.secrets))
+	for _, s := range v.secrets {
+		values = append(values, s.Value)
+	}
+	return values
+}
+
+// ValidName checks a secret name.
+func ValidName(name string) error {
+	if !namePattern.MatchString(name) {
+		return fmt.Errorf("invalid secret name %q (letters, digits and _, not starting with a digit)", name)
+	}
+	return nil
+}
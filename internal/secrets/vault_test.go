@@ -0,0 +1,108 @@
+package secrets
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestKeyFileVault(t *testing.T) {
+	dir := t.TempDir()
+	path, keyFile := filepath.Join(dir, "secrets.vault"), filepath.Join(dir, "secrets.key")
+
+	v, err := Open(path, keyFile, "")
+	if err != nil {
+		t.Fatalf("open new vault: %v", err)
+	}
+	if err := v.Set("OPENAI_KEY", "sk-test"); err != nil {
+		t.Fatal(err)
+	}
+	v.Set("ACME_TOKEN", "ACME-1234")
+	if err := v.Save(); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), "sk-test") || strings.Contains(string(data), "OPENAI_KEY") {
+		t.Error("the vault file should not contain secrets or their names in the clear")
+	}
+	for _, p := range []string{path, keyFile} {
+		if fi, err := os.Stat(p); err != nil || fi.Mode().Perm() != 0600 {
+			t.Errorf("expected %s to be owner-only, got %v", p, fi.Mode())
+		}
+	}
+
+	v, err = Open(path, keyFile, "")
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	if value, ok := v.Get("OPENAI_KEY"); !ok || value != "sk-test" {
+		t.Errorf("expected OPENAI_KEY back, got %q, %v", value, ok)
+	}
+	if list := v.List(); len(list) != 2 || list[0].Name != "ACME_TOKEN" || list[0].Updated.IsZero() {
+		t.Errorf("expected two secrets sorted by name, got %+v", list)
+	}
+	if !v.Delete("ACME_TOKEN") || v.Delete("ACME_TOKEN") || len(v.Values()) != 1 {
+		t.Error("expected ACME_TOKEN deleted once")
+	}
+
+	// Another key can't open it
+	other := filepath.Join(dir, "other.key")
+	o, _ := Open(filepath.Join(dir, "other.vault"), other, "")
+	o.Set("X", "y")
+	o.Save()
+	if _, err := Open(path, other, ""); err == nil || !strings.Contains(err.Error(), "wrong key") {
+		t.Errorf("expected a wrong key error, got %v", err)
+	}
+}
+
+func TestPassphraseVault(t *testing.T) {
+	dir := t.TempDir()
+	path, keyFile := filepath.Join(dir, "secrets.vault"), filepath.Join(dir, "secrets.key")
+
+	v, _ := Open(path, keyFile, "correct horse")
+	v.Set("BOT_TOKEN", "123:abc")
+	if err := v.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(keyFile); err == nil {
+		t.Error("a passphrase vault should not create a key file")
+	}
+
+	if _, err := Open(path, keyFile, ""); !errors.Is(err, ErrLocked) {
+		t.Errorf("expected ErrLocked without a passphrase, got %v", err)
+	}
+	if _, err := Open(path, keyFile, "wrong"); err == nil || !strings.Contains(err.Error(), "wrong passphrase") {
+		t.Errorf("expected a wrong passphrase error, got %v", err)
+	}
+	v, err := Open(path, keyFile, "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value, _ := v.Get("BOT_TOKEN"); value != "123:abc" {
+		t.Errorf("expected BOT_TOKEN back, got %q", value)
+	}
+}
+
+func TestValidName(t *testing.T) {
+	for name, ok := range map[string]bool{"OPENAI_KEY": true, "_x1": true, "1KEY": false, "A-B": false, "": false} {
+		if (ValidName(name) == nil) != ok {
+			t.Errorf("ValidName(%q): expected valid=%v", name, ok)
+		}
+	}
+}
+
+func TestPassphraseLeavesEnvironment(t *testing.T) {
+	t.Setenv(PassphraseEnv, "correct horse")
+	if got := Passphrase(); got != "correct horse" {
+		t.Fatalf("expected the passphrase, got %q", got)
+	}
+	if _, ok := os.LookupEnv(PassphraseEnv); ok {
+		t.Error("expected the passphrase removed from the environment child processes inherit")
+	}
+	if got := Passphrase(); got != "correct horse" {
+		t.Errorf("expected later calls to return the passphrase, got %q", got)
+	}
+}
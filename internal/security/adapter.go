@@ -1,5 +1,10 @@
 package security
 
+import (
+	"encoding/json"
+	"fmt"
+)
+
 // PolicyAdapter wraps Policy to implement tool-level security interfaces.
 type PolicyAdapter struct {
 	policy *Policy
@@ -21,7 +26,26 @@ func (a *PolicyAdapter) CheckPath(path string) (int, string) {
 	return int(decision), reason
 }
 
+// CheckTool returns the policy file's decision on a tool call — allow, deny,
+// approve or audit — and a reason naming the rule. Both are empty when no
+// rule matched and the default is allow.
+func (a *PolicyAdapter) CheckTool(name string, params json.RawMessage, channel, userID string) (string, string) {
+	m := a.policy.CheckTool(ToolCall{Tool: name, Args: params, Channel: channel, UserID: userID})
+	if m.Rule == nil && m.Decision == RuleAllow {
+		return "", ""
+	}
+	if m.Reason == "" {
+		return m.Decision, m.Label()
+	}
+	return m.Decision, fmt.Sprintf("%s (%s)", m.Reason, m.Label())
+}
+
 // ScrubCredentials removes sensitive data from text.
 func (a *PolicyAdapter) ScrubCredentials(text string) string {
 	return a.policy.ScrubCredentials(text)
 }
+
+// Redactions returns how many credentials have been scrubbed, by source.
+func (a *PolicyAdapter) Redactions() map[string]int64 {
+	return a.policy.Redactions()
+}
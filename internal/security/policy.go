@@ -30,50 +30,28 @@ func (d Decision) String() string {
 }
 
 type Policy struct {
-	denyPatterns    []*regexp.Regexp
-	approvePatterns []*regexp.Regexp
-	allowedPaths    []string
-	credPatterns    []*regexp.Regexp
+	denyPatterns []*regexp.Regexp
+	allowedPaths []string
+	deniedPaths  []string
+	credPatterns []*regexp.Regexp
+	rules        *PolicyFile
+	scrub        scrubState
 }
 
+// NewPolicy creates a policy. Commands are checked by parsing them (see
+// CheckCommand); denyPatterns are extra regexes that block a command when
+// they match it as written or any simple command in it.
 func NewPolicy(denyPatterns []string, allowedPaths []string) *Policy {
 	p := &Policy{
 		allowedPaths: allowedPaths,
 	}
 
-	// DENY — only truly catastrophic / self-destructive commands
-	defaultDeny := []string{
-		`rm\s+-rf\s+/\s*$`,                          // rm -rf / (root filesystem wipe)
-		`rm\s+-rf\s+/\*`,                             // rm -rf /*
-		`mkfs\.\w+\s+/dev/[sv]d`,                     // format disk
-		`dd\s+if=.*of=/dev/[sv]d`,                    // overwrite disk
-		`:\(\)\s*\{\s*:\|:&\s*\}\s*;`,                // fork bomb
-		`>\s*/dev/[sv]d`,                              // redirect to disk device
-		// Self-termination — agent must not kill itself
-		`systemctl\s+(stop|kill|disable)\s+aeon`,
-		`service\s+aeon\s+stop`,
-		`pkill.*aeon`,
-		`killall.*aeon`,
-	}
-
-	// APPROVE — things that benefit from user awareness (not blocking)
-	defaultApprove := []string{
-		`curl\s.*\|\s*(sh|bash)`,  // pipe-to-shell
-		`wget\s.*\|\s*(sh|bash)`,  // pipe-to-shell
-	}
-
-	for _, pat := range append(defaultDeny, denyPatterns...) {
+	for _, pat := range denyPatterns {
 		if re, err := regexp.Compile(pat); err == nil {
 			p.denyPatterns = append(p.denyPatterns, re)
 		}
 	}
 
-	for _, pat := range defaultApprove {
-		if re, err := regexp.Compile(pat); err == nil {
-			p.approvePatterns = append(p.approvePatterns, re)
-		}
-	}
-
 	// Credential patterns for scrubbing (output only — does not block execution)
 	credentialPatterns := []string{
 		// Generic key=value
@@ -106,29 +84,38 @@ func NewPolicy(denyPatterns []string, allowedPaths []string) *Policy {
 	return p
 }
 
-// CheckCommand evaluates a shell command against security policy.
+// CheckCommand evaluates a shell command against security policy. The
+// command is parsed, and every simple command in it — in pipelines,
+// subshells, substitutions, and scripts run by eval or sh -c — is checked
+// with wrappers like sudo and env removed. Only catastrophic or
+// self-destructive commands are denied: recursive deletes of system
+// directories, disk formatting or overwrites, fork bombs, and stopping
+// aeon. Piping into a shell, commands computed at run time and anything
+// that can't be parsed need approval. The reason quotes the offending part.
 func (p *Policy) CheckCommand(command string) (Decision, string) {
 	cmd := strings.TrimSpace(command)
 
-	// Check deny patterns first
 	for _, re := range p.denyPatterns {
 		if re.MatchString(cmd) {
 			return Denied, fmt.Sprintf("Command blocked (matched: %s)", re.String())
 		}
 	}
 
-	// Check approval-needed patterns
-	for _, re := range p.approvePatterns {
-		if re.MatchString(cmd) {
-			return NeedsApproval, fmt.Sprintf("Command requires approval (matched: %s)", re.String())
+	a := &analyzer{policy: p}
+	a.script(cmd, 0)
+	for _, path := range a.paths {
+		if denied, ok := p.deniedPath(path); ok {
+			return Denied, fmt.Sprintf("Command blocked: %s is off limits", denied)
 		}
 	}
-
-	return Allowed, ""
+	return a.decision, a.reason
 }
 
 // CheckPath validates that a file path is within allowed boundaries.
 func (p *Policy) CheckPath(path string) (Decision, string) {
+	if denied, ok := p.deniedPath(path); ok {
+		return Denied, fmt.Sprintf("Path %s is off limits", denied)
+	}
 	if len(p.allowedPaths) == 0 {
 		return Allowed, ""
 	}
@@ -160,15 +147,62 @@ func (p *Policy) CheckPath(path string) (Decision, string) {
 	return Denied, fmt.Sprintf("Path %s is outside allowed directories", absPath)
 }
 
-// ScrubCredentials removes API keys, tokens, and passwords from text.
-func (p *Policy) ScrubCredentials(text string) string {
-	result := text
-	for _, re := range p.credPatterns {
-		result = re.ReplaceAllStringFunc(result, func(match string) string {
-			return "[REDACTED]"
-		})
+// DenyPaths keeps tools away from these files and directories, even inside
+// the allowed paths: file tools are refused them, and commands naming them
+// are blocked.
+func (p *Policy) DenyPaths(paths ...string) {
+	for _, path := range paths {
+		if path != "" {
+			p.deniedPaths = append(p.deniedPaths, path)
+		}
+	}
+}
+
+// deniedPath returns the denied path that path is, or lies under. Globs,
+// as in `cat ~/.aeon/*.key`, are checked against the files they match.
+func (p *Policy) deniedPath(path string) (string, bool) {
+	if len(p.deniedPaths) == 0 {
+		return "", false
+	}
+	candidates := []string{expandHome(path)}
+	if matches, err := filepath.Glob(candidates[0]); err == nil {
+		candidates = append(candidates, matches...)
+	}
+	for _, c := range candidates {
+		abs := resolvePath(c)
+		for _, denied := range p.deniedPaths {
+			d := resolvePath(expandHome(denied))
+			if abs == d || strings.HasPrefix(abs, d+string(filepath.Separator)) {
+				return denied, true
+			}
+		}
+	}
+	return "", false
+}
+
+// resolvePath makes path absolute and resolves symlinks where it exists.
+func resolvePath(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		return resolved
+	}
+	return abs
+}
+
+// SetRules applies the rules of a policy file to tool calls.
+func (p *Policy) SetRules(f *PolicyFile) {
+	p.rules = f
+}
+
+// CheckTool evaluates a tool call against the policy file's rules.
+func (p *Policy) CheckTool(call ToolCall) Match {
+	if p.rules == nil {
+		return Match{Decision: RuleAllow}
 	}
-	return result
+	return p.rules.Rules().Evaluate(call)
 }
 
 func expandHome(path string) string {
@@ -0,0 +1,401 @@
+package security
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Rule decisions.
+const (
+	RuleAllow   = "allow"   // run without the built-in approval prompts; hard blocks still apply
+	RuleDeny    = "deny"    // refuse the call
+	RuleApprove = "approve" // ask a human first
+	RuleAudit   = "audit"   // run as usual and log the call
+)
+
+// RuleSet is a security policy file: rules checked in order, the first one
+// matching a tool call deciding it.
+type RuleSet struct {
+	Default string `yaml:"default"` // decision when no rule matches: allow (default), deny, approve or audit
+	Rules   []Rule `yaml:"rules"`
+}
+
+// Rule matches tool calls. Every condition given must hold, and a list
+// holds when any of its entries does.
+type Rule struct {
+	Name     string            `yaml:"name"`
+	Tools    []string          `yaml:"tools"`    // tool names, with * and ? globs
+	Commands []string          `yaml:"commands"` // programs a shell command runs, with globs
+	Args     map[string]string `yaml:"args"`     // argument name -> regex its value must match
+	Paths    []string          `yaml:"paths"`    // directories, or globs (one without a slash matches file names)
+	Users    []string          `yaml:"users"`    // channel:userID
+	Channels []string          `yaml:"channels"` // channels the call came from
+	Decision string            `yaml:"decision"`
+	Reason   string            `yaml:"reason"` // shown when the rule blocks or asks for approval
+
+	args map[string]*regexp.Regexp
+}
+
+// clientChosenIDs are the channels whose user IDs the client picks, so a
+// rule can't single out their users.
+var clientChosenIDs = map[string]bool{"websocket": true, "webhook": true}
+
+// ToolCall is a tool call to evaluate.
+type ToolCall struct {
+	Tool    string
+	Args    json.RawMessage
+	Channel string
+	UserID  string
+}
+
+// Match is the outcome of evaluating a tool call.
+type Match struct {
+	Decision string
+	Rule     *Rule // nil when no rule matched
+	Index    int   // position of Rule in the file, from 1
+	Reason   string
+}
+
+// Label names the rule that decided, for messages and logs.
+func (m Match) Label() string {
+	switch {
+	case m.Rule == nil:
+		return "policy default"
+	case m.Rule.Name != "":
+		return fmt.Sprintf("policy rule #%d %q", m.Index, m.Rule.Name)
+	default:
+		return fmt.Sprintf("policy rule #%d", m.Index)
+	}
+}
+
+// ParseRules parses a policy file, in YAML or JSON.
+func ParseRules(data []byte) (*RuleSet, error) {
+	rs := &RuleSet{}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(rs); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	if rs.Default == "" {
+		rs.Default = RuleAllow
+	}
+	if !validDecision(rs.Default) {
+		return nil, fmt.Errorf("unknown default decision %q (want allow, deny, approve or audit)", rs.Default)
+	}
+	for i := range rs.Rules {
+		if err := rs.Rules[i].compile(); err != nil {
+			label := Match{Rule: &rs.Rules[i], Index: i + 1}.Label()
+			return nil, fmt.Errorf("%s: %w", strings.TrimPrefix(label, "policy "), err)
+		}
+	}
+	return rs, nil
+}
+
+func validDecision(d string) bool {
+	switch d {
+	case RuleAllow, RuleDeny, RuleApprove, RuleAudit:
+		return true
+	}
+	return false
+}
+
+func (r *Rule) compile() error {
+	if r.Decision == "" {
+		return errors.New("decision is required")
+	}
+	if !validDecision(r.Decision) {
+		return fmt.Errorf("unknown decision %q (want allow, deny, approve or audit)", r.Decision)
+	}
+	for _, globs := range [][]string{r.Tools, r.Commands, r.Paths} {
+		for _, g := range globs {
+			if _, err := path.Match(g, ""); err != nil {
+				return fmt.Errorf("bad pattern %q", g)
+			}
+		}
+	}
+	for _, u := range r.Users {
+		channel, id, ok := strings.Cut(u, ":")
+		if !ok || id == "" {
+			return fmt.Errorf("user %q must be channel:userID, since the same ID may be someone else on another channel", u)
+		}
+		if clientChosenIDs[channel] {
+			return fmt.Errorf("user %q: %s clients choose their own IDs; use channels: [%s] instead", u, channel, channel)
+		}
+	}
+	r.args = make(map[string]*regexp.Regexp, len(r.Args))
+	for key, pat := range r.Args {
+		re, err := regexp.Compile(pat)
+		if err != nil {
+			return fmt.Errorf("args.%s: %w", key, err)
+		}
+		r.args[key] = re
+	}
+	return nil
+}
+
+// Evaluate returns the decision of the first rule matching call, or the
+// default when none does.
+func (rs *RuleSet) Evaluate(call ToolCall) Match {
+	if rs != nil {
+		f := inspectCall(call)
+		for i := range rs.Rules {
+			if r := &rs.Rules[i]; r.matches(call, f) {
+				return Match{Decision: r.Decision, Rule: r, Index: i + 1, Reason: r.Reason}
+			}
+		}
+	}
+	if rs == nil || rs.Default == "" {
+		return Match{Decision: RuleAllow}
+	}
+	return Match{Decision: rs.Default}
+}
+
+// callFacts is what rules match in a call's arguments.
+type callFacts struct {
+	args     map[string]any
+	commands []string
+	paths    []string
+}
+
+// commandArgKeys and pathArgKeys name arguments holding a shell command or a
+// file path, as the MCP argument checks do, along with any key containing "path".
+var (
+	commandArgKeys = map[string]bool{"command": true, "cmd": true, "script": true}
+	pathArgKeys    = map[string]bool{"file": true, "filename": true, "directory": true, "dir": true}
+)
+
+func inspectCall(call ToolCall) callFacts {
+	var f callFacts
+	if json.Unmarshal(call.Args, &f.args) != nil {
+		return f
+	}
+	var walk func(key string, v any)
+	walk = func(key string, v any) {
+		switch v := v.(type) {
+		case map[string]any:
+			for k, child := range v {
+				walk(strings.ToLower(k), child)
+			}
+		case []any:
+			for _, child := range v {
+				walk(key, child)
+			}
+		case string:
+			switch {
+			case commandArgKeys[key]:
+				commands, paths := inspectCommand(v)
+				f.commands = append(f.commands, commands...)
+				f.paths = append(f.paths, paths...)
+			case pathArgKeys[key] || strings.Contains(key, "path"):
+				f.paths = append(f.paths, v)
+			}
+		}
+	}
+	walk("", f.args)
+	for i, p := range f.paths {
+		f.paths[i] = absPath(p)
+	}
+	return f
+}
+
+func (r *Rule) matches(call ToolCall, f callFacts) bool {
+	if len(r.Tools) > 0 && !anyGlob(r.Tools, call.Tool) {
+		return false
+	}
+	if len(r.Channels) > 0 && !contains(r.Channels, call.Channel) {
+		return false
+	}
+	if len(r.Users) > 0 {
+		if call.UserID == "" || !contains(r.Users, call.Channel+":"+call.UserID) {
+			return false
+		}
+	}
+	for key, re := range r.args {
+		v, ok := f.args[key]
+		if !ok || !re.MatchString(argString(v)) {
+			return false
+		}
+	}
+	if len(r.Commands) > 0 && !anyMatch(r.Commands, f.commands, anyGlob) {
+		return false
+	}
+	if len(r.Paths) > 0 && !anyMatch(r.Paths, f.paths, anyPath) {
+		return false
+	}
+	return true
+}
+
+func anyMatch(patterns, values []string, match func([]string, string) bool) bool {
+	for _, v := range values {
+		if match(patterns, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func anyGlob(globs []string, name string) bool {
+	for _, g := range globs {
+		if ok, _ := path.Match(g, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// anyPath reports whether p, or the file it links to, is in one of the
+// directories or matches one of the globs.
+func anyPath(patterns []string, p string) bool {
+	candidates := []string{p}
+	if resolved, err := filepath.EvalSymlinks(p); err == nil && resolved != p {
+		candidates = append(candidates, resolved)
+	}
+	for _, pat := range patterns {
+		for _, c := range candidates {
+			if pathMatch(pat, c) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// pathMatch matches an absolute path against a directory, which takes in
+// everything below it, or a glob, which matches the path or a directory
+// above it. A glob without a slash matches the file name.
+func pathMatch(pattern, p string) bool {
+	if !strings.ContainsAny(pattern, "*?[") {
+		dir := absPath(pattern)
+		return p == dir || strings.HasPrefix(p, strings.TrimSuffix(dir, "/")+"/")
+	}
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, path.Base(p))
+		return ok
+	}
+	pattern = absPath(pattern)
+	for q := p; ; q = path.Dir(q) {
+		if ok, _ := path.Match(pattern, q); ok {
+			return true
+		}
+		if q == "/" || q == "." {
+			return false
+		}
+	}
+}
+
+func absPath(p string) string {
+	p = expandHome(p)
+	if abs, err := filepath.Abs(p); err == nil {
+		return abs
+	}
+	return path.Clean(p)
+}
+
+// argString is an argument's value as rules match it: strings as they are,
+// anything else as JSON.
+func argString(v any) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+func contains(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+// PolicyFile is a RuleSet read from a file, reloaded when the file changes.
+// A missing file has no rules.
+type PolicyFile struct {
+	path  string
+	rules atomic.Pointer[RuleSet]
+	mu    sync.Mutex
+	data  []byte // contents of the loaded version
+}
+
+// LoadPolicyFile reads the policy file at path, failing if it's invalid.
+func LoadPolicyFile(path string) (*PolicyFile, error) {
+	f := &PolicyFile{path: path}
+	f.rules.Store(&RuleSet{Default: RuleAllow})
+	if _, err := f.Reload(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Path returns the file's path.
+func (f *PolicyFile) Path() string {
+	return f.path
+}
+
+// Rules returns the rules currently in force.
+func (f *PolicyFile) Rules() *RuleSet {
+	return f.rules.Load()
+}
+
+// Reload re-reads the file and reports whether the rules changed. When the
+// file is invalid the rules in force stay, and the error says why.
+func (f *PolicyFile) Reload() (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	data, err := os.ReadFile(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		data, err = nil, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("reading policy file: %w", err)
+	}
+	if f.data != nil && bytes.Equal(data, f.data) {
+		return false, nil
+	}
+	rs, err := ParseRules(data)
+	if err != nil {
+		return false, fmt.Errorf("policy file %s: %w", f.path, err)
+	}
+	f.rules.Store(rs)
+	f.data = append([]byte{}, data...)
+	return true, nil
+}
+
+// Watch reloads the file whenever it changes, checking every interval until
+// ctx is done.
+func (f *PolicyFile) Watch(ctx context.Context, interval time.Duration, logger *slog.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			changed, err := f.Reload()
+			switch {
+			case err != nil:
+				logger.Error("policy reload failed, keeping the previous rules", "error", err)
+			case changed:
+				logger.Info("policy reloaded", "path", f.path, "rules", len(f.Rules().Rules))
+			}
+		}
+	}
+}
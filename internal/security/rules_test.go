@@ -0,0 +1,171 @@
+package security
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testRules = `
+default: allow
+rules:
+  - name: keys
+    paths: ["~/.ssh", "*.pem", "/srv/*/secrets"]
+    decision: deny
+    reason: Keys stay private
+  - name: installs
+    tools: [shell_exec]
+    commands: [apt, apt-get, "pip*"]
+    decision: approve
+  - name: ops-curl
+    tools: [shell_exec]
+    commands: [curl]
+    users: ["telegram:42", "slack:alice"]
+    decision: allow
+  - name: no-shell-in-discord
+    tools: [shell_exec]
+    channels: [discord]
+    decision: deny
+  - name: internal-web
+    tools: ["web_*"]
+    args: {url: "^https://internal\\."}
+    decision: audit
+`
+
+func TestRuleSetEvaluate(t *testing.T) {
+	rs, err := ParseRules([]byte(testRules))
+	if err != nil {
+		t.Fatal(err)
+	}
+	home, _ := os.UserHomeDir()
+
+	tests := []struct {
+		tool, args, channel, user string
+		rule                      int
+		decision                  string
+	}{
+		// Paths in file tool arguments, at any depth, and in shell commands
+		{"file_read", `{"path":"~/.ssh/id_rsa"}`, "", "", 1, RuleDeny},
+		{"file_read", `{"path":"` + home + `/.ssh"}`, "", "", 1, RuleDeny},
+		{"file_read", `{"path":"` + home + `/.sshx"}`, "", "", 0, RuleAllow},
+		{"file_write", `{"path":"/etc/tls/server.pem"}`, "", "", 1, RuleDeny},
+		{"file_read", `{"path":"/srv/app/secrets/db"}`, "", "", 1, RuleDeny},
+		{"mcp_fs_read", `{"opts":{"filePath":"/srv/app/secrets"}}`, "", "", 1, RuleDeny},
+		{"shell_exec", `{"command":"cat ~/.ssh/id_rsa"}`, "", "", 1, RuleDeny},
+		{"shell_exec", `{"command":"cd ~ && sh -c 'tar czf /tmp/k.tgz .ssh/id_rsa'"}`, "", "", 1, RuleDeny},
+		{"shell_exec", `{"command":"openssl x509 -in=/etc/tls/server.pem"}`, "", "", 1, RuleDeny},
+		// Commands, including wrapped and nested ones
+		{"shell_exec", `{"command":"sudo apt-get install -y jq"}`, "", "", 2, RuleApprove},
+		{"shell_exec", `{"command":"cd /app && pip3 install -r requirements.txt"}`, "", "", 2, RuleApprove},
+		{"shell_exec", `{"command":"bash -c 'env FOO=1 apt update'"}`, "", "", 2, RuleApprove},
+		{"shell_exec", `{"command":"echo apt"}`, "", "", 0, RuleAllow},
+		// Users, by channel:ID, and channels
+		{"shell_exec", `{"command":"curl -s https://x"}`, "telegram", "42", 3, RuleAllow},
+		{"shell_exec", `{"command":"curl -s https://x"}`, "discord", "42", 4, RuleDeny},
+		{"shell_exec", `{"command":"curl -s https://x"}`, "slack", "alice", 3, RuleAllow},
+		{"shell_exec", `{"command":"curl -s https://x"}`, "webhook", "42", 0, RuleAllow},
+		{"shell_exec", `{"command":"curl -s https://x"}`, "webhook", "alice", 0, RuleAllow},
+		{"shell_exec", `{"command":"curl -s https://x"}`, "", "", 0, RuleAllow},
+		{"shell_exec", `{"command":"ls"}`, "discord", "7", 4, RuleDeny},
+		// Arguments
+		{"web_read", `{"url":"https://internal.acme/wiki"}`, "", "", 5, RuleAudit},
+		{"web_read", `{"url":"https://example.com"}`, "", "", 0, RuleAllow},
+		{"web_search", `{"query":"x"}`, "", "", 0, RuleAllow},
+	}
+	for _, tt := range tests {
+		m := rs.Evaluate(ToolCall{Tool: tt.tool, Args: []byte(tt.args), Channel: tt.channel, UserID: tt.user})
+		if m.Index != tt.rule || m.Decision != tt.decision {
+			t.Errorf("%s %s (%s:%s) = %s by %s, want %s by rule %d", tt.tool, tt.args, tt.channel, tt.user, m.Decision, m.Label(), tt.decision, tt.rule)
+		}
+	}
+}
+
+func TestRulePathsMatchExistingNames(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "token"), []byte("x"), 0o600)
+	rs, err := ParseRules([]byte("rules:\n  - paths: [" + dir + "/token]\n    decision: deny\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for command, want := range map[string]string{
+		"cd " + dir + " && cat token":   RuleDeny,
+		"cd " + dir + " && cat missing": RuleAllow,
+		"cd " + dir + " && echo hi":     RuleAllow,
+	} {
+		if m := rs.Evaluate(ToolCall{Tool: "shell_exec", Args: []byte(`{"command":"` + command + `"}`)}); m.Decision != want {
+			t.Errorf("%s: got %s, want %s", command, m.Decision, want)
+		}
+	}
+}
+
+func TestRuleArgsMatchNonStrings(t *testing.T) {
+	rs, err := ParseRules([]byte(`{"default": "approve", "rules": [{"tools": ["shell_exec"], "args": {"timeout_seconds": "^[0-9]{1,2}$"}, "decision": "allow"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := rs.Evaluate(ToolCall{Tool: "shell_exec", Args: []byte(`{"command":"ls","timeout_seconds":30}`)}); m.Decision != RuleAllow {
+		t.Errorf("expected the rule to match a number argument, got %s", m.Decision)
+	}
+	m := rs.Evaluate(ToolCall{Tool: "shell_exec", Args: []byte(`{"command":"ls","timeout_seconds":300}`)})
+	if m.Decision != RuleApprove || m.Rule != nil {
+		t.Errorf("expected the default to decide, got %s by %s", m.Decision, m.Label())
+	}
+}
+
+func TestParseRulesErrors(t *testing.T) {
+	tests := []struct{ file, want string }{
+		{"default: maybe", `unknown default decision "maybe"`},
+		{"rules:\n  - tools: [x]", "rule #1: decision is required"},
+		{"rules:\n  - name: a\n    decision: allow\n  - name: b\n    decision: block", `rule #2 "b": unknown decision "block"`},
+		{"rules:\n  - args: {url: '('}\n    decision: deny", "rule #1: args.url:"},
+		{"rules:\n  - paths: ['[']\n    decision: deny", `bad pattern "["`},
+		{"rules:\n  - tool: [x]\n    decision: deny", "field tool not found"},
+		{"rules:\n  - users: ['42']\n    decision: allow", `user "42" must be channel:userID`},
+		{"rules:\n  - users: ['webhook:42']\n    decision: allow", "webhook clients choose their own IDs"},
+		{"rules:\n  - users: ['websocket:1']\n    decision: allow", "websocket clients choose their own IDs"},
+	}
+	for _, tt := range tests {
+		_, err := ParseRules([]byte(tt.file))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("ParseRules(%q) = %v, want error containing %q", tt.file, err, tt.want)
+		}
+	}
+}
+
+func TestPolicyFileReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	f, err := LoadPolicyFile(path)
+	if err != nil {
+		t.Fatalf("a missing file should load with no rules: %v", err)
+	}
+	call := ToolCall{Tool: "shell_exec", Args: []byte(`{"command":"ls"}`)}
+	if m := f.Rules().Evaluate(call); m.Rule != nil {
+		t.Fatalf("expected no rules, got %s", m.Label())
+	}
+
+	os.WriteFile(path, []byte("rules:\n  - tools: [shell_exec]\n    decision: deny\n"), 0o644)
+	if changed, err := f.Reload(); !changed || err != nil {
+		t.Fatalf("Reload() = %v, %v", changed, err)
+	}
+	if m := f.Rules().Evaluate(call); m.Decision != RuleDeny {
+		t.Errorf("expected the new rule to apply, got %s", m.Decision)
+	}
+	if changed, _ := f.Reload(); changed {
+		t.Error("an unchanged file should not reload")
+	}
+
+	os.WriteFile(path, []byte("rules:\n  - tools: [shell_exec]\n    decision: nope\n"), 0o644)
+	if _, err := f.Reload(); err == nil {
+		t.Error("expected an error for an invalid file")
+	}
+	if m := f.Rules().Evaluate(call); m.Decision != RuleDeny {
+		t.Errorf("an invalid file should keep the previous rules, got %s", m.Decision)
+	}
+
+	os.Remove(path)
+	f.Reload()
+	if m := f.Rules().Evaluate(call); m.Rule != nil {
+		t.Errorf("a removed file should leave no rules, got %s", m.Label())
+	}
+}
@@ -0,0 +1,278 @@
+package security
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// redacted replaces every credential ScrubCredentials finds.
+const redacted = "[REDACTED]"
+
+// minSecretLen is the shortest known secret redacted wherever it appears;
+// shorter ones would mangle ordinary text. Shorter vault and config values
+// are redacted only as whole words; shorter env and file values, often
+// ports or flags, are not redacted at all.
+const minSecretLen = 4
+
+// wordSources are where a secret shorter than minSecretLen was deliberately
+// declared one, so it is worth redacting as a whole word.
+var wordSources = map[string]bool{"vault": true, "config": true}
+
+// base64Word is a word that may be base64 hiding a known secret among other
+// data, like user:password in a Basic auth header.
+var base64Word = regexp.MustCompile(`[A-Za-z0-9+/_-]{6,}={0,2}`)
+
+// Redaction sources besides those passed to AddSecrets.
+const (
+	SourcePattern = "pattern" // the built-in credential patterns
+	SourceCustom  = "custom"  // patterns added with AddPatterns
+)
+
+// scrubState is what a Policy redacts beyond the built-in patterns, and
+// how much it has redacted.
+type scrubState struct {
+	secrets  []knownSecret // longest first, so a secret containing another goes whole
+	patterns []*regexp.Regexp
+	logger   *slog.Logger
+
+	mu     sync.Mutex
+	counts map[string]int64
+}
+
+// knownSecret is an exact value to redact and where it came from.
+type knownSecret struct {
+	value   string
+	source  string
+	word    bool // only as a whole word, for short secrets
+	encoded bool // an encoding of a secret, not the secret itself
+}
+
+// AddSecrets makes ScrubCredentials redact these exact values, and their
+// base64, URL-encoded and JSON-escaped forms, wherever they appear, and
+// base64 words that decode to something containing them. source says where
+// they came from (vault, config, env, file) in the redaction counts. Call it
+// before the policy is in use.
+func (p *Policy) AddSecrets(source string, values ...string) {
+	for _, v := range values {
+		word := len(v) < minSecretLen
+		if v == "" || word && !wordSources[source] {
+			continue
+		}
+		for i, form := range append([]string{v}, encodings(v)...) {
+			if !p.knows(form) {
+				p.scrub.secrets = append(p.scrub.secrets, knownSecret{value: form, source: source, word: word, encoded: i > 0})
+			}
+		}
+	}
+	sort.SliceStable(p.scrub.secrets, func(i, j int) bool {
+		return len(p.scrub.secrets[i].value) > len(p.scrub.secrets[j].value)
+	})
+}
+
+func (p *Policy) knows(value string) bool {
+	for _, s := range p.scrub.secrets {
+		if s.value == value {
+			return true
+		}
+	}
+	return false
+}
+
+// encodings returns the ways v may show up encoded: base64 with and without
+// padding, URL-safe base64, URL query and path escaping, and escaped inside
+// a JSON string. Forms identical to v are left out.
+func encodings(v string) []string {
+	b := []byte(v)
+	forms := []string{
+		base64.StdEncoding.EncodeToString(b),
+		base64.RawStdEncoding.EncodeToString(b),
+		base64.URLEncoding.EncodeToString(b),
+		base64.RawURLEncoding.EncodeToString(b),
+		url.QueryEscape(v),
+		url.PathEscape(v),
+	}
+	if j, err := json.Marshal(v); err == nil {
+		forms = append(forms, string(j[1:len(j)-1]))
+	}
+	var out []string
+	for _, f := range forms {
+		if f != v {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// hiddenSecret reports whether w decodes from base64 to text containing a
+// known secret, and the secret's source.
+func (p *Policy) hiddenSecret(w string) (string, bool) {
+	w = strings.TrimRight(w, "=")
+	for _, enc := range []*base64.Encoding{base64.RawStdEncoding, base64.RawURLEncoding} {
+		data, err := enc.DecodeString(w)
+		if err != nil {
+			continue
+		}
+		decoded := string(data)
+		for _, s := range p.scrub.secrets {
+			if !s.encoded && !s.word && strings.Contains(decoded, s.value) {
+				return s.source, true
+			}
+		}
+	}
+	return "", false
+}
+
+// replaceWord redacts the occurrences of s in text that aren't part of a
+// longer word, and returns how many there were.
+func replaceWord(text, s string) (string, int) {
+	var b strings.Builder
+	n, i := 0, 0
+	for {
+		j := strings.Index(text[i:], s)
+		if j < 0 {
+			break
+		}
+		j += i
+		end := j + len(s)
+		if (j == 0 || !wordByte(text[j-1])) && (end == len(text) || !wordByte(text[end])) {
+			b.WriteString(text[i:j])
+			b.WriteString(redacted)
+			n++
+			i = end
+		} else {
+			b.WriteString(text[i : j+1])
+			i = j + 1
+		}
+	}
+	if n == 0 {
+		return text, 0
+	}
+	b.WriteString(text[i:])
+	return b.String(), n
+}
+
+// wordByte reports whether c is part of a word: a letter, digit or
+// underscore, or a byte of a non-ASCII character.
+func wordByte(c byte) bool {
+	return c == '_' || c >= 0x80 || '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+// AddPatterns makes ScrubCredentials also redact what these regexes match,
+// for credentials of a format the built-in patterns don't know.
+func (p *Policy) AddPatterns(patterns ...string) error {
+	for _, pat := range patterns {
+		re, err := regexp.Compile(pat)
+		if err != nil {
+			return fmt.Errorf("invalid scrub pattern %q: %v", pat, err)
+		}
+		p.scrub.patterns = append(p.scrub.patterns, re)
+	}
+	return nil
+}
+
+// SetLogger logs a credentials_redacted line, with counts by source but
+// never the values, each time ScrubCredentials redacts something.
+func (p *Policy) SetLogger(l *slog.Logger) {
+	p.scrub.logger = l
+}
+
+// Redactions returns how many credentials ScrubCredentials has redacted,
+// by source. Text scrubbed more than once, like a streamed reply, counts
+// each time.
+func (p *Policy) Redactions() map[string]int64 {
+	p.scrub.mu.Lock()
+	defer p.scrub.mu.Unlock()
+	counts := make(map[string]int64, len(p.scrub.counts))
+	for source, n := range p.scrub.counts {
+		counts[source] = n
+	}
+	return counts
+}
+
+// ScrubCredentials removes API keys, tokens, and passwords from text: known
+// secrets first, then custom and built-in patterns.
+func (p *Policy) ScrubCredentials(text string) string {
+	var counts map[string]int
+	count := func(source string, n int) {
+		if counts == nil {
+			counts = make(map[string]int)
+		}
+		counts[source] += n
+	}
+
+	// Whole base64 words first, before a secret's own encoding inside one
+	// is replaced and leaves the rest of it, like the user of user:password
+	if len(p.scrub.secrets) > 0 {
+		text = base64Word.ReplaceAllStringFunc(text, func(w string) string {
+			if source, ok := p.hiddenSecret(w); ok {
+				count(source, 1)
+				return redacted
+			}
+			return w
+		})
+	}
+	for _, s := range p.scrub.secrets {
+		if s.word {
+			var n int
+			if text, n = replaceWord(text, s.value); n > 0 {
+				count(s.source, n)
+			}
+		} else if n := strings.Count(text, s.value); n > 0 {
+			text = strings.ReplaceAll(text, s.value, redacted)
+			count(s.source, n)
+		}
+	}
+	replace := func(re *regexp.Regexp, source string) {
+		text = re.ReplaceAllStringFunc(text, func(match string) string {
+			// e.g. "password: [REDACTED]" after a known secret went
+			if strings.Contains(match, redacted) {
+				return match
+			}
+			count(source, 1)
+			return redacted
+		})
+	}
+	for _, re := range p.scrub.patterns {
+		replace(re, SourceCustom)
+	}
+	for _, re := range p.credPatterns {
+		replace(re, SourcePattern)
+	}
+
+	if counts != nil {
+		p.record(counts)
+	}
+	return text
+}
+
+// record adds to the redaction counts and logs them.
+func (p *Policy) record(counts map[string]int) {
+	sources := make([]string, 0, len(counts))
+	total := 0
+	p.scrub.mu.Lock()
+	if p.scrub.counts == nil {
+		p.scrub.counts = make(map[string]int64)
+	}
+	for source, n := range counts {
+		p.scrub.counts[source] += int64(n)
+		sources = append(sources, source)
+		total += n
+	}
+	p.scrub.mu.Unlock()
+
+	if p.scrub.logger != nil {
+		sort.Strings(sources)
+		args := []any{"count", total}
+		for _, source := range sources {
+			args = append(args, source, counts[source])
+		}
+		p.scrub.logger.Info("credentials_redacted", args...)
+	}
+}
@@ -0,0 +1,716 @@
+package security
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"mvdan.cc/sh/v3/expand"
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// maxNesting bounds how deep analysis follows scripts run through eval,
+// sh -c and here-documents; anything deeper needs approval.
+const maxNesting = 4
+
+// analyzer parses a command line and checks each simple command in it,
+// including those in pipelines, subshells, command and process substitutions
+// and function bodies. It keeps the most severe finding.
+type analyzer struct {
+	policy   *Policy
+	decision Decision
+	reason   string
+	cwd      string   // set by cd to an absolute path, for relative rm targets
+	commands []string // programs run, for policy rules
+	paths    []string // path operands and redirect targets, for policy rules
+}
+
+// arg is a word of a command after brace expansion and quote removal.
+type arg struct {
+	value string
+	known bool   // false when part of it is only known at run time
+	raw   string // as written
+}
+
+// call is a simple command with wrappers such as sudo and env removed.
+type call struct {
+	name   string // binary without its directory
+	args   []arg
+	text   string // the statement as written, with the stage feeding it, for reasons
+	stmt   *syntax.Stmt
+	pipeIn string // the previous pipeline stage feeding stdin, if any
+}
+
+func (a *analyzer) flag(d Decision, reason string) {
+	if severity(d) > severity(a.decision) {
+		a.decision, a.reason = d, reason
+	}
+}
+
+// severity ranks decisions, so a denied segment of a command line is never
+// outweighed by one that only needs approval.
+func severity(d Decision) int {
+	switch d {
+	case Denied:
+		return 2
+	case NeedsApproval:
+		return 1
+	}
+	return 0
+}
+
+func (a *analyzer) deny(c call, format string, args ...any) {
+	a.flag(Denied, fmt.Sprintf("Command blocked: %s in `%s`", fmt.Sprintf(format, args...), c.text))
+}
+
+func (a *analyzer) approve(c call, format string, args ...any) {
+	a.flag(NeedsApproval, fmt.Sprintf("Command requires approval: %s in `%s`", fmt.Sprintf(format, args...), c.text))
+}
+
+// script analyzes src, run at the given nesting depth.
+func (a *analyzer) script(src string, depth int) {
+	if depth > maxNesting {
+		a.flag(NeedsApproval, "Command requires approval: too deeply nested to check")
+		return
+	}
+	file, err := syntax.NewParser(syntax.Variant(syntax.LangBash)).Parse(strings.NewReader(src), "")
+	if err != nil {
+		a.flag(NeedsApproval, fmt.Sprintf("Command requires approval: could not parse it (%v)", err))
+		return
+	}
+
+	piped := map[*syntax.Stmt]*syntax.Stmt{} // pipeline stage -> the stage feeding it
+	syntax.Walk(file, func(node syntax.Node) bool {
+		switch n := node.(type) {
+		case *syntax.BinaryCmd:
+			if n.Op == syntax.Pipe || n.Op == syntax.PipeAll {
+				piped[firstStage(n.Y)] = lastStage(n.X)
+			}
+		case *syntax.FuncDecl:
+			a.funcDecl(src, n)
+		case *syntax.Stmt:
+			a.redirects(src, n)
+			if ce, ok := n.Cmd.(*syntax.CallExpr); ok && len(ce.Args) > 0 {
+				c := call{text: slice(src, n), stmt: n}
+				if prev, ok := piped[n]; ok {
+					c.pipeIn = slice(src, prev)
+					c.text = strings.TrimSpace(src[prev.Pos().Offset():n.End().Offset()])
+				}
+				a.call(c, ce, depth)
+			}
+		}
+		return true
+	})
+}
+
+// call checks one simple command.
+func (a *analyzer) call(c call, ce *syntax.CallExpr, depth int) {
+	a.run(c, unwrap(words(ce.Args)), depth)
+}
+
+// run checks the command args with wrappers removed.
+func (a *analyzer) run(c call, args []arg, depth int) {
+	if len(args) == 0 {
+		return
+	}
+	if !args[0].known {
+		a.approve(c, "the command to run is only known at run time")
+		return
+	}
+	c.name, c.args = path.Base(args[0].value), args[1:]
+	a.commands = append(a.commands, c.name)
+	for _, x := range c.args {
+		a.addPath(x)
+	}
+
+	for _, re := range a.policy.denyPatterns {
+		if re.MatchString(c.line()) {
+			a.deny(c, "matched %s", re.String())
+		}
+	}
+
+	switch {
+	case c.name == "cd":
+		a.cd(c)
+	case c.name == "rm":
+		a.rm(c)
+	case c.name == "find":
+		a.find(c, depth)
+	case c.name == "dd":
+		for _, x := range c.args {
+			if of, ok := strings.CutPrefix(x.value, "of="); ok && blockDevice(of) {
+				a.deny(c, "overwrites disk %s", of)
+			}
+		}
+	case strings.HasPrefix(c.name, "mkfs") || c.name == "mke2fs" || c.name == "mkswap" || c.name == "wipefs":
+		for _, x := range c.args {
+			if blockDevice(x.value) {
+				a.deny(c, "formats disk %s", x.value)
+			}
+		}
+	case c.name == "systemctl":
+		a.systemctl(c)
+	case c.name == "service":
+		if len(c.args) >= 2 && isAeon(c.args[0].value) && c.args[1].value == "stop" {
+			a.deny(c, "stops aeon")
+		}
+	case c.name == "pkill" || c.name == "killall":
+		for _, x := range c.args {
+			if !strings.HasPrefix(x.value, "-") && strings.Contains(strings.ToLower(x.raw), "aeon") {
+				a.deny(c, "kills aeon")
+			}
+		}
+	case c.name == "kill":
+		for _, x := range c.args {
+			if !x.known && strings.Contains(strings.ToLower(x.raw), "aeon") {
+				a.deny(c, "kills aeon")
+			}
+		}
+	case c.name == "eval":
+		a.nested(c, c.args, "eval", depth)
+	case shells[c.name]:
+		a.shell(c, depth)
+	case c.name == "source" || c.name == ".":
+		a.source(c, depth)
+	case interpreters.MatchString(c.name):
+		if inlineCode(c) {
+			a.approve(c, "%s runs inline code", c.name)
+		} else if c.pipeIn != "" && len(c.operands()) == 0 {
+			a.approve(c, "pipes into %s", c.name)
+		}
+	}
+}
+
+// inlineCode reports whether an interpreter is given code to run in its
+// arguments, like python -c or perl -e, rather than a script file.
+func inlineCode(c call) bool {
+	name := strings.TrimRight(c.name, "0123456789.")
+	for _, x := range c.args {
+		v := x.value
+		if !strings.HasPrefix(v, "-") || v == "-" || v == "--" {
+			return false // options after the script are the script's
+		}
+		if strings.HasPrefix(v, "--") {
+			if name == "node" && (v == "--eval" || v == "--print" || strings.HasPrefix(v, "--eval=") || strings.HasPrefix(v, "--print=")) {
+				return true
+			}
+			continue
+		}
+		flags := map[string]string{"python": "c", "perl": "eE", "ruby": "e", "node": "ep", "php": "r"}[name]
+		if strings.ContainsAny(v[1:], flags) {
+			return true
+		}
+	}
+	return false
+}
+
+// line is the command as run, for custom deny patterns.
+func (c call) line() string {
+	parts := []string{c.name}
+	for _, x := range c.args {
+		parts = append(parts, x.value)
+	}
+	return strings.Join(parts, " ")
+}
+
+// operands returns the arguments that aren't options.
+func (c call) operands() []arg {
+	var out []arg
+	for i, x := range c.args {
+		if x.value == "--" {
+			return append(out, c.args[i+1:]...)
+		}
+		if !strings.HasPrefix(x.value, "-") || x.value == "-" {
+			out = append(out, x)
+		}
+	}
+	return out
+}
+
+// protectedPaths can't be deleted recursively.
+var protectedPaths = map[string]bool{
+	"/": true, "/bin": true, "/boot": true, "/dev": true, "/etc": true, "/lib": true, "/lib64": true,
+	"/proc": true, "/root": true, "/sbin": true, "/sys": true, "/usr": true, "/var": true,
+}
+
+func (a *analyzer) rm(c call) {
+	recursive := false
+	for _, x := range c.args {
+		v := x.value
+		switch {
+		case v == "--":
+		case v == "--recursive":
+			recursive = true
+		case v == "--no-preserve-root":
+			a.deny(c, "rm --no-preserve-root")
+		case strings.HasPrefix(v, "-") && !strings.HasPrefix(v, "--"):
+			recursive = recursive || strings.ContainsAny(v, "rR")
+		}
+	}
+	if !recursive {
+		return
+	}
+	for _, x := range c.operands() {
+		if !x.known {
+			a.approve(c, "recursive delete of a path only known at run time (%s)", x.raw)
+			continue
+		}
+		target := x.value
+		if target == "~" || strings.HasPrefix(target, "~/") {
+			target = expandHome(target)
+		}
+		if !path.IsAbs(target) && a.cwd != "" {
+			target = path.Join(a.cwd, target)
+		}
+		target = path.Clean(target)
+		if protectedPaths[target] || target == homeDir() || (path.Dir(target) == "/" && strings.ContainsAny(target, "*?[")) {
+			a.deny(c, "recursive delete of %s", x.value)
+		}
+	}
+}
+
+// find checks what find deletes or runs: -delete as a recursive delete of
+// its starting points, and the command of -exec or -ok with {} standing for
+// each of them.
+func (a *analyzer) find(c call, depth int) {
+	i := 0
+	for i < len(c.args) && (c.args[i].value == "-H" || c.args[i].value == "-L" || c.args[i].value == "-P") {
+		i++
+	}
+	var starts []arg
+	for ; i < len(c.args); i++ {
+		v := c.args[i].value
+		if strings.HasPrefix(v, "-") || v == "(" || v == "!" {
+			break
+		}
+		starts = append(starts, c.args[i])
+	}
+	if len(starts) == 0 {
+		starts = []arg{{value: ".", known: true, raw: "."}}
+	}
+
+	exec := call{text: c.text, stmt: &syntax.Stmt{}}
+	for ; i < len(c.args); i++ {
+		switch c.args[i].value {
+		case "-delete":
+			a.rm(call{name: "rm", args: append([]arg{{value: "-r", known: true}}, starts...), text: c.text})
+		case "-exec", "-execdir", "-ok", "-okdir":
+			var cmd []arg
+			for i++; i < len(c.args) && c.args[i].value != ";" && c.args[i].value != "+"; i++ {
+				cmd = append(cmd, c.args[i])
+			}
+			for _, start := range starts {
+				args := make([]arg, len(cmd))
+				for j, x := range cmd {
+					args[j] = x
+					if strings.Contains(x.value, "{}") {
+						args[j] = arg{value: strings.ReplaceAll(x.value, "{}", start.value), known: x.known && start.known, raw: x.raw}
+					}
+				}
+				a.run(exec, unwrap(args), depth+1)
+			}
+		}
+	}
+}
+
+// homeDir returns the home directory, which can't be deleted recursively
+// either, or "" if it isn't known.
+func homeDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return path.Clean(home)
+}
+
+// cd follows the working directory, ignoring that subshells restore it.
+func (a *analyzer) cd(c call) {
+	ops := c.operands()
+	switch {
+	case len(ops) == 0 || !ops[0].known:
+		a.cwd = ""
+	case ops[0].value == "~" || strings.HasPrefix(ops[0].value, "~/"):
+		a.cwd = path.Clean(expandHome(ops[0].value))
+	case path.IsAbs(ops[0].value):
+		a.cwd = path.Clean(ops[0].value)
+	case a.cwd != "":
+		a.cwd = path.Join(a.cwd, ops[0].value)
+	}
+}
+
+// addPath records x if it names a file: an operand like /etc/passwd, ./run.sh,
+// ~/.ssh or an existing file's name, or the value of an option like
+// of=/dev/sda or --file=/tmp/x.
+func (a *analyzer) addPath(x arg) {
+	if !x.known {
+		return
+	}
+	v := x.value
+	if strings.HasPrefix(v, "-") || strings.Contains(v, "=") {
+		_, v, _ = strings.Cut(v, "=")
+	}
+	if strings.Contains(v, "://") {
+		return
+	}
+	if !strings.Contains(v, "/") && !strings.HasPrefix(v, "~") {
+		// A bare name counts when there is a file by that name
+		if _, err := os.Lstat(filepath.Join(a.cwd, v)); err != nil {
+			return
+		}
+	}
+	if !path.IsAbs(v) && !strings.HasPrefix(v, "~") && a.cwd != "" {
+		v = path.Join(a.cwd, v)
+	}
+	a.paths = append(a.paths, v)
+}
+
+// inspectCommand returns the programs a shell command runs and the paths it
+// names, including those of scripts it runs through eval or sh -c. Words only
+// known at run time are left out.
+func inspectCommand(command string) (commands, paths []string) {
+	a := &analyzer{policy: &Policy{}}
+	a.script(command, 0)
+	return a.commands, a.paths
+}
+
+func (a *analyzer) systemctl(c call) {
+	ops := c.operands()
+	if len(ops) < 2 {
+		return
+	}
+	switch ops[0].value {
+	case "stop", "kill", "disable", "mask":
+		for _, unit := range ops[1:] {
+			if isAeon(strings.TrimSuffix(unit.value, ".service")) {
+				a.deny(c, "%s aeon", ops[0].value)
+			}
+		}
+	}
+}
+
+func isAeon(name string) bool {
+	return strings.EqualFold(name, "aeon")
+}
+
+var shells = map[string]bool{"sh": true, "bash": true, "dash": true, "zsh": true, "ksh": true, "ash": true}
+
+// interpreters run code read from stdin when given no script.
+var interpreters = regexp.MustCompile(`^(python[0-9.]*|perl|ruby|node|php)$`)
+
+// shell follows the script a shell runs: from -c, a file, a here-document,
+// or a pipe, which needs approval since what flows in is unknown. Code
+// given with -c is checked too, but like an interpreter's inline code it
+// needs approval even when nothing in it is flagged.
+func (a *analyzer) shell(c call, depth int) {
+	for i, x := range c.args {
+		if x.value == "-c" || (strings.HasPrefix(x.value, "-") && !strings.HasPrefix(x.value, "--") && strings.Contains(x.value, "c")) {
+			if i+1 < len(c.args) {
+				a.nested(c, c.args[i+1:i+2], c.name+" -c", depth)
+			}
+			a.approve(c, "%s runs inline code", c.name)
+			return
+		}
+	}
+	if ops := c.operands(); len(ops) > 0 && !readsStdin(ops[0]) {
+		a.scriptFile(c, ops[0])
+		return
+	}
+	a.stdinScript(c, depth)
+}
+
+// source follows the script run by source or ., like a shell's.
+func (a *analyzer) source(c call, depth int) {
+	ops := c.operands()
+	switch {
+	case len(ops) == 0:
+	case readsStdin(ops[0]):
+		a.stdinScript(c, depth)
+	default:
+		a.scriptFile(c, ops[0])
+	}
+}
+
+// readsStdin reports whether a script operand is the shell's stdin.
+func readsStdin(x arg) bool {
+	return x.known && (x.value == "-" || x.value == "/dev/stdin")
+}
+
+// scriptFile checks a script run from a file. One only known at run time,
+// like the output of a process substitution or a file descriptor, needs
+// approval since it may be anything.
+func (a *analyzer) scriptFile(c call, x arg) {
+	if !x.known || strings.HasPrefix(x.value, "/dev/fd/") || strings.HasPrefix(x.value, "/proc/") {
+		a.approve(c, "%s runs a script only known at run time (%s)", c.name, x.raw)
+	}
+}
+
+// stdinScript follows a script read from stdin: a here-document, a file
+// redirected in, or a pipe.
+func (a *analyzer) stdinScript(c call, depth int) {
+	for _, r := range c.stmt.Redirs {
+		switch r.Op {
+		case syntax.Hdoc, syntax.DashHdoc:
+			if body, known := wordValue(r.Hdoc); known {
+				a.nested(c, []arg{{value: body, known: true}}, c.name+" <<", depth)
+			} else {
+				a.approve(c, "runs a here-document only known at run time")
+			}
+			return
+		case syntax.WordHdoc:
+			a.nested(c, words([]*syntax.Word{r.Word}), c.name+" <<<", depth)
+			return
+		case syntax.RdrIn:
+			target, known := wordValue(r.Word)
+			a.scriptFile(c, arg{value: target, known: known, raw: wordSource(r.Word)})
+			return
+		}
+	}
+	if c.pipeIn != "" {
+		a.approve(c, "pipes into %s", c.name)
+	}
+}
+
+// nested analyzes a script passed as arguments, as to eval or sh -c.
+func (a *analyzer) nested(c call, args []arg, via string, depth int) {
+	parts := make([]string, len(args))
+	for i, x := range args {
+		if !x.known {
+			a.approve(c, "%s runs a script only known at run time", via)
+			return
+		}
+		parts[i] = x.value
+	}
+	inner := &analyzer{policy: a.policy, cwd: a.cwd}
+	inner.script(strings.Join(parts, " "), depth+1)
+	a.commands = append(a.commands, inner.commands...)
+	a.paths = append(a.paths, inner.paths...)
+	if inner.decision != Allowed {
+		a.flag(inner.decision, inner.reason+" (via "+via+")")
+	}
+}
+
+// redirects records the files a statement reads and writes, and checks
+// where it writes.
+func (a *analyzer) redirects(src string, stmt *syntax.Stmt) {
+	for _, r := range stmt.Redirs {
+		switch r.Op {
+		case syntax.RdrIn:
+			if target, known := wordValue(r.Word); known {
+				a.addPath(arg{value: target, known: true})
+			}
+		case syntax.RdrOut, syntax.AppOut, syntax.ClbOut, syntax.RdrInOut, syntax.RdrAll, syntax.AppAll, syntax.DplOut:
+			target, known := wordValue(r.Word)
+			if known {
+				a.addPath(arg{value: target, known: true})
+			}
+			if known && blockDevice(target) {
+				a.flag(Denied, fmt.Sprintf("Command blocked: writes to disk %s in `%s`", target, slice(src, stmt)))
+			}
+		}
+	}
+}
+
+// funcDecl blocks fork bombs: functions that start themselves in the
+// background or in a pipeline.
+func (a *analyzer) funcDecl(src string, fn *syntax.FuncDecl) {
+	name := fn.Name.Value
+	piped := map[*syntax.Stmt]bool{}
+	syntax.Walk(fn.Body, func(node syntax.Node) bool {
+		switch n := node.(type) {
+		case *syntax.BinaryCmd:
+			if n.Op == syntax.Pipe || n.Op == syntax.PipeAll {
+				piped[n.X], piped[n.Y] = true, true
+			}
+		case *syntax.Stmt:
+			ce, ok := n.Cmd.(*syntax.CallExpr)
+			if ok && len(ce.Args) > 0 && ce.Args[0].Lit() == name && (n.Background || piped[n]) {
+				a.flag(Denied, fmt.Sprintf("Command blocked: fork bomb in `%s`", slice(src, fn)))
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// blockDevice reports whether p names a disk or partition.
+var blockDevicePath = regexp.MustCompile(`^/dev/(sd|hd|vd|xvd|nvme|mmcblk|dm-|md|mapper/|disk/)`)
+
+func blockDevice(p string) bool {
+	return blockDevicePath.MatchString(p)
+}
+
+// wrappers run the command in their arguments. The values are the options
+// that take a separate value.
+var wrappers = map[string]map[string]bool{
+	"sudo":    {"-u": true, "-g": true, "-p": true, "-C": true, "-D": true, "-h": true, "-r": true, "-t": true, "-U": true, "-T": true, "-R": true},
+	"doas":    {"-u": true, "-C": true},
+	"env":     {"-u": true, "-C": true, "--unset": true, "--chdir": true},
+	"nice":    {"-n": true},
+	"ionice":  {"-c": true, "-n": true, "-p": true},
+	"nohup":   {},
+	"busybox": {},
+	"setsid":  {},
+	"time":    {"-f": true, "-o": true},
+	"command": {},
+	"builtin": {},
+	"exec":    {"-a": true},
+	"stdbuf":  {},
+	"timeout": {"-s": true, "-k": true, "--signal": true, "--kill-after": true},
+	"xargs":   {"-I": true, "-n": true, "-P": true, "-d": true, "-E": true, "-L": true, "-s": true, "-a": true},
+}
+
+// unwrap strips wrappers like sudo, env and xargs, leaving the command they run.
+func unwrap(args []arg) []arg {
+	for len(args) > 0 && args[0].known {
+		name := path.Base(args[0].value)
+		valued, ok := wrappers[name]
+		if !ok {
+			return args
+		}
+		if (name == "command" || name == "builtin") && len(args) > 1 && strings.HasPrefix(args[1].value, "-v") {
+			return nil // only looks the command up
+		}
+		i := 1
+		for i < len(args) {
+			v := args[i].value
+			if v == "--" {
+				i++
+				break
+			}
+			if name == "env" && strings.Contains(v, "=") && !strings.HasPrefix(v, "-") {
+				i++
+				continue
+			}
+			if !strings.HasPrefix(v, "-") || v == "-" {
+				break
+			}
+			if name == "env" && (v == "-S" || strings.HasPrefix(v, "--split-string")) {
+				return []arg{{raw: args[0].raw}} // env -S splits a string into a command
+			}
+			i++
+			if valued[v] {
+				i++
+			}
+		}
+		if name == "timeout" && i < len(args) {
+			i++ // the duration
+		}
+		rest := args[min(i, len(args)):]
+		if name == "xargs" {
+			if len(rest) == 0 {
+				return nil // echoes its input
+			}
+			rest = append(rest, arg{raw: "<stdin>"}) // arguments read from stdin
+		}
+		args = rest
+	}
+	return args
+}
+
+// words expands braces in ws and removes quotes.
+func words(ws []*syntax.Word) []arg {
+	var out []arg
+	for _, w := range ws {
+		raw := wordSource(w)
+		expanded := []*syntax.Word{w}
+		// Split a copy; the walk over the original must not meet BraceExp nodes
+		if split := (&syntax.Word{Parts: append([]syntax.WordPart(nil), w.Parts...)}); syntax.SplitBraces(split) {
+			expanded = expand.Braces(split)
+		}
+		for _, e := range expanded {
+			value, known := wordValue(e)
+			out = append(out, arg{value: value, known: known, raw: raw})
+		}
+	}
+	return out
+}
+
+// wordValue returns the value of w after quote removal, and false when part
+// of it is only known at run time.
+func wordValue(w *syntax.Word) (string, bool) {
+	if w == nil {
+		return "", false
+	}
+	var b strings.Builder
+	for _, part := range w.Parts {
+		switch p := part.(type) {
+		case *syntax.Lit:
+			b.WriteString(unescape(p.Value, ""))
+		case *syntax.SglQuoted:
+			if p.Dollar {
+				return b.String(), false // $'...' escapes
+			}
+			b.WriteString(p.Value)
+		case *syntax.DblQuoted:
+			for _, qp := range p.Parts {
+				lit, ok := qp.(*syntax.Lit)
+				if !ok {
+					return b.String(), false
+				}
+				b.WriteString(unescape(lit.Value, "$`\"\\\n"))
+			}
+		default:
+			return b.String(), false
+		}
+	}
+	return b.String(), true
+}
+
+// unescape removes the backslashes before characters in special, or before
+// any character when special is empty, as the shell does outside quotes.
+func unescape(s, special string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && (special == "" || strings.IndexByte(special, s[i+1]) >= 0) {
+			i++
+			if s[i] == '\n' {
+				continue // line continuation
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+func wordSource(w *syntax.Word) string {
+	var b strings.Builder
+	syntax.NewPrinter().Print(&b, w)
+	return b.String()
+}
+
+// slice returns the source of node.
+func slice(src string, node syntax.Node) string {
+	start, end := int(node.Pos().Offset()), int(node.End().Offset())
+	if start < 0 || end > len(src) || start > end {
+		return ""
+	}
+	return strings.TrimSpace(src[start:end])
+}
+
+// firstStage returns the first command of a pipeline.
+func firstStage(stmt *syntax.Stmt) *syntax.Stmt {
+	for {
+		bin, ok := stmt.Cmd.(*syntax.BinaryCmd)
+		if !ok || (bin.Op != syntax.Pipe && bin.Op != syntax.PipeAll) {
+			return stmt
+		}
+		stmt = bin.X
+	}
+}
+
+// lastStage returns the last command of a pipeline.
+func lastStage(stmt *syntax.Stmt) *syntax.Stmt {
+	for {
+		bin, ok := stmt.Cmd.(*syntax.BinaryCmd)
+		if !ok || (bin.Op != syntax.Pipe && bin.Op != syntax.PipeAll) {
+			return stmt
+		}
+		stmt = bin.Y
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/ImJafran/aeon/internal/sandbox"
 	"gopkg.in/yaml.v3"
 )
 
@@ -46,10 +47,12 @@ const maxFailures = 3
 
 // Loader scans, loads, and executes skills.
 type Loader struct {
-	mu        sync.RWMutex
-	skills    map[string]*Skill
-	skillsDir string
-	venvPath  string // path to base_venv
+	mu         sync.RWMutex
+	skills     map[string]*Skill
+	skillsDir  string
+	venvPath   string // path to base_venv
+	sandboxFor func(skill string) (sandbox.Options, bool)
+	secretsFor func(skill string) map[string]string
 }
 
 // NewLoader creates a skill loader.
@@ -61,6 +64,18 @@ func NewLoader(skillsDir, venvPath string) *Loader {
 	}
 }
 
+// SetSandbox makes skills run in a sandbox when for returns true for their
+// name. The skill's directory stays read-only inside it.
+func (l *Loader) SetSandbox(sandboxFor func(skill string) (sandbox.Options, bool)) {
+	l.sandboxFor = sandboxFor
+}
+
+// SetSecrets passes the secrets secretsFor returns for a skill to it as env
+// vars, named after the secrets. Other skills don't see them.
+func (l *Loader) SetSecrets(secretsFor func(skill string) map[string]string) {
+	l.secretsFor = secretsFor
+}
+
 // LoadAll scans the skills directory and loads all valid skills.
 func (l *Loader) LoadAll() error {
 	l.mu.Lock()
@@ -246,8 +261,28 @@ func (l *Loader) executeSkill(ctx context.Context, skill *Skill, params json.Raw
 		cmd.Env = append(cmd.Env, "PYTHONPATH="+libDir)
 	}
 
+	var granted []string
+	if l.secretsFor != nil {
+		for name, value := range l.secretsFor(skill.Meta.Name) {
+			cmd.Env = append(cmd.Env, name+"="+value)
+			granted = append(granted, name)
+		}
+	}
+
 	cmd.Dir = skill.Dir
 
+	var sb *sandbox.Sandbox
+	if l.sandboxFor != nil {
+		if opts, ok := l.sandboxFor(skill.Meta.Name); ok {
+			opts.Env = append(opts.Env, "SKILL_DIR", "SKILL_NAME", "PYTHONPATH")
+			opts.Env = append(opts.Env, granted...)
+			var err error
+			if sb, err = sandbox.Wrap(cmd, opts); err != nil {
+				return "", fmt.Errorf("skill not run, sandbox unavailable: %w", err)
+			}
+		}
+	}
+
 	// Pass params via stdin
 	cmd.Stdin = bytes.NewReader(params)
 
@@ -255,7 +290,11 @@ func (l *Loader) executeSkill(ctx context.Context, skill *Skill, params json.Raw
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
-	if err := cmd.Run(); err != nil {
+	err := cmd.Run()
+	if note := sb.Finish(); note != "" {
+		stderr.WriteString("\n[sandbox] " + note)
+	}
+	if err != nil {
 		errMsg := stderr.String()
 		if errMsg == "" {
 			errMsg = err.Error()
@@ -289,6 +289,33 @@ echo '{"result": "shell works"}'
 	}
 }
 
+func TestSkillSecrets(t *testing.T) {
+	skillsDir := setupTestSkillDir(t)
+	for _, name := range []string{"granted", "other"} {
+		skillDir := filepath.Join(skillsDir, name)
+		os.MkdirAll(skillDir, 0755)
+		os.WriteFile(filepath.Join(skillDir, "SKILL.md"), []byte("---\nname: "+name+"\ndescription: prints a key\nentrypoint: main.sh\ntimeout: 10\n---\n"), 0644)
+		os.WriteFile(filepath.Join(skillDir, "main.sh"), []byte("#!/bin/sh\necho \"key=$PRICES_KEY\"\n"), 0755)
+	}
+
+	loader := NewLoader(skillsDir, "")
+	loader.SetSecrets(func(skill string) map[string]string {
+		if skill == "granted" {
+			return map[string]string{"PRICES_KEY": "k-123"}
+		}
+		return nil
+	})
+	loader.LoadAll()
+
+	ctx := context.Background()
+	if out, err := loader.Execute(ctx, "granted", json.RawMessage(`{}`)); err != nil || out != "key=k-123\n" {
+		t.Errorf("expected the granted skill to see its secret, got %q, %v", out, err)
+	}
+	if out, err := loader.Execute(ctx, "other", json.RawMessage(`{}`)); err != nil || out != "key=\n" {
+		t.Errorf("expected other skills not to see it, got %q, %v", out, err)
+	}
+}
+
 func contains(s, substr string) bool {
 	return len(s) >= len(substr) && (s == substr || len(s) > 0 && containsSubstr(s, substr))
 }
@@ -10,6 +10,11 @@ import (
 	"github.com/ImJafran/aeon/internal/scheduler"
 )
 
+const (
+	cronPreviewRuns = 5                          // fire times shown on create
+	cronTimeLayout  = "Mon 2006-01-02 15:04 MST" // fire times in the job's timezone
+)
+
 // CronManageTool allows the LLM to manage scheduled jobs.
 type CronManageTool struct {
 	sched *scheduler.Scheduler
@@ -21,7 +26,7 @@ func NewCronManage(sched *scheduler.Scheduler) *CronManageTool {
 
 func (t *CronManageTool) Name() string { return "cron_manage" }
 func (t *CronManageTool) Description() string {
-	return "Set reminders and schedule tasks. Use this when a user says 'remind me', 'in X minutes', 'at X o'clock', or wants something recurring."
+	return "Set reminders and schedule tasks, and show a job's run history. Use this when a user says 'remind me', 'in X minutes', 'at X o'clock', or wants something recurring. Recurring tasks take standard cron expressions with an optional timezone. A job sends a reminder, runs a skill, runs a shell command, or runs an agent prompt; its result is sent to this chat."
 }
 func (t *CronManageTool) Parameters() json.RawMessage {
 	return json.RawMessage(`{
@@ -29,12 +34,12 @@ func (t *CronManageTool) Parameters() json.RawMessage {
 		"properties": {
 			"action": {
 				"type": "string",
-				"enum": ["create", "list", "pause", "resume", "delete", "get"],
+				"enum": ["create", "list", "pause", "resume", "delete", "get", "history"],
 				"description": "The action to perform"
 			},
 			"id": {
 				"type": "integer",
-				"description": "Job ID (for pause, resume, delete, get)"
+				"description": "Job ID (for pause, resume, delete, get; optional for history)"
 			},
 			"name": {
 				"type": "string",
@@ -42,19 +47,41 @@ func (t *CronManageTool) Parameters() json.RawMessage {
 			},
 			"schedule": {
 				"type": "string",
-				"description": "When to fire. Examples: 'in 1m', 'in 10m', 'in 2h' (one-time after delay), 'at 16:50', 'at 4:50pm' (one-time at clock time), 'every 5m', 'every 1h', 'daily', 'hourly' (recurring)"
+				"description": "When to fire. One-time: 'in 10m', 'in 2h', 'at 16:50', 'at 4:50pm', 'at 2025-03-01 09:00', 'at tomorrow 9am'. Recurring: cron 'minute hour day month weekday' such as '0 9 * * 1-5' (weekdays 9:00), '*/15 9-17 * * *', '30 8 1 * *'; '@hourly', '@daily', '@weekly'; or 'every 5m', 'every 1h'"
+			},
+			"timezone": {
+				"type": "string",
+				"description": "IANA timezone the schedule is in, e.g. 'Europe/Berlin' or 'America/New_York' (optional, default: server timezone)"
+			},
+			"type": {
+				"type": "string",
+				"enum": ["reminder", "skill", "shell", "agent"],
+				"description": "What the job does: 'reminder' sends the command text, 'skill' runs skill_name with params, 'shell' runs the command (dangerous commands fail, nobody is there to approve them), 'agent' runs the command as a prompt with tools. Default: 'skill' if skill_name is set, 'reminder' for one-time schedules, else 'agent'"
 			},
 			"command": {
 				"type": "string",
-				"description": "The reminder text or message to send when it fires (e.g. 'Team standup meeting', 'Take medication')"
+				"description": "Reminder text (e.g. 'Take medication'), shell command (e.g. 'df -h /'), or agent prompt (e.g. 'Summarize new GitHub issues')"
 			},
 			"skill_name": {
 				"type": "string",
-				"description": "Skill to run instead of a reminder message (optional, advanced)"
+				"description": "Skill to run for type 'skill'"
 			},
 			"params": {
 				"type": "string",
 				"description": "JSON params for the skill (optional, default: '{}')"
+			},
+			"timeout_seconds": {
+				"type": "integer",
+				"description": "Maximum run time in seconds (optional, default: 300)"
+			},
+			"misfire": {
+				"type": "string",
+				"enum": ["skip", "once", "all"],
+				"description": "Runs missed while Aeon was down: 'skip' them, run 'once' late (default), or run 'all' of them (up to 10)"
+			},
+			"limit": {
+				"type": "integer",
+				"description": "Number of runs to show for history (default: 10)"
 			}
 		},
 		"required": ["action"]
@@ -66,12 +93,17 @@ type cronManageParams struct {
 	ID        int64  `json:"id"`
 	Name      string `json:"name"`
 	Schedule  string `json:"schedule"`
+	Timezone  string `json:"timezone"`
+	Type      string `json:"type"`
 	SkillName string `json:"skill_name"`
 	Command   string `json:"command"`
 	Params    string `json:"params"`
+	Timeout   int    `json:"timeout_seconds"`
+	Misfire   string `json:"misfire"`
+	Limit     int    `json:"limit"`
 }
 
-func (t *CronManageTool) Execute(_ context.Context, params json.RawMessage) (ToolResult, error) {
+func (t *CronManageTool) Execute(ctx context.Context, params json.RawMessage) (ToolResult, error) {
 	var p cronManageParams
 	if err := json.Unmarshal(params, &p); err != nil {
 		return ToolResult{}, fmt.Errorf("parsing params: %w", err)
@@ -79,7 +111,7 @@ func (t *CronManageTool) Execute(_ context.Context, params json.RawMessage) (Too
 
 	switch p.Action {
 	case "create":
-		return t.create(p)
+		return t.create(ctx, p)
 	case "list":
 		return t.list()
 	case "pause":
@@ -90,12 +122,14 @@ func (t *CronManageTool) Execute(_ context.Context, params json.RawMessage) (Too
 		return t.delete(p.ID)
 	case "get":
 		return t.get(p.ID)
+	case "history":
+		return t.history(p.ID, p.Limit)
 	default:
-		return ToolResult{ForLLM: fmt.Sprintf("Unknown action: %s. Use: create, list, pause, resume, delete, get.", p.Action)}, nil
+		return ToolResult{ForLLM: fmt.Sprintf("Unknown action: %s. Use: create, list, pause, resume, delete, get, history.", p.Action)}, nil
 	}
 }
 
-func (t *CronManageTool) create(p cronManageParams) (ToolResult, error) {
+func (t *CronManageTool) create(ctx context.Context, p cronManageParams) (ToolResult, error) {
 	if p.Name == "" || p.Schedule == "" {
 		return ToolResult{ForLLM: "Error: name and schedule are required for create"}, nil
 	}
@@ -106,18 +140,48 @@ func (t *CronManageTool) create(p cronManageParams) (ToolResult, error) {
 		p.Params = "{}"
 	}
 
-	id, err := t.sched.Create(p.Name, p.Schedule, p.SkillName, p.Command, p.Params)
+	// Results go back to the chat that asked for the job, which runs within
+	// the limits of the asking user's role
+	channel, chatID := OriginFrom(ctx)
+	id, err := t.sched.CreateJob(scheduler.Job{
+		Name:      p.Name,
+		Schedule:  p.Schedule,
+		Timezone:  p.Timezone,
+		Kind:      p.Type,
+		SkillName: p.SkillName,
+		Command:   p.Command,
+		Params:    p.Params,
+		Channel:   channel,
+		ChatID:    chatID,
+		Timeout:   time.Duration(p.Timeout) * time.Second,
+		Misfire:   p.Misfire,
+		Role:      RoleFrom(ctx),
+	})
 	if err != nil {
 		return ToolResult{ForLLM: fmt.Sprintf("Error creating job: %v", err)}, nil
 	}
 
+	kind := scheduler.Job{Kind: p.Type, SkillName: p.SkillName, Schedule: p.Schedule}.JobKind()
 	label := "Scheduled"
-	if scheduler.IsOneShot(p.Schedule) {
+	if kind == scheduler.KindReminder {
 		label = "Reminder set"
 	}
+	schedule := p.Schedule
+	if p.Timezone != "" {
+		schedule += " " + p.Timezone
+	}
+
+	var next string
+	if runs, err := t.sched.NextRuns(p.Schedule, p.Timezone, cronPreviewRuns); err == nil {
+		var times []string
+		for _, r := range runs {
+			times = append(times, r.Format(cronTimeLayout))
+		}
+		next = "\nNext: " + strings.Join(times, ", ")
+	}
 	return ToolResult{
-		ForLLM:  fmt.Sprintf("Created (id=%d, name=%s, schedule=%s, command=%s)", id, p.Name, p.Schedule, p.Command),
-		ForUser: fmt.Sprintf("%s: %s (%s)", label, p.Name, p.Schedule),
+		ForLLM:  fmt.Sprintf("Created (id=%d, name=%s, type=%s, schedule=%s, command=%s)%s", id, p.Name, kind, schedule, p.Command, next),
+		ForUser: fmt.Sprintf("%s: %s (%s)%s", label, p.Name, schedule, next),
 	}, nil
 }
 
@@ -135,15 +199,17 @@ func (t *CronManageTool) list() (ToolResult, error) {
 	b.WriteString(fmt.Sprintf("Scheduled jobs (%d):\n", len(jobs)))
 	for _, j := range jobs {
 		status := "enabled"
-		if !j.Enabled {
+		if j.AutoPaused() {
+			status = "AUTO-PAUSED"
+		} else if !j.Enabled {
 			status = "PAUSED"
 		}
 		lastRun := "never"
 		if j.LastRun != nil {
 			lastRun = j.LastRun.Format("2006-01-02 15:04")
 		}
-		b.WriteString(fmt.Sprintf("\n[%d] %s — %s [%s]", j.ID, j.Name, j.Schedule, status))
-		b.WriteString(fmt.Sprintf("\n    last: %s | next: %s | fails: %d", lastRun, j.NextRun.Format("2006-01-02 15:04"), j.FailCount))
+		b.WriteString(fmt.Sprintf("\n[%d] %s (%s) — %s [%s]", j.ID, j.Name, j.JobKind(), jobSchedule(j), status))
+		b.WriteString(fmt.Sprintf("\n    last: %s | next: %s | fails: %d", lastRun, t.inJobZone(j, j.NextRun).Format(cronTimeLayout), j.FailCount))
 		if j.SkillName != "" {
 			b.WriteString(fmt.Sprintf("\n    skill: %s", j.SkillName))
 		}
@@ -196,13 +262,14 @@ func (t *CronManageTool) get(id int64) (ToolResult, error) {
 
 	var b strings.Builder
 	b.WriteString(fmt.Sprintf("Job #%d: %s\n", job.ID, job.Name))
-	b.WriteString(fmt.Sprintf("Schedule: %s\n", job.Schedule))
+	b.WriteString(fmt.Sprintf("Type: %s\n", job.JobKind()))
+	b.WriteString(fmt.Sprintf("Schedule: %s\n", jobSchedule(*job)))
 	b.WriteString(fmt.Sprintf("Enabled: %v\n", job.Enabled))
 	b.WriteString(fmt.Sprintf("Failures: %d\n", job.FailCount))
 	if job.LastRun != nil {
 		b.WriteString(fmt.Sprintf("Last run: %s\n", job.LastRun.Format(time.RFC3339)))
 	}
-	b.WriteString(fmt.Sprintf("Next run: %s\n", job.NextRun.Format(time.RFC3339)))
+	b.WriteString(fmt.Sprintf("Next run: %s\n", t.inJobZone(*job, job.NextRun).Format(time.RFC3339)))
 	if job.SkillName != "" {
 		b.WriteString(fmt.Sprintf("Skill: %s\n", job.SkillName))
 		b.WriteString(fmt.Sprintf("Params: %s\n", job.Params))
@@ -210,6 +277,90 @@ func (t *CronManageTool) get(id int64) (ToolResult, error) {
 	if job.Command != "" {
 		b.WriteString(fmt.Sprintf("Command: %s\n", job.Command))
 	}
+	if job.Timeout > 0 {
+		b.WriteString(fmt.Sprintf("Timeout: %v\n", job.Timeout))
+	}
+	if job.Channel != "" {
+		b.WriteString(fmt.Sprintf("Delivers to: %s %s\n", job.Channel, job.ChatID))
+	}
+	b.WriteString(fmt.Sprintf("Misfire: %s\n", job.MisfirePolicy()))
+	if job.AutoPaused() {
+		b.WriteString("Auto-paused after repeated failures; see history for the errors.\n")
+	}
+
+	return ToolResult{ForLLM: b.String()}, nil
+}
+
+func (t *CronManageTool) history(id int64, limit int) (ToolResult, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+	runs, err := t.sched.History(id, limit)
+	if err != nil {
+		return ToolResult{ForLLM: fmt.Sprintf("Error reading history: %v", err)}, nil
+	}
+
+	var b strings.Builder
+	if id > 0 {
+		job, err := t.sched.Get(id)
+		if err != nil {
+			return ToolResult{ForLLM: fmt.Sprintf("Error getting job: %v", err)}, nil
+		}
+		b.WriteString(fmt.Sprintf("Job #%d: %s — %d consecutive failures", job.ID, job.Name, job.FailCount))
+		if job.AutoPaused() {
+			b.WriteString(", auto-paused; resume it once the cause is fixed")
+		}
+		b.WriteString("\n")
+	}
+	if len(runs) == 0 {
+		b.WriteString("No runs recorded.")
+		return ToolResult{ForLLM: b.String()}, nil
+	}
 
+	for _, r := range runs {
+		b.WriteString(fmt.Sprintf("\n%s %s", r.StartedAt.Local().Format("2006-01-02 15:04:05"), r.Status))
+		if id == 0 {
+			b.WriteString(fmt.Sprintf(" [%d] %s", r.JobID, r.JobName))
+		}
+		if r.Trigger != scheduler.TriggerSchedule {
+			b.WriteString(" (" + r.Trigger + ")")
+		}
+		if d := r.Duration(); d > 0 {
+			b.WriteString(fmt.Sprintf(" in %v", d.Round(time.Millisecond)))
+		}
+		if r.Error != "" {
+			b.WriteString("\n    error: " + r.Error)
+		}
+		if r.Output != "" {
+			b.WriteString("\n    output: " + firstLines(r.Output, 3))
+		}
+	}
 	return ToolResult{ForLLM: b.String()}, nil
 }
+
+// firstLines returns up to n lines of s.
+func firstLines(s string, n int) string {
+	lines := strings.SplitN(strings.TrimSpace(s), "\n", n+1)
+	if len(lines) > n {
+		lines = append(lines[:n], "...")
+	}
+	return strings.Join(lines, "\n    ")
+}
+
+// jobSchedule formats a job's schedule with its timezone, if it has one.
+func jobSchedule(j scheduler.Job) string {
+	if j.Timezone != "" {
+		return j.Schedule + " (" + j.Timezone + ")"
+	}
+	return j.Schedule
+}
+
+// inJobZone converts a stored time to the job's timezone for display.
+func (t *CronManageTool) inJobZone(j scheduler.Job, ts time.Time) time.Time {
+	if j.Timezone != "" {
+		if loc, err := time.LoadLocation(j.Timezone); err == nil {
+			return ts.In(loc)
+		}
+	}
+	return ts.Local()
+}
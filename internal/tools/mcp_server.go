@@ -0,0 +1,133 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ImJafran/aeon/internal/audit"
+	"github.com/ImJafran/aeon/internal/mcp"
+)
+
+// MCPApprover asks a human to approve a tool call the security policy
+// flagged, and returns who answered (channel:user) for the audit log. It
+// returns ErrApprovalTimeout when nobody answers in time.
+type MCPApprover func(ctx context.Context, description string) (approved bool, approver string, err error)
+
+// ErrApprovalTimeout is returned when an approval request goes unanswered.
+var ErrApprovalTimeout = errors.New("approval timed out")
+
+// mcpServerExcluded are tools not exported by default: subagents report back
+// through a chat channel, and imported MCP tools would let two servers proxy
+// each other in a loop.
+var mcpServerExcluded = map[string]bool{"spawn_agent": true, "list_tasks": true}
+
+// MCPServerHandler exposes a registry to MCP clients. Calls run through
+// Registry.Execute, so parameter validation, timeouts and each tool's
+// security checks apply as they do for the agent loop.
+type MCPServerHandler struct {
+	registry *Registry
+	scrubber interface{ ScrubCredentials(string) string }
+	approve  MCPApprover
+	allow    map[string]bool
+}
+
+func NewMCPServerHandler(registry *Registry, scrubber interface{ ScrubCredentials(string) string }) *MCPServerHandler {
+	return &MCPServerHandler{registry: registry, scrubber: scrubber}
+}
+
+// SetApprover escalates calls that need approval. Without one they are rejected.
+func (h *MCPServerHandler) SetApprover(fn MCPApprover) {
+	h.approve = fn
+}
+
+// SetAllowedTools limits the exported tools to names. Empty exports all
+// tools except the default exclusions.
+func (h *MCPServerHandler) SetAllowedTools(names []string) {
+	h.allow = nil
+	if len(names) > 0 {
+		h.allow = make(map[string]bool, len(names))
+		for _, n := range names {
+			h.allow[n] = true
+		}
+	}
+}
+
+func (h *MCPServerHandler) exported(name string) bool {
+	if h.allow != nil {
+		return h.allow[name]
+	}
+	return !mcpServerExcluded[name] && !strings.HasPrefix(name, "mcp_")
+}
+
+// Tools lists the exported tools from Registry.ToolDefs.
+func (h *MCPServerHandler) Tools() []mcp.Tool {
+	var out []mcp.Tool
+	for _, def := range h.registry.ToolDefs() {
+		if !h.exported(def.Name) {
+			continue
+		}
+		schema, err := json.Marshal(def.Parameters)
+		if err != nil {
+			continue
+		}
+		out = append(out, mcp.Tool{Name: def.Name, Description: def.Description, InputSchema: schema})
+	}
+	return out
+}
+
+// CallTool executes a tool. A call that needs approval is escalated to the
+// approver and re-run with the approval bypass if granted; deny patterns
+// are still enforced on the second run.
+func (h *MCPServerHandler) CallTool(ctx context.Context, name string, args json.RawMessage) (*mcp.CallToolResult, error) {
+	if !h.exported(name) {
+		return mcpErrorResult(fmt.Sprintf("unknown tool: %s", name)), nil
+	}
+
+	result, err := h.registry.Execute(ctx, name, args)
+	if err != nil {
+		return nil, fmt.Errorf("%s", h.scrub(err.Error()))
+	}
+
+	if result.NeedsApproval {
+		if h.approve == nil {
+			return mcpErrorResult(h.scrub(fmt.Sprintf("REJECTED: this call needs human approval and no approval channel is configured.\n%s", result.ApprovalInfo))), nil
+		}
+		approved, approver, err := h.approve(ctx, h.scrub(result.ApprovalInfo))
+		if err != nil {
+			decision := audit.Rejected
+			if errors.Is(err, ErrApprovalTimeout) {
+				decision = audit.Timeout
+			}
+			h.registry.AuditApproval(ctx, name, args, decision, approver)
+			return mcpErrorResult(fmt.Sprintf("REJECTED: approval failed: %v", err)), nil
+		}
+		if !approved {
+			h.registry.AuditApproval(ctx, name, args, audit.Rejected, approver)
+			return mcpErrorResult("DENIED: the user denied this call."), nil
+		}
+		result, err = h.registry.Execute(WithApprovedBy(ctx, approver), name, args)
+		if err != nil {
+			return nil, fmt.Errorf("%s", h.scrub(err.Error()))
+		}
+	}
+
+	text := h.scrub(result.ForLLM)
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{{Type: "text", Text: text}},
+		IsError: result.IsError || strings.HasPrefix(text, "BLOCKED") || strings.HasPrefix(text, "Error:"),
+	}, nil
+}
+
+func (h *MCPServerHandler) scrub(s string) string {
+	if h.scrubber == nil {
+		return s
+	}
+	return h.scrubber.ScrubCredentials(s)
+}
+
+func mcpErrorResult(text string) *mcp.CallToolResult {
+	return &mcp.CallToolResult{Content: []mcp.Content{{Type: "text", Text: text}}, IsError: true}
+}
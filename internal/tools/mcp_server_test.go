@@ -0,0 +1,81 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// guardedTool needs approval unless the context carries it.
+type guardedTool struct{ runs int }
+
+func (g *guardedTool) Name() string                { return "guarded" }
+func (g *guardedTool) Description() string         { return "needs approval" }
+func (g *guardedTool) Parameters() json.RawMessage { return json.RawMessage(`{"type":"object"}`) }
+func (g *guardedTool) Execute(ctx context.Context, _ json.RawMessage) (ToolResult, error) {
+	if !isApproved(ctx) {
+		return ToolResult{ForLLM: "REQUIRES APPROVAL", NeedsApproval: true, ApprovalInfo: "Command: sudo reboot"}, nil
+	}
+	g.runs++
+	return ToolResult{ForLLM: "rebooted with token sk-secret"}, nil
+}
+
+type maskScrubber struct{}
+
+func (maskScrubber) ScrubCredentials(s string) string {
+	return strings.ReplaceAll(s, "sk-secret", "[REDACTED]")
+}
+
+func TestMCPServerHandlerExports(t *testing.T) {
+	reg := NewRegistry()
+	reg.Register(&mockTool{name: "shell_exec"})
+	reg.Register(&mockTool{name: "spawn_agent"})
+	reg.Register(&mockTool{name: "mcp_github_search"})
+
+	h := NewMCPServerHandler(reg, nil)
+	tools := h.Tools()
+	if len(tools) != 1 || tools[0].Name != "shell_exec" || len(tools[0].InputSchema) == 0 {
+		t.Fatalf("expected only shell_exec with its schema, got %+v", tools)
+	}
+	if res, _ := h.CallTool(context.Background(), "spawn_agent", nil); !res.IsError {
+		t.Error("excluded tools must not be callable")
+	}
+
+	h.SetAllowedTools([]string{"spawn_agent"})
+	if tools := h.Tools(); len(tools) != 1 || tools[0].Name != "spawn_agent" {
+		t.Fatalf("allow-list not applied: %+v", tools)
+	}
+}
+
+func TestMCPServerHandlerApproval(t *testing.T) {
+	tool := &guardedTool{}
+	reg := NewRegistry()
+	reg.Register(tool)
+	h := NewMCPServerHandler(reg, maskScrubber{})
+	ctx := context.Background()
+
+	res, err := h.CallTool(ctx, "guarded", json.RawMessage(`{}`))
+	if err != nil || !res.IsError || !strings.Contains(res.Content[0].Text, "REJECTED") {
+		t.Fatalf("expected rejection without an approver, got %+v, %v", res, err)
+	}
+
+	var asked string
+	h.SetApprover(func(_ context.Context, description string) (bool, string, error) {
+		asked = description
+		return false, "", nil
+	})
+	res, _ = h.CallTool(ctx, "guarded", json.RawMessage(`{}`))
+	if !res.IsError || !strings.Contains(res.Content[0].Text, "DENIED") || asked != "Command: sudo reboot" {
+		t.Fatalf("expected denial after asking, got %+v (asked %q)", res, asked)
+	}
+
+	h.SetApprover(func(context.Context, string) (bool, string, error) { return true, "telegram:1", nil })
+	res, _ = h.CallTool(ctx, "guarded", json.RawMessage(`{}`))
+	if res.IsError || tool.runs != 1 {
+		t.Fatalf("expected the approved call to run once, got %+v (runs %d)", res, tool.runs)
+	}
+	if strings.Contains(res.Content[0].Text, "sk-secret") {
+		t.Errorf("output not scrubbed: %s", res.Content[0].Text)
+	}
+}
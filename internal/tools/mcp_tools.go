@@ -0,0 +1,474 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"log/slog"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/ImJafran/aeon/internal/mcp"
+)
+
+// maxMCPOutputLen caps text returned by MCP tools, resources and prompts.
+const maxMCPOutputLen = 30000
+
+// MCPSecurity is the subset of the security policy applied to MCP calls.
+type MCPSecurity interface {
+	CheckCommand(command string) (int, string) // 0=allowed, 1=denied, 2=needs_approval
+	CheckPath(path string) (int, string)       // 0=allowed, 1=denied
+}
+
+// ---- MCP tool adapter ----
+
+// MCPTool exposes one MCP server tool as an Aeon tool named mcp_<server>_<tool>.
+type MCPTool struct {
+	name     string
+	client   *mcp.Client
+	tool     mcp.Tool
+	security MCPSecurity
+}
+
+var invalidToolNameChars = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// mcpToolName builds a registry name that every provider accepts
+// (^[a-zA-Z0-9_-]{1,64}$), hashing the tail of names that are too long.
+func mcpToolName(server, tool string) string {
+	name := invalidToolNameChars.ReplaceAllString("mcp_"+server+"_"+tool, "_")
+	if len(name) <= 64 {
+		return name
+	}
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	return fmt.Sprintf("%s_%08x", name[:55], h.Sum32())
+}
+
+func (t *MCPTool) Name() string { return t.name }
+func (t *MCPTool) Description() string {
+	desc := t.tool.Description
+	if desc == "" {
+		desc = t.tool.Title
+	}
+	return fmt.Sprintf("[MCP %s] %s", t.client.Name(), desc)
+}
+func (t *MCPTool) Parameters() json.RawMessage {
+	if len(t.tool.InputSchema) == 0 {
+		return json.RawMessage(`{"type":"object","properties":{}}`)
+	}
+	return t.tool.InputSchema
+}
+
+func (t *MCPTool) Execute(ctx context.Context, params json.RawMessage) (ToolResult, error) {
+	if blocked, ok := checkMCPArgs(ctx, t.security, params); !ok {
+		return blocked, nil
+	}
+
+	res, err := t.client.CallTool(ctx, t.tool.Name, params)
+	if err != nil {
+		return ToolResult{}, err
+	}
+
+	out := formatMCPContent(res.Content)
+	if out == "" && len(res.StructuredContent) > 0 {
+		out = string(res.StructuredContent)
+	}
+	if res.IsError {
+		out = "Error: " + out
+	}
+	return ToolResult{ForLLM: truncateMCP(out)}, nil
+}
+
+// commandArgKeys and pathArgKeys name arguments that get the same checks
+// shell_exec and the file tools apply.
+var (
+	commandArgKeys = map[string]bool{"command": true, "cmd": true, "script": true}
+	pathArgKeys    = map[string]bool{"file": true, "filename": true, "directory": true, "dir": true}
+)
+
+// checkMCPArgs runs the command deny-list and path containment checks on any
+// argument that holds a shell command or file path, at any depth. It returns
+// false and a result for the model when the call must not go ahead.
+func checkMCPArgs(ctx context.Context, sec MCPSecurity, params json.RawMessage) (ToolResult, bool) {
+	if sec == nil || len(params) == 0 {
+		return ToolResult{}, true
+	}
+	var args any
+	if err := json.Unmarshal(params, &args); err != nil {
+		return ToolResult{}, true
+	}
+
+	var result ToolResult
+	ok := true
+	var walk func(key string, v any)
+	walk = func(key string, v any) {
+		if !ok {
+			return
+		}
+		switch v := v.(type) {
+		case map[string]any:
+			for k, child := range v {
+				walk(strings.ToLower(k), child)
+			}
+		case []any:
+			for _, child := range v {
+				walk(key, child)
+			}
+		case string:
+			switch {
+			case commandArgKeys[key]:
+				decision, reason := sec.CheckCommand(v)
+				switch {
+				case decision == 1:
+					result, ok = ToolResult{ForLLM: fmt.Sprintf("BLOCKED: %s", reason)}, false
+				case decision == 2 && !isApproved(ctx):
+					result, ok = ToolResult{
+						ForLLM:        fmt.Sprintf("REQUIRES APPROVAL: %s\nCommand: %s", reason, v),
+						ForUser:       fmt.Sprintf("⚠️ Command requires approval: %s\nReason: %s", v, reason),
+						NeedsApproval: true,
+						ApprovalInfo:  fmt.Sprintf("Command: %s\nReason: %s", v, reason),
+					}, false
+				}
+			case pathArgKeys[key] || strings.Contains(key, "path"):
+				if decision, reason := sec.CheckPath(v); decision != 0 {
+					result, ok = ToolResult{ForLLM: fmt.Sprintf("BLOCKED: %s", reason)}, false
+				}
+			}
+		}
+	}
+	walk("", args)
+	return result, ok
+}
+
+// formatMCPContent renders tool or prompt content as text for the model.
+func formatMCPContent(content []mcp.Content) string {
+	var parts []string
+	for _, c := range content {
+		switch c.Type {
+		case "text":
+			parts = append(parts, c.Text)
+		case "image", "audio":
+			parts = append(parts, fmt.Sprintf("[%s content, %s, %d bytes base64]", c.Type, c.MimeType, len(c.Data)))
+		case "resource":
+			if c.Resource != nil {
+				parts = append(parts, formatResourceContents(*c.Resource))
+			}
+		case "resource_link":
+			parts = append(parts, fmt.Sprintf("[resource: %s %s]", c.URI, c.Name))
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+func formatResourceContents(rc mcp.ResourceContents) string {
+	if rc.Text != "" {
+		return rc.Text
+	}
+	return fmt.Sprintf("[binary resource %s, %s, %d bytes base64]", rc.URI, rc.MimeType, len(rc.Blob))
+}
+
+// truncateMCP caps s at maxMCPOutputLen bytes, backing up to a rune
+// boundary so the cut leaves valid UTF-8.
+func truncateMCP(s string) string {
+	if len(s) <= maxMCPOutputLen {
+		return s
+	}
+	cut := maxMCPOutputLen
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + "\n... [output truncated]"
+}
+
+// ---- registry sync ----
+
+// MCPBridge keeps a server's tools registered in a registry, replacing them
+// when the server reconnects or reports that its tool list changed.
+type MCPBridge struct {
+	registry *Registry
+	client   *mcp.Client
+	security MCPSecurity
+	logger   *slog.Logger
+
+	mu    sync.Mutex
+	names []string
+}
+
+// NewMCPBridge wires client to registry. Tools are synced every time the
+// client (re)connects.
+func NewMCPBridge(registry *Registry, client *mcp.Client, security MCPSecurity, logger *slog.Logger) *MCPBridge {
+	b := &MCPBridge{registry: registry, client: client, security: security, logger: logger}
+	client.OnToolsChanged(func() {
+		if err := b.Sync(context.Background()); err != nil {
+			logger.Warn("mcp tool sync failed", "mcp_server", client.Name(), "error", err)
+		}
+	})
+	return b
+}
+
+// Sync lists the server's tools and updates the registry to match.
+func (b *MCPBridge) Sync(ctx context.Context) error {
+	list, err := b.client.ListTools(ctx)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	current := make(map[string]bool, len(list))
+	var names []string
+	for _, tool := range list {
+		name := mcpToolName(b.client.Name(), tool.Name)
+		if current[name] {
+			continue
+		}
+		current[name] = true
+		names = append(names, name)
+		b.registry.Register(&MCPTool{name: name, client: b.client, tool: tool, security: b.security})
+	}
+	for _, name := range b.names {
+		if !current[name] {
+			b.registry.Deregister(name)
+		}
+	}
+	b.names = names
+
+	b.logger.Info("mcp tools registered", "mcp_server", b.client.Name(), "count", len(names))
+	return nil
+}
+
+// ---- mcp_resources ----
+
+// MCPResourcesTool lists and reads resources across all MCP servers.
+type MCPResourcesTool struct {
+	clients  []*mcp.Client
+	security MCPSecurity
+}
+
+func NewMCPResources(clients []*mcp.Client, security MCPSecurity) *MCPResourcesTool {
+	return &MCPResourcesTool{clients: clients, security: security}
+}
+
+func (t *MCPResourcesTool) Name() string { return "mcp_resources" }
+func (t *MCPResourcesTool) Description() string {
+	return "List or read resources (files, records, documents) exposed by connected MCP servers."
+}
+func (t *MCPResourcesTool) Parameters() json.RawMessage {
+	return json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"action": {
+				"type": "string",
+				"enum": ["list", "read"],
+				"description": "list resources, or read one by URI"
+			},
+			"server": {
+				"type": "string",
+				"description": "MCP server name (optional for list; for read, needed only if several servers have resources)"
+			},
+			"uri": {
+				"type": "string",
+				"description": "Resource URI to read"
+			}
+		},
+		"required": ["action"]
+	}`)
+}
+
+type mcpResourcesParams struct {
+	Action string `json:"action"`
+	Server string `json:"server"`
+	URI    string `json:"uri"`
+}
+
+func (t *MCPResourcesTool) Execute(ctx context.Context, params json.RawMessage) (ToolResult, error) {
+	var p mcpResourcesParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return ToolResult{}, fmt.Errorf("parsing params: %w", err)
+	}
+
+	clients := selectMCPClients(t.clients, p.Server, func(c mcp.ServerCapabilities) bool { return c.Resources != nil })
+	if len(clients) == 0 {
+		return ToolResult{ForLLM: "No connected MCP server offers resources."}, nil
+	}
+
+	switch p.Action {
+	case "list":
+		var b strings.Builder
+		for _, c := range clients {
+			list, err := c.ListResources(ctx)
+			if err != nil {
+				fmt.Fprintf(&b, "%s: error: %v\n", c.Name(), err)
+				continue
+			}
+			for _, r := range list {
+				fmt.Fprintf(&b, "%s: %s — %s", c.Name(), r.URI, r.Name)
+				if r.MimeType != "" {
+					fmt.Fprintf(&b, " (%s)", r.MimeType)
+				}
+				if r.Description != "" {
+					fmt.Fprintf(&b, ": %s", r.Description)
+				}
+				b.WriteByte('\n')
+			}
+		}
+		if b.Len() == 0 {
+			return ToolResult{ForLLM: "No resources available."}, nil
+		}
+		return ToolResult{ForLLM: truncateMCP(b.String())}, nil
+
+	case "read":
+		if p.URI == "" {
+			return ToolResult{ForLLM: "Error: uri is required for read"}, nil
+		}
+		if len(clients) > 1 {
+			return ToolResult{ForLLM: "Error: several MCP servers offer resources; set server"}, nil
+		}
+		if u, err := url.Parse(p.URI); err == nil && u.Scheme == "file" && t.security != nil {
+			if decision, reason := t.security.CheckPath(u.Path); decision != 0 {
+				return ToolResult{ForLLM: fmt.Sprintf("BLOCKED: %s", reason)}, nil
+			}
+		}
+		contents, err := clients[0].ReadResource(ctx, p.URI)
+		if err != nil {
+			return ToolResult{}, err
+		}
+		var parts []string
+		for _, rc := range contents {
+			parts = append(parts, formatResourceContents(rc))
+		}
+		return ToolResult{ForLLM: truncateMCP(strings.Join(parts, "\n"))}, nil
+	}
+	return ToolResult{ForLLM: "Error: action must be list or read"}, nil
+}
+
+// ---- mcp_prompts ----
+
+// MCPPromptsTool lists and renders prompt templates across all MCP servers.
+type MCPPromptsTool struct {
+	clients []*mcp.Client
+}
+
+func NewMCPPrompts(clients []*mcp.Client) *MCPPromptsTool {
+	return &MCPPromptsTool{clients: clients}
+}
+
+func (t *MCPPromptsTool) Name() string { return "mcp_prompts" }
+func (t *MCPPromptsTool) Description() string {
+	return "List or get prompt templates offered by connected MCP servers. Getting a prompt returns its rendered messages."
+}
+func (t *MCPPromptsTool) Parameters() json.RawMessage {
+	return json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"action": {
+				"type": "string",
+				"enum": ["list", "get"],
+				"description": "list prompts, or get one rendered"
+			},
+			"server": {
+				"type": "string",
+				"description": "MCP server name (optional for list; for get, needed only if several servers have prompts)"
+			},
+			"name": {
+				"type": "string",
+				"description": "Prompt name to get"
+			},
+			"arguments": {
+				"type": "object",
+				"description": "Prompt arguments as string values"
+			}
+		},
+		"required": ["action"]
+	}`)
+}
+
+type mcpPromptsParams struct {
+	Action    string            `json:"action"`
+	Server    string            `json:"server"`
+	Name      string            `json:"name"`
+	Arguments map[string]string `json:"arguments"`
+}
+
+func (t *MCPPromptsTool) Execute(ctx context.Context, params json.RawMessage) (ToolResult, error) {
+	var p mcpPromptsParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return ToolResult{}, fmt.Errorf("parsing params: %w", err)
+	}
+
+	clients := selectMCPClients(t.clients, p.Server, func(c mcp.ServerCapabilities) bool { return c.Prompts != nil })
+	if len(clients) == 0 {
+		return ToolResult{ForLLM: "No connected MCP server offers prompts."}, nil
+	}
+
+	switch p.Action {
+	case "list":
+		var b strings.Builder
+		for _, c := range clients {
+			list, err := c.ListPrompts(ctx)
+			if err != nil {
+				fmt.Fprintf(&b, "%s: error: %v\n", c.Name(), err)
+				continue
+			}
+			for _, pr := range list {
+				fmt.Fprintf(&b, "%s: %s", c.Name(), pr.Name)
+				if pr.Description != "" {
+					fmt.Fprintf(&b, " — %s", pr.Description)
+				}
+				for _, a := range pr.Arguments {
+					req := ""
+					if a.Required {
+						req = ", required"
+					}
+					fmt.Fprintf(&b, "\n    %s (%s%s)", a.Name, a.Description, req)
+				}
+				b.WriteByte('\n')
+			}
+		}
+		if b.Len() == 0 {
+			return ToolResult{ForLLM: "No prompts available."}, nil
+		}
+		return ToolResult{ForLLM: truncateMCP(b.String())}, nil
+
+	case "get":
+		if p.Name == "" {
+			return ToolResult{ForLLM: "Error: name is required for get"}, nil
+		}
+		if len(clients) > 1 {
+			return ToolResult{ForLLM: "Error: several MCP servers offer prompts; set server"}, nil
+		}
+		res, err := clients[0].GetPrompt(ctx, p.Name, p.Arguments)
+		if err != nil {
+			return ToolResult{}, err
+		}
+		var b strings.Builder
+		if res.Description != "" {
+			fmt.Fprintf(&b, "%s\n\n", res.Description)
+		}
+		for _, m := range res.Messages {
+			fmt.Fprintf(&b, "[%s] %s\n", m.Role, formatMCPContent([]mcp.Content{m.Content}))
+		}
+		return ToolResult{ForLLM: truncateMCP(b.String())}, nil
+	}
+	return ToolResult{ForLLM: "Error: action must be list or get"}, nil
+}
+
+// selectMCPClients returns the connected clients that have a capability,
+// limited to the named server if one is given.
+func selectMCPClients(clients []*mcp.Client, server string, has func(mcp.ServerCapabilities) bool) []*mcp.Client {
+	var out []*mcp.Client
+	for _, c := range clients {
+		if server != "" && c.Name() != server {
+			continue
+		}
+		if c.Connected() && has(c.Capabilities()) {
+			out = append(out, c)
+		}
+	}
+	return out
+}
@@ -0,0 +1,182 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/ImJafran/aeon/internal/mcp"
+)
+
+type mockMCPSecurity struct{}
+
+func (mockMCPSecurity) CheckCommand(command string) (int, string) {
+	switch {
+	case strings.Contains(command, "rm -rf"):
+		return 1, "destructive command"
+	case strings.Contains(command, "sudo"):
+		return 2, "privilege escalation"
+	}
+	return 0, ""
+}
+
+func (mockMCPSecurity) CheckPath(path string) (int, string) {
+	if strings.HasPrefix(path, "/etc") {
+		return 1, "path outside workspace"
+	}
+	return 0, ""
+}
+
+// newMCPTestServer serves a minimal streamable HTTP MCP server whose tool
+// list grows by one "second" tool once withSecond is set.
+func newMCPTestServer(t *testing.T, withSecond *atomic.Bool, calls *atomic.Int32) *mcp.Client {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		var req struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.ID == nil {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+
+		var result string
+		switch req.Method {
+		case "initialize":
+			result = `{"protocolVersion":"2025-06-18","capabilities":{"tools":{}},"serverInfo":{"name":"test"}}`
+		case "tools/list":
+			result = `{"tools":[{"name":"run.script","description":"Run a script","inputSchema":{"type":"object"}}`
+			if withSecond.Load() {
+				result += `,{"name":"second"}`
+			}
+			result += `]}`
+		case "tools/call":
+			calls.Add(1)
+			result = `{"content":[{"type":"text","text":"done"},{"type":"image","mimeType":"image/png","data":"AAAA"}]}`
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"jsonrpc":"2.0","id":` + string(req.ID) + `,"result":` + result + `}`))
+	}))
+	t.Cleanup(ts.Close)
+
+	c := mcp.NewHTTPClient("test", ts.URL, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	t.Cleanup(func() { c.Close() })
+	if err := c.Connect(context.Background()); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	return c
+}
+
+func TestMCPToolName(t *testing.T) {
+	if got := mcpToolName("git-hub", "create.issue"); got != "mcp_git-hub_create_issue" {
+		t.Errorf("unexpected name %q", got)
+	}
+
+	long := mcpToolName("server", strings.Repeat("x", 80))
+	if len(long) != 64 {
+		t.Errorf("expected 64 chars, got %d: %s", len(long), long)
+	}
+	if other := mcpToolName("server", strings.Repeat("x", 79)+"y"); other == long {
+		t.Error("long names sharing a prefix should not collide")
+	}
+}
+
+func TestCheckMCPArgs(t *testing.T) {
+	sec := mockMCPSecurity{}
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		args     string
+		ok       bool
+		approval bool
+	}{
+		{"plain args", `{"query":"weather"}`, true, false},
+		{"denied command", `{"command":"rm -rf /"}`, false, false},
+		{"nested path", `{"options":{"outputPath":"/etc/passwd"}}`, false, false},
+		{"path list", `{"files":[{"file":"/etc/shadow"}]}`, false, false},
+		{"needs approval", `{"cmd":"sudo reboot"}`, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, ok := checkMCPArgs(ctx, sec, json.RawMessage(tt.args))
+			if ok != tt.ok {
+				t.Fatalf("expected ok=%v, got %v (%s)", tt.ok, ok, result.ForLLM)
+			}
+			if result.NeedsApproval != tt.approval {
+				t.Errorf("expected NeedsApproval=%v, got %v", tt.approval, result.NeedsApproval)
+			}
+		})
+	}
+
+	if _, ok := checkMCPArgs(WithApproved(ctx), sec, json.RawMessage(`{"cmd":"sudo reboot"}`)); !ok {
+		t.Error("approved context should allow a command that needs approval")
+	}
+}
+
+func TestTruncateMCP(t *testing.T) {
+	out := truncateMCP(strings.Repeat("é", maxMCPOutputLen))
+	if !utf8.ValidString(out) || !strings.HasSuffix(out, "[output truncated]") {
+		t.Errorf("expected a valid truncated string, got %d bytes ending %q", len(out), out[len(out)-30:])
+	}
+	if len(out) > maxMCPOutputLen+len("\n... [output truncated]") {
+		t.Errorf("truncated output too long: %d bytes", len(out))
+	}
+}
+
+func TestMCPBridgeSync(t *testing.T) {
+	var withSecond atomic.Bool
+	var calls atomic.Int32
+	client := newMCPTestServer(t, &withSecond, &calls)
+
+	reg := NewRegistry()
+	bridge := NewMCPBridge(reg, client, mockMCPSecurity{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	ctx := context.Background()
+
+	if err := bridge.Sync(ctx); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	if _, ok := reg.Get("mcp_test_run_script"); !ok {
+		t.Fatal("expected mcp_test_run_script to be registered")
+	}
+
+	withSecond.Store(true)
+	bridge.Sync(ctx)
+	if reg.Count() != 2 {
+		t.Fatalf("expected 2 tools after the list grew, got %d", reg.Count())
+	}
+
+	withSecond.Store(false)
+	bridge.Sync(ctx)
+	if _, ok := reg.Get("mcp_test_second"); ok {
+		t.Error("removed tool should be deregistered")
+	}
+
+	result, err := reg.Execute(ctx, "mcp_test_run_script", json.RawMessage(`{"script":"ls"}`))
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if !strings.Contains(result.ForLLM, "done") || !strings.Contains(result.ForLLM, "[image content, image/png") {
+		t.Errorf("unexpected output: %s", result.ForLLM)
+	}
+
+	result, _ = reg.Execute(ctx, "mcp_test_run_script", json.RawMessage(`{"script":"rm -rf /"}`))
+	if !strings.HasPrefix(result.ForLLM, "BLOCKED") {
+		t.Errorf("expected BLOCKED, got: %s", result.ForLLM)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("blocked call should not reach the server, got %d calls", calls.Load())
+	}
+}
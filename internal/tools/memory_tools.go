@@ -41,6 +41,15 @@ func (t *MemoryStoreTool) Parameters() json.RawMessage {
 			"importance": {
 				"type": "number",
 				"description": "Importance score 0.0-1.0. Higher = persists longer. Corrections=0.9, lessons=0.85, core=0.8, facts=0.5, casual=0.3. Auto-set from category if omitted."
+			},
+			"supersedes": {
+				"type": "integer",
+				"description": "ID of an existing memory this one replaces, e.g. a stale fact being corrected. The old memory is retired into this one's history."
+			},
+			"scope": {
+				"type": "string",
+				"enum": ["user", "chat", "global"],
+				"description": "Who can recall it: 'user' (only the person you are talking to), 'chat' (everyone in this chat or team channel), 'global' (everyone). Omit for the configured default, normally 'user'. Use 'global' only for facts about the system itself."
 			}
 		},
 		"required": ["content"]
@@ -52,6 +61,8 @@ type memoryStoreParams struct {
 	Category   string  `json:"category"`
 	Tags       string  `json:"tags"`
 	Importance float64 `json:"importance"`
+	Supersedes int64   `json:"supersedes"`
+	Scope      string  `json:"scope"`
 }
 
 func (t *MemoryStoreTool) Execute(ctx context.Context, params json.RawMessage) (ToolResult, error) {
@@ -64,31 +75,72 @@ func (t *MemoryStoreTool) Execute(ctx context.Context, params json.RawMessage) (
 		return ToolResult{ForLLM: "Error: content is required"}, nil
 	}
 
-	category := memory.CategoryCustom
-	switch p.Category {
-	case "core":
-		category = memory.CategoryCore
-	case "daily":
-		category = memory.CategoryDaily
-	case "conversation":
-		category = memory.CategoryConversation
-	case "lesson":
-		category = memory.CategoryLesson
-	case "correction":
-		category = memory.CategoryCorrection
+	category := parseCategory(p.Category)
+	if category == "" {
+		category = memory.CategoryCustom
 	}
-
-	id, err := t.store.MemStore(ctx, category, p.Content, p.Tags, p.Importance)
+	if p.Scope != "" && !memory.ValidKind(p.Scope) {
+		return ToolResult{ForLLM: fmt.Sprintf("Error: unknown scope %q", p.Scope)}, nil
+	}
+	scope := t.store.ScopeFor(ctx, p.Scope)
+	if p.Supersedes > 0 {
+		old, err := t.store.Get(ctx, p.Supersedes)
+		if err != nil {
+			return ToolResult{ForLLM: fmt.Sprintf("Error: memory %d not found", p.Supersedes)}, nil
+		}
+		scope = old.Scope // a correction stays where the memory it replaces was
+	}
+	id, err := t.store.Save(ctx, memory.Entry{
+		Category:   category,
+		Content:    p.Content,
+		Tags:       p.Tags,
+		Importance: p.Importance,
+		Supersedes: p.Supersedes,
+		Source:     provenance(ctx),
+		Scope:      scope,
+	})
 	if err != nil {
 		return ToolResult{ForLLM: fmt.Sprintf("Error storing memory: %v", err)}, nil
 	}
 
+	if p.Supersedes > 0 {
+		return ToolResult{
+			ForLLM: fmt.Sprintf("Memory stored (id=%d, category=%s, scope=%s), replacing memory %d", id, category, scope.Kind(), p.Supersedes),
+			Silent: true,
+		}, nil
+	}
 	return ToolResult{
-		ForLLM: fmt.Sprintf("Memory stored (id=%d, category=%s)", id, category),
+		ForLLM: fmt.Sprintf("Memory stored (id=%d, category=%s, scope=%s)", id, category, scope.Kind()),
 		Silent: true,
 	}, nil
 }
 
+// parseCategory maps a category name to a Category, or "" if unknown.
+func parseCategory(name string) memory.Category {
+	switch name {
+	case "core":
+		return memory.CategoryCore
+	case "daily":
+		return memory.CategoryDaily
+	case "conversation":
+		return memory.CategoryConversation
+	case "lesson":
+		return memory.CategoryLesson
+	case "correction":
+		return memory.CategoryCorrection
+	case "custom":
+		return memory.CategoryCustom
+	}
+	return ""
+}
+
+// provenance describes the turn a memory tool was called from.
+func provenance(ctx context.Context) memory.Provenance {
+	channel, _ := OriginFrom(ctx)
+	session, user, message := SourceFrom(ctx)
+	return memory.Provenance{Session: session, Channel: channel, UserID: user, Message: message}
+}
+
 // ---- memory_recall ----
 
 type MemoryRecallTool struct {
@@ -156,7 +208,11 @@ func (t *MemoryRecallTool) Execute(ctx context.Context, params json.RawMessage)
 	var b strings.Builder
 	b.WriteString(fmt.Sprintf("Found %d memories:\n", len(entries)))
 	for _, e := range entries {
-		b.WriteString(fmt.Sprintf("\n[%d] (%s) %s", e.ID, e.Category, e.Content))
+		if kind := e.Scope.Kind(); kind != memory.KindGlobal {
+			b.WriteString(fmt.Sprintf("\n[%d] (%s, %s) %s", e.ID, e.Category, kind, e.Content))
+		} else {
+			b.WriteString(fmt.Sprintf("\n[%d] (%s) %s", e.ID, e.Category, e.Content))
+		}
 		if e.Tags != "" {
 			b.WriteString(fmt.Sprintf(" [tags: %s]", e.Tags))
 		}
@@ -164,3 +220,144 @@ func (t *MemoryRecallTool) Execute(ctx context.Context, params json.RawMessage)
 
 	return ToolResult{ForLLM: b.String(), Silent: true}, nil
 }
+
+// ---- memory_update ----
+
+type MemoryUpdateTool struct {
+	store *memory.Store
+}
+
+func NewMemoryUpdate(store *memory.Store) *MemoryUpdateTool {
+	return &MemoryUpdateTool{store: store}
+}
+
+func (t *MemoryUpdateTool) Name() string { return "memory_update" }
+func (t *MemoryUpdateTool) Description() string {
+	return "Edit a stored memory in place. The previous version is kept in its history."
+}
+func (t *MemoryUpdateTool) Parameters() json.RawMessage {
+	return json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"id": {
+				"type": "integer",
+				"description": "ID of the memory to edit (from memory_recall)"
+			},
+			"content": {
+				"type": "string",
+				"description": "New content (omit to keep)"
+			},
+			"category": {
+				"type": "string",
+				"enum": ["core", "daily", "conversation", "custom", "lesson", "correction"],
+				"description": "New category (omit to keep)"
+			},
+			"tags": {
+				"type": "string",
+				"description": "New comma-separated tags (omit to keep)"
+			},
+			"importance": {
+				"type": "number",
+				"description": "New importance 0.0-1.0 (omit to keep)"
+			}
+		},
+		"required": ["id"]
+	}`)
+}
+
+type memoryUpdateParams struct {
+	ID         int64   `json:"id"`
+	Content    string  `json:"content"`
+	Category   string  `json:"category"`
+	Tags       string  `json:"tags"`
+	Importance float64 `json:"importance"`
+}
+
+func (t *MemoryUpdateTool) Execute(ctx context.Context, params json.RawMessage) (ToolResult, error) {
+	var p memoryUpdateParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return ToolResult{}, fmt.Errorf("parsing params: %w", err)
+	}
+
+	if p.ID <= 0 {
+		return ToolResult{ForLLM: "Error: id is required"}, nil
+	}
+	if p.Content == "" && p.Category == "" && p.Tags == "" && p.Importance <= 0 {
+		return ToolResult{ForLLM: "Error: nothing to change; give content, category, tags or importance"}, nil
+	}
+	category := parseCategory(p.Category)
+	if p.Category != "" && category == "" {
+		return ToolResult{ForLLM: fmt.Sprintf("Error: unknown category %q", p.Category)}, nil
+	}
+	if _, err := t.store.Get(ctx, p.ID); err != nil {
+		return ToolResult{ForLLM: fmt.Sprintf("Error: memory %d not found", p.ID)}, nil
+	}
+
+	version, err := t.store.Update(ctx, p.ID, memory.Change{
+		Category:   category,
+		Content:    p.Content,
+		Tags:       p.Tags,
+		Importance: p.Importance,
+		Source:     provenance(ctx),
+	})
+	if err != nil {
+		return ToolResult{ForLLM: fmt.Sprintf("Error updating memory: %v", err)}, nil
+	}
+
+	return ToolResult{
+		ForLLM: fmt.Sprintf("Memory %d updated (version %d)", p.ID, version),
+		Silent: true,
+	}, nil
+}
+
+// ---- memory_forget ----
+
+type MemoryForgetTool struct {
+	store *memory.Store
+}
+
+func NewMemoryForget(store *memory.Store) *MemoryForgetTool {
+	return &MemoryForgetTool{store: store}
+}
+
+func (t *MemoryForgetTool) Name() string { return "memory_forget" }
+func (t *MemoryForgetTool) Description() string {
+	return "Delete a stored memory that is wrong or no longer wanted. To correct a fact, prefer memory_store with supersedes."
+}
+func (t *MemoryForgetTool) Parameters() json.RawMessage {
+	return json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"id": {
+				"type": "integer",
+				"description": "ID of the memory to delete (from memory_recall)"
+			}
+		},
+		"required": ["id"]
+	}`)
+}
+
+func (t *MemoryForgetTool) Execute(ctx context.Context, params json.RawMessage) (ToolResult, error) {
+	var p struct {
+		ID int64 `json:"id"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return ToolResult{}, fmt.Errorf("parsing params: %w", err)
+	}
+
+	if p.ID <= 0 {
+		return ToolResult{ForLLM: "Error: id is required"}, nil
+	}
+	e, err := t.store.Get(ctx, p.ID)
+	if err != nil {
+		return ToolResult{ForLLM: fmt.Sprintf("Error: memory %d not found", p.ID)}, nil
+	}
+	if err := t.store.Forget(ctx, p.ID); err != nil {
+		return ToolResult{ForLLM: fmt.Sprintf("Error deleting memory: %v", err)}, nil
+	}
+
+	return ToolResult{
+		ForLLM: fmt.Sprintf("Memory %d forgotten: %s", p.ID, e.Content),
+		Silent: true,
+	}, nil
+}
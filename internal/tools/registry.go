@@ -6,9 +6,11 @@ import (
 	"fmt"
 	"log/slog"
 	"sort"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/ImJafran/aeon/internal/audit"
 	"github.com/ImJafran/aeon/internal/providers"
 )
 
@@ -28,6 +30,20 @@ type ToolResult struct {
 	Silent        bool
 	NeedsApproval bool   // If true, the tool execution needs human approval before proceeding
 	ApprovalInfo  string // Description of what needs approval
+	IsError       bool   // The tool ran but the operation failed (blocked, non-zero exit, timed out)
+	ExitCode      *int   // Exit status of the command the tool ran, for the audit log; nil if none
+}
+
+// ToolPolicy decides tool calls by the rules of the security policy file.
+type ToolPolicy interface {
+	// CheckTool returns "allow", "deny", "approve" or "audit" and a reason,
+	// or empty strings when no rule applies.
+	CheckTool(name string, params json.RawMessage, channel, userID string) (decision, reason string)
+}
+
+// Auditor appends tool calls to the audit log.
+type Auditor interface {
+	Record(ctx context.Context, e audit.Entry) (audit.Entry, error)
 }
 
 type Registry struct {
@@ -35,6 +51,8 @@ type Registry struct {
 	mu             sync.RWMutex
 	defaultTimeout time.Duration
 	logger         *slog.Logger
+	policy         ToolPolicy
+	auditor        Auditor
 }
 
 func NewRegistry() *Registry {
@@ -54,6 +72,17 @@ func (r *Registry) SetDefaultTimeout(d time.Duration) {
 	r.defaultTimeout = d
 }
 
+// SetPolicy checks every call against the policy file's rules before it runs.
+func (r *Registry) SetPolicy(p ToolPolicy) {
+	r.policy = p
+}
+
+// SetAuditor records every call Execute makes, and approvals that were
+// refused, in the audit log.
+func (r *Registry) SetAuditor(a Auditor) {
+	r.auditor = a
+}
+
 func (r *Registry) Register(tool Tool) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -74,16 +103,31 @@ func (r *Registry) Get(name string) (Tool, bool) {
 }
 
 func (r *Registry) Execute(ctx context.Context, name string, params json.RawMessage) (ToolResult, error) {
+	start := time.Now()
+	result, err := r.execute(ctx, name, params)
+	r.Audit(ctx, name, params, result, err, time.Since(start))
+	return result, err
+}
+
+func (r *Registry) execute(ctx context.Context, name string, params json.RawMessage) (ToolResult, error) {
 	tool, ok := r.Get(name)
 	if !ok {
 		return ToolResult{}, fmt.Errorf("tool not found: %s", name)
 	}
+	if role, ok := toolAllowed(ctx, name); !ok {
+		return ToolResult{ForLLM: fmt.Sprintf("BLOCKED: the %s role may not use %s", role, name), IsError: true}, nil
+	}
 
 	// Validate parameters before execution
 	if err := ValidateParams(tool.Parameters(), params); err != nil {
 		return ToolResult{ForLLM: fmt.Sprintf("Parameter validation error: %v", err)}, nil
 	}
 
+	ctx, blocked := r.Authorize(ctx, name, params)
+	if blocked != nil {
+		return *blocked, nil
+	}
+
 	start := time.Now()
 
 	// Enforce timeout: run tool in goroutine with deadline
@@ -126,11 +170,105 @@ func (r *Registry) Execute(ctx context.Context, name string, params json.RawMess
 			)
 		}
 		return ToolResult{
-			ForLLM: fmt.Sprintf("Tool %q timed out after %v. The operation may still be running in the background.", name, timeout),
+			ForLLM:  fmt.Sprintf("Tool %q timed out after %v. The operation may still be running in the background.", name, timeout),
+			IsError: true,
 		}, nil
 	}
 }
 
+// Authorize applies the policy file's rules to a call without running it.
+// It returns the context to run the call with, or the result to return
+// instead when a rule denies the call or wants it approved first. A rule
+// allowing the call waives the tool's own approval prompts, not its blocks.
+func (r *Registry) Authorize(ctx context.Context, name string, params json.RawMessage) (context.Context, *ToolResult) {
+	if r.policy == nil {
+		return ctx, nil
+	}
+	channel, _ := OriginFrom(ctx)
+	_, userID, _ := SourceFrom(ctx)
+	decision, reason := r.policy.CheckTool(name, params, channel, userID)
+	switch decision {
+	case "deny":
+		return ctx, &ToolResult{ForLLM: fmt.Sprintf("BLOCKED: %s", reason), IsError: true}
+	case "approve":
+		if isApproved(ctx) {
+			break
+		}
+		return ctx, &ToolResult{
+			ForLLM:        fmt.Sprintf("REQUIRES APPROVAL: %s\nTool: %s", reason, name),
+			ForUser:       fmt.Sprintf("⚠️ %s requires approval\nReason: %s", name, reason),
+			NeedsApproval: true,
+			ApprovalInfo:  fmt.Sprintf("Tool: %s\nArguments: %s\nReason: %s", name, params, reason),
+		}
+	case "allow":
+		return WithApproved(ctx), nil
+	case "audit":
+		if r.logger != nil {
+			r.logger.Info("tool_audit",
+				"tool", name,
+				"channel", channel,
+				"user", userID,
+				"params", string(params),
+				"rule", reason,
+			)
+		}
+	}
+	return ctx, nil
+}
+
+// Audit records a call and its outcome in the audit log, working out the
+// decision from the result: blocked, held for approval, or run, with or
+// without a person's approval. Execute audits every call; callers that run
+// a tool directly audit it themselves.
+func (r *Registry) Audit(ctx context.Context, name string, params json.RawMessage, result ToolResult, err error, took time.Duration) {
+	if r.auditor == nil {
+		return
+	}
+	e := audit.Entry{
+		Tool:       name,
+		Args:       string(params),
+		Decision:   audit.Allowed,
+		DurationMS: took.Milliseconds(),
+		ExitCode:   result.ExitCode,
+		IsError:    result.IsError || err != nil,
+	}
+	switch {
+	case err != nil:
+		e.Reason = err.Error()
+		if _, ok := r.Get(name); !ok {
+			e.Decision = audit.Denied
+		}
+	case result.NeedsApproval:
+		e.Decision, e.Reason = audit.Pending, result.ApprovalInfo
+	case strings.HasPrefix(result.ForLLM, "BLOCKED: "):
+		e.Decision, e.Reason = audit.Denied, strings.TrimPrefix(result.ForLLM, "BLOCKED: ")
+	case strings.HasPrefix(result.ForLLM, "Parameter validation error"):
+		e.Decision, e.Reason = audit.Denied, result.ForLLM
+	case approverFrom(ctx) != "":
+		e.Decision, e.Approver = audit.Approved, approverFrom(ctx)
+	}
+	if e.Decision == audit.Allowed || e.Decision == audit.Approved {
+		e.ResultHash = audit.HashResult(result.ForLLM)
+	}
+	r.record(ctx, e)
+}
+
+// AuditApproval records an approval that was refused: decision is
+// audit.Rejected or audit.Timeout, approver who answered, if anyone.
+func (r *Registry) AuditApproval(ctx context.Context, name string, params json.RawMessage, decision, approver string) {
+	if r.auditor != nil {
+		r.record(ctx, audit.Entry{Tool: name, Args: string(params), Decision: decision, Approver: approver})
+	}
+}
+
+func (r *Registry) record(ctx context.Context, e audit.Entry) {
+	e.Channel, e.ChatID = OriginFrom(ctx)
+	_, e.UserID, _ = SourceFrom(ctx)
+	if _, err := r.auditor.Record(ctx, e); err != nil && r.logger != nil {
+		r.logger.Error("audit record failed", "tool", e.Tool, "decision", e.Decision, "error", err)
+	}
+}
+
 // ToolDefs returns sorted tool definitions for provider (sorted for KV cache stability).
 func (r *Registry) ToolDefs() []providers.ToolDef {
 	r.mu.RLock()
@@ -154,8 +292,103 @@ func (r *Registry) ToolDefs() []providers.ToolDef {
 	return defs
 }
 
+// ToolDefsFor is ToolDefs without the tools ctx's WithToolFilter rules out.
+func (r *Registry) ToolDefsFor(ctx context.Context) []providers.ToolDef {
+	defs := r.ToolDefs()
+	allowed := defs[:0]
+	for _, def := range defs {
+		if _, ok := toolAllowed(ctx, def.Name); ok {
+			allowed = append(allowed, def)
+		}
+	}
+	return allowed
+}
+
 func (r *Registry) Count() int {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 	return len(r.tools)
 }
+
+// originContextKey carries the channel and chat a tool call came from.
+type originContextKey struct{}
+
+type origin struct{ channel, chatID string }
+
+// WithOrigin records the channel and chat a tool call came from, so tools
+// that act later, like scheduled jobs, know where to report back.
+func WithOrigin(ctx context.Context, channel, chatID string) context.Context {
+	return context.WithValue(ctx, originContextKey{}, origin{channel, chatID})
+}
+
+// OriginFrom returns the channel and chat recorded by WithOrigin, or empty strings.
+func OriginFrom(ctx context.Context) (channel, chatID string) {
+	o, _ := ctx.Value(originContextKey{}).(origin)
+	return o.channel, o.chatID
+}
+
+// toolFilterContextKey carries the tools a user's role allows.
+type toolFilterContextKey struct{}
+
+type toolFilter struct {
+	role  string
+	allow func(name string) bool
+}
+
+// WithToolFilter limits the calls made with ctx, and the tools ToolDefsFor
+// lists, to those allow accepts. role names the limit in refusals.
+func WithToolFilter(ctx context.Context, role string, allow func(name string) bool) context.Context {
+	return context.WithValue(ctx, toolFilterContextKey{}, toolFilter{role, allow})
+}
+
+// toolAllowed reports whether ctx may use the tool, and the role limiting it.
+func toolAllowed(ctx context.Context, name string) (string, bool) {
+	f, ok := ctx.Value(toolFilterContextKey{}).(toolFilter)
+	if !ok {
+		return "", true
+	}
+	return f.role, f.allow(name)
+}
+
+// ToolAllowed reports whether ctx's WithToolFilter lets it use the tool.
+func ToolAllowed(ctx context.Context, name string) bool {
+	_, ok := toolAllowed(ctx, name)
+	return ok
+}
+
+// RoleFrom returns the role WithToolFilter limits ctx to, or "" if none.
+func RoleFrom(ctx context.Context) string {
+	f, _ := ctx.Value(toolFilterContextKey{}).(toolFilter)
+	return f.role
+}
+
+// approverContextKey carries who approved a call.
+type approverContextKey struct{}
+
+// WithApprovedBy is WithApproved for a call a person approved; approver,
+// as channel:userID, goes in the audit log.
+func WithApprovedBy(ctx context.Context, approver string) context.Context {
+	return context.WithValue(WithApproved(ctx), approverContextKey{}, approver)
+}
+
+func approverFrom(ctx context.Context) string {
+	a, _ := ctx.Value(approverContextKey{}).(string)
+	return a
+}
+
+// sourceContextKey carries the session, user and message behind a tool call.
+type sourceContextKey struct{}
+
+type source struct{ sessionID, userID, message string }
+
+// WithSource records the session, user and message that led to a tool call,
+// so memory tools can store where a memory came from.
+func WithSource(ctx context.Context, sessionID, userID, message string) context.Context {
+	return context.WithValue(ctx, sourceContextKey{}, source{sessionID, userID, message})
+}
+
+// SourceFrom returns what WithSource recorded, or empty strings.
+func SourceFrom(ctx context.Context) (sessionID, userID, message string) {
+	s, _ := ctx.Value(sourceContextKey{}).(source)
+	return s.sessionID, s.userID, s.message
+}
@@ -3,7 +3,10 @@ package tools
 import (
 	"context"
 	"encoding/json"
+	"strings"
 	"testing"
+
+	"github.com/ImJafran/aeon/internal/audit"
 )
 
 type mockTool struct {
@@ -58,3 +61,137 @@ func TestRegistryExecuteNotFound(t *testing.T) {
 		t.Fatal("expected error for nonexistent tool")
 	}
 }
+
+// mockPolicy decides by tool name and records who made the call.
+type mockPolicy struct {
+	decisions       map[string]string
+	channel, userID string
+}
+
+func (m *mockPolicy) CheckTool(name string, _ json.RawMessage, channel, userID string) (string, string) {
+	m.channel, m.userID = channel, userID
+	return m.decisions[name], "rule for " + name
+}
+
+// approvalTool reports whether it ran with the approval bypass.
+type approvalTool struct{ mockTool }
+
+func (a *approvalTool) Execute(ctx context.Context, _ json.RawMessage) (ToolResult, error) {
+	if isApproved(ctx) {
+		return ToolResult{ForLLM: "approved"}, nil
+	}
+	return ToolResult{ForLLM: "not approved"}, nil
+}
+
+func TestRegistryPolicy(t *testing.T) {
+	policy := &mockPolicy{decisions: map[string]string{"denied": "deny", "gated": "approve", "trusted": "allow", "watched": "audit"}}
+	r := NewRegistry()
+	r.SetPolicy(policy)
+	for _, name := range []string{"denied", "gated", "trusted", "watched", "other"} {
+		r.Register(&approvalTool{mockTool{name: name}})
+	}
+	ctx := WithSource(WithOrigin(context.Background(), "telegram", "100"), "s1", "42", "hi")
+
+	result, _ := r.Execute(ctx, "denied", nil)
+	if !result.IsError || result.ForLLM != "BLOCKED: rule for denied" {
+		t.Errorf("deny: unexpected result %+v", result)
+	}
+	if policy.channel != "telegram" || policy.userID != "42" {
+		t.Errorf("policy saw channel %q user %q", policy.channel, policy.userID)
+	}
+
+	result, _ = r.Execute(ctx, "gated", json.RawMessage(`{}`))
+	if !result.NeedsApproval || !strings.Contains(result.ApprovalInfo, "Tool: gated") {
+		t.Errorf("approve: unexpected result %+v", result)
+	}
+	if result, _ = r.Execute(WithApproved(ctx), "gated", nil); result.ForLLM != "approved" {
+		t.Errorf("approve: expected the approved call to run, got %+v", result)
+	}
+
+	for name, want := range map[string]string{"trusted": "approved", "watched": "not approved", "other": "not approved"} {
+		if result, _ = r.Execute(ctx, name, nil); result.ForLLM != want {
+			t.Errorf("%s: got %q, want %q", name, result.ForLLM, want)
+		}
+	}
+}
+
+// mockAuditor keeps the entries it's given.
+type mockAuditor struct{ entries []audit.Entry }
+
+func (m *mockAuditor) Record(_ context.Context, e audit.Entry) (audit.Entry, error) {
+	m.entries = append(m.entries, e)
+	return e, nil
+}
+
+func TestRegistryAudit(t *testing.T) {
+	auditor := &mockAuditor{}
+	r := NewRegistry()
+	r.SetPolicy(&mockPolicy{decisions: map[string]string{"denied": "deny", "gated": "approve"}})
+	r.SetAuditor(auditor)
+	for _, name := range []string{"denied", "gated", "plain"} {
+		r.Register(&approvalTool{mockTool{name: name}})
+	}
+	ctx := WithSource(WithOrigin(context.Background(), "telegram", "100"), "s1", "42", "hi")
+	args := json.RawMessage(`{"x":1}`)
+
+	r.Execute(ctx, "plain", args)
+	r.Execute(ctx, "denied", args)
+	r.Execute(ctx, "gated", args)
+	r.Execute(WithApprovedBy(ctx, "telegram:7"), "gated", args)
+	r.AuditApproval(ctx, "gated", args, audit.Timeout, "")
+	r.Execute(ctx, "missing", args)
+
+	want := []struct{ tool, decision, approver string }{
+		{"plain", audit.Allowed, ""},
+		{"denied", audit.Denied, ""},
+		{"gated", audit.Pending, ""},
+		{"gated", audit.Approved, "telegram:7"},
+		{"gated", audit.Timeout, ""},
+		{"missing", audit.Denied, ""},
+	}
+	if len(auditor.entries) != len(want) {
+		t.Fatalf("expected %d entries, got %+v", len(want), auditor.entries)
+	}
+	for i, w := range want {
+		e := auditor.entries[i]
+		if e.Tool != w.tool || e.Decision != w.decision || e.Approver != w.approver {
+			t.Errorf("entry %d: got %s %s by %q, want %s %s by %q", i, e.Tool, e.Decision, e.Approver, w.tool, w.decision, w.approver)
+		}
+		if e.Channel != "telegram" || e.ChatID != "100" || e.UserID != "42" || e.Args != `{"x":1}` {
+			t.Errorf("entry %d: origin or arguments not recorded: %+v", i, e)
+		}
+	}
+	if e := auditor.entries[0]; e.ResultHash != audit.HashResult("not approved") {
+		t.Errorf("expected the result hashed, got %q", e.ResultHash)
+	}
+	if e := auditor.entries[1]; e.ResultHash != "" || e.Reason != "rule for denied" {
+		t.Errorf("expected a denied call to keep its reason and no result, got %+v", e)
+	}
+}
+
+func TestRegistryToolFilter(t *testing.T) {
+	r := NewRegistry()
+	r.Register(&mockTool{name: "file_read"})
+	r.Register(&mockTool{name: "file_write"})
+	auditor := &mockAuditor{}
+	r.SetAuditor(auditor)
+
+	ctx := WithToolFilter(context.Background(), "viewer", func(name string) bool { return name == "file_read" })
+	if defs := r.ToolDefsFor(ctx); len(defs) != 1 || defs[0].Name != "file_read" {
+		t.Errorf("expected only file_read listed, got %+v", defs)
+	}
+	if defs := r.ToolDefsFor(context.Background()); len(defs) != 2 {
+		t.Errorf("expected every tool listed without a filter, got %d", len(defs))
+	}
+
+	result, err := r.Execute(ctx, "file_write", json.RawMessage(`{}`))
+	if err != nil || !result.IsError || result.ForLLM != "BLOCKED: the viewer role may not use file_write" {
+		t.Errorf("expected file_write blocked, got %+v, %v", result, err)
+	}
+	if e := auditor.entries[len(auditor.entries)-1]; e.Decision != audit.Denied {
+		t.Errorf("expected the refusal audited as denied, got %+v", e)
+	}
+	if result, _ := r.Execute(ctx, "file_read", json.RawMessage(`{}`)); result.IsError {
+		t.Errorf("expected file_read allowed, got %+v", result)
+	}
+}
@@ -9,6 +9,8 @@ import (
 	"strings"
 	"syscall"
 	"time"
+
+	"github.com/ImJafran/aeon/internal/sandbox"
 )
 
 const maxOutputLen = 10000
@@ -37,6 +39,7 @@ func isApproved(ctx context.Context) bool {
 type ShellExecTool struct {
 	timeout  time.Duration
 	security SecurityChecker
+	sandbox  *sandbox.Options // nil runs commands unconfined
 }
 
 func NewShellExec() *ShellExecTool {
@@ -47,6 +50,12 @@ func (t *ShellExecTool) SetSecurity(s SecurityChecker) {
 	t.security = s
 }
 
+// SetSandbox runs every command in a sandbox with opts. Commands fail rather
+// than run unconfined when the sandbox can't be set up.
+func (t *ShellExecTool) SetSandbox(opts *sandbox.Options) {
+	t.sandbox = opts
+}
+
 func (t *ShellExecTool) Name() string        { return "shell_exec" }
 func (t *ShellExecTool) Description() string { return "Execute a shell command and return the output." }
 func (t *ShellExecTool) Parameters() json.RawMessage {
@@ -86,7 +95,7 @@ func (t *ShellExecTool) Execute(ctx context.Context, params json.RawMessage) (To
 		decision, reason := t.security.CheckCommand(p.Command)
 		switch decision {
 		case 1: // Denied — always blocked
-			return ToolResult{ForLLM: fmt.Sprintf("BLOCKED: %s", reason)}, nil
+			return ToolResult{ForLLM: fmt.Sprintf("BLOCKED: %s", reason), IsError: true}, nil
 		case 2: // NeedsApproval — skip if user already approved
 			if !isApproved(ctx) {
 				return ToolResult{
@@ -114,9 +123,19 @@ func (t *ShellExecTool) Execute(ctx context.Context, params json.RawMessage) (To
 
 	// Set process group so we can kill the entire tree
 	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	// Kill the whole group on cancel, or a child holding stdout open keeps Run waiting
+	cmd.Cancel = func() error { return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL) }
 
 	// Inherit full environment — the agent needs full system access.
 	// Credential scrubbing on output prevents leaking secrets to conversation history.
+	// A sandbox narrows both, see SetSandbox.
+	var sb *sandbox.Sandbox
+	if t.sandbox != nil {
+		var err error
+		if sb, err = sandbox.Wrap(cmd, *t.sandbox); err != nil {
+			return ToolResult{ForLLM: fmt.Sprintf("Error: sandbox unavailable, command not run: %v", err), IsError: true}, nil
+		}
+	}
 
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
@@ -128,6 +147,7 @@ func (t *ShellExecTool) Execute(ctx context.Context, params json.RawMessage) (To
 	if ctx.Err() == context.DeadlineExceeded && cmd.Process != nil {
 		syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
 	}
+	limitNote := sb.Finish()
 
 	output := stdout.String()
 	errOutput := stderr.String()
@@ -157,11 +177,19 @@ func (t *ShellExecTool) Execute(ctx context.Context, params json.RawMessage) (To
 			exitCode = exitErr.ExitCode()
 		} else if ctx.Err() == context.DeadlineExceeded {
 			return ToolResult{
-				ForLLM: fmt.Sprintf("Command timed out after %v\n%s", timeout, result.String()),
+				ForLLM:  fmt.Sprintf("Command timed out after %v\n%s", timeout, result.String()),
+				IsError: true,
 			}, nil
 		} else {
-			return ToolResult{ForLLM: fmt.Sprintf("Error: %v\n%s", err, result.String())}, nil
+			return ToolResult{ForLLM: fmt.Sprintf("Error: %v\n%s", err, result.String()), IsError: true}, nil
+		}
+	}
+
+	if limitNote != "" {
+		if result.Len() > 0 {
+			result.WriteString("\n")
 		}
+		result.WriteString("[sandbox] " + limitNote)
 	}
 
 	resultStr := result.String()
@@ -171,5 +199,5 @@ func (t *ShellExecTool) Execute(ctx context.Context, params json.RawMessage) (To
 		resultStr = fmt.Sprintf("Exit code %d:\n%s", exitCode, resultStr)
 	}
 
-	return ToolResult{ForLLM: resultStr}, nil
+	return ToolResult{ForLLM: resultStr, IsError: exitCode != 0, ExitCode: &exitCode}, nil
 }
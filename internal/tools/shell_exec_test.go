@@ -3,10 +3,19 @@ package tools
 import (
 	"context"
 	"encoding/json"
+	"os"
 	"strings"
 	"testing"
+
+	"github.com/ImJafran/aeon/internal/sandbox"
 )
 
+// Sandboxed commands re-execute the test binary as the sandbox's init.
+func TestMain(m *testing.M) {
+	sandbox.Init()
+	os.Exit(m.Run())
+}
+
 func TestShellExecBasic(t *testing.T) {
 	tool := NewShellExec()
 
@@ -45,3 +54,21 @@ func TestShellExecNonZeroExit(t *testing.T) {
 		t.Errorf("expected exit code in output, got: %s", result.ForLLM)
 	}
 }
+
+func TestShellExecSandboxed(t *testing.T) {
+	if err := sandbox.Check(sandbox.Options{}); err != nil {
+		t.Skipf("sandbox unavailable: %v", err)
+	}
+	tool := NewShellExec()
+	tool.SetSandbox(&sandbox.Options{})
+	t.Setenv("AEON_TEST_TOKEN", "s3cret")
+
+	params, _ := json.Marshal(shellExecParams{Command: `echo "[$AEON_TEST_TOKEN]"; touch /etc/aeon-test || echo read-only`})
+	result, err := tool.Execute(context.Background(), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(result.ForLLM, "[]") || !strings.Contains(result.ForLLM, "read-only") {
+		t.Errorf("expected a filtered environment and read-only root, got: %s", result.ForLLM)
+	}
+}